@@ -9,8 +9,10 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
+	"donegeon/internal/auth"
 	"donegeon/internal/ops"
 )
 
@@ -36,6 +38,16 @@ func main() {
 			fmt.Fprintln(os.Stderr, "drill failed:", err)
 			os.Exit(1)
 		}
+	case "blueprints":
+		if err := cmdBlueprints(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "blueprints failed:", err)
+			os.Exit(1)
+		}
+	case "account":
+		if err := cmdAccount(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "account failed:", err)
+			os.Exit(1)
+		}
 	default:
 		printUsage()
 		os.Exit(2)
@@ -115,6 +127,112 @@ func cmdDrill(args []string) error {
 	return nil
 }
 
+func cmdBlueprints(args []string) error {
+	if len(args) < 1 || args[0] != "load" {
+		return fmt.Errorf("unknown blueprints command (want load)")
+	}
+	fs := flag.NewFlagSet("blueprints load", flag.ContinueOnError)
+	dataDir := fs.String("data-dir", "data", "path to data directory")
+	email := fs.String("email", "", "owner's email (the account is created if missing)")
+	userID := fs.String("user", "", "owner's user ID (instead of --email)")
+	team := fs.Bool("team", false, "also share each blueprint into the owner's team library")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("at least one blueprint file or directory is required")
+	}
+
+	id, err := resolveUser(*dataDir, *email, *userID)
+	if err != nil {
+		return err
+	}
+	teamOwnerID := ""
+	if *team {
+		teamOwnerID = id
+	}
+
+	results, err := ops.LoadBlueprintPacks(*dataDir, id, teamOwnerID, fs.Args())
+	for _, res := range results {
+		fmt.Printf("%s: %d blueprint(s)\n", res.Path, len(res.Blueprints))
+	}
+	return err
+}
+
+func cmdAccount(args []string) error {
+	if len(args) < 1 || (args[0] != "export" && args[0] != "import") {
+		return fmt.Errorf("unknown account command (want export or import)")
+	}
+	fs := flag.NewFlagSet("account "+args[0], flag.ContinueOnError)
+	dataDir := fs.String("data-dir", "data", "path to data directory")
+	email := fs.String("email", "", "account email (import creates the account if missing)")
+	userID := fs.String("user", "", "account user ID (instead of --email)")
+	out := fs.String("out", "", "output archive path (export)")
+	archive := fs.String("archive", "", "input archive path (import)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if args[0] == "export" && strings.TrimSpace(*email) != "" && strings.TrimSpace(*userID) == "" {
+		// Exporting never creates an account.
+		authRepo, err := auth.NewFileRepo(filepath.Join(*dataDir, "auth"))
+		if err != nil {
+			return err
+		}
+		u, ok := authRepo.GetUserByEmail(strings.ToLower(strings.TrimSpace(*email)))
+		if !ok {
+			return fmt.Errorf("no account for %s", *email)
+		}
+		*userID = u.ID
+	}
+	id, err := resolveUser(*dataDir, *email, *userID)
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "export" {
+		if *out == "" {
+			ts := time.Now().UTC().Format("20060102T150405Z")
+			*out = filepath.Join("exports", "donegeon-account-"+id+"-"+ts+".tar.gz")
+		}
+		if err := ops.ExportAccount(*dataDir, id, *out); err != nil {
+			return err
+		}
+		fmt.Println(*out)
+		return nil
+	}
+
+	if *archive == "" {
+		return fmt.Errorf("--archive is required")
+	}
+	sum, err := ops.ImportAccount(*dataDir, id, *archive)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %s into %s: %d task(s), %d board(s), %d blueprint(s), %d plugin(s)\n",
+		sum.Manifest.UserID, id, sum.Tasks, sum.Boards, sum.Blueprints, sum.Plugins)
+	return nil
+}
+
+// resolveUser returns userID, or the ID of the account for email, creating
+// it if missing.
+func resolveUser(dataDir, email, userID string) (string, error) {
+	if id := strings.TrimSpace(userID); id != "" {
+		return id, nil
+	}
+	if strings.TrimSpace(email) == "" {
+		return "", fmt.Errorf("--email or --user is required")
+	}
+	authRepo, err := auth.NewFileRepo(filepath.Join(dataDir, "auth"))
+	if err != nil {
+		return "", err
+	}
+	u, _, err := authRepo.GetOrCreateUser(strings.ToLower(strings.TrimSpace(email)), time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	return u.ID, nil
+}
+
 func dirDigest(root string) (string, error) {
 	root = filepath.Clean(root)
 	entries := []string{}
@@ -157,4 +275,7 @@ func printUsage() {
 	fmt.Println("  donegeon-ops backup  --data-dir data --out backups/backup.tar.gz")
 	fmt.Println("  donegeon-ops restore --archive backups/backup.tar.gz --target-dir data-restored")
 	fmt.Println("  donegeon-ops drill   --data-dir data --work-dir /tmp")
+	fmt.Println("  donegeon-ops blueprints load --data-dir data --email you@example.com [--team] packs/")
+	fmt.Println("  donegeon-ops account export --data-dir data --email you@example.com --out account.tar.gz")
+	fmt.Println("  donegeon-ops account import --data-dir data --email you@example.com --archive account.tar.gz")
 }
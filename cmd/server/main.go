@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"donegeon/internal/config"
+	"donegeon/internal/mailin"
 	"donegeon/internal/serverapp"
 )
 
@@ -14,17 +15,32 @@ func main() {
 		log.Fatalf("load config: %v", err)
 	}
 
+	// The SMTP listener for email-to-task is off unless DONEGEON_SMTP_ADDR
+	// is set.
+	var mailServer *mailin.Server
+	if mailCfg, ok := mailin.ConfigFromEnv(); ok {
+		mailServer = mailin.NewServer(mailCfg, log.Default())
+		log.Printf("accepting mail on %s", mailCfg.Addr)
+	}
+
 	handler, err := serverapp.NewHandler(serverapp.Options{
 		Config:        cfg,
 		DataDir:       "data",
 		StaticDir:     "static",
 		UseDiskStatic: serverapp.UseDiskStaticByEnv(),
 		Logger:        log.Default(),
+		Mail:          mailServer,
 	})
 	if err != nil {
 		log.Fatalf("build server: %v", err)
 	}
 
+	if mailServer != nil {
+		go func() {
+			log.Fatalf("smtp listener: %v", mailServer.ListenAndServe())
+		}()
+	}
+
 	addr := ":42069"
 	log.Printf("listening on http://localhost%s", addr)
 	log.Fatal(http.ListenAndServe(addr, handler))
@@ -278,6 +278,110 @@ func TestServer_BoardTaskRoundTripAndCalendarExport(t *testing.T) {
 	}
 }
 
+func TestServer_TeamMembersCommentOnSharedTasks(t *testing.T) {
+	app := newTestApp(t)
+	app.loginAndOnboard(t, "owner@example.com")
+
+	createRes := app.json(http.MethodPost, "/api/tasks", map[string]any{"title": "Plan offsite"})
+	if createRes.Code != http.StatusCreated {
+		t.Fatalf("create task expected 201, got %d body=%s", createRes.Code, createRes.Body.String())
+	}
+	taskID := asString(t, decodeBodyMap(t, createRes)["id"])
+
+	inviteRes := app.json(http.MethodPost, "/api/player/team/invite", map[string]any{"email": "mate@example.com"})
+	if inviteRes.Code != http.StatusOK {
+		t.Fatalf("invite expected 200, got %d body=%s", inviteRes.Code, inviteRes.Body.String())
+	}
+	ownerComment := app.json(http.MethodPost, "/api/tasks/"+taskID+"/comments", map[string]any{"body": "Venue **booked**"})
+	if ownerComment.Code != http.StatusCreated {
+		t.Fatalf("owner comment expected 201, got %d body=%s", ownerComment.Code, ownerComment.Body.String())
+	}
+	ownerCommentID := asString(t, decodeBodyMap(t, ownerComment)["id"])
+
+	app.cookies = map[string]*http.Cookie{}
+	app.loginAndOnboard(t, "mate@example.com")
+
+	if res := app.json(http.MethodPost, "/api/tasks/"+taskID+"/comments", map[string]any{"body": "too early"}); res.Code != http.StatusNotFound {
+		t.Fatalf("comment before accepting the invite expected 404, got %d body=%s", res.Code, res.Body.String())
+	}
+	invitesRes := app.request(http.MethodGet, "/api/player/team/invitations", nil, "")
+	var invites []map[string]any
+	if err := json.Unmarshal(invitesRes.Body.Bytes(), &invites); err != nil || len(invites) != 1 {
+		t.Fatalf("expected one invitation, got %d body=%s", invitesRes.Code, invitesRes.Body.String())
+	}
+	acceptRes := app.json(http.MethodPost, "/api/player/team/accept", map[string]any{"ownerId": invites[0]["ownerId"]})
+	if acceptRes.Code != http.StatusOK {
+		t.Fatalf("accept expected 200, got %d body=%s", acceptRes.Code, acceptRes.Body.String())
+	}
+
+	mateComment := app.json(http.MethodPost, "/api/tasks/"+taskID+"/comments", map[string]any{"body": "I'll bring snacks"})
+	if mateComment.Code != http.StatusCreated {
+		t.Fatalf("teammate comment expected 201, got %d body=%s", mateComment.Code, mateComment.Body.String())
+	}
+	if got := asString(t, decodeBodyMap(t, mateComment)["authorName"]); got != "Integration User" {
+		t.Fatalf("expected teammate display name as author, got %q", got)
+	}
+	deleteRes := app.request(http.MethodDelete, "/api/tasks/"+taskID+"/comments/"+ownerCommentID, nil, "")
+	if deleteRes.Code != http.StatusForbidden {
+		t.Fatalf("teammate deleting owner comment expected 403, got %d body=%s", deleteRes.Code, deleteRes.Body.String())
+	}
+	if res := app.request(http.MethodGet, "/api/tasks/"+taskID, nil, ""); res.Code != http.StatusNotFound {
+		t.Fatalf("teammate should not read the task itself, got %d", res.Code)
+	}
+
+	app.cookies = map[string]*http.Cookie{}
+	app.loginAndOnboard(t, "owner@example.com")
+	listRes := app.request(http.MethodGet, "/api/tasks", nil, "")
+	if !strings.Contains(listRes.Body.String(), `"commentCount":2`) {
+		t.Fatalf("expected comment count in list response, got %s", listRes.Body.String())
+	}
+}
+
+func TestServer_TaskWritesCreateProjectEntities(t *testing.T) {
+	app := newTestApp(t)
+	app.loginAndOnboard(t, "projects@example.com")
+
+	createRes := app.json(http.MethodPost, "/api/tasks", map[string]any{"title": "Plant bulbs", "project": "Garden"})
+	if createRes.Code != http.StatusCreated {
+		t.Fatalf("create task expected 201, got %d body=%s", createRes.Code, createRes.Body.String())
+	}
+	taskID := asString(t, decodeBodyMap(t, createRes)["id"])
+	if res := app.json(http.MethodPatch, "/api/tasks/"+taskID, map[string]any{"project": "Errands"}); res.Code != http.StatusOK {
+		t.Fatalf("patch task expected 200, got %d body=%s", res.Code, res.Body.String())
+	}
+
+	if res := app.json(http.MethodPost, "/api/board/cmd", map[string]any{
+		"cmd":  "board.seed_default",
+		"args": map[string]any{"deckRowY": 560},
+	}); res.Code != http.StatusOK {
+		t.Fatalf("board seed expected 200, got %d body=%s", res.Code, res.Body.String())
+	}
+	blankRes := app.json(http.MethodPost, "/api/board/cmd", map[string]any{
+		"cmd":  "task.create_blank",
+		"args": map[string]any{"x": 320, "y": 260},
+	})
+	if blankRes.Code != http.StatusOK {
+		t.Fatalf("task.create_blank expected 200, got %d body=%s", blankRes.Code, blankRes.Body.String())
+	}
+	cardID := asString(t, asMap(t, asMap(t, decodeBodyMap(t, blankRes)["patch"])["card"])["id"])
+	if res := app.json(http.MethodPost, "/api/board/cmd", map[string]any{
+		"cmd":  "task.set_project",
+		"args": map[string]any{"taskCardId": cardID, "project": "Workshop"},
+	}); res.Code != http.StatusOK {
+		t.Fatalf("task.set_project expected 200, got %d body=%s", res.Code, res.Body.String())
+	}
+
+	listRes := app.request(http.MethodGet, "/api/projects", nil, "")
+	if listRes.Code != http.StatusOK {
+		t.Fatalf("list projects expected 200, got %d body=%s", listRes.Code, listRes.Body.String())
+	}
+	for _, name := range []string{"Garden", "Errands", "Workshop"} {
+		if !strings.Contains(listRes.Body.String(), `"name":"`+name+`"`) {
+			t.Fatalf("expected project %q to exist, got %s", name, listRes.Body.String())
+		}
+	}
+}
+
 type testApp struct {
 	handler http.Handler
 	logs    *bytes.Buffer
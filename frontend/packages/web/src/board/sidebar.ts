@@ -259,7 +259,8 @@ function collectBoardSnapshot(engine: Engine): BoardSnapshot {
 }
 
 async function fetchTasksAll(): Promise<TaskDTO[]> {
-  const res = await fetch("/api/tasks?status=all");
+  // Only the fields the sidebar stats read; keeps large histories cheap.
+  const res = await fetch("/api/tasks?status=all&fields=done,updatedAt,assignedVillagerId");
   if (!res.ok) throw new Error(`GET /api/tasks failed: ${res.status}`);
   return (await res.json()) as TaskDTO[];
 }
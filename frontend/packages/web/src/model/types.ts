@@ -47,8 +47,15 @@ type OpenDeckOpts = {
 }
 
 type RecurrenceDTO = {
-    type: "daily" | "weekly" | "monthly";
+    type: "daily" | "weekly" | "monthly" | "yearly";
     interval: number;
+    mode?: "schedule" | "after_completion";
+    byDay?: string[];
+    byMonthDay?: number[];
+    bySetPos?: number[];
+    count?: number;
+    until?: string;
+    occurrences?: number;
 };
 
 type TaskModifierSlotDTO = { defId: string; data?: Record<string, unknown> };
@@ -1,4 +1,13 @@
-type Recurrence = { type: string; interval: number };
+type Recurrence = {
+  type: string;
+  interval: number;
+  mode?: string;
+  byDay?: string[];
+  byMonthDay?: number[];
+  bySetPos?: number[];
+  count?: number;
+  until?: string;
+};
 
 type TaskModifierSlot = { defId: string; data?: Record<string, any> };
 
@@ -10,10 +19,14 @@ type TaskDTO = {
   project?: string;
   tags: string[];
   modifiers: TaskModifierSlot[];
-  dueDate?: string;
+  dueDate?: string | null;
+  dueTime?: string | null;
+  startDate?: string | null;
   nextAction: boolean;
-  recurrence?: Recurrence;
+  recurrence?: Recurrence | null;
   live?: boolean; 
+  archived?: boolean;
+  parentId?: string;
   createdAt?: string;
   updatedAt?: string;
   assignedVillagerId?: string;
@@ -24,6 +37,10 @@ type TaskDTO = {
   habitTier?: number;
   habitStreak?: number;
   lastCompletedDate?: string;
+  estimateMinutes?: number;
+  trackedSeconds?: number;
+  externalRefs?: Record<string, string>;
+  revision?: number;
 };
 
 type BlueprintDTO = {
@@ -32,6 +49,9 @@ type BlueprintDTO = {
   description: string;
   modifierSlots: string[];
   steps: string[];
+  variables?: { name: string; label?: string; type: "string" | "date" | "offset" | "choice"; choices?: string[]; default?: string }[];
+  due?: string;
+  version?: number;
   createdAt?: string;
 };
 
@@ -130,12 +150,17 @@ async function apiCreate(input: Omit<TaskDTO, "id">) {
   return (await res.json()) as TaskDTO;
 }
 
-async function apiPatch(id: string, patch: Partial<Omit<TaskDTO, "id">>) {
+async function apiPatch(id: string, patch: Partial<Omit<TaskDTO, "id">>, revision?: number) {
+  const headers: Record<string, string> = { "Content-Type": "application/json" };
+  if (revision) headers["If-Match"] = `"${revision}"`;
   const res = await fetch(`/api/tasks/${encodeURIComponent(id)}`, {
     method: "PATCH",
-    headers: { "Content-Type": "application/json" },
+    headers,
     body: JSON.stringify(patch),
   });
+  if (res.status === 412) {
+    throw new Error("This task was changed elsewhere. Close and reopen it to load the latest version.");
+  }
   if (!res.ok) throw new Error(`PATCH /api/tasks/${id} failed: ${res.status}`);
   return (await res.json()) as TaskDTO;
 }
@@ -450,6 +475,7 @@ let unlockButtons:
   | null = null;
 
 let editingId: string | null = null;
+let editingRevision: number | undefined;
 let playerState: PlayerStateDTO | null = null;
 
 function coinBalance(): number {
@@ -965,6 +991,7 @@ async function openEditor(task?: TaskDTO) {
   }
   editor.err.textContent = "";
   editingId = task?.id ?? null;
+  editingRevision = task?.revision;
 
   editor.title.value = task?.title ?? "";
   editor.desc.value = task?.description ?? "";
@@ -1019,14 +1046,17 @@ function collectEditor(): Omit<TaskDTO, "id"> {
   const nextUnlocked = isUnlocked(FEATURE_NEXT_ACTION);
   const recurrenceUnlocked = isUnlocked(FEATURE_RECURRENCE);
 
+  // null clears the field on PATCH; undefined leaves it untouched.
   const recType = recurrenceUnlocked ? editor.recType.value.trim() : "";
   const recurrence =
-    recType === ""
+    !recurrenceUnlocked
       ? undefined
-      : {
-          type: recType,
-          interval: Math.max(1, Number(editor.recInv.value || "1")),
-        };
+      : recType === ""
+        ? null
+        : {
+            type: recType,
+            interval: Math.max(1, Number(editor.recInv.value || "1")),
+          };
 
   return {
     title,
@@ -1034,7 +1064,7 @@ function collectEditor(): Omit<TaskDTO, "id"> {
     done: editor.done.checked,
     project: normalizeProject(editor.project.value),
     tags: editor.tags.value.split(",").map(s => s.trim()).filter(Boolean),
-    dueDate: dueUnlocked && editor.dueDate.value ? editor.dueDate.value : undefined,
+    dueDate: !dueUnlocked ? undefined : editor.dueDate.value ? editor.dueDate.value : null,
     nextAction: nextUnlocked ? editor.nextAction.checked : false,
     recurrence,
     modifiers: parseModifiers(editor.modifiers.value),
@@ -1297,7 +1327,7 @@ function initOnce() {
     try {
       const payload = collectEditor();
       if (!editingId) await apiCreate(payload);
-      else await apiPatch(editingId, payload);
+      else await apiPatch(editingId, payload, editingRevision);
 
       closeEditor();
       await refresh();
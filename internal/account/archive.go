@@ -0,0 +1,747 @@
+package account
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"donegeon/internal/auth"
+	"donegeon/internal/blueprint"
+	"donegeon/internal/board"
+	"donegeon/internal/importer"
+	"donegeon/internal/mailin"
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/plugin"
+	"donegeon/internal/project"
+	"donegeon/internal/savedfilter"
+	"donegeon/internal/tag"
+	"donegeon/internal/task"
+	"donegeon/internal/timetrack"
+	"donegeon/internal/webhook"
+)
+
+// Archive identity. Bump ArchiveVersion when a section changes shape;
+// Import reads every version up to it. Version 2 added projects, tag
+// colors, saved filters, time entries, attachments, webhooks and import
+// jobs.
+const (
+	ArchiveFormat  = "donegeon-account"
+	ArchiveVersion = 2
+)
+
+// Archive sections, one JSON file each.
+const (
+	sectionManifest   = "manifest.json"
+	sectionProfile    = "profile.json"
+	sectionPlayer     = "player.json"
+	sectionTasks      = "tasks.json"
+	sectionBoards     = "boards.json"
+	sectionBlueprints = "blueprints.json"
+	sectionPlugins    = "plugins.json"
+	sectionProjects   = "projects.json"
+	sectionTags       = "tags.json"
+	sectionFilters    = "filters.json"
+	sectionTime       = "time.json"
+	sectionAttachment = "attachments.json"
+	sectionWebhooks   = "webhooks.json"
+	sectionImports    = "imports.json"
+
+	// attachmentDir holds attachment bodies, one file per attachment ID.
+	attachmentDir = "attachments/"
+)
+
+// omitted lists what an archive deliberately leaves out, recorded in every
+// manifest. API tokens (calendar feed, CalDAV, capture) and sessions are
+// credentials and are reissued after import; delivery and run logs are
+// history of the old account. Webhook and plugin signing secrets are
+// stripped too, and fresh ones are generated on import.
+var omitted = []string{"apiTokens", "sessions", "webhookDeliveries", "pluginRuns"}
+
+// maxSectionBytes caps one section when reading an archive.
+const maxSectionBytes = 64 << 20
+
+var (
+	ErrNotArchive         = errors.New("not a donegeon account archive")
+	ErrUnsupportedVersion = errors.New("account archive version is not supported")
+	ErrNotEmpty           = errors.New("account already has data; import into a new or empty account")
+)
+
+// Manifest describes an archive.
+type Manifest struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	UserID     string    `json:"userId"`
+	Email      string    `json:"email,omitempty"`
+	Sections   []string  `json:"sections"`
+	Omitted    []string  `json:"omitted,omitempty"`
+}
+
+// Profile is the exported identity: the login email and the player profile.
+type Profile struct {
+	Email string `json:"email,omitempty"`
+	player.PlayerProfile
+}
+
+// Stores are the repos an account spans, unscoped.
+type Stores struct {
+	Auth        *auth.FileRepo
+	Tasks       *task.FileRepo
+	Boards      *board.FileRepo
+	Players     *player.FileRepo
+	Blueprints  *blueprint.FileRepo
+	Plugins     *plugin.FileRepo
+	Projects    *project.FileRepo
+	Tags        *tag.FileRepo
+	Filters     *savedfilter.FileRepo
+	Time        *timetrack.FileRepo
+	Attachments *mailin.FileRepo
+	Webhooks    *webhook.FileRepo
+	Imports     *importer.FileRepo
+}
+
+// OpenStores opens the repos under a data directory, laid out as the
+// server lays them out.
+func OpenStores(dataDir string) (Stores, error) {
+	var (
+		st  Stores
+		err error
+	)
+	if st.Auth, err = auth.NewFileRepo(filepath.Join(dataDir, "auth")); err != nil {
+		return Stores{}, err
+	}
+	if st.Tasks, err = task.NewFileRepo(filepath.Join(dataDir, "tasks")); err != nil {
+		return Stores{}, err
+	}
+	if st.Boards, err = board.NewFileRepo(filepath.Join(dataDir, "boards")); err != nil {
+		return Stores{}, err
+	}
+	if st.Players, err = player.NewFileRepo(filepath.Join(dataDir, "player")); err != nil {
+		return Stores{}, err
+	}
+	if st.Blueprints, err = blueprint.NewFileRepo(filepath.Join(dataDir, "blueprints")); err != nil {
+		return Stores{}, err
+	}
+	if st.Plugins, err = plugin.NewFileRepo(filepath.Join(dataDir, "plugins")); err != nil {
+		return Stores{}, err
+	}
+	if st.Projects, err = project.NewFileRepo(filepath.Join(dataDir, "projects")); err != nil {
+		return Stores{}, err
+	}
+	if st.Tags, err = tag.NewFileRepo(filepath.Join(dataDir, "tags")); err != nil {
+		return Stores{}, err
+	}
+	if st.Filters, err = savedfilter.NewFileRepo(filepath.Join(dataDir, "filters")); err != nil {
+		return Stores{}, err
+	}
+	if st.Time, err = timetrack.NewFileRepo(filepath.Join(dataDir, "time")); err != nil {
+		return Stores{}, err
+	}
+	if st.Attachments, err = mailin.NewFileRepo(filepath.Join(dataDir, "mail")); err != nil {
+		return Stores{}, err
+	}
+	if st.Webhooks, err = webhook.NewFileRepo(filepath.Join(dataDir, "webhooks")); err != nil {
+		return Stores{}, err
+	}
+	if st.Imports, err = importer.NewFileRepo(filepath.Join(dataDir, "imports")); err != nil {
+		return Stores{}, err
+	}
+	return st, nil
+}
+
+// Export writes userID's data to w as a gzipped tar of JSON sections,
+// manifest first, so it can be streamed as it is built.
+func Export(w io.Writer, st Stores, userID string, now time.Time) error {
+	state := st.Players.ForUser(userID).GetState()
+	profile := Profile{PlayerProfile: state.Profile}
+	if u, ok := st.Auth.GetUserByID(userID); ok {
+		profile.Email = u.Email
+	}
+	state.Profile = player.PlayerProfile{} // exported on its own
+
+	boardNames, err := st.Boards.UserBoards(userID)
+	if err != nil {
+		return err
+	}
+	boards := make(map[string]*model.BoardState, len(boardNames))
+	for _, name := range boardNames {
+		b, err := st.Boards.Load(board.UserBoardID(userID, name))
+		if err != nil {
+			return err
+		}
+		boards[name] = b
+	}
+	blueprints, err := st.Blueprints.ForUser(userID).List()
+	if err != nil {
+		return err
+	}
+	plugins := st.Plugins.ForUser(userID).Snapshot()
+	for i := range plugins.Installed {
+		plugins.Installed[i].Secret = ""
+	}
+	projects, err := st.Projects.ForUser(userID).List()
+	if err != nil {
+		return err
+	}
+	tags, err := st.Tags.ForUser(userID).List()
+	if err != nil {
+		return err
+	}
+	filters, err := st.Filters.ForUser(userID).List()
+	if err != nil {
+		return err
+	}
+	entries, err := st.Time.ForUser(userID).List(timetrack.ListFilter{})
+	if err != nil {
+		return err
+	}
+	attachments, err := st.Attachments.ForUser(userID).List("")
+	if err != nil {
+		return err
+	}
+	webhooks, err := st.Webhooks.ForUser(userID).List()
+	if err != nil {
+		return err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	imports, err := st.Imports.ForUser(userID).List()
+	if err != nil {
+		return err
+	}
+
+	sections := []struct {
+		name string
+		v    any
+	}{
+		{sectionProfile, profile},
+		{sectionPlayer, state},
+		{sectionTasks, st.Tasks.ForUser(userID).Snapshot()},
+		{sectionBoards, boards},
+		{sectionBlueprints, blueprints},
+		{sectionPlugins, plugins},
+		{sectionProjects, projects},
+		{sectionTags, tags},
+		{sectionFilters, filters},
+		{sectionTime, entries},
+		{sectionAttachment, attachments},
+		{sectionWebhooks, webhooks},
+		{sectionImports, imports},
+	}
+	manifest := Manifest{
+		Format:     ArchiveFormat,
+		Version:    ArchiveVersion,
+		ExportedAt: now.UTC(),
+		UserID:     userID,
+		Email:      profile.Email,
+		Omitted:    omitted,
+	}
+	for _, s := range sections {
+		manifest.Sections = append(manifest.Sections, s.name)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeSection(tw, sectionManifest, manifest, now); err != nil {
+		return err
+	}
+	for _, s := range sections {
+		if err := writeSection(tw, s.name, s.v, now); err != nil {
+			return err
+		}
+	}
+	attRepo := st.Attachments.ForUser(userID)
+	for _, a := range attachments {
+		_, path, err := attRepo.Get(a.ID)
+		if err != nil {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // listed without a body; Import skips it
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeFile(tw, attachmentDir+string(a.ID), b, now); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeSection(tw *tar.Writer, name string, v any, now time.Time) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(tw, name, b, now)
+}
+
+func writeFile(tw *tar.Writer, name string, b []byte, now time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(b)),
+		ModTime: now.UTC(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(b)
+	return err
+}
+
+// Summary reports what an import restored.
+type Summary struct {
+	Manifest    Manifest `json:"manifest"`
+	Tasks       int      `json:"tasks"`
+	Boards      int      `json:"boards"`
+	Blueprints  int      `json:"blueprints"`
+	Plugins     int      `json:"plugins"`
+	Projects    int      `json:"projects"`
+	Tags        int      `json:"tags"`
+	Filters     int      `json:"filters"`
+	TimeEntries int      `json:"timeEntries"`
+	Attachments int      `json:"attachments"`
+	Webhooks    int      `json:"webhooks"`
+	ImportJobs  int      `json:"importJobs"`
+}
+
+// Import restores an archive into userID, which must not have tasks,
+// blueprints, board cards, projects, saved filters, time entries or
+// webhooks yet. Every ID is reissued and every reference to a task or
+// project (parents, history, comments, board cards, time entries,
+// attachments, import jobs) is rewritten, as are references to the
+// exporting user's ID, so an archive can be imported next to the account it
+// came from. Plugins and webhooks get new signing secrets. The whole
+// archive is read and checked before anything is written.
+func Import(r io.Reader, st Stores, userID string) (Summary, error) {
+	files, err := readArchive(r)
+	if err != nil {
+		return Summary{}, err
+	}
+	var manifest Manifest
+	if err := decodeSection(files, sectionManifest, &manifest, true); err != nil {
+		return Summary{}, err
+	}
+	if manifest.Format != ArchiveFormat {
+		return Summary{}, ErrNotArchive
+	}
+	if manifest.Version < 1 || manifest.Version > ArchiveVersion {
+		return Summary{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, manifest.Version)
+	}
+
+	var (
+		profile     Profile
+		state       player.UserState
+		tasks       task.UserSnapshot
+		boards      map[string]*model.BoardState
+		blueprints  []model.Blueprint
+		plugins     plugin.UserSnapshot
+		projects    []model.Project
+		tags        []model.Tag
+		filters     []model.SavedFilter
+		entries     []model.TimeEntry
+		attachments []model.Attachment
+		webhooks    []model.Webhook
+		imports     []model.ImportJob
+	)
+	for _, s := range []struct {
+		name string
+		v    any
+	}{
+		{sectionProfile, &profile},
+		{sectionPlayer, &state},
+		{sectionTasks, &tasks},
+		{sectionBoards, &boards},
+		{sectionBlueprints, &blueprints},
+		{sectionPlugins, &plugins},
+		{sectionProjects, &projects},
+		{sectionTags, &tags},
+		{sectionFilters, &filters},
+		{sectionTime, &entries},
+		{sectionAttachment, &attachments},
+		{sectionWebhooks, &webhooks},
+		{sectionImports, &imports},
+	} {
+		if err := decodeSection(files, s.name, s.v, false); err != nil {
+			return Summary{}, err
+		}
+	}
+
+	for name := range boards {
+		if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+			return Summary{}, fmt.Errorf("%w: bad board name %q", ErrNotArchive, name)
+		}
+	}
+	if err := checkEmpty(st, userID); err != nil {
+		return Summary{}, err
+	}
+
+	ids := remapTasks(&tasks, manifest.UserID, userID)
+	for _, b := range boards {
+		remapBoard(b, ids)
+	}
+
+	state.Profile = profile.PlayerProfile
+	state.Profile.Team.ID = "" // re-derived for the new owner
+	if _, err := st.Players.ForUser(userID).ReplaceState(state); err != nil {
+		return Summary{}, err
+	}
+	if err := st.Tasks.ForUser(userID).Restore(tasks); err != nil {
+		return Summary{}, err
+	}
+	for name, b := range boards {
+		if b == nil {
+			continue
+		}
+		if err := st.Boards.Save(board.UserBoardID(userID, name), b); err != nil {
+			return Summary{}, err
+		}
+	}
+	bpRepo := st.Blueprints.ForUser(userID)
+	for _, b := range blueprints {
+		b.ID = ""
+		if _, err := bpRepo.Create(b); err != nil {
+			return Summary{}, err
+		}
+	}
+	if err := st.Plugins.ForUser(userID).Restore(plugins); err != nil {
+		return Summary{}, err
+	}
+
+	sum := Summary{
+		Manifest:   manifest,
+		Tasks:      len(tasks.Tasks),
+		Boards:     len(boards),
+		Blueprints: len(blueprints),
+		Plugins:    len(plugins.Installed),
+	}
+	if sum.Projects, err = importProjects(st.Projects.ForUser(userID), projects); err != nil {
+		return Summary{}, err
+	}
+	tagRepo := st.Tags.ForUser(userID)
+	for _, t := range tags {
+		if t.Color == "" {
+			continue
+		}
+		if _, err := tagRepo.SetColor(t.Name, t.Color); err != nil {
+			return Summary{}, err
+		}
+		sum.Tags++
+	}
+	filterRepo := st.Filters.ForUser(userID)
+	for _, f := range filters {
+		f.ID = ""
+		if _, err := filterRepo.Create(f); err != nil {
+			return Summary{}, err
+		}
+		sum.Filters++
+	}
+	if sum.TimeEntries, err = importTime(st.Time.ForUser(userID), entries, ids); err != nil {
+		return Summary{}, err
+	}
+	attRepo := st.Attachments.ForUser(userID)
+	for _, a := range attachments {
+		taskID, ok := ids[a.TaskID]
+		body, found := files[attachmentDir+string(a.ID)]
+		if !ok || !found {
+			continue
+		}
+		if _, err := attRepo.Add(taskID, mailin.Attachment{Filename: a.Filename, ContentType: a.ContentType, Data: body}); err != nil {
+			return Summary{}, err
+		}
+		sum.Attachments++
+	}
+	hookRepo := st.Webhooks.ForUser(userID)
+	for _, h := range webhooks {
+		h.ID, h.Secret = "", "" // Create signs with a new secret
+		if _, err := hookRepo.Create(h); err != nil {
+			return Summary{}, err
+		}
+		sum.Webhooks++
+	}
+	importRepo := st.Imports.ForUser(userID)
+	for _, j := range imports {
+		remapImportJob(&j, ids)
+		if _, err := importRepo.Create(j); err != nil {
+			return Summary{}, err
+		}
+		sum.ImportJobs++
+	}
+	return sum, nil
+}
+
+func readArchive(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotArchive, err)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrNotArchive, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Size > maxSectionBytes {
+			return nil, fmt.Errorf("%w: %s is too large", ErrNotArchive, hdr.Name)
+		}
+		b, err := io.ReadAll(io.LimitReader(tr, maxSectionBytes))
+		if err != nil {
+			return nil, err
+		}
+		files[strings.TrimPrefix(hdr.Name, "./")] = b
+	}
+}
+
+func decodeSection(files map[string][]byte, name string, v any, required bool) error {
+	b, ok := files[name]
+	if !ok {
+		if required {
+			return fmt.Errorf("%w: missing %s", ErrNotArchive, name)
+		}
+		return nil
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrNotArchive, name, err)
+	}
+	return nil
+}
+
+// checkEmpty refuses accounts that already hold work. Player state, tag
+// colors and import jobs do not count: every account has player state from
+// its first request, and the others are only merged into.
+func checkEmpty(st Stores, userID string) error {
+	if len(st.Tasks.ForUser(userID).Snapshot().Tasks) > 0 {
+		return ErrNotEmpty
+	}
+	if bs, err := st.Blueprints.ForUser(userID).List(); err != nil {
+		return err
+	} else if len(bs) > 0 {
+		return ErrNotEmpty
+	}
+	if ps, err := st.Projects.ForUser(userID).List(); err != nil {
+		return err
+	} else if len(ps) > 0 {
+		return ErrNotEmpty
+	}
+	if fs, err := st.Filters.ForUser(userID).List(); err != nil {
+		return err
+	} else if len(fs) > 0 {
+		return ErrNotEmpty
+	}
+	if es, err := st.Time.ForUser(userID).List(timetrack.ListFilter{}); err != nil {
+		return err
+	} else if len(es) > 0 {
+		return ErrNotEmpty
+	}
+	if hs, err := st.Webhooks.ForUser(userID).List(); err != nil {
+		return err
+	} else if len(hs) > 0 {
+		return ErrNotEmpty
+	}
+	names, err := st.Boards.UserBoards(userID)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		b, err := st.Boards.Load(board.UserBoardID(userID, name))
+		if err != nil {
+			return err
+		}
+		if len(b.Cards) > 0 {
+			return ErrNotEmpty
+		}
+	}
+	return nil
+}
+
+// remapTasks gives every task a fresh ID, rewrites the references to it
+// and moves the old owner's ID on history and comments to newUserID. It
+// returns the old-to-new ID map.
+func remapTasks(s *task.UserSnapshot, oldUserID, newUserID string) map[model.TaskID]model.TaskID {
+	ids := make(map[model.TaskID]model.TaskID, len(s.Tasks))
+	for _, t := range s.Tasks {
+		ids[t.ID] = task.NewID()
+	}
+	user := func(id string) string {
+		if oldUserID != "" && id == oldUserID {
+			return newUserID
+		}
+		return id
+	}
+
+	for i := range s.Tasks {
+		t := &s.Tasks[i]
+		t.ID = ids[t.ID]
+		if t.ParentID != nil {
+			if id, ok := ids[*t.ParentID]; ok {
+				t.ParentID = &id
+			} else {
+				t.ParentID = nil
+			}
+		}
+	}
+	for i, id := range s.Live {
+		s.Live[i] = ids[id]
+	}
+	history := make(map[model.TaskID][]model.TaskActivity, len(s.History))
+	for old, entries := range s.History {
+		id, ok := ids[old]
+		if !ok {
+			continue
+		}
+		for i := range entries {
+			entries[i].TaskID = id
+			entries[i].Actor = user(entries[i].Actor)
+		}
+		history[id] = entries
+	}
+	s.History = history
+	comments := make(map[model.TaskID][]model.TaskComment, len(s.Comments))
+	for old, thread := range s.Comments {
+		id, ok := ids[old]
+		if !ok {
+			continue
+		}
+		for i := range thread {
+			thread[i].TaskID = id
+			thread[i].AuthorID = user(thread[i].AuthorID)
+		}
+		comments[id] = thread
+	}
+	s.Comments = comments
+	return ids
+}
+
+// remapBoard points cards at the reissued task IDs. Any string in card data
+// that names an old task is rewritten, which covers taskId and the ids
+// loot and completion cards remember.
+func remapBoard(b *model.BoardState, ids map[model.TaskID]model.TaskID) {
+	if b == nil {
+		return
+	}
+	for _, c := range b.Cards {
+		if c == nil {
+			continue
+		}
+		for k, v := range c.Data {
+			if s, ok := v.(string); ok {
+				if id, ok := ids[model.TaskID(s)]; ok {
+					c.Data[k] = string(id)
+				}
+			}
+		}
+	}
+}
+
+// importProjects recreates projects under new IDs, parents before their
+// children. A project whose parent is missing from the archive becomes a
+// top-level one.
+func importProjects(repo *project.FileRepo, ps []model.Project) (int, error) {
+	known := make(map[model.ProjectID]bool, len(ps))
+	for _, p := range ps {
+		known[p.ID] = true
+	}
+	ids := make(map[model.ProjectID]model.ProjectID, len(ps))
+	for pending := ps; len(pending) > 0; {
+		var later []model.Project
+		for _, p := range pending {
+			if p.ParentID != nil && known[*p.ParentID] {
+				parent, ok := ids[*p.ParentID]
+				if !ok {
+					later = append(later, p)
+					continue
+				}
+				p.ParentID = &parent
+			} else {
+				p.ParentID = nil
+			}
+			old := p.ID
+			p.ID = ""
+			created, err := repo.Create(p)
+			if err != nil {
+				return len(ids), err
+			}
+			ids[old] = created.ID
+		}
+		if len(later) == len(pending) {
+			// Only a parent loop is left; its members lose their parents.
+			for i := range later {
+				later[i].ParentID = nil
+			}
+		}
+		pending = later
+	}
+	return len(ids), nil
+}
+
+// importTime recreates time entries against the reissued task IDs. Entries
+// for tasks not in the archive are dropped; a running timer keeps running.
+func importTime(repo *timetrack.FileRepo, es []model.TimeEntry, ids map[model.TaskID]model.TaskID) (int, error) {
+	n := 0
+	for _, e := range es {
+		taskID, ok := ids[e.TaskID]
+		if !ok {
+			continue
+		}
+		var err error
+		if e.Running() {
+			_, _, err = repo.Start(taskID, e.Note, e.Start)
+		} else {
+			e.ID, e.TaskID = "", taskID
+			_, err = repo.Create(e)
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// remapImportJob points a job at the reissued task IDs, so an applied job
+// can still be undone. Tasks not in the archive are dropped from it.
+func remapImportJob(j *model.ImportJob, ids map[model.TaskID]model.TaskID) {
+	j.ID = ""
+	taskIDs := make([]model.TaskID, 0, len(j.TaskIDs))
+	for _, id := range j.TaskIDs {
+		if id, ok := ids[id]; ok {
+			taskIDs = append(taskIDs, id)
+		}
+	}
+	j.TaskIDs = taskIDs
+	for i := range j.Rows {
+		row := &j.Rows[i]
+		if row.TaskID != "" {
+			row.TaskID = ids[row.TaskID]
+		}
+		if row.Task != nil && row.Task.ID != "" {
+			t := *row.Task
+			t.ID = ids[t.ID]
+			row.Task = &t
+		}
+	}
+}
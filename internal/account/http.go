@@ -0,0 +1,96 @@
+package account
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// maxImportBytes caps an uploaded archive.
+const maxImportBytes = 256 << 20
+
+type Handler struct {
+	stores       Stores
+	userResolver func(*http.Request) string
+}
+
+func NewHandler(stores Stores) *Handler {
+	return &Handler{stores: stores}
+}
+
+// SetUserResolver returns the requesting user's ID ("" when there is none).
+func (h *Handler) SetUserResolver(fn func(*http.Request) string) {
+	h.userResolver = fn
+}
+
+func (h *Handler) userForRequest(r *http.Request) string {
+	if h.userResolver == nil {
+		return ""
+	}
+	return h.userResolver(r)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeErr(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]any{"error": msg})
+}
+
+// GET /api/account/export
+//
+// Streams the requester's archive as a download.
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID := h.userForRequest(r)
+	if userID == "" {
+		writeErr(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	now := time.Now()
+	name := "donegeon-account-" + now.UTC().Format("20060102T150405Z") + ".tar.gz"
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	// Headers are gone once the body starts; a failure mid-stream leaves a
+	// truncated archive, which Import rejects.
+	_ = Export(w, h.stores, userID, now)
+}
+
+// POST /api/account/import
+//
+// The body is an archive from Export. It is restored into the requester's
+// account, which must be new or empty.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID := h.userForRequest(r)
+	if userID == "" {
+		writeErr(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	summary, err := Import(http.MaxBytesReader(w, r.Body, maxImportBytes), h.stores, userID)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeErr(w, http.StatusRequestEntityTooLarge, "archive is too large")
+		case errors.Is(err, ErrNotEmpty):
+			writeErr(w, http.StatusConflict, err.Error())
+		case errors.Is(err, ErrNotArchive), errors.Is(err, ErrUnsupportedVersion):
+			writeErr(w, http.StatusBadRequest, err.Error())
+		default:
+			writeErr(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusCreated, summary)
+}
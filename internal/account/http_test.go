@@ -0,0 +1,209 @@
+package account
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"donegeon/internal/board"
+	"donegeon/internal/mailin"
+	"donegeon/internal/model"
+	"donegeon/internal/timetrack"
+)
+
+func TestExportImport_RemapsIDsIntoEmptyAccount(t *testing.T) {
+	st, err := OpenStores(t.TempDir())
+	if err != nil {
+		t.Fatalf("open stores: %v", err)
+	}
+	src, _, err := st.Auth.GetOrCreateUser("old@example.com", time.Now())
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	dst, _, _ := st.Auth.GetOrCreateUser("new@example.com", time.Now())
+
+	tasks := st.Tasks.ForUser(src.ID)
+	parent, _ := tasks.Create(model.Task{Title: "Move house"})
+	step, _ := tasks.Create(model.Task{Title: "Pack books", ParentID: &parent.ID})
+	if _, err := tasks.AddComment(step.ID, model.TaskComment{AuthorID: src.ID, Body: "start with the big shelf"}); err != nil {
+		t.Fatalf("add comment: %v", err)
+	}
+	b := model.NewBoardState()
+	b.Cards["c1"] = model.NewCard("c1", "task.blank", map[string]any{"taskId": string(step.ID)})
+	if err := st.Boards.Save(board.UserBoardID(src.ID, "default"), b); err != nil {
+		t.Fatalf("save board: %v", err)
+	}
+	if _, err := st.Blueprints.ForUser(src.ID).Create(model.Blueprint{Title: "Weekly review", Steps: []string{"Inbox zero"}}); err != nil {
+		t.Fatalf("create blueprint: %v", err)
+	}
+	market, _ := st.Plugins.ForUser(src.ID).ListMarketplace()
+	if _, _, _, err := st.Plugins.ForUser(src.ID).Install(market[0].ID); err != nil {
+		t.Fatalf("install plugin: %v", err)
+	}
+	if _, err := st.Players.ForUser(src.ID).UpdateProfile(strPtr("Old Me"), nil, nil); err != nil {
+		t.Fatalf("update profile: %v", err)
+	}
+	home, _ := st.Projects.ForUser(src.ID).Create(model.Project{Name: "Home"})
+	if _, err := st.Projects.ForUser(src.ID).Create(model.Project{Name: "Garage", ParentID: &home.ID}); err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	if _, err := st.Tags.ForUser(src.ID).SetColor("errands", "#4f7cac"); err != nil {
+		t.Fatalf("set tag color: %v", err)
+	}
+	if _, err := st.Filters.ForUser(src.ID).Create(model.SavedFilter{Name: "Errands", Query: model.FilterQuery{Tags: []string{"errands"}}}); err != nil {
+		t.Fatalf("create filter: %v", err)
+	}
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	if err := st.Time.ForUser(src.ID).LogWork(step.ID, start, start.Add(time.Hour), "first shelf"); err != nil {
+		t.Fatalf("log work: %v", err)
+	}
+	if _, err := st.Attachments.ForUser(src.ID).Add(step.ID, mailin.Attachment{Filename: "list.txt", ContentType: "text/plain", Data: []byte("books")}); err != nil {
+		t.Fatalf("add attachment: %v", err)
+	}
+	hook, _ := st.Webhooks.ForUser(src.ID).Create(model.Webhook{URL: "https://hooks.example.com/in", Active: true})
+	if _, err := st.Imports.ForUser(src.ID).Create(model.ImportJob{Source: "csv", Status: model.ImportJobApplied, TaskIDs: []model.TaskID{parent.ID}}); err != nil {
+		t.Fatalf("create import job: %v", err)
+	}
+	srcPlugins := st.Plugins.ForUser(src.ID).Snapshot()
+
+	users := map[string]string{"src": src.ID, "dst": dst.ID}
+	h := NewHandler(st)
+	h.SetUserResolver(func(r *http.Request) string { return users[r.Header.Get("X-Test-User")] })
+	do := func(user, method, path string, body []byte, fn http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("X-Test-User", user)
+		rec := httptest.NewRecorder()
+		fn(rec, req)
+		return rec
+	}
+
+	rec := do("src", http.MethodGet, "/api/account/export", nil, h.Export)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/gzip" {
+		t.Fatalf("expected gzip export, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	archive := rec.Body.Bytes()
+	for _, secret := range []string{hook.Secret, srcPlugins.Installed[0].Secret} {
+		if secret == "" || archiveContains(t, archive, secret) {
+			t.Fatalf("signing secret %q leaked into the archive", secret)
+		}
+	}
+
+	rec = do("dst", http.MethodPost, "/api/account/import", archive, h.Import)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var sum Summary
+	_ = json.NewDecoder(rec.Body).Decode(&sum)
+	if sum.Manifest.Version != ArchiveVersion || sum.Manifest.Email != "old@example.com" ||
+		sum.Tasks != 2 || sum.Boards != 1 || sum.Blueprints != 1 || sum.Plugins != 1 ||
+		sum.Projects != 2 || sum.Tags != 1 || sum.Filters != 1 || sum.TimeEntries != 1 ||
+		sum.Attachments != 1 || sum.Webhooks != 1 || sum.ImportJobs != 1 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+
+	got := st.Tasks.ForUser(dst.ID).Snapshot()
+	byTitle := map[string]model.Task{}
+	for _, tk := range got.Tasks {
+		byTitle[tk.Title] = tk
+		if tk.ID == parent.ID || tk.ID == step.ID {
+			t.Fatalf("expected reissued task IDs, got %s", tk.ID)
+		}
+	}
+	newParent, newStep := byTitle["Move house"], byTitle["Pack books"]
+	if newStep.ParentID == nil || *newStep.ParentID != newParent.ID {
+		t.Fatalf("expected parent remapped, got %+v", newStep.ParentID)
+	}
+	if c := got.Comments[newStep.ID]; len(c) != 1 || c[0].TaskID != newStep.ID || c[0].AuthorID != dst.ID {
+		t.Fatalf("unexpected comments: %+v", got.Comments)
+	}
+	if h := got.History[newStep.ID]; len(h) == 0 || h[0].TaskID != newStep.ID {
+		t.Fatalf("unexpected history: %+v", got.History)
+	}
+	restored, _ := st.Boards.Load(board.UserBoardID(dst.ID, "default"))
+	if restored.Cards["c1"].Data["taskId"] != string(newStep.ID) {
+		t.Fatalf("expected card remapped, got %+v", restored.Cards["c1"].Data)
+	}
+	profile := st.Players.ForUser(dst.ID).GetProfile()
+	if profile.DisplayName != "Old Me" || profile.Team.ID != "team_"+dst.ID {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+	if installed, _ := st.Plugins.ForUser(dst.ID).ListInstalled(); len(installed) != 1 {
+		t.Fatalf("expected plugin restored, got %+v", installed)
+	}
+	if installed := st.Plugins.ForUser(dst.ID).Snapshot().Installed; installed[0].Secret == "" || installed[0].Secret == srcPlugins.Installed[0].Secret {
+		t.Fatalf("expected a new plugin secret, got %q", installed[0].Secret)
+	}
+	garage, err := st.Projects.ForUser(dst.ID).GetByName("Garage")
+	if err != nil || garage.ParentID == nil {
+		t.Fatalf("expected the child project restored, got %+v (%v)", garage, err)
+	}
+	if newHome, _ := st.Projects.ForUser(dst.ID).Get(*garage.ParentID); newHome.Name != "Home" || newHome.ID == home.ID {
+		t.Fatalf("expected the parent project reissued, got %+v", newHome)
+	}
+	if tg, _ := st.Tags.ForUser(dst.ID).Get("errands"); tg.Color != "#4f7cac" {
+		t.Fatalf("expected tag color restored, got %+v", tg)
+	}
+	if entries, _ := st.Time.ForUser(dst.ID).List(timetrack.ListFilter{}); len(entries) != 1 || entries[0].TaskID != newStep.ID {
+		t.Fatalf("expected time entry on the new task, got %+v", entries)
+	}
+	atts, _ := st.Attachments.ForUser(dst.ID).List(newStep.ID)
+	if len(atts) != 1 {
+		t.Fatalf("expected attachment on the new task, got %+v", atts)
+	}
+	if _, path, _ := st.Attachments.ForUser(dst.ID).Get(atts[0].ID); readFile(t, path) != "books" {
+		t.Fatalf("attachment body not restored")
+	}
+	hooks, _ := st.Webhooks.ForUser(dst.ID).List()
+	if len(hooks) != 1 || hooks[0].URL != hook.URL || hooks[0].Secret == "" || hooks[0].Secret == hook.Secret {
+		t.Fatalf("expected webhook with a new secret, got %+v", hooks)
+	}
+	if jobs, _ := st.Imports.ForUser(dst.ID).List(); len(jobs) != 1 || len(jobs[0].TaskIDs) != 1 || jobs[0].TaskIDs[0] != newParent.ID {
+		t.Fatalf("expected import job remapped, got %+v", jobs)
+	}
+	// The source account is untouched.
+	if len(st.Tasks.ForUser(src.ID).Snapshot().Tasks) != 2 {
+		t.Fatalf("source account changed")
+	}
+
+	if rec := do("dst", http.MethodPost, "/api/account/import", archive, h.Import); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 importing into a non-empty account, got %d", rec.Code)
+	}
+	if rec := do("dst", http.MethodPost, "/api/account/import", []byte("not an archive"), h.Import); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for garbage, got %d", rec.Code)
+	}
+	if _, err := st.Tasks.ForUser(dst.ID).Get(newStep.ID); err != nil {
+		t.Fatalf("restored task not readable through the repo: %v", err)
+	}
+}
+
+// archiveContains reports whether any file in a gzipped tar contains s.
+func archiveContains(t *testing.T, archive []byte, s string) bool {
+	t.Helper()
+	files, err := readArchive(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	for _, b := range files {
+		if bytes.Contains(b, []byte(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(b)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -7,6 +7,7 @@ type ctxKey string
 const (
 	userContextKey    ctxKey = "donegeon.auth.user"
 	sessionContextKey ctxKey = "donegeon.auth.session"
+	tokenContextKey   ctxKey = "donegeon.auth.api_token"
 )
 
 func withUserContext(ctx context.Context, u User) context.Context {
@@ -17,6 +18,10 @@ func withSessionContext(ctx context.Context, s Session) context.Context {
 	return context.WithValue(ctx, sessionContextKey, s)
 }
 
+func withAPITokenContext(ctx context.Context, t APIToken) context.Context {
+	return context.WithValue(ctx, tokenContextKey, t)
+}
+
 func UserFromContext(ctx context.Context) (User, bool) {
 	v := ctx.Value(userContextKey)
 	u, ok := v.(User)
@@ -28,3 +33,10 @@ func SessionFromContext(ctx context.Context) (Session, bool) {
 	s, ok := v.(Session)
 	return s, ok
 }
+
+// APITokenFromContext is the API token a request authenticated with.
+func APITokenFromContext(ctx context.Context) (APIToken, bool) {
+	v := ctx.Value(tokenContextKey)
+	t, ok := v.(APIToken)
+	return t, ok
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 )
@@ -16,6 +17,8 @@ type state struct {
 	ChallengesByEmail    map[string]OTPChallenge `json:"challengesByEmail"`
 	SessionsByID         map[string]Session      `json:"sessionsById"`
 	SessionIDByTokenHash map[string]string       `json:"sessionIdByTokenHash"`
+	APITokensByID        map[string]APIToken     `json:"apiTokensById,omitempty"`
+	APITokenIDByHash     map[string]string       `json:"apiTokenIdByHash,omitempty"`
 }
 
 func newState() state {
@@ -25,6 +28,8 @@ func newState() state {
 		ChallengesByEmail:    map[string]OTPChallenge{},
 		SessionsByID:         map[string]Session{},
 		SessionIDByTokenHash: map[string]string{},
+		APITokensByID:        map[string]APIToken{},
+		APITokenIDByHash:     map[string]string{},
 	}
 }
 
@@ -79,6 +84,12 @@ func (r *FileRepo) load() error {
 	if loaded.SessionIDByTokenHash == nil {
 		loaded.SessionIDByTokenHash = map[string]string{}
 	}
+	if loaded.APITokensByID == nil {
+		loaded.APITokensByID = map[string]APIToken{}
+	}
+	if loaded.APITokenIDByHash == nil {
+		loaded.APITokenIDByHash = map[string]string{}
+	}
 	r.s = loaded
 	return nil
 }
@@ -127,6 +138,17 @@ func (r *FileRepo) GetUserByID(id string) (User, bool) {
 	return u, ok
 }
 
+func (r *FileRepo) GetUserByEmail(email string) (User, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.s.UserIDByEmail[email]
+	if !ok {
+		return User{}, false
+	}
+	u, ok := r.s.UsersByID[id]
+	return u, ok
+}
+
 func (r *FileRepo) PutChallenge(ch OTPChallenge) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -205,3 +227,66 @@ func (r *FileRepo) TouchSession(sessionID string, lastSeen time.Time) error {
 	r.s.SessionsByID[sessionID] = s
 	return r.saveLocked()
 }
+
+func (r *FileRepo) CreateAPIToken(t APIToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.s.APITokensByID[t.ID] = t
+	r.s.APITokenIDByHash[t.TokenHash] = t.ID
+	return r.saveLocked()
+}
+
+func (r *FileRepo) GetAPITokenByHash(tokenHash string) (APIToken, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.s.APITokenIDByHash[tokenHash]
+	if !ok {
+		return APIToken{}, false
+	}
+	t, ok := r.s.APITokensByID[id]
+	return t, ok
+}
+
+// ListAPITokens returns the user's tokens, oldest first.
+func (r *FileRepo) ListAPITokens(userID string) []APIToken {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := []APIToken{}
+	for _, t := range r.s.APITokensByID {
+		if t.UserID == userID {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out
+}
+
+// DeleteAPIToken revokes one of the user's tokens; it reports false if the
+// user has no such token.
+func (r *FileRepo) DeleteAPIToken(userID, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.s.APITokensByID[id]
+	if !ok || t.UserID != userID {
+		return false, nil
+	}
+	delete(r.s.APITokensByID, id)
+	delete(r.s.APITokenIDByHash, t.TokenHash)
+	return true, r.saveLocked()
+}
+
+func (r *FileRepo) TouchAPIToken(id string, lastUsed time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.s.APITokensByID[id]
+	if !ok {
+		return nil
+	}
+	t.LastUsed = lastUsed
+	r.s.APITokensByID[id] = t
+	return r.saveLocked()
+}
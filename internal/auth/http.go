@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -139,3 +140,90 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	h.service.ClearSessionCookie(w, r)
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
+
+// apiTokenView hides the token hash.
+type apiTokenView struct {
+	ID        string     `json:"id"`
+	Scope     string     `json:"scope"`
+	Label     string     `json:"label,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	LastUsed  *time.Time `json:"lastUsed,omitempty"`
+}
+
+func viewAPIToken(t APIToken) apiTokenView {
+	v := apiTokenView{ID: t.ID, Scope: t.Scope, Label: t.Label, CreatedAt: t.CreatedAt}
+	if !t.LastUsed.IsZero() {
+		lu := t.LastUsed
+		v.LastUsed = &lu
+	}
+	return v
+}
+
+// GET  /api/auth/tokens
+// POST /api/auth/tokens  { "scope": "calendar.feed", "label": "Phone" }
+//
+// The plain token is only in the POST response.
+func (h *Handler) Tokens(w http.ResponseWriter, r *http.Request) {
+	u, ok := UserFromContext(r.Context())
+	if !ok {
+		writeErr(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		tokens := h.service.repo.ListAPITokens(u.ID)
+		out := make([]apiTokenView, 0, len(tokens))
+		for _, t := range tokens {
+			out = append(out, viewAPIToken(t))
+		}
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		var in struct {
+			Scope string `json:"scope"`
+			Label string `json:"label"`
+		}
+		if err := decodeJSON(r, &in); err != nil {
+			writeErr(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		t, token, err := h.service.IssueAPIToken(u.ID, in.Scope, in.Label, time.Now().UTC())
+		if err != nil {
+			if errors.Is(err, ErrInvalidScope) {
+				writeErr(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			writeErr(w, http.StatusInternalServerError, "could not create token")
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"token":    token,
+			"apiToken": viewAPIToken(t),
+		})
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// DELETE /api/auth/tokens/{id}
+func (h *Handler) TokensSub(w http.ResponseWriter, r *http.Request) {
+	u, ok := UserFromContext(r.Context())
+	if !ok {
+		writeErr(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if r.Method != http.MethodDelete {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/auth/tokens/"), "/")
+	deleted, err := h.service.repo.DeleteAPIToken(u.ID, id)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "could not revoke token")
+		return
+	}
+	if !deleted {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
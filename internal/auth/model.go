@@ -24,3 +24,32 @@ type Session struct {
 	LastSeen  time.Time `json:"lastSeen"`
 	ExpiresAt time.Time `json:"expiresAt"`
 }
+
+// APIToken is a long-lived credential for clients that cannot hold a
+// session cookie, such as calendar apps. Each token is limited to one scope,
+// and only its hash is stored.
+type APIToken struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	Scope     string    `json:"scope"`
+	Label     string    `json:"label,omitempty"`
+	TokenHash string    `json:"tokenHash"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastUsed  time.Time `json:"lastUsed,omitempty"`
+}
+
+// API token scopes.
+const (
+	ScopeCalendarFeed = "calendar.feed" // read-only GET /api/calendar/feed.ics
+	ScopeCalDAV       = "caldav"        // app password for CalDAV clients
+	ScopeCapture      = "capture"       // POST /api/capture/{token} and mail to {token}@domain
+)
+
+// ValidScope reports whether scope can be issued.
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeCalendarFeed, ScopeCalDAV, ScopeCapture:
+		return true
+	}
+	return false
+}
@@ -24,6 +24,7 @@ var (
 	ErrInvalidOTP         = errors.New("invalid otp code")
 	ErrOTPExpired         = errors.New("otp code expired")
 	ErrTooManyOTPAttempts = errors.New("too many invalid otp attempts")
+	ErrInvalidScope       = errors.New("unknown token scope")
 )
 
 type Service struct {
@@ -245,6 +246,53 @@ func (s *Service) AuthenticateRequest(r *http.Request, now time.Time) (User, Ses
 	return u, sess, true
 }
 
+// IssueAPIToken creates a token for userID limited to scope. The plain token
+// is returned once; only its hash is kept.
+func (s *Service) IssueAPIToken(userID, scope, label string, now time.Time) (APIToken, string, error) {
+	if !ValidScope(scope) {
+		return APIToken{}, "", ErrInvalidScope
+	}
+	token, err := generateToken()
+	if err != nil {
+		return APIToken{}, "", err
+	}
+	t := APIToken{
+		ID:        newID("tok"),
+		UserID:    userID,
+		Scope:     scope,
+		Label:     strings.TrimSpace(label),
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateAPIToken(t); err != nil {
+		return APIToken{}, "", err
+	}
+	return t, token, nil
+}
+
+// AuthenticateAPIToken resolves a plain token to its user, if the token
+// exists and carries scope.
+func (s *Service) AuthenticateAPIToken(token, scope string, now time.Time) (User, APIToken, bool) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return User{}, APIToken{}, false
+	}
+	t, ok := s.repo.GetAPITokenByHash(hashToken(token))
+	if !ok || t.Scope != scope {
+		return User{}, APIToken{}, false
+	}
+	u, ok := s.repo.GetUserByID(t.UserID)
+	if !ok {
+		return User{}, APIToken{}, false
+	}
+	// Same throttling as sessions: feeds are polled often.
+	if now.Sub(t.LastUsed) >= 5*time.Minute {
+		_ = s.repo.TouchAPIToken(t.ID, now)
+		t.LastUsed = now
+	}
+	return u, t, true
+}
+
 func (s *Service) RevokeSessionForRequest(r *http.Request) {
 	cookie, err := r.Cookie(s.cookieName)
 	if err != nil || cookie.Value == "" {
@@ -335,6 +383,65 @@ func (s *Service) RequireAPI(next http.Handler) http.Handler {
 	})
 }
 
+// RequireToken authenticates with an API token of the given scope, taken
+// from the "token" query parameter or an "Authorization: Bearer" header.
+func (s *Service) RequireToken(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+		u, _, ok := s.AuthenticateAPIToken(token, scope, time.Now())
+		if !ok {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withUserContext(r.Context(), u)))
+	})
+}
+
+// RequirePathToken authenticates with an API token of the given scope
+// that is the last segment of the URL path under prefix, for clients that
+// can only be given a URL (phone shortcuts, mail forwarding rules).
+func (s *Service) RequirePathToken(scope, prefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+		u, t, ok := s.AuthenticateAPIToken(token, scope, time.Now())
+		if !ok || strings.Contains(token, "/") {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "unauthorized"})
+			return
+		}
+		ctx := withAPITokenContext(withUserContext(r.Context(), u), t)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireBasicToken authenticates with HTTP Basic auth where the password
+// is an API token of the given scope (an app password) and the username is
+// the account's email. Failures get a Basic challenge so clients prompt.
+func (s *Service) RequireBasicToken(scope, realm string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, token, ok := r.BasicAuth()
+		if ok {
+			var u User
+			u, _, ok = s.AuthenticateAPIToken(token, scope, time.Now())
+			if ok && normalizeEmail(name) != u.Email && name != u.ID {
+				ok = false
+			}
+			if ok {
+				next.ServeHTTP(w, r.WithContext(withUserContext(r.Context(), u)))
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
 func (s *Service) HandleAppRoute(w http.ResponseWriter, r *http.Request) {
 	if _, _, ok := s.AuthenticateRequest(r, time.Now()); ok {
 		http.Redirect(w, r, "/tasks", http.StatusSeeOther)
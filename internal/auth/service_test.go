@@ -128,3 +128,127 @@ func TestService_SetSessionCookie_DowngradesSameSiteNoneWithoutSecure(t *testing
 func newSessionCookie(name, value string) *http.Cookie {
 	return &http.Cookie{Name: name, Value: value}
 }
+
+func TestService_RequireToken_ScopedAndRevocable(t *testing.T) {
+	svc := newAuthServiceForTests(t)
+	now := time.Date(2026, 2, 7, 11, 0, 0, 0, time.UTC)
+	u, _, err := svc.repo.GetOrCreateUser("feed@example.com", now)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if _, _, err := svc.IssueAPIToken(u.ID, "everything", "", now); err != ErrInvalidScope {
+		t.Fatalf("expected ErrInvalidScope, got %v", err)
+	}
+	tok, plain, err := svc.IssueAPIToken(u.ID, ScopeCalendarFeed, "Phone", now)
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+
+	var seen string
+	h := svc.RequireToken(ScopeCalendarFeed, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cu, _ := UserFromContext(r.Context())
+		seen = cu.ID
+	}))
+	call := func(target string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Code
+	}
+
+	if code := call("/api/calendar/feed.ics?token=" + plain); code != http.StatusOK || seen != u.ID {
+		t.Fatalf("expected token to authenticate %s, got code=%d user=%q", u.ID, code, seen)
+	}
+	if code := call("/api/calendar/feed.ics?token=nope"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for unknown token, got %d", code)
+	}
+	other := svc.RequireToken("other.scope", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	rec := httptest.NewRecorder()
+	other.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token="+plain, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for wrong scope, got %d", rec.Code)
+	}
+
+	if ok, err := svc.repo.DeleteAPIToken("someone-else", tok.ID); ok || err != nil {
+		t.Fatalf("expected other users not to revoke the token, got ok=%v err=%v", ok, err)
+	}
+	if ok, err := svc.repo.DeleteAPIToken(u.ID, tok.ID); !ok || err != nil {
+		t.Fatalf("revoke token: ok=%v err=%v", ok, err)
+	}
+	if code := call("/api/calendar/feed.ics?token=" + plain); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 after revoke, got %d", code)
+	}
+}
+
+func TestService_RequireBasicToken_ChecksUserAndScope(t *testing.T) {
+	svc := newAuthServiceForTests(t)
+	now := time.Date(2026, 2, 7, 12, 0, 0, 0, time.UTC)
+	u, _, err := svc.repo.GetOrCreateUser("dav@example.com", now)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	_, plain, err := svc.IssueAPIToken(u.ID, ScopeCalDAV, "Thunderbird", now)
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	h := svc.RequireBasicToken(ScopeCalDAV, "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	call := func(user, pass string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PROPFIND", "/caldav/", nil)
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := call("", ""); rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected 401 challenge without credentials, got %d", rec.Code)
+	}
+	if rec := call("DAV@example.com", plain); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected app password to authenticate, got %d", rec.Code)
+	}
+	if rec := call("other@example.com", plain); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected mismatched username to fail, got %d", rec.Code)
+	}
+}
+
+func TestService_RequirePathToken_SetsUserAndToken(t *testing.T) {
+	svc := newAuthServiceForTests(t)
+	now := time.Date(2026, 2, 7, 13, 0, 0, 0, time.UTC)
+	u, _, err := svc.repo.GetOrCreateUser("capture@example.com", now)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	tok, plain, err := svc.IssueAPIToken(u.ID, ScopeCapture, "Shortcut", now)
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	var seenUser, seenToken string
+	h := svc.RequirePathToken(ScopeCapture, "/api/capture/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cu, _ := UserFromContext(r.Context())
+		ct, _ := APITokenFromContext(r.Context())
+		seenUser, seenToken = cu.ID, ct.ID
+	}))
+	call := func(target string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, nil))
+		return rec.Code
+	}
+
+	if code := call("/api/capture/" + plain); code != http.StatusOK || seenUser != u.ID || seenToken != tok.ID {
+		t.Fatalf("expected token to authenticate, got code=%d user=%q token=%q", code, seenUser, seenToken)
+	}
+	for _, target := range []string{"/api/capture/", "/api/capture/nope", "/api/capture/" + plain + "/x"} {
+		if code := call(target); code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for %s, got %d", target, code)
+		}
+	}
+	if ok, err := svc.repo.DeleteAPIToken(u.ID, tok.ID); !ok || err != nil {
+		t.Fatalf("revoke token: ok=%v err=%v", ok, err)
+	}
+	if code := call("/api/capture/" + plain); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 after revoke, got %d", code)
+	}
+}
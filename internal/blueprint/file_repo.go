@@ -13,7 +13,9 @@ import (
 )
 
 type fileState struct {
-	Users map[string]map[model.BlueprintID]model.Blueprint `json:"users"`
+	Users    map[string]map[model.BlueprintID]model.Blueprint          `json:"users"`
+	Versions map[string]map[model.BlueprintID][]model.BlueprintVersion `json:"versions,omitempty"`
+	Library  map[string][]model.BlueprintLibraryEntry                  `json:"library,omitempty"`
 }
 
 type fileStore struct {
@@ -35,7 +37,9 @@ func NewFileRepo(dataDir string) (*FileRepo, error) {
 	st := &fileStore{
 		path: filepath.Join(dataDir, "blueprints.json"),
 		s: fileState{
-			Users: map[string]map[model.BlueprintID]model.Blueprint{},
+			Users:    map[string]map[model.BlueprintID]model.Blueprint{},
+			Versions: map[string]map[model.BlueprintID][]model.BlueprintVersion{},
+			Library:  map[string][]model.BlueprintLibraryEntry{},
 		},
 	}
 	if err := st.load(); err != nil {
@@ -55,6 +59,8 @@ func (s *fileStore) load() error {
 	if err != nil {
 		if os.IsNotExist(err) {
 			s.s.Users = map[string]map[model.BlueprintID]model.Blueprint{}
+			s.s.Versions = map[string]map[model.BlueprintID][]model.BlueprintVersion{}
+			s.s.Library = map[string][]model.BlueprintLibraryEntry{}
 			return nil
 		}
 		return err
@@ -71,6 +77,12 @@ func (s *fileStore) load() error {
 			loaded.Users[uid] = map[model.BlueprintID]model.Blueprint{}
 		}
 	}
+	if loaded.Versions == nil {
+		loaded.Versions = map[string]map[model.BlueprintID][]model.BlueprintVersion{}
+	}
+	if loaded.Library == nil {
+		loaded.Library = map[string][]model.BlueprintLibraryEntry{}
+	}
 	s.s = loaded
 	return nil
 }
@@ -160,6 +172,129 @@ func (r *FileRepo) List() ([]model.Blueprint, error) {
 	return out, nil
 }
 
+func (r *FileRepo) Update(id model.BlueprintID, p Patch) (model.Blueprint, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	cur, ok := m[id]
+	if !ok {
+		return model.Blueprint{}, ErrNotFound
+	}
+	normalizeBlueprint(&cur)
+	next := cur
+	applyPatch(&next, p)
+	normalizeBlueprint(&next)
+	if err := Validate(next); err != nil {
+		return model.Blueprint{}, err
+	}
+	if sameContent(cur, next) {
+		return cur, nil
+	}
+
+	now := nowUTC()
+	vm, ok := r.store.s.Versions[r.userID]
+	if !ok || vm == nil {
+		vm = map[model.BlueprintID][]model.BlueprintVersion{}
+		r.store.s.Versions[r.userID] = vm
+	}
+	vm[id] = append(vm[id], snapshot(cur, now))
+	next.Version = cur.Version + 1
+	next.UpdatedAt = now
+	m[id] = next
+	if err := r.store.saveLocked(); err != nil {
+		return model.Blueprint{}, err
+	}
+	return next, nil
+}
+
+func (r *FileRepo) Delete(id model.BlueprintID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	if _, ok := m[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m, id)
+	if vm := r.store.s.Versions[r.userID]; vm != nil {
+		delete(vm, id)
+	}
+	return r.store.saveLocked()
+}
+
+func (r *FileRepo) Versions(id model.BlueprintID) ([]model.BlueprintVersion, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if _, ok := r.store.s.Users[r.userID][id]; !ok {
+		return nil, ErrNotFound
+	}
+	versions := r.store.s.Versions[r.userID][id]
+	out := make([]model.BlueprintVersion, len(versions))
+	copy(out, versions)
+	return out, nil
+}
+
+// ShareToLibrary copies b into the team's library, attributed to this repo's
+// user. Sharing again replaces the user's earlier copy of the same blueprint.
+func (r *FileRepo) ShareToLibrary(teamOwnerID string, b model.Blueprint) (model.BlueprintLibraryEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	normalizeBlueprint(&b)
+	entry := model.BlueprintLibraryEntry{
+		ID:          "lib_" + string(newID("bp")),
+		TeamOwnerID: teamOwnerID,
+		SharedBy:    r.userID,
+		Blueprint:   b,
+		SharedAt:    nowUTC(),
+	}
+	entries := r.store.s.Library[teamOwnerID]
+	kept := make([]model.BlueprintLibraryEntry, 0, len(entries)+1)
+	for _, e := range entries {
+		if e.SharedBy == r.userID && e.Blueprint.ID == b.ID {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	r.store.s.Library[teamOwnerID] = append(kept, entry)
+	if err := r.store.saveLocked(); err != nil {
+		return model.BlueprintLibraryEntry{}, err
+	}
+	return entry, nil
+}
+
+func (r *FileRepo) Library() ([]model.BlueprintLibraryEntry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	out := []model.BlueprintLibraryEntry{}
+	for _, entries := range r.store.s.Library {
+		out = append(out, entries...)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].SharedAt.After(out[j].SharedAt)
+	})
+	return out, nil
+}
+
+func (r *FileRepo) RemoveFromLibrary(entryID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for owner, entries := range r.store.s.Library {
+		for i, e := range entries {
+			if e.ID != entryID {
+				continue
+			}
+			r.store.s.Library[owner] = append(entries[:i:i], entries[i+1:]...)
+			return r.store.saveLocked()
+		}
+	}
+	return ErrEntryNotFound
+}
+
 func nowUTC() time.Time {
 	return time.Now().UTC()
 }
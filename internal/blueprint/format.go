@@ -0,0 +1,145 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"donegeon/internal/model"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatVersion is the version written to exported blueprint files. Files
+// with a higher version are rejected rather than half-read.
+const FormatVersion = 1
+
+var (
+	ErrFormatVersion = errors.New("blueprint file needs a version")
+	ErrEmptyPack     = errors.New("blueprint file has no blueprints")
+)
+
+// File is the portable blueprint format: a pack of one or more blueprints,
+// as YAML or JSON. IDs, versions and timestamps are not carried over.
+//
+//	version: 1
+//	name: Onboarding
+//	blueprints:
+//	  - title: Onboard {{name}}
+//	    steps: [Laptop, Accounts]
+//	    variables:
+//	      - name: name
+type File struct {
+	Version    int        `json:"version" yaml:"version"`
+	Name       string     `json:"name,omitempty" yaml:"name,omitempty"`
+	Blueprints []Portable `json:"blueprints" yaml:"blueprints"`
+}
+
+// Portable is one blueprint in a File.
+type Portable struct {
+	Title         string             `json:"title" yaml:"title"`
+	Description   string             `json:"description,omitempty" yaml:"description,omitempty"`
+	ModifierSlots []string           `json:"modifierSlots,omitempty" yaml:"modifierSlots,omitempty"`
+	Steps         []string           `json:"steps,omitempty" yaml:"steps,omitempty"`
+	Variables     []PortableVariable `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Due           string             `json:"due,omitempty" yaml:"due,omitempty"`
+}
+
+type PortableVariable struct {
+	Name    string   `json:"name" yaml:"name"`
+	Label   string   `json:"label,omitempty" yaml:"label,omitempty"`
+	Type    string   `json:"type,omitempty" yaml:"type,omitempty"`
+	Choices []string `json:"choices,omitempty" yaml:"choices,omitempty"`
+	Default string   `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// ToPortable strips a blueprint down to its shareable content.
+func ToPortable(b model.Blueprint) Portable {
+	p := Portable{
+		Title:         b.Title,
+		Description:   b.Description,
+		ModifierSlots: append([]string(nil), b.ModifierSlots...),
+		Steps:         append([]string(nil), b.Steps...),
+		Due:           b.Due,
+	}
+	for _, v := range b.Variables {
+		p.Variables = append(p.Variables, PortableVariable{
+			Name:    v.Name,
+			Label:   v.Label,
+			Type:    v.Type,
+			Choices: append([]string(nil), v.Choices...),
+			Default: v.Default,
+		})
+	}
+	return p
+}
+
+// Upsert turns a portable blueprint back into create input.
+func (p Portable) Upsert() model.BlueprintUpsert {
+	u := model.BlueprintUpsert{
+		Title:         p.Title,
+		Description:   p.Description,
+		ModifierSlots: append([]string(nil), p.ModifierSlots...),
+		Steps:         append([]string(nil), p.Steps...),
+		Due:           p.Due,
+	}
+	for _, v := range p.Variables {
+		u.Variables = append(u.Variables, model.BlueprintVariable{
+			Name:    v.Name,
+			Label:   v.Label,
+			Type:    v.Type,
+			Choices: append([]string(nil), v.Choices...),
+			Default: v.Default,
+		})
+	}
+	return u
+}
+
+// Export builds a File from blueprints.
+func Export(name string, bs []model.Blueprint) File {
+	f := File{Version: FormatVersion, Name: name, Blueprints: make([]Portable, 0, len(bs))}
+	for _, b := range bs {
+		f.Blueprints = append(f.Blueprints, ToPortable(b))
+	}
+	return f
+}
+
+// Encode writes f as "yaml" or "json".
+func Encode(f File, format string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "yaml", "yml":
+		return yaml.Marshal(f)
+	case "", "json":
+		return json.MarshalIndent(f, "", "  ")
+	default:
+		return nil, fmt.Errorf("unknown format %q (want yaml or json)", format)
+	}
+}
+
+// Decode reads a YAML or JSON blueprint file (JSON is valid YAML) and
+// returns the blueprints it holds, ready to create. Every blueprint is
+// validated; the first problem is reported with its position.
+func Decode(data []byte) (File, []model.Blueprint, error) {
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return File{}, nil, fmt.Errorf("parse blueprint file: %w", err)
+	}
+	switch {
+	case f.Version == 0:
+		return File{}, nil, ErrFormatVersion
+	case f.Version > FormatVersion:
+		return File{}, nil, fmt.Errorf("blueprint file version %d is newer than supported (%d)", f.Version, FormatVersion)
+	case len(f.Blueprints) == 0:
+		return File{}, nil, ErrEmptyPack
+	}
+	out := make([]model.Blueprint, 0, len(f.Blueprints))
+	for i, p := range f.Blueprints {
+		b := newBlueprintFromUpsert(p.Upsert())
+		if err := Validate(b); err != nil {
+			return File{}, nil, fmt.Errorf("blueprint %d (%q): %w", i+1, p.Title, err)
+		}
+		out = append(out, b)
+	}
+	return f, out, nil
+}
@@ -3,16 +3,33 @@ package blueprint
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"donegeon/internal/config"
 	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/task"
+	"donegeon/internal/webhook"
 )
 
 type Handler struct {
-	repo         Repo
-	repoResolver func(*http.Request) Repo
+	repo              Repo
+	repoResolver      func(*http.Request) Repo
+	taskRepoResolver  func(*http.Request) task.Repo
+	playerResolver    func(*http.Request) *player.FileRepo
+	publisherResolver func(*http.Request) webhook.Publisher
+	pluginResolver    func(*http.Request) task.PluginRunner
+	boardCommand      BoardCommandFunc
+	teamResolver      TeamResolverFunc
+	cfg               *config.Config
 }
 
+// BoardCommandFunc runs a board command (as POST /api/board/cmd would) for
+// the requesting user.
+type BoardCommandFunc func(r *http.Request, cmd string, args map[string]any) (any, error)
+
 func NewHandler(repo Repo) *Handler {
 	return &Handler{repo: repo}
 }
@@ -21,6 +38,86 @@ func (h *Handler) SetRepoResolver(fn func(*http.Request) Repo) {
 	h.repoResolver = fn
 }
 
+func (h *Handler) SetTaskRepoResolver(fn func(*http.Request) task.Repo) {
+	h.taskRepoResolver = fn
+}
+
+func (h *Handler) SetPlayerResolver(fn func(*http.Request) *player.FileRepo) {
+	h.playerResolver = fn
+}
+
+// SetPublisherResolver publishes task.created for the tasks instantiate
+// creates.
+func (h *Handler) SetPublisherResolver(fn func(*http.Request) webhook.Publisher) {
+	h.publisherResolver = fn
+}
+
+// SetPluginResolver runs the requester's plugins on instantiated tasks that
+// carry plugin cards.
+func (h *Handler) SetPluginResolver(fn func(*http.Request) task.PluginRunner) {
+	h.pluginResolver = fn
+}
+
+// SetBoardCommand lets instantiate place the new task on the board.
+func (h *Handler) SetBoardCommand(fn BoardCommandFunc) {
+	h.boardCommand = fn
+}
+
+func (h *Handler) SetConfig(cfg *config.Config) {
+	h.cfg = cfg
+}
+
+func (h *Handler) taskRepoForRequest(r *http.Request) task.Repo {
+	if h.taskRepoResolver == nil {
+		return nil
+	}
+	return h.taskRepoResolver(r)
+}
+
+func (h *Handler) playerForRequest(r *http.Request) *player.FileRepo {
+	if h.playerResolver == nil {
+		return nil
+	}
+	return h.playerResolver(r)
+}
+
+// tasksCreated publishes task.created and runs plugins for the tasks an
+// instantiate made, the top task first.
+func (h *Handler) tasksCreated(r *http.Request, created Instantiated) {
+	all := append([]model.Task{created.Task}, created.Steps...)
+	if h.publisherResolver != nil {
+		if p := h.publisherResolver(r); p != nil {
+			for _, t := range all {
+				p.Publish(webhook.EventTaskCreated, t)
+			}
+		}
+	}
+	if h.pluginResolver == nil {
+		return
+	}
+	var runner task.PluginRunner
+	for _, t := range all {
+		if len(task.PluginCards(t)) == 0 {
+			continue
+		}
+		if runner == nil {
+			if runner = h.pluginResolver(r); runner == nil {
+				return
+			}
+		}
+		runner.TaskChanged(t)
+	}
+}
+
+func hasSlot(slots []string, want string) bool {
+	for _, s := range slots {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *Handler) repoForRequest(r *http.Request) Repo {
 	if h.repoResolver != nil {
 		if repo := h.repoResolver(r); repo != nil {
@@ -68,6 +165,10 @@ func (h *Handler) Root(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		b := newBlueprintFromUpsert(in)
+		if err := Validate(b); err != nil {
+			writeRepoErr(w, err)
+			return
+		}
 		out, err := repo.Create(b)
 		if err != nil {
 			writeErr(w, http.StatusInternalServerError, err.Error())
@@ -82,6 +183,14 @@ func (h *Handler) Root(w http.ResponseWriter, r *http.Request) {
 }
 
 // /api/blueprints/{id}
+// /api/blueprints/{id}/versions
+// /api/blueprints/{id}/versions/{version}/restore
+// /api/blueprints/{id}/instantiate
+// /api/blueprints/{id}/export
+// /api/blueprints/{id}/share
+// /api/blueprints/export
+// /api/blueprints/import
+// /api/blueprints/library[/...]
 func (h *Handler) Sub(w http.ResponseWriter, r *http.Request) {
 	repo := h.repoForRequest(r)
 	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/blueprints/"), "/")
@@ -89,22 +198,227 @@ func (h *Handler) Sub(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, http.StatusNotFound, "not found")
 		return
 	}
+	parts := strings.Split(path, "/")
+	switch parts[0] {
+	case "export":
+		var ids []model.BlueprintID
+		for _, id := range r.URL.Query()["id"] {
+			ids = append(ids, model.BlueprintID(id))
+		}
+		h.export(w, r, repo, ids)
+		return
+	case "import":
+		h.importFile(w, r, repo)
+		return
+	case "library":
+		h.library(w, r, repo, parts[1:])
+		return
+	}
+	id := model.BlueprintID(parts[0])
+
+	switch {
+	case len(parts) == 2 && parts[1] == "export":
+		h.export(w, r, repo, []model.BlueprintID{id})
+		return
+	case len(parts) == 2 && parts[1] == "share":
+		h.share(w, r, repo, id)
+		return
+	case len(parts) == 2 && parts[1] == "versions":
+		h.versions(w, r, repo, id)
+		return
+	case len(parts) == 4 && parts[1] == "versions" && parts[3] == "restore":
+		h.restore(w, r, repo, id, parts[2])
+		return
+	case len(parts) == 2 && parts[1] == "instantiate":
+		h.instantiate(w, r, repo, id)
+		return
+	case len(parts) != 1:
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
 
 	switch r.Method {
 	case http.MethodGet:
-		b, err := repo.Get(model.BlueprintID(path))
-		if err == ErrNotFound {
-			writeErr(w, http.StatusNotFound, "not found")
+		b, err := repo.Get(id)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, b)
+		return
+	case http.MethodPatch:
+		var p Patch
+		if err := decodeJSON(r, &p); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad json")
 			return
 		}
+		b, err := repo.Update(id, p)
 		if err != nil {
-			writeErr(w, http.StatusInternalServerError, err.Error())
+			writeRepoErr(w, err)
 			return
 		}
 		writeJSON(w, http.StatusOK, b)
 		return
+	case http.MethodDelete:
+		if err := repo.Delete(id); err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+		return
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+}
+
+func writeRepoErr(w http.ResponseWriter, err error) {
+	if verrs, ok := err.(VariableErrors); ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":  err.Error(),
+			"fields": verrs,
+		})
+		return
+	}
+	switch err {
+	case ErrNotFound, ErrVersionNotFound:
+		writeErr(w, http.StatusNotFound, "not found")
+	case ErrTitleRequired, task.ErrTooManyMods:
+		writeErr(w, http.StatusBadRequest, err.Error())
 	default:
+		writeErr(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (h *Handler) versions(w http.ResponseWriter, r *http.Request, repo Repo, id model.BlueprintID) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	versions, err := repo.Versions(id)
+	if err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// restore makes an earlier version current again; the replaced content is
+// itself kept as a version.
+func (h *Handler) restore(w http.ResponseWriter, r *http.Request, repo Repo, id model.BlueprintID, rawVersion string) {
+	if r.Method != http.MethodPost {
 		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
+	n, err := strconv.Atoi(rawVersion)
+	if err != nil {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+	versions, err := repo.Versions(id)
+	if err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+	for _, v := range versions {
+		if v.Version != n {
+			continue
+		}
+		b, err := repo.Update(id, Patch{
+			Title:         &v.Title,
+			Description:   &v.Description,
+			ModifierSlots: &v.ModifierSlots,
+			Steps:         &v.Steps,
+			Variables:     &v.Variables,
+			Due:           &v.Due,
+		})
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, b)
+		return
+	}
+	writeRepoErr(w, ErrVersionNotFound)
+}
+
+// POST /api/blueprints/{id}/instantiate
+//
+//	{ "project": "home", "values": { "name": "Ada" }, "spawn": { "x": 120, "y": 80 } }
+//
+// values fills the blueprint's variables (see Resolve). spawn is optional;
+// when set, the new top task is placed on the board as a stack, paying the
+// usual spawn cost.
+func (h *Handler) instantiate(w http.ResponseWriter, r *http.Request, repo Repo, id model.BlueprintID) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var in struct {
+		Project string            `json:"project"`
+		Values  map[string]string `json:"values"`
+		Spawn   *struct {
+			X int `json:"x"`
+			Y int `json:"y"`
+		} `json:"spawn"`
+	}
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &in); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad json")
+			return
+		}
+	}
+	taskRepo := h.taskRepoForRequest(r)
+	if taskRepo == nil {
+		writeErr(w, http.StatusInternalServerError, "task repository unavailable")
+		return
+	}
+	if in.Spawn != nil && h.boardCommand == nil {
+		writeErr(w, http.StatusInternalServerError, "board unavailable")
+		return
+	}
+	b, err := repo.Get(id)
+	if err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+
+	playerRepo := h.playerForRequest(r)
+	b, err = Resolve(b, in.Values, time.Now(), playerRepo.Location())
+	if err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+	if b.Due != "" && playerRepo != nil &&
+		!playerRepo.IsUnlocked(player.FeatureTaskDueDate) &&
+		!hasSlot(b.ModifierSlots, "mod.deadline_pin") {
+		writeErr(w, http.StatusForbidden, "feature locked: "+player.FeatureTaskDueDate)
+		return
+	}
+
+	created, err := Instantiate(taskRepo, h.cfg, b, in.Project)
+	if err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+	h.tasksCreated(r, created)
+	resp := map[string]any{
+		"task":  created.Task,
+		"steps": created.Steps,
+	}
+	if in.Spawn != nil {
+		spawned, err := h.boardCommand(r, "task.spawn_existing", map[string]any{
+			"taskId": string(created.Task.ID),
+			// board command args are decoded JSON, so numbers are float64
+			"x": float64(in.Spawn.X),
+			"y": float64(in.Spawn.Y),
+		})
+		if err != nil {
+			// The tasks exist either way; report why the board placement failed.
+			resp["spawnError"] = err.Error()
+		} else {
+			resp["spawn"] = spawned
+		}
+	}
+	writeJSON(w, http.StatusCreated, resp)
 }
@@ -3,9 +3,16 @@ package blueprint
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+	"donegeon/internal/webhook"
 )
 
 func TestRoot_CreateAndListBlueprints(t *testing.T) {
@@ -40,3 +47,319 @@ func TestRoot_CreateAndListBlueprints(t *testing.T) {
 		t.Fatalf("expected 1 blueprint, got %d", len(out))
 	}
 }
+
+func TestSub_UpdateVersionsInstantiateAndDelete(t *testing.T) {
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	repo = repo.ForUser("u-blueprint")
+	tasks := task.NewMemoryRepo()
+	h := NewHandler(repo)
+	h.SetTaskRepoResolver(func(*http.Request) task.Repo { return tasks })
+	var spawnedArgs map[string]any
+	h.SetBoardCommand(func(_ *http.Request, cmd string, args map[string]any) (any, error) {
+		spawnedArgs = args
+		return map[string]any{"cmd": cmd}, nil
+	})
+
+	b, err := repo.Create(newBlueprintFromUpsert(model.BlueprintUpsert{
+		Title:         "Weekly review",
+		ModifierSlots: []string{"next_action"},
+		Steps:         []string{"Clear inbox"},
+	}))
+	if err != nil {
+		t.Fatalf("create blueprint: %v", err)
+	}
+	base := "/api/blueprints/" + string(b.ID)
+
+	rec := httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodPatch, base, bytes.NewReader([]byte(`{"steps":["Clear inbox","Plan week"]}`))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var updated model.Blueprint
+	if err := json.NewDecoder(rec.Body).Decode(&updated); err != nil {
+		t.Fatalf("decode update: %v", err)
+	}
+	if updated.Version != 2 || len(updated.Steps) != 2 {
+		t.Fatalf("expected version 2 with two steps, got %+v", updated)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodGet, base+"/versions", nil))
+	var versions []model.BlueprintVersion
+	if err := json.NewDecoder(rec.Body).Decode(&versions); err != nil {
+		t.Fatalf("decode versions: %v", err)
+	}
+	if len(versions) != 1 || versions[0].Version != 1 || len(versions[0].Steps) != 1 {
+		t.Fatalf("expected the original as version 1, got %+v", versions)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodPost, base+"/instantiate", bytes.NewReader([]byte(`{"project":"home","spawn":{"x":40,"y":60}}`))))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var created Instantiated
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode instantiate: %v", err)
+	}
+	if len(created.Task.Modifiers) != 1 || created.Task.Modifiers[0].DefID != "mod.next_action" {
+		t.Fatalf("expected modifier slot applied, got %+v", created.Task.Modifiers)
+	}
+	if len(created.Steps) != 2 || created.Steps[1].ParentID == nil || *created.Steps[1].ParentID != created.Task.ID {
+		t.Fatalf("expected two step tasks under the parent, got %+v", created.Steps)
+	}
+	if spawnedArgs["taskId"] != string(created.Task.ID) {
+		t.Fatalf("expected the parent spawned on the board, got %v", spawnedArgs)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodDelete, base, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodGet, base, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", rec.Code)
+	}
+}
+
+func TestSub_InstantiateSubstitutesVariables(t *testing.T) {
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	repo = repo.ForUser("u-blueprint")
+	tasks := task.NewMemoryRepo()
+	h := NewHandler(repo)
+	h.SetTaskRepoResolver(func(*http.Request) task.Repo { return tasks })
+
+	rec := httptest.NewRecorder()
+	h.Root(rec, httptest.NewRequest(http.MethodPost, "/api/blueprints", bytes.NewReader([]byte(`{"title":"Release {{version}}"}`))))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an undeclared placeholder, got %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.Root(rec, httptest.NewRequest(http.MethodPost, "/api/blueprints", bytes.NewReader([]byte(`{"title":"Onboard {{name}}","steps":["Laptop for {{ name }} ({{team}})"],"due":"{{start}}","variables":[{"name":"name"},{"name":"start","type":"offset","default":"+3d"},{"name":"team","type":"choice","choices":["eng","ops"]}]}`))))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var b model.Blueprint
+	if err := json.NewDecoder(rec.Body).Decode(&b); err != nil {
+		t.Fatalf("decode blueprint: %v", err)
+	}
+	path := "/api/blueprints/" + string(b.ID) + "/instantiate"
+
+	rec = httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(`{"values":{"team":"sales"}}`))))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var invalid struct {
+		Fields map[string]string `json:"fields"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&invalid); err != nil {
+		t.Fatalf("decode errors: %v", err)
+	}
+	if invalid.Fields["name"] == "" || invalid.Fields["team"] == "" {
+		t.Fatalf("expected errors for name and team, got %v", invalid.Fields)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(`{"values":{"name":"Ada","team":"ops"}}`))))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var created Instantiated
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode instantiate: %v", err)
+	}
+	wantDue := time.Now().AddDate(0, 0, 3).Format("2006-01-02")
+	if created.Task.Title != "Onboard Ada" || created.Task.DueDate == nil || *created.Task.DueDate != wantDue {
+		t.Fatalf("unexpected task: %+v", created.Task)
+	}
+	if len(created.Steps) != 1 || created.Steps[0].Title != "Laptop for Ada (ops)" {
+		t.Fatalf("unexpected steps: %+v", created.Steps)
+	}
+}
+
+func TestExportImportAndTeamLibrary(t *testing.T) {
+	base, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	users := map[string]*FileRepo{"owner": base.ForUser("owner"), "member": base.ForUser("member"), "outsider": base.ForUser("outsider")}
+	h := NewHandler(base)
+	h.SetRepoResolver(func(r *http.Request) Repo { return users[r.Header.Get("X-User")] })
+	h.SetTeamResolver(func(r *http.Request, team string) (string, bool) {
+		self := r.Header.Get("X-User")
+		owner := strings.TrimPrefix(team, "team_")
+		if owner == "" || owner == self {
+			return self, true
+		}
+		return owner, owner == "owner" && self == "member"
+	})
+	do := func(user, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		h.Sub(rec, req)
+		return rec
+	}
+
+	b, err := users["member"].Create(newBlueprintFromUpsert(model.BlueprintUpsert{
+		Title:     "Onboard {{name}}",
+		Steps:     []string{"Laptop for {{name}}"},
+		Variables: []model.BlueprintVariable{{Name: "name", Type: model.BlueprintVarString}},
+	}))
+	if err != nil {
+		t.Fatalf("create blueprint: %v", err)
+	}
+
+	rec := do("member", http.MethodGet, "/api/blueprints/"+string(b.ID)+"/export?format=yaml", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	exported := rec.Body.String()
+	if !strings.Contains(exported, "version: 1") || !strings.Contains(exported, "Onboard {{name}}") {
+		t.Fatalf("unexpected export:\n%s", exported)
+	}
+
+	rec = do("outsider", http.MethodPost, "/api/blueprints/import", exported)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("import: expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got, _ := users["outsider"].List(); len(got) != 1 || got[0].Variables[0].Name != "name" {
+		t.Fatalf("expected imported blueprint with its variable, got %+v", got)
+	}
+	rec = do("outsider", http.MethodPost, "/api/blueprints/import", `{"version":1,"blueprints":[{"title":"Hi {{who}}"}]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("import undeclared variable: expected 400, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	rec = do("outsider", http.MethodPost, "/api/blueprints/import", `{"version":9,"blueprints":[{"title":"Later"}]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("import future version: expected 400, got %d", rec.Code)
+	}
+
+	rec = do("member", http.MethodPost, "/api/blueprints/"+string(b.ID)+"/share", `{"team":"team_owner"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("share: expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var entry model.BlueprintLibraryEntry
+	_ = json.NewDecoder(rec.Body).Decode(&entry)
+	if rec := do("outsider", http.MethodPost, "/api/blueprints/"+string(b.ID)+"/share", `{"team":"team_owner"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("outsider share: expected 403, got %d", rec.Code)
+	}
+
+	var listed []model.BlueprintLibraryEntry
+	rec = do("owner", http.MethodGet, "/api/blueprints/library", "")
+	_ = json.NewDecoder(rec.Body).Decode(&listed)
+	if len(listed) != 1 || listed[0].SharedBy != "member" {
+		t.Fatalf("owner should see shared entry, got %+v", listed)
+	}
+	rec = do("outsider", http.MethodGet, "/api/blueprints/library", "")
+	listed = nil
+	_ = json.NewDecoder(rec.Body).Decode(&listed)
+	if len(listed) != 0 {
+		t.Fatalf("outsider should not see team library, got %+v", listed)
+	}
+
+	rec = do("owner", http.MethodPost, "/api/blueprints/library/"+entry.ID+"/import", "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("library import: expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got, _ := users["owner"].List(); len(got) != 1 || got[0].Title != "Onboard {{name}}" {
+		t.Fatalf("expected copy in owner's blueprints, got %+v", got)
+	}
+
+	if rec := do("outsider", http.MethodDelete, "/api/blueprints/library/"+entry.ID, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("outsider delete: expected 404, got %d", rec.Code)
+	}
+	if rec := do("owner", http.MethodDelete, "/api/blueprints/library/"+entry.ID, ""); rec.Code != http.StatusOK {
+		t.Fatalf("owner delete: expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+// failingCreates fails every Create after the first ok ones.
+type failingCreates struct {
+	task.Repo
+	ok int
+}
+
+func (f *failingCreates) Create(t model.Task) (model.Task, error) {
+	if f.ok == 0 {
+		return model.Task{}, errors.New("disk full")
+	}
+	f.ok--
+	return f.Repo.Create(t)
+}
+
+func TestInstantiate_ArchivesCreatedTasksWhenAStepFails(t *testing.T) {
+	tasks := task.NewMemoryRepo()
+	repo := &failingCreates{Repo: tasks, ok: 2}
+	b := model.Blueprint{Title: "Launch", Steps: []string{"Draft", "Review", "Ship"}}
+
+	if _, err := Instantiate(repo, nil, b, ""); err == nil {
+		t.Fatalf("expected the third Create to fail")
+	}
+	all, err := tasks.List(task.ListFilter{Status: "all"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected the parent and first step to have been created, got %d", len(all))
+	}
+	for _, tk := range all {
+		if !tk.Archived {
+			t.Fatalf("task %q left behind after a failed instantiation", tk.Title)
+		}
+	}
+
+	if _, err := Instantiate(tasks, nil, model.Blueprint{Title: "  "}, ""); err != ErrTitleRequired {
+		t.Fatalf("expected ErrTitleRequired before any Create, got %v", err)
+	}
+}
+
+type changedTasks []model.Task
+
+func (c *changedTasks) CardAttached(model.Task, string) {}
+func (c *changedTasks) TaskChanged(t model.Task)        { *c = append(*c, t) }
+
+type publishedEvents []string
+
+func (p *publishedEvents) Publish(event string, _ any) { *p = append(*p, event) }
+
+func TestSub_InstantiatePublishesAndRunsPlugins(t *testing.T) {
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	repo = repo.ForUser("u-blueprint")
+	tasks := task.NewMemoryRepo()
+	var changed changedTasks
+	var events publishedEvents
+	h := NewHandler(repo)
+	h.SetTaskRepoResolver(func(*http.Request) task.Repo { return tasks })
+	h.SetPluginResolver(func(*http.Request) task.PluginRunner { return &changed })
+	h.SetPublisherResolver(func(*http.Request) webhook.Publisher { return &events })
+
+	b, err := repo.Create(model.Blueprint{Title: "Trip", ModifierSlots: []string{"mod.plugin_cal_sync"}, Steps: []string{"Book", "Pack"}})
+	if err != nil {
+		t.Fatalf("create blueprint: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodPost, "/api/blueprints/"+string(b.ID)+"/instantiate", nil))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("instantiate: expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if want := strings.Repeat(webhook.EventTaskCreated+",", 3); strings.Join(events, ",")+"," != want {
+		t.Fatalf("expected task.created for the task and both steps, got %v", events)
+	}
+	if len(changed) != 1 || changed[0].Title != "Trip" || changed[0].ID == "" {
+		t.Fatalf("expected the plugin to see the top task, got %+v", changed)
+	}
+}
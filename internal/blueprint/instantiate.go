@@ -0,0 +1,93 @@
+package blueprint
+
+import (
+	"strings"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+// maxModifierSlots mirrors the per-task modifier limit.
+const maxModifierSlots = 4
+
+// Instantiated is the result of turning a blueprint into tasks.
+type Instantiated struct {
+	Task  model.Task   `json:"task"`
+	Steps []model.Task `json:"steps,omitempty"`
+}
+
+// Instantiate creates a task from b, with one child task per step when the
+// blueprint has steps. The blueprint's modifier slots (and any tags they add)
+// and due date go on the top task. b should already be Resolved; a blank
+// project means the inbox.
+//
+// Every task is rendered and checked before the first is created. If a
+// later Create fails, the tasks already created are archived again, so a
+// failed instantiation leaves nothing behind in the user's lists.
+func Instantiate(repo task.Repo, cfg *config.Config, b model.Blueprint, project string) (Instantiated, error) {
+	if len(b.ModifierSlots) > maxModifierSlots {
+		return Instantiated{}, task.ErrTooManyMods
+	}
+	if strings.TrimSpace(b.Title) == "" {
+		return Instantiated{}, ErrTitleRequired
+	}
+	project = strings.TrimSpace(project)
+	if project == "" {
+		project = "inbox"
+	}
+
+	mods := make([]model.TaskModifierSlot, 0, len(b.ModifierSlots))
+	for _, slot := range b.ModifierSlots {
+		mods = append(mods, model.TaskModifierSlot{DefID: slot})
+	}
+	var due *string
+	if b.Due != "" {
+		d := b.Due
+		due = &d
+	}
+	top := model.Task{
+		Title:       strings.TrimSpace(b.Title),
+		Description: b.Description,
+		Project:     &project,
+		Tags:        task.ModifierTags(cfg, mods),
+		Modifiers:   mods,
+		DueDate:     due,
+	}
+	steps := make([]model.Task, 0, len(b.Steps))
+	for _, step := range b.Steps {
+		// A variable can render a step down to nothing; there is no task
+		// to make for it.
+		if step = strings.TrimSpace(step); step != "" {
+			steps = append(steps, model.Task{Title: step, Project: &project})
+		}
+	}
+
+	var out Instantiated
+	parent, err := repo.Create(top)
+	if err != nil {
+		return Instantiated{}, err
+	}
+	out.Task = parent
+	for _, t := range steps {
+		parentID := parent.ID
+		t.ParentID = &parentID
+		created, err := repo.Create(t)
+		if err != nil {
+			rollback(repo, out)
+			return Instantiated{}, err
+		}
+		out.Steps = append(out.Steps, created)
+	}
+	return out, nil
+}
+
+// rollback archives the tasks of a partial instantiation, steps first.
+// Archiving is how tasks are deleted elsewhere, so they stay restorable.
+func rollback(repo task.Repo, partial Instantiated) {
+	archived := true
+	for i := len(partial.Steps) - 1; i >= 0; i-- {
+		_, _ = repo.Update(partial.Steps[i].ID, task.Patch{Archived: &archived})
+	}
+	_, _ = repo.Update(partial.Task.ID, task.Patch{Archived: &archived})
+}
@@ -0,0 +1,239 @@
+package blueprint
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"donegeon/internal/model"
+)
+
+// maxImportBytes caps the size of an uploaded blueprint file.
+const maxImportBytes = 1 << 20
+
+// TeamResolverFunc resolves a team reference ("" for the requester's own
+// team, or a team/owner ID) to the owner's user ID. ok is false when the
+// requester is neither the owner nor a member.
+type TeamResolverFunc func(r *http.Request, team string) (ownerID string, ok bool)
+
+// SetTeamResolver enables the team library.
+func (h *Handler) SetTeamResolver(fn TeamResolverFunc) {
+	h.teamResolver = fn
+}
+
+func (h *Handler) resolveTeam(r *http.Request, team string) (string, bool) {
+	if h.teamResolver == nil {
+		return "", false
+	}
+	return h.teamResolver(r, strings.TrimSpace(team))
+}
+
+// GET /api/blueprints/export?format=yaml&id=bp_1&id=bp_2
+// GET /api/blueprints/{id}/export?format=yaml
+//
+// Without ids every blueprint is exported. format is yaml or json (default).
+func (h *Handler) export(w http.ResponseWriter, r *http.Request, repo Repo, ids []model.BlueprintID) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var bs []model.Blueprint
+	if len(ids) == 0 {
+		all, err := repo.List()
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		bs = all
+	}
+	for _, id := range ids {
+		b, err := repo.Get(id)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		bs = append(bs, b)
+	}
+
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" && len(bs) == 1 {
+		name = bs[0].Title
+	}
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	data, err := Encode(Export(name, bs), format)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ext, ctype := "json", "application/json; charset=utf-8"
+	if format == "yaml" || format == "yml" {
+		ext, ctype = "yaml", "application/yaml; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Content-Disposition", `attachment; filename="blueprints.`+ext+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// POST /api/blueprints/import
+//
+// The body is a blueprint file (YAML or JSON). Nothing is created unless
+// every blueprint in it is valid.
+func (h *Handler) importFile(w http.ResponseWriter, r *http.Request, repo Repo) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		writeErr(w, http.StatusRequestEntityTooLarge, "blueprint file is too large")
+		return
+	}
+	_, bs, err := Decode(data)
+	if err != nil {
+		var verrs VariableErrors
+		if errors.As(err, &verrs) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error(), "fields": verrs})
+			return
+		}
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	created, err := createAll(repo, bs)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"imported": created})
+}
+
+func createAll(repo Repo, bs []model.Blueprint) ([]model.Blueprint, error) {
+	out := make([]model.Blueprint, 0, len(bs))
+	for _, b := range bs {
+		c, err := repo.Create(b)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// POST /api/blueprints/{id}/share  { "team": "team_u_1" }
+//
+// A blank team shares into the requester's own team.
+func (h *Handler) share(w http.ResponseWriter, r *http.Request, repo Repo, id model.BlueprintID) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var in struct {
+		Team string `json:"team"`
+	}
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &in); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad json")
+			return
+		}
+	}
+	if h.teamResolver == nil {
+		writeErr(w, http.StatusInternalServerError, "team library unavailable")
+		return
+	}
+	ownerID, ok := h.resolveTeam(r, in.Team)
+	if !ok {
+		writeErr(w, http.StatusForbidden, "not a member of that team")
+		return
+	}
+	b, err := repo.Get(id)
+	if err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+	entry, err := repo.ShareToLibrary(ownerID, b)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+// GET    /api/blueprints/library
+// POST   /api/blueprints/library/{entryId}/import
+// DELETE /api/blueprints/library/{entryId}
+func (h *Handler) library(w http.ResponseWriter, r *http.Request, repo Repo, parts []string) {
+	if h.teamResolver == nil {
+		writeErr(w, http.StatusInternalServerError, "team library unavailable")
+		return
+	}
+	entries, err := repo.Library()
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	visible := make([]model.BlueprintLibraryEntry, 0, len(entries))
+	for _, e := range entries {
+		if _, ok := h.resolveTeam(r, e.TeamOwnerID); ok {
+			visible = append(visible, e)
+		}
+	}
+
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, visible)
+		return
+	}
+
+	var entry *model.BlueprintLibraryEntry
+	for i := range visible {
+		if visible[i].ID == parts[0] {
+			entry = &visible[i]
+			break
+		}
+	}
+	if entry == nil {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch {
+	case len(parts) == 2 && parts[1] == "import":
+		if r.Method != http.MethodPost {
+			writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		b := newBlueprintFromUpsert(ToPortable(entry.Blueprint).Upsert())
+		created, err := repo.Create(b)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+	case len(parts) == 1:
+		if r.Method != http.MethodDelete {
+			writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		// Only whoever shared it, or the team owner, may take it down.
+		self, _ := h.resolveTeam(r, "")
+		if self != entry.SharedBy && self != entry.TeamOwnerID {
+			writeErr(w, http.StatusForbidden, "only the sharer or team owner can remove this")
+			return
+		}
+		if err := repo.RemoveFromLibrary(entry.ID); err != nil {
+			if err == ErrEntryNotFound {
+				writeErr(w, http.StatusNotFound, "not found")
+				return
+			}
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	default:
+		writeErr(w, http.StatusNotFound, "not found")
+	}
+}
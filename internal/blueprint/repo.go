@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"reflect"
 	"strings"
 	"time"
 
@@ -11,13 +12,41 @@ import (
 )
 
 var (
-	ErrNotFound = errors.New("blueprint not found")
+	ErrNotFound        = errors.New("blueprint not found")
+	ErrVersionNotFound = errors.New("blueprint version not found")
+	ErrTitleRequired   = errors.New("title is required")
+	ErrEntryNotFound   = errors.New("library entry not found")
 )
 
+// Patch represents a partial update.
+// nil pointer => "no change"
+type Patch struct {
+	Title         *string                    `json:"title,omitempty"`
+	Description   *string                    `json:"description,omitempty"`
+	ModifierSlots *[]string                  `json:"modifierSlots,omitempty"`
+	Steps         *[]string                  `json:"steps,omitempty"`
+	Variables     *[]model.BlueprintVariable `json:"variables,omitempty"`
+	Due           *string                    `json:"due,omitempty"`
+}
+
 type Repo interface {
 	Create(b model.Blueprint) (model.Blueprint, error)
 	Get(id model.BlueprintID) (model.Blueprint, error)
 	List() ([]model.Blueprint, error)
+
+	// Update snapshots the current blueprint into its history and bumps
+	// Version; a patch that changes nothing is not recorded.
+	Update(id model.BlueprintID, patch Patch) (model.Blueprint, error)
+	// Delete removes the blueprint and its history.
+	Delete(id model.BlueprintID) error
+	// Versions returns earlier versions, oldest first.
+	Versions(id model.BlueprintID) ([]model.BlueprintVersion, error)
+
+	// Team library. Entries are stored per team (owner user ID) and listed
+	// across all teams; callers check membership.
+	ShareToLibrary(teamOwnerID string, b model.Blueprint) (model.BlueprintLibraryEntry, error)
+	Library() ([]model.BlueprintLibraryEntry, error)
+	RemoveFromLibrary(entryID string) error
 }
 
 func newID(prefix string) model.BlueprintID {
@@ -35,6 +64,10 @@ func normalizeBlueprint(in *model.Blueprint) {
 	}
 	in.Title = strings.TrimSpace(in.Title)
 	in.Description = strings.TrimSpace(in.Description)
+	if in.Version < 1 {
+		// blueprints saved before versioning
+		in.Version = 1
+	}
 
 	slots := make([]string, 0, len(in.ModifierSlots))
 	for _, s := range in.ModifierSlots {
@@ -58,6 +91,77 @@ func normalizeBlueprint(in *model.Blueprint) {
 		steps = append(steps, s)
 	}
 	in.Steps = steps
+
+	in.Due = strings.TrimSpace(in.Due)
+	vars := make([]model.BlueprintVariable, 0, len(in.Variables))
+	for _, v := range in.Variables {
+		v.Name = strings.TrimSpace(v.Name)
+		v.Label = strings.TrimSpace(v.Label)
+		v.Type = strings.ToLower(strings.TrimSpace(v.Type))
+		if v.Type == "" {
+			v.Type = model.BlueprintVarString
+		}
+		v.Default = strings.TrimSpace(v.Default)
+		choices := make([]string, 0, len(v.Choices))
+		for _, c := range v.Choices {
+			if c = strings.TrimSpace(c); c != "" {
+				choices = append(choices, c)
+			}
+		}
+		v.Choices = choices
+		if len(v.Choices) == 0 {
+			v.Choices = nil
+		}
+		vars = append(vars, v)
+	}
+	in.Variables = vars
+	if len(in.Variables) == 0 {
+		in.Variables = nil
+	}
+}
+
+func applyPatch(b *model.Blueprint, p Patch) {
+	if p.Title != nil {
+		b.Title = *p.Title
+	}
+	if p.Description != nil {
+		b.Description = *p.Description
+	}
+	if p.ModifierSlots != nil {
+		b.ModifierSlots = append([]string{}, (*p.ModifierSlots)...)
+	}
+	if p.Steps != nil {
+		b.Steps = append([]string{}, (*p.Steps)...)
+	}
+	if p.Variables != nil {
+		b.Variables = append([]model.BlueprintVariable{}, (*p.Variables)...)
+	}
+	if p.Due != nil {
+		b.Due = *p.Due
+	}
+}
+
+// sameContent reports whether two blueprints differ only in bookkeeping.
+func sameContent(a, b model.Blueprint) bool {
+	return a.Title == b.Title &&
+		a.Description == b.Description &&
+		strings.Join(a.ModifierSlots, "\x00") == strings.Join(b.ModifierSlots, "\x00") &&
+		strings.Join(a.Steps, "\x00") == strings.Join(b.Steps, "\x00") &&
+		reflect.DeepEqual(a.Variables, b.Variables) &&
+		a.Due == b.Due
+}
+
+func snapshot(b model.Blueprint, at time.Time) model.BlueprintVersion {
+	return model.BlueprintVersion{
+		Version:       b.Version,
+		Title:         b.Title,
+		Description:   b.Description,
+		ModifierSlots: append([]string{}, b.ModifierSlots...),
+		Steps:         append([]string{}, b.Steps...),
+		Variables:     append([]model.BlueprintVariable{}, b.Variables...),
+		Due:           b.Due,
+		SavedAt:       at,
+	}
 }
 
 func newBlueprintFromUpsert(u model.BlueprintUpsert) model.Blueprint {
@@ -68,6 +172,9 @@ func newBlueprintFromUpsert(u model.BlueprintUpsert) model.Blueprint {
 		Description:   u.Description,
 		ModifierSlots: append([]string{}, u.ModifierSlots...),
 		Steps:         append([]string{}, u.Steps...),
+		Variables:     append([]model.BlueprintVariable{}, u.Variables...),
+		Due:           u.Due,
+		Version:       1,
 		CreatedAt:     now,
 		UpdatedAt:     now,
 	}
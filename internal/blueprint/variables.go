@@ -0,0 +1,193 @@
+package blueprint
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	varNameRe     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	offsetRe      = regexp.MustCompile(`^([+-]?\d{1,4})([dw])$`)
+)
+
+// VariableErrors maps a variable name (or "due") to what is wrong with it.
+type VariableErrors map[string]string
+
+func (e VariableErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+": "+e[k])
+	}
+	return "invalid variables: " + strings.Join(parts, "; ")
+}
+
+// Validate checks a blueprint before it is saved: it needs a title, its
+// variables must be well formed and every {{placeholder}} must be declared.
+func Validate(b model.Blueprint) error {
+	if b.Title == "" {
+		return ErrTitleRequired
+	}
+	errs := VariableErrors{}
+	declared := map[string]bool{}
+	for _, v := range b.Variables {
+		switch {
+		case !varNameRe.MatchString(v.Name):
+			errs[v.Name] = "name must be letters, digits or _"
+			continue
+		case declared[v.Name]:
+			errs[v.Name] = "declared twice"
+			continue
+		}
+		declared[v.Name] = true
+		switch v.Type {
+		case model.BlueprintVarString, model.BlueprintVarDate, model.BlueprintVarOffset:
+		case model.BlueprintVarChoice:
+			if len(v.Choices) == 0 {
+				errs[v.Name] = "choice variables need choices"
+				continue
+			}
+		default:
+			errs[v.Name] = "type must be string, date, offset or choice"
+			continue
+		}
+		if v.Default != "" {
+			if _, msg := resolveValue(v, v.Default, time.Time{}, time.UTC); msg != "" {
+				errs[v.Name] = "default " + msg
+			}
+		}
+	}
+
+	texts := append([]string{b.Title, b.Description, b.Due}, b.Steps...)
+	for _, text := range texts {
+		for _, m := range placeholderRe.FindAllStringSubmatch(text, -1) {
+			if !declared[m[1]] {
+				errs[m[1]] = "used but not declared"
+			}
+		}
+	}
+	if b.Due != "" && !placeholderRe.MatchString(b.Due) {
+		if _, ok := resolveDue(b.Due, time.Time{}, time.UTC); !ok {
+			errs["due"] = "must be YYYY-MM-DD, an offset like +3d or a {{variable}}"
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Resolve returns a copy of b with values substituted for its placeholders
+// and Due turned into a date. now and loc give the day offsets count from.
+func Resolve(b model.Blueprint, values map[string]string, now time.Time, loc *time.Location) (model.Blueprint, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	errs := VariableErrors{}
+	resolved := map[string]string{}
+	declared := map[string]bool{}
+	for _, v := range b.Variables {
+		declared[v.Name] = true
+		raw := strings.TrimSpace(values[v.Name])
+		if raw == "" {
+			raw = v.Default
+		}
+		if raw == "" {
+			errs[v.Name] = "is required"
+			continue
+		}
+		val, msg := resolveValue(v, raw, now, loc)
+		if msg != "" {
+			errs[v.Name] = msg
+			continue
+		}
+		resolved[v.Name] = val
+	}
+	for name := range values {
+		if !declared[name] {
+			errs[name] = "unknown variable"
+		}
+	}
+	if len(errs) > 0 {
+		return model.Blueprint{}, errs
+	}
+
+	sub := func(s string) string {
+		return placeholderRe.ReplaceAllStringFunc(s, func(m string) string {
+			return resolved[placeholderRe.FindStringSubmatch(m)[1]]
+		})
+	}
+	out := b
+	out.Title = sub(b.Title)
+	out.Description = sub(b.Description)
+	out.Steps = make([]string, 0, len(b.Steps))
+	for _, step := range b.Steps {
+		out.Steps = append(out.Steps, sub(step))
+	}
+	out.Due = ""
+	if due := strings.TrimSpace(sub(b.Due)); due != "" {
+		date, ok := resolveDue(due, now, loc)
+		if !ok {
+			return model.Blueprint{}, VariableErrors{"due": "must resolve to a date, got " + strconv.Quote(due)}
+		}
+		out.Due = date
+	}
+	return out, nil
+}
+
+// resolveValue checks raw against the variable's type and returns the text
+// to substitute, or a message saying what is wrong.
+func resolveValue(v model.BlueprintVariable, raw string, now time.Time, loc *time.Location) (string, string) {
+	switch v.Type {
+	case model.BlueprintVarDate:
+		if _, err := time.Parse(dateLayout, raw); err != nil {
+			return "", "must be YYYY-MM-DD"
+		}
+	case model.BlueprintVarOffset:
+		date, ok := applyOffset(raw, now, loc)
+		if !ok {
+			return "", "must be an offset like +3d or +2w"
+		}
+		return date, ""
+	case model.BlueprintVarChoice:
+		for _, c := range v.Choices {
+			if c == raw {
+				return raw, ""
+			}
+		}
+		return "", "must be one of " + strings.Join(v.Choices, ", ")
+	}
+	return raw, ""
+}
+
+// resolveDue accepts a date or an offset.
+func resolveDue(due string, now time.Time, loc *time.Location) (string, bool) {
+	if _, err := time.Parse(dateLayout, due); err == nil {
+		return due, true
+	}
+	return applyOffset(due, now, loc)
+}
+
+func applyOffset(raw string, now time.Time, loc *time.Location) (string, bool) {
+	m := offsetRe.FindStringSubmatch(strings.ToLower(strings.TrimSpace(raw)))
+	if m == nil {
+		return "", false
+	}
+	n, _ := strconv.Atoi(m[1])
+	if m[2] == "w" {
+		n *= 7
+	}
+	return now.In(loc).AddDate(0, 0, n).Format(dateLayout), true
+}
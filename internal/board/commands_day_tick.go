@@ -20,12 +20,13 @@ func (h *Handler) cmdWorldEndDay(state *model.BoardState, taskRepo task.Repo, pl
 	if taskRepo == nil {
 		return nil, fmt.Errorf("task repository unavailable")
 	}
+	taskRepo = taskRepo.WithOrigin(task.SourceDayTick, "")
 
-	now := time.Now().In(time.Local)
+	now := time.Now().In(playerRepo.Location())
 	tickDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
 	recurrenceSpawnEnabled := h.cfg != nil && h.cfg.World.DayTick.RecurrenceRules.SpawnIfDue
 
-	allTasks, err := taskRepo.List(task.ListFilter{Status: "all"})
+	allTasks, err := taskRepo.List(task.ListFilter{Status: "all", Archived: "exclude"})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tasks: %w", err)
 	}
@@ -46,12 +47,16 @@ func (h *Handler) cmdWorldEndDay(state *model.BoardState, taskRepo task.Repo, pl
 		}
 
 		if recurrenceSpawnEnabled && t.Done && t.Recurrence != nil {
-			nextDue := nextRecurrenceDueDate(t, tickDate)
-			done := false
-			patch.Done = &done
-			patch.DueDate = &nextDue
-			needsUpdate = true
-			respawned = true
+			if nextDue, ok := task.NextRecurrenceDueDate(t, tickDate); ok {
+				done := false
+				rec := *t.Recurrence
+				rec.Occurrences++
+				patch.Done = &done
+				patch.DueDate = task.Some(nextDue)
+				patch.Recurrence = task.Some(rec)
+				needsUpdate = true
+				respawned = true
+			}
 		}
 
 		if !needsUpdate {
@@ -67,7 +72,7 @@ func (h *Handler) cmdWorldEndDay(state *model.BoardState, taskRepo task.Repo, pl
 		}
 	}
 
-	pendingTasks, err := taskRepo.List(task.ListFilter{Status: "pending"})
+	pendingTasks, err := taskRepo.List(task.ListFilter{Status: "pending", Archived: "exclude"})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list pending tasks: %w", err)
 	}
@@ -461,6 +466,9 @@ func stackHasKind(state *model.BoardState, stack *model.Stack, kind string) bool
 	return false
 }
 
+// isTaskOverdueAtTick compares in tickDate's location (the user's timezone).
+// Date-only tasks are measured from the start of their due day, as before
+// due times existed; timed tasks from their due instant.
 func isTaskOverdueAtTick(t model.Task, tickDate time.Time, graceHours int) bool {
 	if t.Done || t.DueDate == nil {
 		return false
@@ -469,49 +477,21 @@ func isTaskOverdueAtTick(t model.Task, tickDate time.Time, graceHours int) bool
 	if raw == "" {
 		return false
 	}
-	dueDate, err := time.ParseInLocation(ymdLayout, raw, time.Local)
+	dueDate, err := time.ParseInLocation(ymdLayout, raw, tickDate.Location())
 	if err != nil {
 		return false
 	}
+	if t.DueTime != nil {
+		if dueAt, ok := task.DueAt(t, tickDate.Location()); ok {
+			dueDate = dueAt
+		}
+	}
 	if graceHours > 0 {
 		dueDate = dueDate.Add(time.Duration(graceHours) * time.Hour)
 	}
 	return dueDate.Before(tickDate)
 }
 
-func addRecurrence(base time.Time, recurrence *model.Recurrence) time.Time {
-	if recurrence == nil {
-		return base
-	}
-	interval := recurrence.Interval
-	if interval <= 0 {
-		interval = 1
-	}
-	switch strings.ToLower(strings.TrimSpace(recurrence.Type)) {
-	case "weekly":
-		return base.AddDate(0, 0, 7*interval)
-	case "monthly":
-		return base.AddDate(0, interval, 0)
-	case "daily", "":
-		return base.AddDate(0, 0, interval)
-	default:
-		return base.AddDate(0, 0, interval)
-	}
-}
-
-func nextRecurrenceDueDate(t model.Task, tickDate time.Time) string {
-	next := tickDate
-	if t.DueDate != nil {
-		if parsed, err := time.ParseInLocation(ymdLayout, strings.TrimSpace(*t.DueDate), time.Local); err == nil {
-			next = parsed
-		}
-	}
-	for next.Before(tickDate) {
-		next = addRecurrence(next, t.Recurrence)
-	}
-	return next.Format(ymdLayout)
-}
-
 func (h *Handler) spawnOverdueZombies(state *model.BoardState, overdueTaskIDs []string) []*model.Stack {
 	if len(overdueTaskIDs) == 0 {
 		return nil
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"donegeon/internal/config"
 	"donegeon/internal/model"
 	"donegeon/internal/player"
 	"donegeon/internal/task"
@@ -82,7 +83,7 @@ func (h *Handler) cmdTaskCompleteByTaskID(state *model.BoardState, taskRepo task
 		patch := task.Patch{Done: &done}
 		habitBonusCoin := 0
 		if cur, err := taskRepo.Get(model.TaskID(taskID)); err == nil && !cur.Done {
-			habitPatch, habitResult := task.BuildHabitCompletionUpdate(cur, time.Now())
+			habitPatch, habitResult := task.BuildHabitCompletionUpdate(cur, time.Now().In(playerRepo.Location()))
 			patch.CompletionCountDelta = habitPatch.CompletionCountDelta
 			patch.Habit = habitPatch.Habit
 			patch.HabitTier = habitPatch.HabitTier
@@ -194,12 +195,17 @@ func (h *Handler) cmdTaskCompleteStack(state *model.BoardState, taskRepo task.Re
 		createdStacks = append(createdStacks, ns)
 	}
 
+	var trackedSeconds int64
 	if taskRepo != nil && taskID != "" {
 		done := true
 		patch := task.Patch{Done: &done}
 		habitBonusCoin := 0
-		if cur, err := taskRepo.Get(model.TaskID(taskID)); err == nil && !cur.Done {
-			habitPatch, habitResult := task.BuildHabitCompletionUpdate(cur, time.Now())
+		cur, err := taskRepo.Get(model.TaskID(taskID))
+		if err == nil {
+			trackedSeconds = cur.TrackedSeconds
+		}
+		if err == nil && !cur.Done {
+			habitPatch, habitResult := task.BuildHabitCompletionUpdate(cur, time.Now().In(playerRepo.Location()))
 			patch.CompletionCountDelta = habitPatch.CompletionCountDelta
 			patch.Habit = habitPatch.Habit
 			patch.HabitTier = habitPatch.HabitTier
@@ -218,7 +224,7 @@ func (h *Handler) cmdTaskCompleteStack(state *model.BoardState, taskRepo task.Re
 	villagerProgress := player.VillagerProgress{Level: 1}
 	awardedPerks := []string{}
 	if playerRepo != nil && hasVillager && villagerID != "" {
-		xpGained = h.taskCompleteXP()
+		xpGained = h.taskCompleteXP(trackedSeconds)
 		if xpGained > 0 {
 			vp, newPerks, _, err := h.awardVillagerXP(playerRepo, villagerID, xpGained)
 			if err != nil {
@@ -251,7 +257,7 @@ func (h *Handler) cmdTaskCompleteStack(state *model.BoardState, taskRepo task.Re
 	}, nil
 }
 
-func (h *Handler) taskCompleteXP() int {
+func (h *Handler) taskCompleteXP(trackedSeconds int64) int {
 	if h.cfg == nil {
 		return 0
 	}
@@ -259,8 +265,21 @@ func (h *Handler) taskCompleteXP() int {
 	if bonus, ok := h.cfg.Villagers.Leveling.XPSources.CompleteTask.ByPriority["none"]; ok {
 		xp += bonus
 	}
+	xp += trackedTimeXP(h.cfg.Villagers.Leveling.XPSources.TrackedTime, trackedSeconds)
 	if xp < 0 {
 		xp = 0
 	}
 	return xp
 }
+
+// trackedTimeXP awards xp_per_hour pro rata for time logged on the task.
+func trackedTimeXP(cfg config.TrackedTimeXP, trackedSeconds int64) int {
+	if cfg.XPPerHour <= 0 || trackedSeconds <= 0 {
+		return 0
+	}
+	xp := int(trackedSeconds * int64(cfg.XPPerHour) / 3600)
+	if cfg.MaxXPPerTask > 0 && xp > cfg.MaxXPPerTask {
+		xp = cfg.MaxXPPerTask
+	}
+	return xp
+}
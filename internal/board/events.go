@@ -0,0 +1,111 @@
+package board
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/task"
+	"donegeon/internal/webhook"
+)
+
+// boardEvent is a webhook event caused by a board command.
+type boardEvent struct {
+	name string
+	data any
+}
+
+func (h *Handler) SetPublisherResolver(fn func(*http.Request) webhook.Publisher) {
+	h.publisherResolver = fn
+}
+
+func (h *Handler) publish(r *http.Request, events []boardEvent) {
+	if h.publisherResolver == nil || len(events) == 0 {
+		return
+	}
+	p := h.publisherResolver(r)
+	if p == nil {
+		return
+	}
+	for _, e := range events {
+		p.Publish(e.name, e.data)
+	}
+}
+
+// villagerLevels snapshots villager levels so level-ups can be found after
+// a command runs.
+func villagerLevels(playerRepo *player.FileRepo) map[string]int {
+	out := map[string]int{}
+	if playerRepo == nil {
+		return out
+	}
+	for id, vp := range playerRepo.GetState().Villagers {
+		out[id] = vp.Level
+	}
+	return out
+}
+
+// commandEvents lists the events a successful command caused, read from its
+// result and from villager levels before and after it ran.
+func commandEvents(cmd string, result any, taskRepo task.Repo, playerRepo *player.FileRepo, levelsBefore map[string]int) []boardEvent {
+	out := make([]boardEvent, 0)
+	res, _ := result.(map[string]any)
+
+	taskEvent := func(name string, id any) {
+		if taskRepo == nil || id == nil {
+			return
+		}
+		taskID := model.TaskID(fmt.Sprint(id))
+		if taskID == "" {
+			return
+		}
+		if t, err := taskRepo.Get(taskID); err == nil {
+			out = append(out, boardEvent{name, t})
+		}
+	}
+	switch cmd {
+	case "task.create_blank":
+		taskEvent(webhook.EventTaskCreated, res["taskId"])
+	case "task.complete_stack", "task.complete_by_task_id":
+		taskEvent(webhook.EventTaskCompleted, res["completedTaskId"])
+	case "deck.open_pack":
+		out = append(out, boardEvent{webhook.EventDeckOpened, res["deck"]})
+	case "world.end_day":
+		if n, _ := res["spawnedZombieCount"].(int); n > 0 {
+			out = append(out, boardEvent{webhook.EventZombieSpawned, map[string]any{
+				"count":          n,
+				"stacks":         res["spawnedZombieStacks"],
+				"overdueTaskIds": res["overdueTaskIds"],
+			}})
+		}
+		out = append(out, boardEvent{webhook.EventDayEnded, res})
+	}
+
+	if playerRepo != nil {
+		villagers := playerRepo.GetState().Villagers
+		ids := make([]string, 0, len(villagers))
+		for id := range villagers {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			vp := villagers[id]
+			before, ok := levelsBefore[id]
+			if !ok {
+				before = 1
+			}
+			if vp.Level > before {
+				out = append(out, boardEvent{webhook.EventVillagerLevelUp, map[string]any{
+					"villagerId":    id,
+					"level":         vp.Level,
+					"previousLevel": before,
+					"xp":            vp.XP,
+					"perks":         vp.Perks,
+				}})
+			}
+		}
+	}
+	return out
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 
 	"donegeon/internal/model"
@@ -94,3 +96,39 @@ func (r *FileRepo) Save(boardID string, state *model.BoardState) error {
 	path := r.filePath(boardID)
 	return os.WriteFile(path, data, 0644)
 }
+
+// UserBoardID is the storage ID of one of a user's named boards.
+func UserBoardID(userID, name string) string {
+	return "user_" + userID + "__" + name
+}
+
+// UserBoards lists the names of the boards stored for userID, sorted.
+func (r *FileRepo) UserBoards(userID string) ([]string, error) {
+	prefix := UserBoardID(userID, "")
+	names := map[string]bool{}
+
+	r.mu.RLock()
+	for id := range r.cache {
+		if strings.HasPrefix(id, prefix) {
+			names[strings.TrimPrefix(id, prefix)] = true
+		}
+	}
+	r.mu.RUnlock()
+
+	entries, err := os.ReadDir(r.dataDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if ok && !e.IsDir() && strings.HasPrefix(id, prefix) {
+			names[strings.TrimPrefix(id, prefix)] = true
+		}
+	}
+	out := make([]string, 0, len(names))
+	for name := range names {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
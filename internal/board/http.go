@@ -4,22 +4,26 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"donegeon/internal/config"
 	"donegeon/internal/model"
 	"donegeon/internal/player"
 	"donegeon/internal/task"
+	"donegeon/internal/webhook"
 )
 
 // Handler handles board-related HTTP requests.
 type Handler struct {
-	repo             Repo
-	taskRepo         task.Repo
-	validator        *Validator
-	cfg              *config.Config
-	boardIDResolver  func(*http.Request) string
-	taskRepoResolver func(*http.Request) task.Repo
-	playerResolver   func(*http.Request) *player.FileRepo
+	repo              Repo
+	taskRepo          task.Repo
+	validator         *Validator
+	cfg               *config.Config
+	boardIDResolver   func(*http.Request) string
+	taskRepoResolver  func(*http.Request) task.Repo
+	playerResolver    func(*http.Request) *player.FileRepo
+	publisherResolver func(*http.Request) webhook.Publisher
+	pluginResolver    func(*http.Request) task.PluginRunner
 }
 
 // NewHandler creates a new board handler.
@@ -229,7 +233,9 @@ func (h *Handler) Command(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	patch, err := h.executeCommand(state, h.taskRepoFromRequest(r), h.playerRepoFromRequest(r), req.Cmd, req.Args)
+	taskRepo, playerRepo := h.taskRepoFromRequest(r), h.playerRepoFromRequest(r)
+	levels := villagerLevels(playerRepo)
+	patch, err := h.executeCommand(state, taskRepo, playerRepo, req.Cmd, req.Args)
 	if err != nil {
 		writeJSON(w, 400, CommandResponse{
 			OK:    false,
@@ -242,6 +248,8 @@ func (h *Handler) Command(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, 500, err.Error())
 		return
 	}
+	h.publish(r, commandEvents(req.Cmd, patch, taskRepo, playerRepo, levels))
+	h.runPlugins(r, req.Cmd, patch, taskRepo)
 
 	writeJSON(w, 200, CommandResponse{
 		OK:         true,
@@ -250,8 +258,33 @@ func (h *Handler) Command(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// RunCommand executes one board command for the request's user outside of
+// POST /api/board/cmd (no client version check) and saves the board.
+func (h *Handler) RunCommand(r *http.Request, cmd string, args map[string]any) (any, error) {
+	boardID := h.boardIDFromRequest(r)
+	state, err := h.repo.Load(boardID)
+	if err != nil {
+		return nil, err
+	}
+	taskRepo, playerRepo := h.taskRepoFromRequest(r), h.playerRepoFromRequest(r)
+	levels := villagerLevels(playerRepo)
+	patch, err := h.executeCommand(state, taskRepo, playerRepo, cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.repo.Save(boardID, state); err != nil {
+		return nil, err
+	}
+	h.publish(r, commandEvents(cmd, patch, taskRepo, playerRepo, levels))
+	h.runPlugins(r, cmd, patch, taskRepo)
+	return patch, nil
+}
+
 // executeCommand dispatches the command to the appropriate handler.
 func (h *Handler) executeCommand(state *model.BoardState, taskRepo task.Repo, playerRepo *player.FileRepo, cmd string, args map[string]any) (any, error) {
+	if taskRepo != nil {
+		taskRepo = taskRepo.WithOrigin(task.SourceBoard, "")
+	}
 	switch cmd {
 	case "board.seed_default":
 		return h.cmdBoardSeedDefault(state, args)
@@ -266,7 +299,7 @@ func (h *Handler) executeCommand(state *model.BoardState, taskRepo task.Repo, pl
 	case "stack.bringToFront":
 		return h.cmdStackBringToFront(state, args)
 	case "stack.merge":
-		return h.cmdStackMerge(state, args)
+		return h.cmdStackMerge(state, taskRepo, args)
 	case "stack.split":
 		return h.cmdStackSplit(state, args)
 	case "stack.unstack":
@@ -281,10 +314,12 @@ func (h *Handler) executeCommand(state *model.BoardState, taskRepo task.Repo, pl
 		return h.cmdTaskSetTitle(state, taskRepo, args)
 	case "task.set_description":
 		return h.cmdTaskSetDescription(state, taskRepo, args)
+	case "task.set_project":
+		return h.cmdTaskSetProject(state, taskRepo, args)
 	case "task.set_task_id":
 		return h.cmdTaskSetTaskID(state, taskRepo, args)
 	case "task.add_modifier":
-		return h.cmdTaskAddModifier(state, args)
+		return h.cmdTaskAddModifier(state, taskRepo, args)
 	case "task.assign_villager":
 		return h.cmdTaskAssignVillager(state, taskRepo, args)
 	case "task.complete_stack":
@@ -422,7 +457,7 @@ func (h *Handler) cmdStackBringToFront(state *model.BoardState, args map[string]
 }
 
 // stack.merge { targetId, sourceId }
-func (h *Handler) cmdStackMerge(state *model.BoardState, args map[string]any) (any, error) {
+func (h *Handler) cmdStackMerge(state *model.BoardState, taskRepo task.Repo, args map[string]any) (any, error) {
 	targetID, err := getString(args, "targetId")
 	if err != nil {
 		return nil, err
@@ -451,10 +486,12 @@ func (h *Handler) cmdStackMerge(state *model.BoardState, args map[string]any) (a
 	state.MergeStacks(model.StackID(targetID), model.StackID(sourceID))
 	ensureTaskFaceCard(state, target)
 
-	return map[string]any{
+	out := map[string]any{
 		"target":        target,
 		"removedSource": sourceID,
-	}, nil
+	}
+	addPluginAttach(out, attachPluginCards(state, target, taskRepo))
+	return out, nil
 }
 
 // stack.split { stackId, index, offsetX, offsetY }
@@ -662,7 +699,7 @@ func (h *Handler) cmdTaskSetDescription(state *model.BoardState, taskRepo task.R
 	// Sync to task repo if linked
 	if taskRepo != nil {
 		if taskIDStr, ok := card.Data["taskId"].(string); ok && taskIDStr != "" {
-			_, _ = taskRepo.Update(model.TaskID(taskIDStr), task.Patch{Description: &description})
+			_, _ = taskRepo.Update(model.TaskID(taskIDStr), task.Patch{Description: task.Some(description)})
 			_ = taskRepo.SetLive(model.TaskID(taskIDStr), true)
 		}
 	}
@@ -672,8 +709,46 @@ func (h *Handler) cmdTaskSetDescription(state *model.BoardState, taskRepo task.R
 	}, nil
 }
 
+// task.set_project { taskCardId, project }
+// Dropping a task onto a project card files it there; "" means inbox.
+func (h *Handler) cmdTaskSetProject(state *model.BoardState, taskRepo task.Repo, args map[string]any) (any, error) {
+	cardID, err := getString(args, "taskCardId")
+	if err != nil {
+		return nil, err
+	}
+	project, err := getString(args, "project")
+	if err != nil {
+		return nil, err
+	}
+	project = strings.TrimSpace(project)
+	if project == "" {
+		project = "inbox"
+	}
+
+	card := state.GetCard(model.CardID(cardID))
+	if card == nil {
+		return nil, fmt.Errorf("card not found: %s", cardID)
+	}
+
+	if card.Data == nil {
+		card.Data = make(map[string]any)
+	}
+	card.Data["project"] = project
+
+	// Sync to task repo if linked
+	if taskRepo != nil {
+		if taskIDStr, ok := card.Data["taskId"].(string); ok && taskIDStr != "" {
+			_, _ = taskRepo.Update(model.TaskID(taskIDStr), task.Patch{Project: task.Some(project)})
+		}
+	}
+
+	return map[string]any{
+		"card": card,
+	}, nil
+}
+
 // task.add_modifier { taskStackId, modifierDefId }
-func (h *Handler) cmdTaskAddModifier(state *model.BoardState, args map[string]any) (any, error) {
+func (h *Handler) cmdTaskAddModifier(state *model.BoardState, taskRepo task.Repo, args map[string]any) (any, error) {
 	stackID, err := getString(args, "taskStackId")
 	if err != nil {
 		return nil, err
@@ -702,10 +777,12 @@ func (h *Handler) cmdTaskAddModifier(state *model.BoardState, args map[string]an
 	stack.Cards = append(stack.Cards, modCard.ID)
 	ensureTaskFaceCard(state, stack)
 
-	return map[string]any{
+	out := map[string]any{
 		"stack":    stack,
 		"modifier": modCard,
-	}, nil
+	}
+	addPluginAttach(out, attachPluginCards(state, stack, taskRepo))
+	return out, nil
 }
 
 // task.assign_villager { taskStackId, villagerStackId }
@@ -771,7 +848,7 @@ func (h *Handler) cmdTaskAssignVillager(state *model.BoardState, taskRepo task.R
 	}
 	if taskRepo != nil && taskID != "" {
 		assigned := villagerStackID
-		_, _ = taskRepo.Update(model.TaskID(taskID), task.Patch{AssignedVillagerID: &assigned})
+		_, _ = taskRepo.Update(model.TaskID(taskID), task.Patch{AssignedVillagerID: task.Some(assigned)})
 	}
 
 	return map[string]any{
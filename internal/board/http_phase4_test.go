@@ -240,3 +240,61 @@ func TestCommand_WorldEndDay_ZombieSpawnChanceZeroSkipsSpawn(t *testing.T) {
 		t.Fatalf("expected 0 zombies with spawn chance 0, got %d", got)
 	}
 }
+
+func TestCommand_WorldEndDay_IgnoresArchivedTasks(t *testing.T) {
+	taskRepo := task.NewMemoryRepo()
+	playerRepo, err := player.NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new player repo: %v", err)
+	}
+	playerRepo = playerRepo.ForUser("u-phase4-archived")
+
+	cfg := testBoardConfig()
+	cfg.World.DayTick.OverdueRules.ZombieSpawn = config.ZombieSpawn{
+		Enabled:        true,
+		PerOverdueTask: 1,
+		CapPerDay:      5,
+	}
+	cfg.World.DayTick.RecurrenceRules.SpawnIfDue = true
+	cfg.Zombies.Types = []config.ZombieType{{ID: "default_zombie"}}
+	cfg.Tasks.DueDate.GraceHours = 0
+
+	inbox := "inbox"
+	yesterday := time.Now().AddDate(0, 0, -1).Format(ymdLayout)
+	today := time.Now().Format(ymdLayout)
+	if _, err := taskRepo.Create(model.Task{
+		Title:    "Archived overdue",
+		Project:  &inbox,
+		DueDate:  &yesterday,
+		Archived: true,
+	}); err != nil {
+		t.Fatalf("create archived overdue task: %v", err)
+	}
+	recurring, err := taskRepo.Create(model.Task{
+		Title:      "Archived recurring",
+		Project:    &inbox,
+		DueDate:    &today,
+		Done:       true,
+		Archived:   true,
+		Recurrence: &model.Recurrence{Type: "weekly", Interval: 1},
+	})
+	if err != nil {
+		t.Fatalf("create archived recurring task: %v", err)
+	}
+
+	h := NewHandler(NewMemoryRepo(), taskRepo, cfg)
+	state := model.NewBoardState()
+	if _, err := h.executeCommand(state, taskRepo, playerRepo, "world.end_day", map[string]any{}); err != nil {
+		t.Fatalf("world.end_day: %v", err)
+	}
+	if got := countZombieStacks(state); got != 0 {
+		t.Fatalf("expected no zombies for an archived task, got %d", got)
+	}
+	got, err := taskRepo.Get(recurring.ID)
+	if err != nil {
+		t.Fatalf("get recurring task: %v", err)
+	}
+	if !got.Done || got.DueDate == nil || *got.DueDate != today {
+		t.Fatalf("archived recurring task was respawned: done=%v due=%v", got.Done, got.DueDate)
+	}
+}
@@ -1,6 +1,8 @@
 package board
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -364,3 +366,151 @@ func TestCommand_FoodConsume_RestoresStaminaAndConsumesFood(t *testing.T) {
 		t.Fatalf("expected food stack removed after consume")
 	}
 }
+
+func TestCommandEvents_TaskCompleteStackReportsCompletionAndLevelUp(t *testing.T) {
+	taskRepo := task.NewMemoryRepo()
+	playerRepo, err := player.NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new player repo: %v", err)
+	}
+	playerRepo = playerRepo.ForUser("u-events")
+
+	cfg := testBoardConfig()
+	cfg.Villagers.Leveling.Thresholds = map[int]int{1: 0, 2: 5}
+	cfg.Villagers.Leveling.XPSources.CompleteTask.BaseXP = 5
+	h := NewHandler(NewMemoryRepo(), taskRepo, cfg)
+	state := model.NewBoardState()
+
+	taskRow, err := taskRepo.Create(model.Task{Title: "Evented task"})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	villager := state.CreateCard("villager.basic", map[string]any{"name": "Pip"})
+	taskCard := state.CreateCard("task.instance", map[string]any{
+		"taskId":             string(taskRow.ID),
+		"assignedVillagerId": "villager_events_1",
+	})
+	stack := state.CreateStack(model.Point{X: 300, Y: 300}, []model.CardID{villager.ID, taskCard.ID})
+
+	levels := villagerLevels(playerRepo)
+	result, err := h.executeCommand(state, taskRepo, playerRepo, "task.complete_stack", map[string]any{
+		"stackId": string(stack.ID),
+	})
+	if err != nil {
+		t.Fatalf("task.complete_stack: %v", err)
+	}
+	events := commandEvents("task.complete_stack", result, taskRepo, playerRepo, levels)
+	names := make([]string, 0, len(events))
+	for _, e := range events {
+		names = append(names, e.name)
+	}
+	if strings.Join(names, ",") != "task.completed,villager.level_up" {
+		t.Fatalf("unexpected events: %v", names)
+	}
+	if done, ok := events[0].data.(model.Task); !ok || done.ID != taskRow.ID || !done.Done {
+		t.Fatalf("expected the completed task as event data, got %+v", events[0].data)
+	}
+}
+
+type recordingPluginRunner struct {
+	attached []string
+	changed  []model.Task
+}
+
+func (p *recordingPluginRunner) CardAttached(_ model.Task, cardDefID string) {
+	p.attached = append(p.attached, cardDefID)
+}
+
+func (p *recordingPluginRunner) TaskChanged(t model.Task) {
+	p.changed = append(p.changed, t)
+}
+
+func TestCommand_TaskAddModifier_AttachesPluginCardToTask(t *testing.T) {
+	taskRepo := task.NewMemoryRepo()
+	h := NewHandler(NewMemoryRepo(), taskRepo, testBoardConfig())
+	runner := &recordingPluginRunner{}
+	h.SetPluginResolver(func(*http.Request) task.PluginRunner { return runner })
+	state := model.NewBoardState()
+
+	taskRow, err := taskRepo.Create(model.Task{Title: "Sync me"})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	taskCard := state.CreateCard("task.instance", map[string]any{"taskId": string(taskRow.ID)})
+	stack := state.CreateStack(model.Point{X: 100, Y: 100}, []model.CardID{taskCard.ID})
+
+	patch, err := h.executeCommand(state, taskRepo, nil, "task.add_modifier", map[string]any{
+		"taskStackId":   string(stack.ID),
+		"modifierDefId": "mod.plugin_cal_sync",
+	})
+	if err != nil {
+		t.Fatalf("task.add_modifier: %v", err)
+	}
+	h.runPlugins(httptest.NewRequest(http.MethodPost, "/api/board/cmd", nil), "task.add_modifier", patch, taskRepo)
+
+	updated, err := taskRepo.Get(taskRow.ID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if cards := task.PluginCards(updated); len(cards) != 1 || cards[0] != "mod.plugin_cal_sync" {
+		t.Fatalf("plugin card not recorded on task: %+v", updated.Modifiers)
+	}
+	if len(runner.attached) != 1 || runner.attached[0] != "mod.plugin_cal_sync" {
+		t.Fatalf("expected the plugin to run once, got %v", runner.attached)
+	}
+
+	// Adding it again is not a new attachment.
+	patch, err = h.executeCommand(state, taskRepo, nil, "task.add_modifier", map[string]any{
+		"taskStackId":   string(stack.ID),
+		"modifierDefId": "mod.plugin_cal_sync",
+	})
+	if err == nil {
+		h.runPlugins(httptest.NewRequest(http.MethodPost, "/api/board/cmd", nil), "task.add_modifier", patch, taskRepo)
+	}
+	if len(runner.attached) != 1 {
+		t.Fatalf("plugin ran again for a card already on the task: %v", runner.attached)
+	}
+}
+
+func TestCommand_TaskEditsRunPluginsOnTheTask(t *testing.T) {
+	taskRepo := task.NewMemoryRepo()
+	h := NewHandler(NewMemoryRepo(), taskRepo, testBoardConfig())
+	runner := &recordingPluginRunner{}
+	h.SetPluginResolver(func(*http.Request) task.PluginRunner { return runner })
+	state := model.NewBoardState()
+	req := httptest.NewRequest(http.MethodPost, "/api/board/cmd", nil)
+
+	taskRow, err := taskRepo.Create(model.Task{
+		Title:     "Sync me",
+		Modifiers: []model.TaskModifierSlot{{DefID: "mod.plugin_cal_sync"}},
+	})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	taskCard := state.CreateCard("task.instance", map[string]any{"taskId": string(taskRow.ID)})
+	state.CreateStack(model.Point{X: 100, Y: 100}, []model.CardID{taskCard.ID})
+
+	for _, c := range []struct {
+		cmd  string
+		args map[string]any
+	}{
+		{"task.set_title", map[string]any{"taskCardId": string(taskCard.ID), "title": "Synced"}},
+		{"task.set_description", map[string]any{"taskCardId": string(taskCard.ID), "description": "details"}},
+		{"task.set_project", map[string]any{"taskCardId": string(taskCard.ID), "project": "Home"}},
+		{"task.complete_by_task_id", map[string]any{"taskId": string(taskRow.ID)}},
+	} {
+		patch, err := h.executeCommand(state, taskRepo, nil, c.cmd, c.args)
+		if err != nil {
+			t.Fatalf("%s: %v", c.cmd, err)
+		}
+		h.runPlugins(req, c.cmd, patch, taskRepo)
+	}
+
+	if len(runner.changed) != 4 {
+		t.Fatalf("expected a task change per edit, got %d", len(runner.changed))
+	}
+	last := runner.changed[3]
+	if last.ID != taskRow.ID || last.Title != "Synced" || !last.Done {
+		t.Fatalf("expected the edited task, got %+v", last)
+	}
+}
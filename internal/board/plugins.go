@@ -0,0 +1,129 @@
+package board
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+// pluginAttach is what attachPluginCards did to a stack's task.
+type pluginAttach struct {
+	TaskID model.TaskID
+	Cards  []string
+}
+
+// SetPluginResolver runs the requester's plugins when their cards are
+// stacked on a task.
+func (h *Handler) SetPluginResolver(fn func(*http.Request) task.PluginRunner) {
+	h.pluginResolver = fn
+}
+
+// attachPluginCards records the plugin cards on stack against its task, so
+// later edits to the task reach the plugins too, and returns the ones that
+// were new.
+func attachPluginCards(state *model.BoardState, stack *model.Stack, taskRepo task.Repo) pluginAttach {
+	if taskRepo == nil || stack == nil {
+		return pluginAttach{}
+	}
+	var taskID model.TaskID
+	var cards []string
+	for _, cid := range stack.Cards {
+		card := state.GetCard(cid)
+		if card == nil {
+			continue
+		}
+		if extractKind(card.DefID) == "task" && card.Data != nil {
+			if v, _ := card.Data["taskId"].(string); strings.TrimSpace(v) != "" && taskID == "" {
+				taskID = model.TaskID(strings.TrimSpace(v))
+			}
+			continue
+		}
+		if def := string(card.DefID); strings.HasPrefix(def, task.PluginCardPrefix) && !slices.Contains(cards, def) {
+			cards = append(cards, def)
+		}
+	}
+	if taskID == "" || len(cards) == 0 {
+		return pluginAttach{}
+	}
+	t, err := taskRepo.Get(taskID)
+	if err != nil {
+		return pluginAttach{}
+	}
+	existing := task.PluginCards(t)
+	mods := append([]model.TaskModifierSlot{}, t.Modifiers...)
+	var added []string
+	for _, def := range cards {
+		if slices.Contains(existing, def) {
+			continue
+		}
+		mods = append(mods, model.TaskModifierSlot{DefID: def})
+		added = append(added, def)
+	}
+	if len(added) == 0 {
+		return pluginAttach{}
+	}
+	// Past the task's modifier limit (task.ErrTooManyMods) the cards stay
+	// on the board only.
+	if _, err := taskRepo.SetModifiers(taskID, mods); err != nil {
+		return pluginAttach{}
+	}
+	return pluginAttach{TaskID: taskID, Cards: added}
+}
+
+// addPluginAttach adds the attached cards to a command result.
+func addPluginAttach(out map[string]any, a pluginAttach) {
+	if len(a.Cards) == 0 {
+		return
+	}
+	out["pluginTaskId"] = a.TaskID
+	out["pluginCards"] = a.Cards
+}
+
+// runPlugins tells the requester's plugins about cards a command attached
+// and about the task it edited, if any.
+func (h *Handler) runPlugins(r *http.Request, cmd string, result any, taskRepo task.Repo) {
+	if h.pluginResolver == nil || taskRepo == nil {
+		return
+	}
+	res, _ := result.(map[string]any)
+	cards, _ := res["pluginCards"].([]string)
+	taskID, _ := res["pluginTaskId"].(model.TaskID)
+	changed := changedTaskID(cmd, res)
+	if (len(cards) == 0 || taskID == "") && changed == "" {
+		return
+	}
+	runner := h.pluginResolver(r)
+	if runner == nil {
+		return
+	}
+	if len(cards) > 0 && taskID != "" {
+		if t, err := taskRepo.Get(taskID); err == nil {
+			for _, def := range cards {
+				runner.CardAttached(t, def)
+			}
+		}
+	}
+	if changed != "" {
+		if t, err := taskRepo.Get(changed); err == nil {
+			runner.TaskChanged(t)
+		}
+	}
+}
+
+// changedTaskID is the task a command edited through the task repo, read
+// from its result.
+func changedTaskID(cmd string, res map[string]any) model.TaskID {
+	var id string
+	switch cmd {
+	case "task.set_title", "task.set_description", "task.set_project":
+		if card, _ := res["card"].(*model.Card); card != nil && card.Data != nil {
+			id, _ = card.Data["taskId"].(string)
+		}
+	case "task.complete_stack", "task.complete_by_task_id":
+		id, _ = res["completedTaskId"].(string)
+	}
+	return model.TaskID(strings.TrimSpace(id))
+}
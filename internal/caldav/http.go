@@ -0,0 +1,544 @@
+package caldav
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"donegeon/internal/auth"
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/task"
+	"donegeon/internal/webhook"
+)
+
+// Prefix is where the CalDAV tree is mounted.
+const Prefix = "/caldav/"
+
+// ExternalRefCalDAV keys the resource name a client chose for a task it
+// created, so the task keeps answering at that href.
+const ExternalRefCalDAV = "caldav"
+
+// maxObjectBytes caps a PUT calendar object.
+const maxObjectBytes = 1 << 20
+
+// Handler serves each user's tasks as VTODO resources, one calendar
+// collection per project:
+//
+//	/caldav/                          principal
+//	/caldav/calendars/                calendar home
+//	/caldav/calendars/{project}/      calendar collection
+//	/caldav/calendars/{project}/{name}.ics
+//
+// Deleting a resource archives the task, the same way it leaves every
+// other listing.
+type Handler struct {
+	repo              task.Repo
+	repoResolver      func(*http.Request) task.Repo
+	playerResolver    func(*http.Request) *player.FileRepo
+	pluginResolver    func(*http.Request) task.PluginRunner
+	publisherResolver func(*http.Request) webhook.Publisher
+	updater           Updater
+}
+
+// Updater stores a PUT's changes to an existing task under the task rules,
+// so a completion gets its villager check, habit progress, metric and
+// task.completed event. task.Handler.Apply is one.
+type Updater func(repo task.Repo, playerRepo *player.FileRepo, pub webhook.Publisher, id model.TaskID, p task.Patch) (model.Task, error)
+
+func NewHandler(repo task.Repo) *Handler {
+	return &Handler{repo: repo}
+}
+
+func (h *Handler) SetRepoResolver(fn func(*http.Request) task.Repo) {
+	h.repoResolver = fn
+}
+
+func (h *Handler) SetPlayerResolver(fn func(*http.Request) *player.FileRepo) {
+	h.playerResolver = fn
+}
+
+// SetPluginResolver runs the requester's plugins when a PUT changes a task
+// carrying plugin cards.
+func (h *Handler) SetPluginResolver(fn func(*http.Request) task.PluginRunner) {
+	h.pluginResolver = fn
+}
+
+// SetPublisherResolver publishes task.created for PUTs that create a task;
+// completions publish through the Updater.
+func (h *Handler) SetPublisherResolver(fn func(*http.Request) webhook.Publisher) {
+	h.publisherResolver = fn
+}
+
+// SetUpdater sets how PUTs to existing tasks are stored. Without one the
+// patch goes straight to the repo, with no completion effects.
+func (h *Handler) SetUpdater(fn Updater) {
+	h.updater = fn
+}
+
+func (h *Handler) publisherForRequest(r *http.Request) webhook.Publisher {
+	if h.publisherResolver == nil {
+		return nil
+	}
+	return h.publisherResolver(r)
+}
+
+func (h *Handler) taskChanged(r *http.Request, t model.Task) {
+	if h.pluginResolver == nil || len(task.PluginCards(t)) == 0 {
+		return
+	}
+	if p := h.pluginResolver(r); p != nil {
+		p.TaskChanged(t)
+	}
+}
+
+func (h *Handler) repoForRequest(r *http.Request) task.Repo {
+	if h.repoResolver != nil {
+		if repo := h.repoResolver(r); repo != nil {
+			return repo
+		}
+	}
+	return h.repo
+}
+
+func (h *Handler) playerForRequest(r *http.Request) *player.FileRepo {
+	if h.playerResolver == nil {
+		return nil
+	}
+	return h.playerResolver(r)
+}
+
+func writeStatus(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	_, _ = io.WriteString(w, msg+"\n")
+}
+
+// DAV handles every request below Prefix.
+func (h *Handler) DAV(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", "OPTIONS, PROPFIND, REPORT, GET, HEAD, PUT, DELETE")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(Prefix, "/")), "/")
+	parts := []string{}
+	if rest != "" {
+		parts = strings.Split(rest, "/")
+	}
+
+	switch {
+	case len(parts) == 0 || (len(parts) == 1 && parts[0] == "principal"):
+		h.principal(w, r)
+	case parts[0] != "calendars" || len(parts) > 3:
+		writeStatus(w, http.StatusNotFound, "not found")
+	case len(parts) == 1:
+		h.home(w, r)
+	case len(parts) == 2:
+		h.collection(w, r, parts[1])
+	default:
+		h.object(w, r, parts[1], parts[2])
+	}
+}
+
+func homeHref() string      { return Prefix + "calendars/" }
+func principalHref() string { return Prefix + "principal/" }
+
+func collectionHref(project string) string {
+	return homeHref() + url.PathEscape(project) + "/"
+}
+
+// objectName is the resource name a task answers at.
+func objectName(t model.Task) string {
+	if name := t.ExternalRefs[ExternalRefCalDAV]; name != "" {
+		return name
+	}
+	return string(t.ID) + ".ics"
+}
+
+func projectOf(t model.Task) string {
+	if t.Project == nil || strings.TrimSpace(*t.Project) == "" {
+		return "inbox"
+	}
+	return *t.Project
+}
+
+func depth(r *http.Request) int {
+	if strings.TrimSpace(r.Header.Get("Depth")) == "0" {
+		return 0
+	}
+	return 1
+}
+
+// projects lists the collections: every project with a visible task, plus
+// the inbox.
+func projects(repo task.Repo) ([]string, error) {
+	ts, err := repo.List(task.ListFilter{Archived: "exclude"})
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{"inbox": true}
+	out := []string{"inbox"}
+	for _, t := range ts {
+		if p := projectOf(t); !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out[1:])
+	return out, nil
+}
+
+func collectionTasks(repo task.Repo, project string) ([]model.Task, error) {
+	ts, err := repo.List(task.ListFilter{Project: project, Archived: "exclude", Deferred: "include"})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i].ID < ts[j].ID })
+	return ts, nil
+}
+
+// ctag changes whenever any task in the collection does.
+func ctag(ts []model.Task) string {
+	sum := sha256.New()
+	for _, t := range ts {
+		_, _ = io.WriteString(sum, string(t.ID)+":"+strconv.FormatInt(t.Revision, 10)+"\n")
+	}
+	return hex.EncodeToString(sum.Sum(nil))[:16]
+}
+
+func (h *Handler) principal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PROPFIND" {
+		writeStatus(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, _ := auth.UserFromContext(r.Context())
+	ms := newMultistatus()
+	ms.response(r.URL.Path,
+		rawProp("d:resourcetype", "<d:collection/><d:principal/>"),
+		textProp("d:displayname", u.Email),
+		hrefProp("d:current-user-principal", principalHref()),
+		hrefProp("d:principal-URL", principalHref()),
+		hrefProp("c:calendar-home-set", homeHref()),
+	)
+	ms.write(w)
+}
+
+func (h *Handler) home(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PROPFIND" {
+		writeStatus(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	repo := h.repoForRequest(r)
+	ms := newMultistatus()
+	ms.response(homeHref(),
+		rawProp("d:resourcetype", "<d:collection/>"),
+		hrefProp("d:current-user-principal", principalHref()),
+	)
+	if depth(r) > 0 {
+		names, err := projects(repo)
+		if err != nil {
+			writeStatus(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, p := range names {
+			ts, err := collectionTasks(repo, p)
+			if err != nil {
+				writeStatus(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			ms.response(collectionHref(p), collectionProps(p, ts)...)
+		}
+	}
+	ms.write(w)
+}
+
+func collectionProps(project string, ts []model.Task) []string {
+	return []string{
+		rawProp("d:resourcetype", "<d:collection/><c:calendar/>"),
+		textProp("d:displayname", project),
+		rawProp("c:supported-calendar-component-set", `<c:comp name="VTODO"/>`),
+		rawProp("d:supported-report-set",
+			"<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>"+
+				"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>"),
+		rawProp("d:current-user-privilege-set",
+			"<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>"+
+				"<d:privilege><d:write-content/></d:privilege><d:privilege><d:bind/></d:privilege>"+
+				"<d:privilege><d:unbind/></d:privilege>"),
+		textProp("cs:getctag", ctag(ts)),
+		hrefProp("d:current-user-principal", principalHref()),
+	}
+}
+
+func objectProps(t model.Task) []string {
+	return []string{
+		rawProp("d:resourcetype", ""),
+		textProp("d:getetag", task.TaskETag(t)),
+		textProp("d:getcontenttype", "text/calendar; charset=utf-8; component=vtodo"),
+	}
+}
+
+func (h *Handler) collection(w http.ResponseWriter, r *http.Request, project string) {
+	repo := h.repoForRequest(r)
+	names, err := projects(repo)
+	if err != nil {
+		writeStatus(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !contains(names, project) {
+		writeStatus(w, http.StatusNotFound, "not found")
+		return
+	}
+	ts, err := collectionTasks(repo, project)
+	if err != nil {
+		writeStatus(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch r.Method {
+	case "PROPFIND":
+		ms := newMultistatus()
+		ms.response(collectionHref(project), collectionProps(project, ts)...)
+		if depth(r) > 0 {
+			for _, t := range ts {
+				ms.response(collectionHref(project)+url.PathEscape(objectName(t)), objectProps(t)...)
+			}
+		}
+		ms.write(w)
+	case "REPORT":
+		h.report(w, r, project, ts)
+	default:
+		writeStatus(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handler) report(w http.ResponseWriter, r *http.Request, project string, ts []model.Task) {
+	req, err := parseReport(io.LimitReader(r.Body, maxObjectBytes))
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, "bad xml")
+		return
+	}
+	now := time.Now().In(h.playerForRequest(r).Location())
+	base := collectionHref(project)
+	ms := newMultistatus()
+	withData := func(t model.Task) []string {
+		return append(objectProps(t), textProp("c:calendar-data", task.BuildTaskTodoICS(t, now)))
+	}
+
+	switch req.Kind {
+	case "calendar-query":
+		// Only to-dos live here; a query for events matches nothing.
+		for _, c := range req.Comps {
+			if c != "VTODO" {
+				ms.write(w)
+				return
+			}
+		}
+		for _, t := range ts {
+			ms.response(base+url.PathEscape(objectName(t)), withData(t)...)
+		}
+	case "calendar-multiget":
+		byName := make(map[string]model.Task, len(ts))
+		for _, t := range ts {
+			byName[objectName(t)] = t
+		}
+		for _, href := range req.Hrefs {
+			path := href
+			if u, err := url.Parse(href); err == nil {
+				path = u.Path
+			}
+			name := path[strings.LastIndex(path, "/")+1:]
+			t, ok := byName[name]
+			if !ok || !strings.HasPrefix(path, base) {
+				ms.missing(href)
+				continue
+			}
+			ms.response(href, withData(t)...)
+		}
+	default:
+		writeStatus(w, http.StatusForbidden, "unsupported report: "+req.Kind)
+		return
+	}
+	ms.write(w)
+}
+
+func (h *Handler) object(w http.ResponseWriter, r *http.Request, project, name string) {
+	repo := h.repoForRequest(r)
+	ts, err := collectionTasks(repo, project)
+	if err != nil {
+		writeStatus(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var cur *model.Task
+	for i := range ts {
+		if objectName(ts[i]) == name {
+			cur = &ts[i]
+			break
+		}
+	}
+
+	if cur != nil {
+		if inm := r.Header.Get("If-None-Match"); inm != "" && (r.Method == http.MethodPut || r.Method == http.MethodDelete) &&
+			etagMatches(inm, task.TaskETag(*cur)) {
+			writeStatus(w, http.StatusPreconditionFailed, "resource exists")
+			return
+		}
+	}
+	if im := r.Header.Get("If-Match"); im != "" && (cur == nil || !etagMatches(im, task.TaskETag(*cur))) {
+		writeStatus(w, http.StatusPreconditionFailed, "resource changed")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if cur == nil {
+			writeStatus(w, http.StatusNotFound, "not found")
+			return
+		}
+		body := task.BuildTaskTodoICS(*cur, time.Now().In(h.playerForRequest(r).Location()))
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8; component=vtodo")
+		w.Header().Set("ETag", task.TaskETag(*cur))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = io.WriteString(w, body)
+		}
+	case "PROPFIND":
+		if cur == nil {
+			writeStatus(w, http.StatusNotFound, "not found")
+			return
+		}
+		ms := newMultistatus()
+		ms.response(collectionHref(project)+url.PathEscape(name), objectProps(*cur)...)
+		ms.write(w)
+	case http.MethodPut:
+		h.put(w, r, repo, project, name, cur)
+	case http.MethodDelete:
+		if cur == nil {
+			writeStatus(w, http.StatusNotFound, "not found")
+			return
+		}
+		archived := true
+		rev := cur.Revision
+		if _, err := repo.Update(cur.ID, task.Patch{Archived: &archived, IfRevision: &rev}); err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeStatus(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// put creates or updates a task from a VTODO. Schedule fields the player
+// has not unlocked are dropped, as on import.
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, repo task.Repo, project, name string, cur *model.Task) {
+	if !strings.HasSuffix(name, ".ics") {
+		writeStatus(w, http.StatusForbidden, "calendar object names must end in .ics")
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxObjectBytes))
+	if err != nil {
+		writeStatus(w, http.StatusRequestEntityTooLarge, "calendar object is too large")
+		return
+	}
+	playerRepo := h.playerForRequest(r)
+	items, err := task.ParseICS(data, playerRepo.Location())
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(items) != 1 || items[0].Component != task.ICSTodo {
+		writeStatus(w, http.StatusForbidden, "supported-calendar-component: exactly one VTODO is required")
+		return
+	}
+	it := items[0]
+	if it.Summary == "" {
+		it.Summary = "Untitled task"
+	}
+	task.StripLockedICSFields(&it, playerRepo)
+
+	if cur == nil {
+		t := task.ICSTask(it, &project)
+		if name != string(t.ID)+".ics" {
+			if t.ExternalRefs == nil {
+				t.ExternalRefs = map[string]string{}
+			}
+			t.ExternalRefs[ExternalRefCalDAV] = name
+		}
+		created, err := repo.Create(t)
+		if err != nil {
+			writeStatus(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if pub := h.publisherForRequest(r); pub != nil {
+			pub.Publish(webhook.EventTaskCreated, created)
+		}
+		h.taskChanged(r, created)
+		w.Header().Set("ETag", task.TaskETag(created))
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	p, changes := task.ICSPatch(*cur, it)
+	if len(changes) == 0 {
+		w.Header().Set("ETag", task.TaskETag(*cur))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	rev := cur.Revision
+	p.IfRevision = &rev
+	var updated model.Task
+	if h.updater != nil {
+		updated, err = h.updater(repo, playerRepo, h.publisherForRequest(r), cur.ID, p)
+	} else {
+		updated, err = repo.Update(cur.ID, p)
+	}
+	if err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+	h.taskChanged(r, updated)
+	w.Header().Set("ETag", task.TaskETag(updated))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeRepoErr(w http.ResponseWriter, err error) {
+	switch err {
+	case task.ErrRevisionConflict:
+		writeStatus(w, http.StatusPreconditionFailed, err.Error())
+	case task.ErrNotFound:
+		writeStatus(w, http.StatusNotFound, "not found")
+	case task.ErrVillagerRequired:
+		writeStatus(w, http.StatusForbidden, err.Error())
+	default:
+		writeStatus(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// etagMatches compares an If-Match / If-None-Match list against etag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package caldav
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/task"
+	"donegeon/internal/webhook"
+)
+
+func newCalDAVHandlerForTests(t *testing.T) (*Handler, *task.MemoryRepo) {
+	t.Helper()
+	repo := task.NewMemoryRepo()
+	playerRepo, err := player.NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new player repo: %v", err)
+	}
+	playerRepo = playerRepo.ForUser("u-dav")
+	if _, _, _, err := playerRepo.UnlockFeature(player.FeatureTaskDueDate, 0); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	h := NewHandler(repo)
+	h.SetPlayerResolver(func(*http.Request) *player.FileRepo { return playerRepo })
+	return h, repo
+}
+
+func davReq(h *Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.DAV(rec, req)
+	return rec
+}
+
+const newTodo = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:client-uid-1\r\nSUMMARY:Buy milk\r\nDUE;VALUE=DATE:20260310\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+
+func TestDAV_PutReportUpdateAndDelete(t *testing.T) {
+	h, repo := newCalDAVHandlerForTests(t)
+	home := "home"
+	if _, err := repo.Create(model.Task{Title: "Water plants", Project: &home}); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	rec := davReq(h, "PROPFIND", "/caldav/calendars/", "", map[string]string{"Depth": "1"})
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND home: expected 207, got %d", rec.Code)
+	}
+	for _, want := range []string{"/caldav/calendars/inbox/", "/caldav/calendars/home/", `<c:comp name="VTODO"/>`, "<cs:getctag>"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("PROPFIND home missing %q: %s", want, rec.Body.String())
+		}
+	}
+
+	obj := "/caldav/calendars/home/client-uid-1.ics"
+	rec = davReq(h, http.MethodPut, obj, newTodo, map[string]string{"If-None-Match": "*"})
+	if rec.Code != http.StatusCreated || rec.Header().Get("ETag") == "" {
+		t.Fatalf("PUT new: expected 201 with ETag, got %d %s", rec.Code, rec.Body.String())
+	}
+	etag := rec.Header().Get("ETag")
+	if rec := davReq(h, http.MethodPut, obj, newTodo, map[string]string{"If-None-Match": "*"}); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("PUT over existing with If-None-Match: expected 412, got %d", rec.Code)
+	}
+
+	query := `<?xml version="1.0"?><c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop><d:getetag/><c:calendar-data/></d:prop><c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO"/></c:comp-filter></c:filter></c:calendar-query>`
+	rec = davReq(h, "REPORT", "/caldav/calendars/home/", query, map[string]string{"Depth": "1"})
+	body := rec.Body.String()
+	if rec.Code != http.StatusMultiStatus || !strings.Contains(body, "SUMMARY:Buy milk") || !strings.Contains(body, "UID:client-uid-1") || !strings.Contains(body, "SUMMARY:Water plants") {
+		t.Fatalf("REPORT: unexpected %d %s", rec.Code, body)
+	}
+	events := strings.Replace(query, `name="VTODO"`, `name="VEVENT"`, 1)
+	if rec := davReq(h, "REPORT", "/caldav/calendars/home/", events, nil); strings.Contains(rec.Body.String(), "<d:response>") {
+		t.Fatalf("REPORT for VEVENT should match nothing: %s", rec.Body.String())
+	}
+	multiget := `<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop><c:calendar-data/></d:prop><d:href>` + obj + `</d:href><d:href>/caldav/calendars/home/nope.ics</d:href></c:calendar-multiget>`
+	rec = davReq(h, "REPORT", "/caldav/calendars/home/", multiget, nil)
+	if !strings.Contains(rec.Body.String(), "Buy milk") || !strings.Contains(rec.Body.String(), "404 Not Found") {
+		t.Fatalf("multiget: unexpected %s", rec.Body.String())
+	}
+
+	updated := strings.Replace(newTodo, "SUMMARY:Buy milk", "SUMMARY:Buy oat milk\r\nSTATUS:COMPLETED", 1)
+	if rec := davReq(h, http.MethodPut, obj, updated, map[string]string{"If-Match": `"999"`}); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("PUT stale If-Match: expected 412, got %d", rec.Code)
+	}
+	rec = davReq(h, http.MethodPut, obj, updated, map[string]string{"If-Match": etag})
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT update: expected 204, got %d %s", rec.Code, rec.Body.String())
+	}
+	rec = davReq(h, http.MethodGet, obj, "", nil)
+	if !strings.Contains(rec.Body.String(), "SUMMARY:Buy oat milk") || !strings.Contains(rec.Body.String(), "STATUS:COMPLETED") {
+		t.Fatalf("GET after update: %s", rec.Body.String())
+	}
+
+	if rec := davReq(h, http.MethodDelete, obj, "", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: expected 204, got %d", rec.Code)
+	}
+	if rec := davReq(h, http.MethodGet, obj, "", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET after delete: expected 404, got %d", rec.Code)
+	}
+	all, _ := repo.List(task.ListFilter{Archived: "only"})
+	if len(all) != 1 || all[0].Title != "Buy oat milk" {
+		t.Fatalf("expected deleted task to be archived, got %+v", all)
+	}
+}
+
+type changedTasks []model.Task
+
+func (c *changedTasks) CardAttached(model.Task, string) {}
+func (c *changedTasks) TaskChanged(t model.Task)        { *c = append(*c, t) }
+
+func TestDAV_PutRunsPluginsOnTheTask(t *testing.T) {
+	h, repo := newCalDAVHandlerForTests(t)
+	var changed changedTasks
+	h.SetPluginResolver(func(*http.Request) task.PluginRunner { return &changed })
+	home := "home"
+	tk, err := repo.Create(model.Task{
+		Title:     "Dentist",
+		Project:   &home,
+		Modifiers: []model.TaskModifierSlot{{DefID: "mod.plugin_cal_sync"}},
+	})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	body := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:" + string(tk.ID) + "\r\nSUMMARY:Dentist at 3\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	rec := davReq(h, http.MethodPut, "/caldav/calendars/home/"+string(tk.ID)+".ics", body, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT: expected 204, got %d %s", rec.Code, rec.Body.String())
+	}
+	if len(changed) != 1 || changed[0].ID != tk.ID || changed[0].Title != "Dentist at 3" {
+		t.Fatalf("expected the plugin to see the edited task, got %+v", changed)
+	}
+}
+
+type publishedEvents []string
+
+func (p *publishedEvents) Publish(event string, _ any) { *p = append(*p, event) }
+
+func TestDAV_PutCompletionFollowsTheCompletionRules(t *testing.T) {
+	h, repo := newCalDAVHandlerForTests(t)
+	cfg := &config.Config{}
+	cfg.Tasks.Processing.CompletionRequiresAssignedVillager = true
+	th := task.NewHandler(repo)
+	th.SetConfig(cfg)
+	h.SetUpdater(th.Apply)
+	var pub publishedEvents
+	h.SetPublisherResolver(func(*http.Request) webhook.Publisher { return &pub })
+	home := "home"
+	tk, err := repo.Create(model.Task{Title: "Dentist", Project: &home})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	obj := "/caldav/calendars/home/" + string(tk.ID) + ".ics"
+	body := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:" + string(tk.ID) + "\r\nSUMMARY:Dentist\r\nSTATUS:COMPLETED\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	if rec := davReq(h, http.MethodPut, obj, body, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("PUT completion without a villager: expected 403, got %d %s", rec.Code, rec.Body.String())
+	}
+	if got, _ := repo.Get(tk.ID); got.Done {
+		t.Fatalf("CalDAV completed a task without an assigned villager")
+	}
+
+	villager := "v-1"
+	if _, err := repo.Update(tk.ID, task.Patch{AssignedVillagerID: task.Some(villager)}); err != nil {
+		t.Fatalf("assign villager: %v", err)
+	}
+	if rec := davReq(h, http.MethodPut, obj, body, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT completion: expected 204, got %d %s", rec.Code, rec.Body.String())
+	}
+	got, _ := repo.Get(tk.ID)
+	if !got.Done || got.CompletionCount != 1 {
+		t.Fatalf("expected the task completed with habit progress, got %+v", got)
+	}
+	playerRepo := h.playerForRequest(httptest.NewRequest(http.MethodGet, "/", nil))
+	if n := playerRepo.GetMetric(player.MetricTasksCompleted); n != 1 {
+		t.Fatalf("expected tasks_completed 1, got %d", n)
+	}
+	if len(pub) != 1 || pub[0] != webhook.EventTaskCompleted {
+		t.Fatalf("expected one task.completed event, got %v", pub)
+	}
+}
+
+func TestDAV_PutNewTaskPublishesTaskCreated(t *testing.T) {
+	h, _ := newCalDAVHandlerForTests(t)
+	var pub publishedEvents
+	h.SetPublisherResolver(func(*http.Request) webhook.Publisher { return &pub })
+
+	rec := davReq(h, http.MethodPut, "/caldav/calendars/home/client-uid-1.ics", newTodo, map[string]string{"If-None-Match": "*"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("PUT new: expected 201, got %d %s", rec.Code, rec.Body.String())
+	}
+	if len(pub) != 1 || pub[0] != webhook.EventTaskCreated {
+		t.Fatalf("expected one task.created event, got %v", pub)
+	}
+}
@@ -0,0 +1,122 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// XML namespaces used in responses.
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	nsCS     = "http://calendarserver.org/ns/"
+)
+
+// multistatus builds a 207 body. Properties are passed as ready-made XML
+// fragments using the d:, c: and cs: prefixes declared on the root.
+type multistatus struct {
+	b strings.Builder
+}
+
+func newMultistatus() *multistatus {
+	m := &multistatus{}
+	m.b.WriteString(xml.Header)
+	m.b.WriteString(`<d:multistatus xmlns:d="` + nsDAV + `" xmlns:c="` + nsCalDAV + `" xmlns:cs="` + nsCS + `">`)
+	return m
+}
+
+func (m *multistatus) response(href string, props ...string) {
+	m.b.WriteString("<d:response><d:href>")
+	m.b.WriteString(escapeXML(href))
+	m.b.WriteString("</d:href><d:propstat><d:prop>")
+	for _, p := range props {
+		m.b.WriteString(p)
+	}
+	m.b.WriteString("</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>")
+}
+
+// missing reports an href that could not be found (calendar-multiget).
+func (m *multistatus) missing(href string) {
+	m.b.WriteString("<d:response><d:href>")
+	m.b.WriteString(escapeXML(href))
+	m.b.WriteString("</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>")
+}
+
+func (m *multistatus) write(w http.ResponseWriter) {
+	m.b.WriteString("</d:multistatus>")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = io.WriteString(w, m.b.String())
+}
+
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+func textProp(name, value string) string {
+	return "<" + name + ">" + escapeXML(value) + "</" + name + ">"
+}
+
+func hrefProp(name, href string) string {
+	return "<" + name + "><d:href>" + escapeXML(href) + "</d:href></" + name + ">"
+}
+
+func rawProp(name, inner string) string {
+	return "<" + name + ">" + inner + "</" + name + ">"
+}
+
+// reportRequest is the part of a REPORT body we act on.
+type reportRequest struct {
+	Kind  string   // calendar-query | calendar-multiget | ...
+	Hrefs []string // calendar-multiget
+	Comps []string // comp-filter names below VCALENDAR (calendar-query)
+}
+
+// parseReport reads the root element, multiget hrefs and query component
+// filters. Other filters (time ranges, text matches) are ignored, so a
+// query may return more than asked for, which clients tolerate.
+func parseReport(body io.Reader) (reportRequest, error) {
+	var (
+		out   reportRequest
+		stack []string
+	)
+	dec := xml.NewDecoder(body)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return reportRequest{}, err
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			if out.Kind == "" {
+				out.Kind = el.Name.Local
+			}
+			if el.Name.Local == "comp-filter" && len(stack) > 0 && stack[len(stack)-1] == "comp-filter" {
+				for _, a := range el.Attr {
+					if a.Name.Local == "name" {
+						out.Comps = append(out.Comps, strings.ToUpper(a.Value))
+					}
+				}
+			}
+			stack = append(stack, el.Name.Local)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 && stack[len(stack)-1] == "href" {
+				if href := strings.TrimSpace(string(el)); href != "" {
+					out.Hrefs = append(out.Hrefs, href)
+				}
+			}
+		}
+	}
+}
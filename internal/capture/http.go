@@ -0,0 +1,260 @@
+// Package capture creates inbox tasks from anything that can POST to a
+// URL: phone shortcuts, mail forwarding rules, bookmarklets.
+package capture
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/task"
+	"donegeon/internal/webhook"
+)
+
+// maxCaptureBytes caps a captured body; forwarded mail is trimmed to fit.
+const maxCaptureBytes = 64 << 10
+
+type Handler struct {
+	repoResolver      func(*http.Request) task.Repo
+	playerResolver    func(*http.Request) *player.FileRepo
+	publisherResolver func(*http.Request) webhook.Publisher
+	keyResolver       func(*http.Request) string
+	cfg               *config.Config
+	limiter           *limiter
+}
+
+func NewHandler() *Handler {
+	return &Handler{limiter: newLimiter(defaultBurst, defaultInterval)}
+}
+
+func (h *Handler) SetConfig(cfg *config.Config) {
+	h.cfg = cfg
+}
+
+func (h *Handler) SetRepoResolver(fn func(*http.Request) task.Repo) {
+	h.repoResolver = fn
+}
+
+func (h *Handler) SetPlayerResolver(fn func(*http.Request) *player.FileRepo) {
+	h.playerResolver = fn
+}
+
+func (h *Handler) SetPublisherResolver(fn func(*http.Request) webhook.Publisher) {
+	h.publisherResolver = fn
+}
+
+// SetKeyResolver sets what requests are rate limited by, normally the ID
+// of the capture token they came in with.
+func (h *Handler) SetKeyResolver(fn func(*http.Request) string) {
+	h.keyResolver = fn
+}
+
+// SetRateLimit allows burst captures at once per token, refilled at one
+// per interval. A zero burst turns the limit off.
+func (h *Handler) SetRateLimit(burst int, interval time.Duration) {
+	h.limiter = newLimiter(burst, interval)
+}
+
+func (h *Handler) playerForRequest(r *http.Request) *player.FileRepo {
+	if h.playerResolver == nil {
+		return nil
+	}
+	return h.playerResolver(r)
+}
+
+func (h *Handler) keyForRequest(r *http.Request) string {
+	if h.keyResolver == nil {
+		return ""
+	}
+	return h.keyResolver(r)
+}
+
+func (h *Handler) publish(r *http.Request, event string, data any) {
+	if h.publisherResolver == nil {
+		return
+	}
+	if p := h.publisherResolver(r); p != nil {
+		p.Publish(event, data)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeErr(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]any{"error": msg})
+}
+
+// captureInput is a capture however it was posted.
+type captureInput struct {
+	Title       string   `json:"title"`
+	Text        string   `json:"text"` // alias of title
+	Description string   `json:"description"`
+	Project     string   `json:"project"`
+	Tags        []string `json:"tags"`
+	Parse       bool     `json:"parse"`
+}
+
+// readInput reads a JSON, form or plain-text body. Plain text is the title
+// on the first line and the description after it.
+func readInput(r *http.Request) (captureInput, error) {
+	var in captureInput
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			return in, err
+		}
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		if err := r.ParseMultipartForm(maxCaptureBytes); err != nil && err != http.ErrNotMultipart {
+			return in, err
+		}
+		in.Title = firstValue(r.PostForm, "title", "text", "subject")
+		in.Description = firstValue(r.PostForm, "description", "body", "body-plain", "stripped-text")
+		in.Project = r.PostForm.Get("project")
+		if tags := r.PostForm.Get("tags"); tags != "" {
+			in.Tags = strings.Split(tags, ",")
+		}
+		in.Parse = truthy(r.PostForm.Get("parse"))
+	default:
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return in, err
+		}
+		text := strings.TrimLeft(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n")
+		in.Title, in.Description, _ = strings.Cut(text, "\n")
+	}
+	if in.Title == "" {
+		in.Title = in.Text
+	}
+	in.Title = strings.Join(strings.Fields(in.Title), " ")
+	in.Description = strings.TrimSpace(in.Description)
+	return in, nil
+}
+
+func firstValue(form map[string][]string, keys ...string) string {
+	for _, k := range keys {
+		if v := form[k]; len(v) > 0 && strings.TrimSpace(v[0]) != "" {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+func truthy(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// POST /api/capture/{token}
+//
+// Creates one inbox task from a JSON { title, description?, project?,
+// tags?, parse? }, form or plain-text body. With parse (or ?parse=1) the
+// title is read as quick-add shorthand, see ParseQuickAdd. Schedule fields
+// the player has not unlocked are dropped with a warning rather than
+// refusing the capture.
+func (h *Handler) Capture(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if ok, wait := h.limiter.allow(h.keyForRequest(r), time.Now()); !ok {
+		secs := int(wait/time.Second) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+		writeErr(w, http.StatusTooManyRequests, "too many captures; retry in "+strconv.Itoa(secs)+"s")
+		return
+	}
+	var repo task.Repo
+	if h.repoResolver != nil {
+		repo = h.repoResolver(r)
+	}
+	if repo == nil {
+		writeErr(w, http.StatusInternalServerError, "no task repo")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCaptureBytes)
+	in, err := readInput(r)
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			writeErr(w, http.StatusRequestEntityTooLarge, "capture is too large")
+			return
+		}
+		writeErr(w, http.StatusBadRequest, "bad body")
+		return
+	}
+	if in.Title == "" {
+		writeErr(w, http.StatusBadRequest, "title required")
+		return
+	}
+
+	playerRepo := h.playerForRequest(r)
+	t := model.Task{
+		Title:       in.Title,
+		Description: in.Description,
+		Tags:        in.Tags,
+	}
+	if p := strings.TrimSpace(in.Project); p != "" {
+		t.Project = &p
+	}
+	var warnings []string
+	if in.Parse || truthy(r.URL.Query().Get("parse")) {
+		warnings = h.applyQuickAdd(&t, ParseQuickAdd(in.Title, time.Now().In(playerRepo.Location())))
+	}
+	if t.Project == nil {
+		inbox := "inbox"
+		t.Project = &inbox
+	}
+	t.Tags = task.NormalizeTags(append(t.Tags, task.ModifierTags(h.cfg, t.Modifiers)...))
+	warnings = append(warnings, task.StripLockedFields(&t, playerRepo)...)
+
+	created, err := repo.WithOrigin(task.SourceCapture, "").Create(t)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.publish(r, webhook.EventTaskCreated, created)
+	writeJSON(w, http.StatusCreated, map[string]any{"task": created, "warnings": warnings})
+}
+
+// applyQuickAdd writes a parsed line onto t. A priority no modifier sets
+// is kept as a priority-<level> tag, as the importers do.
+func (h *Handler) applyQuickAdd(t *model.Task, q QuickAdd) []string {
+	var warnings []string
+	t.Title = q.Title
+	if q.Project != "" {
+		t.Project = &q.Project
+	}
+	t.Tags = append(t.Tags, q.Tags...)
+	if q.DueDate != "" {
+		t.DueDate = &q.DueDate
+	}
+	if q.DueTime != "" {
+		t.DueTime = &q.DueTime
+	}
+	t.Recurrence = q.Recurrence
+	if q.Priority != "" {
+		if defID, ok := task.PriorityModifier(h.cfg, q.Priority); ok {
+			t.Modifiers = []model.TaskModifierSlot{{DefID: defID}}
+		} else {
+			t.Tags = append(t.Tags, "priority-"+q.Priority)
+			warnings = append(warnings, "no modifier sets priority "+q.Priority+"; tagged instead")
+		}
+	}
+	return warnings
+}
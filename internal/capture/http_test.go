@@ -0,0 +1,135 @@
+package capture
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/task"
+)
+
+func newTestHandler(t *testing.T) (*Handler, *task.MemoryRepo, *player.FileRepo) {
+	t.Helper()
+	taskRepo := task.NewMemoryRepo()
+	playerRepo, err := player.NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new player repo: %v", err)
+	}
+	playerRepo = playerRepo.ForUser("u-capture")
+	h := NewHandler()
+	h.SetRepoResolver(func(*http.Request) task.Repo { return taskRepo })
+	h.SetPlayerResolver(func(*http.Request) *player.FileRepo { return playerRepo })
+	return h, taskRepo, playerRepo
+}
+
+type captureResponse struct {
+	Task     model.Task `json:"task"`
+	Warnings []string   `json:"warnings"`
+}
+
+func post(t *testing.T, h *Handler, target, contentType, body string) (int, captureResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.Capture(rec, req)
+	var out captureResponse
+	_ = json.NewDecoder(rec.Body).Decode(&out)
+	return rec.Code, out
+}
+
+func TestParseQuickAdd(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC) // a Sunday
+	q := ParseQuickAdd("Pay rent +home #Bills @errands friday at 5pm !high", now)
+	if q.Title != "Pay rent" || q.Project != "home" || strings.Join(q.Tags, ",") != "bills,errands" {
+		t.Fatalf("unexpected title/project/tags: %+v", q)
+	}
+	if q.DueDate != "2026-10-23" || q.DueTime != "17:00" || q.Priority != "high" {
+		t.Fatalf("unexpected schedule: %+v", q)
+	}
+
+	q = ParseQuickAdd("Water plants every 3 days", now)
+	if q.Title != "Water plants" || q.Recurrence == nil || q.Recurrence.Interval != 3 || q.DueDate != "2026-10-18" {
+		t.Fatalf("expected a 3-day recurrence starting today, got %+v", q)
+	}
+
+	q = ParseQuickAdd("Call mom in 2 weeks", now)
+	if q.Title != "Call mom" || q.DueDate != "2026-11-01" {
+		t.Fatalf("unexpected relative date: %+v", q)
+	}
+
+	q = ParseQuickAdd("tomorrow", now)
+	if q.Title != "tomorrow" || q.DueDate != "2026-10-19" {
+		t.Fatalf("expected the whole line kept as title, got %+v", q)
+	}
+}
+
+func TestCapture_TextJSONAndForm(t *testing.T) {
+	h, repo, playerRepo := newTestHandler(t)
+
+	code, out := post(t, h, "/api/capture/tok?parse=1", "text/plain", "Buy milk tomorrow #shop\nsemi-skimmed\n")
+	if code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if out.Task.Title != "Buy milk" || out.Task.Description != "semi-skimmed" || *out.Task.Project != "inbox" ||
+		strings.Join(out.Task.Tags, ",") != "shop" {
+		t.Fatalf("unexpected task %+v", out.Task)
+	}
+	if out.Task.DueDate != nil || len(out.Warnings) != 1 {
+		t.Fatalf("expected the locked due date dropped with a warning, got %+v %v", out.Task.DueDate, out.Warnings)
+	}
+	history, _ := repo.History(out.Task.ID)
+	if len(history) == 0 || history[0].Source != task.SourceCapture {
+		t.Fatalf("expected the capture recorded as the source, got %+v", history)
+	}
+
+	if _, _, _, err := playerRepo.UnlockFeature(player.FeatureTaskDueDate, 0); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	code, out = post(t, h, "/api/capture/tok", "application/json", `{"text":"Renew passport 2026-11-02 !low","parse":true}`)
+	if code != http.StatusCreated || out.Task.Title != "Renew passport" || out.Task.DueDate == nil || *out.Task.DueDate != "2026-11-02" {
+		t.Fatalf("unexpected JSON capture %d %+v", code, out.Task)
+	}
+	if strings.Join(out.Task.Tags, ",") != "priority-low" || len(out.Warnings) != 1 {
+		t.Fatalf("expected an unmapped priority kept as a tag, got %v %v", out.Task.Tags, out.Warnings)
+	}
+
+	form := url.Values{"subject": {"Fwd: invoice #42"}, "body-plain": {"See attached."}, "project": {"work"}}
+	code, out = post(t, h, "/api/capture/tok", "application/x-www-form-urlencoded", form.Encode())
+	if code != http.StatusCreated || out.Task.Title != "Fwd: invoice #42" || out.Task.Description != "See attached." || *out.Task.Project != "work" {
+		t.Fatalf("unexpected form capture %d %+v", code, out.Task)
+	}
+
+	if code, _ = post(t, h, "/api/capture/tok", "text/plain", "\n  \n"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty capture, got %d", code)
+	}
+}
+
+func TestCapture_RateLimitedPerToken(t *testing.T) {
+	h, _, _ := newTestHandler(t)
+	h.SetRateLimit(2, time.Hour)
+	h.SetKeyResolver(func(r *http.Request) string { return r.URL.Query().Get("key") })
+
+	for i := 0; i < 2; i++ {
+		if code, _ := post(t, h, "/api/capture/tok?key=a", "text/plain", "note"); code != http.StatusCreated {
+			t.Fatalf("capture %d: expected 201, got %d", i, code)
+		}
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/capture/tok?key=a", strings.NewReader("note"))
+	rec := httptest.NewRecorder()
+	h.Capture(rec, req)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+	if code, _ := post(t, h, "/api/capture/tok?key=b", "text/plain", "note"); code != http.StatusCreated {
+		t.Fatalf("expected another token unaffected, got %d", code)
+	}
+}
@@ -0,0 +1,217 @@
+package capture
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"donegeon/internal/importer"
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+const (
+	dateLayout  = "2006-01-02"
+	clockLayout = "15:04"
+)
+
+// QuickAdd is a task read from one line of shorthand, e.g.
+//
+//	Pay rent +home #bills tomorrow 9am !high every month
+//
+// +word sets the project, #word and @word add tags, !high/!medium/!low
+// (or !1..!3) set the priority, and today, tomorrow, weekdays, "in 3
+// days", dates, clock times and "every ..." phrases set the schedule.
+// Whatever is left is the title.
+type QuickAdd struct {
+	Title      string
+	Project    string
+	Tags       []string
+	Priority   string
+	DueDate    string
+	DueTime    string
+	Recurrence *model.Recurrence
+}
+
+var (
+	clock24 = regexp.MustCompile(`^([01]?\d|2[0-3]):([0-5]\d)$`)
+	clock12 = regexp.MustCompile(`^(1[0-2]|0?[1-9])(?::([0-5]\d))?(am|pm)$`)
+)
+
+var quickWeekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday,
+	"wednesday": time.Wednesday, "thursday": time.Thursday, "friday": time.Friday,
+	"saturday": time.Saturday,
+}
+
+var quickPriorities = map[string]string{
+	"high": "high", "h": "high", "1": "high",
+	"medium": "medium", "med": "medium", "m": "medium", "2": "medium",
+	"low": "low", "l": "low", "3": "low",
+}
+
+// ParseQuickAdd reads line relative to now, which should be in the user's
+// timezone.
+func ParseQuickAdd(line string, now time.Time) QuickAdd {
+	var out QuickAdd
+	words := strings.Fields(line)
+	title := make([]string, 0, len(words))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		lw := strings.ToLower(strings.TrimRight(w, ",."))
+		next := func(k int) string {
+			if i+k < len(words) {
+				return strings.ToLower(strings.TrimRight(words[i+k], ",."))
+			}
+			return ""
+		}
+
+		switch {
+		case len(w) > 1 && (w[0] == '#' || w[0] == '@'):
+			if tag := task.NormalizeTag(w[1:]); tag != "" {
+				out.Tags = append(out.Tags, tag)
+				continue
+			}
+		case len(w) > 1 && w[0] == '+':
+			out.Project = strings.TrimSpace(w[1:])
+			continue
+		case len(w) > 1 && w[0] == '!':
+			if p, ok := quickPriorities[strings.ToLower(w[1:])]; ok {
+				out.Priority = p
+				continue
+			}
+		case strings.HasPrefix(lw, "due:"):
+			if d, ok := quickDate(lw[len("due:"):], today); ok {
+				out.DueDate = d
+				continue
+			}
+		case lw == "every" || lw == "every!":
+			if rec, n := quickRecurrence(words[i:]); rec != nil {
+				out.Recurrence = rec
+				i += n - 1
+				continue
+			}
+		case lw == "at" && quickClock(next(1)) != "":
+			out.DueTime = quickClock(next(1))
+			i++
+			continue
+		case lw == "in":
+			if d, ok := quickOffset(next(1), next(2), today); ok {
+				out.DueDate = d
+				i += 2
+				continue
+			}
+		case lw == "next":
+			if wd, ok := quickWeekdays[next(1)]; ok {
+				out.DueDate = nextWeekday(today, wd, false)
+				i++
+				continue
+			}
+		}
+		if d, ok := quickDate(lw, today); ok {
+			out.DueDate = d
+			continue
+		}
+		if c := quickClock(lw); c != "" {
+			out.DueTime = c
+			continue
+		}
+		title = append(title, w)
+	}
+
+	out.Title = strings.Join(title, " ")
+	if out.Title == "" {
+		out.Title = strings.Join(words, " ")
+	}
+	out.Tags = task.NormalizeTags(out.Tags)
+	if out.DueDate == "" && (out.DueTime != "" || out.Recurrence != nil) {
+		out.DueDate = today.Format(dateLayout)
+	}
+	return out
+}
+
+// quickDate reads today, tomorrow, a weekday name or an ISO date.
+func quickDate(s string, today time.Time) (string, bool) {
+	switch s {
+	case "today", "tonight":
+		return today.Format(dateLayout), true
+	case "tomorrow", "tmrw":
+		return today.AddDate(0, 0, 1).Format(dateLayout), true
+	}
+	if wd, ok := quickWeekdays[s]; ok {
+		return nextWeekday(today, wd, true), true
+	}
+	if t, err := time.ParseInLocation(dateLayout, s, today.Location()); err == nil {
+		return t.Format(dateLayout), true
+	}
+	return "", false
+}
+
+// nextWeekday is the next wd on or (unless includeToday) after today.
+func nextWeekday(today time.Time, wd time.Weekday, includeToday bool) string {
+	days := (int(wd) - int(today.Weekday()) + 7) % 7
+	if days == 0 && !includeToday {
+		days = 7
+	}
+	return today.AddDate(0, 0, days).Format(dateLayout)
+}
+
+// quickOffset reads "3 days", "2 weeks" after "in".
+func quickOffset(num, unit string, today time.Time) (string, bool) {
+	n, err := strconv.Atoi(num)
+	if err != nil || n <= 0 {
+		return "", false
+	}
+	switch strings.TrimSuffix(unit, "s") {
+	case "day":
+		return today.AddDate(0, 0, n).Format(dateLayout), true
+	case "week":
+		return today.AddDate(0, 0, 7*n).Format(dateLayout), true
+	case "month":
+		return today.AddDate(0, n, 0).Format(dateLayout), true
+	}
+	return "", false
+}
+
+// quickClock reads 17:30, 5pm or 5:30pm as HH:MM, or returns "".
+func quickClock(s string) string {
+	if m := clock24.FindStringSubmatch(s); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		return time.Date(0, 1, 1, h, 0, 0, 0, time.UTC).Format("15:") + m[2]
+	}
+	if m := clock12.FindStringSubmatch(s); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		min := 0
+		if m[2] != "" {
+			min, _ = strconv.Atoi(m[2])
+		}
+		if m[3] == "pm" && h != 12 {
+			h += 12
+		}
+		if m[3] == "am" && h == 12 {
+			h = 0
+		}
+		return time.Date(0, 1, 1, h, min, 0, 0, time.UTC).Format(clockLayout)
+	}
+	return ""
+}
+
+// quickRecurrence reads the longest "every ..." phrase at the start of
+// words (up to five words, stopping at tags and the like) and returns the
+// rule and how many words it used.
+func quickRecurrence(words []string) (*model.Recurrence, int) {
+	end := 1
+	for end < len(words) && end < 5 && !strings.ContainsAny(words[end][:1], "#@+!") {
+		end++
+	}
+	for n := end; n > 1; n-- {
+		rec, err := importer.ParseRecurrence(strings.Join(words[:n], " "))
+		if err == nil && rec != nil {
+			return rec, n
+		}
+	}
+	return nil, 0
+}
@@ -0,0 +1,68 @@
+package capture
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBurst    = 10
+	defaultInterval = 6 * time.Second // one more capture allowed per interval
+	// maxIdleBuckets bounds the limiter's memory; full buckets carry no
+	// state and are dropped once there are more than this.
+	maxIdleBuckets = 1000
+)
+
+// limiter is a token bucket per capture token.
+type limiter struct {
+	mu       sync.Mutex
+	burst    int
+	interval time.Duration
+	buckets  map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(burst int, interval time.Duration) *limiter {
+	return &limiter{burst: burst, interval: interval, buckets: map[string]*bucket{}}
+}
+
+// allow spends one token from key's bucket. When the bucket is empty it
+// returns false and how long until the next token.
+func (l *limiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.burst <= 0 || l.interval <= 0 {
+		return true, 0
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxIdleBuckets {
+			l.pruneLocked(now)
+		}
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+float64(now.Sub(b.last))/float64(l.interval))
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) * float64(l.interval))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (l *limiter) pruneLocked(now time.Time) {
+	full := time.Duration(l.burst) * l.interval
+	for k, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, k)
+		}
+	}
+}
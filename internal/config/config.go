@@ -112,6 +112,13 @@ type XPSources struct {
 	CompleteTask        CompleteTaskXP `yaml:"complete_task" json:"complete_task"`
 	ClearZombie         BaseXP         `yaml:"clear_zombie" json:"clear_zombie"`
 	GatherResourceCycle BaseXP         `yaml:"gather_resource_cycle" json:"gather_resource_cycle"`
+	TrackedTime         TrackedTimeXP  `yaml:"tracked_time" json:"tracked_time"`
+}
+
+// TrackedTimeXP scales completion XP with time logged on the task.
+type TrackedTimeXP struct {
+	XPPerHour    int `yaml:"xp_per_hour" json:"xp_per_hour"`
+	MaxXPPerTask int `yaml:"max_xp_per_task" json:"max_xp_per_task"` // 0 = uncapped
 }
 
 type CompleteTaskXP struct {
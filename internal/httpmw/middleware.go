@@ -18,6 +18,10 @@ type contextKey string
 
 const requestIDKey contextKey = "donegeon.request_id"
 
+// secretPathPrefixes are routes whose last path segment is a credential
+// (see auth.RequirePathToken); it is redacted before a path is logged.
+var secretPathPrefixes = []string{"/api/capture/"}
+
 func Chain(h http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
 	if h == nil {
 		h = http.NotFoundHandler()
@@ -62,7 +66,7 @@ func WithRecover(logger *log.Logger) func(http.Handler) http.Handler {
 						"msg":        "panic_recovered",
 						"request_id": RequestIDFromContext(r.Context()),
 						"method":     r.Method,
-						"path":       r.URL.Path,
+						"path":       logPath(r.URL.Path),
 						"panic":      fmt.Sprint(rec),
 						"stack":      string(debug.Stack()),
 					})
@@ -102,7 +106,7 @@ func WithAccessLog(logger *log.Logger) func(http.Handler) http.Handler {
 				"msg":         "http_request",
 				"request_id":  RequestIDFromContext(r.Context()),
 				"method":      r.Method,
-				"path":        r.URL.Path,
+				"path":        logPath(r.URL.Path),
 				"status":      sw.status,
 				"bytes":       sw.bytes,
 				"duration_ms": dur.Milliseconds(),
@@ -129,6 +133,15 @@ func (w *statusWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+func logPath(p string) string {
+	for _, prefix := range secretPathPrefixes {
+		if strings.HasPrefix(p, prefix) && len(p) > len(prefix) {
+			return prefix + "[redacted]"
+		}
+	}
+	return p
+}
+
 func newRequestID() string {
 	var b [12]byte
 	if _, err := rand.Read(b[:]); err == nil {
@@ -0,0 +1,33 @@
+package httpmw
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogs_RedactCaptureTokens(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New(&buf, "", 0)
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), WithAccessLog(logger), WithRecover(logger))
+
+	const token = "dgn_capture_s3cr3t"
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/capture/"+token, strings.NewReader("milk")))
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("status: got %d", rr.Code)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, token) {
+		t.Fatalf("token leaked into logs: %s", out)
+	}
+	if got := strings.Count(out, `"path":"/api/capture/[redacted]"`); got != 2 {
+		t.Fatalf("expected both log lines to carry the redacted path, got %d: %s", got, out)
+	}
+}
@@ -0,0 +1,136 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Record fields a CSV column can map to.
+const (
+	FieldID          = "id"
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldProject     = "project"
+	FieldTags        = "tags"
+	FieldPriority    = "priority"
+	FieldDue         = "due"
+	FieldDueTime     = "due_time"
+	FieldRecurrence  = "recurrence"
+	FieldDone        = "done"
+)
+
+// csvSynonyms guesses the field of an unmapped column from its header.
+var csvSynonyms = map[string]string{
+	"id": FieldID, "uid": FieldID, "uuid": FieldID, "external id": FieldID,
+	"title": FieldTitle, "name": FieldTitle, "task": FieldTitle, "content": FieldTitle, "summary": FieldTitle,
+	"description": FieldDescription, "notes": FieldDescription, "note": FieldDescription, "details": FieldDescription,
+	"project": FieldProject, "list": FieldProject, "folder": FieldProject,
+	"tags": FieldTags, "tag": FieldTags, "labels": FieldTags, "label": FieldTags, "categories": FieldTags,
+	"priority": FieldPriority,
+	"due":      FieldDue, "due date": FieldDue, "duedate": FieldDue, "date": FieldDue, "deadline": FieldDue,
+	"due time": FieldDueTime, "time": FieldDueTime,
+	"recurrence": FieldRecurrence, "repeat": FieldRecurrence, "repeats": FieldRecurrence, "rrule": FieldRecurrence,
+	"done": FieldDone, "completed": FieldDone, "complete": FieldDone, "finished": FieldDone, "status": FieldDone,
+}
+
+func validField(f string) bool {
+	switch f {
+	case FieldID, FieldTitle, FieldDescription, FieldProject, FieldTags,
+		FieldPriority, FieldDue, FieldDueTime, FieldRecurrence, FieldDone:
+		return true
+	}
+	return false
+}
+
+// parseGenericCSV reads any CSV with a header row. opts.Mapping maps
+// header names (case-insensitive) to record fields; unmapped columns are
+// matched by common header names, and the rest are ignored.
+func parseGenericCSV(data []byte, opts Options) ([]Row, error) {
+	header, records, lines, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]int{}
+	for name, i := range header {
+		if f, ok := csvSynonyms[name]; ok {
+			// The leftmost of several matching columns wins.
+			if j, taken := fields[f]; !taken || i < j {
+				fields[f] = i
+			}
+		}
+	}
+	for name, f := range opts.Mapping {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if !validField(f) {
+			return nil, fmt.Errorf("%w: unknown field %q for column %q", ErrBadMapping, f, name)
+		}
+		i, ok := header[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("%w: no column %q", ErrBadMapping, name)
+		}
+		fields[f] = i
+	}
+	if _, ok := fields[FieldTitle]; !ok {
+		return nil, fmt.Errorf("%w: no title column", ErrBadMapping)
+	}
+	col := func(rec []string, field string) string {
+		if i, ok := fields[field]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	rows := make([]Row, 0, len(records))
+	for i, rec := range records {
+		r := &Record{
+			ExternalID:  col(rec, FieldID),
+			Title:       col(rec, FieldTitle),
+			Description: col(rec, FieldDescription),
+			Project:     col(rec, FieldProject),
+			Recurrence:  col(rec, FieldRecurrence),
+			Done:        csvDone(col(rec, FieldDone)),
+		}
+		r.Tags = strings.FieldsFunc(col(rec, FieldTags), func(c rune) bool { return c == ',' || c == ';' })
+		if p, ok := csvPriority(col(rec, FieldPriority)); ok {
+			r.Priority = p
+		} else {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("priority dropped: unrecognised value %q", col(rec, FieldPriority)))
+		}
+		if raw := col(rec, FieldDue); raw != "" {
+			d, clock, err := parseDue(raw, opts)
+			if err != nil {
+				r.Warnings = append(r.Warnings, "due date dropped: "+err.Error())
+			} else {
+				r.Due, r.DueTime = d, clock
+			}
+		}
+		if raw := col(rec, FieldDueTime); raw != "" && r.Due != "" {
+			r.DueTime = raw
+		}
+		rows = append(rows, Row{Line: lines[i], Record: r})
+	}
+	return rows, nil
+}
+
+func csvDone(v string) bool {
+	switch strings.ToLower(v) {
+	case "true", "yes", "y", "1", "x", "done", "completed", "complete":
+		return true
+	}
+	return false
+}
+
+// csvPriority accepts level names, their initials and p1..p4.
+func csvPriority(v string) (string, bool) {
+	switch strings.ToLower(v) {
+	case "", "none", "no", "0", "p4", "4":
+		return "", true
+	case "low", "l", "p3", "3":
+		return "low", true
+	case "medium", "med", "m", "normal", "p2", "2":
+		return "medium", true
+	case "high", "h", "urgent", "p1", "1":
+		return "high", true
+	}
+	return "", false
+}
@@ -0,0 +1,188 @@
+package importer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+const (
+	dateLayout  = "2006-01-02"
+	clockLayout = "15:04"
+)
+
+// dateTimeLayouts are tried in order on free-form due values. Layouts
+// without a clock yield a date-only due.
+var dateTimeLayouts = []struct {
+	layout string
+	clock  bool
+}{
+	{time.RFC3339, true},
+	{"2006-01-02T15:04:05", true},
+	{"2006-01-02 15:04:05", true},
+	{"2006-01-02 15:04", true},
+	{"2006-01-02", false},
+	{"2006/01/02", false},
+	{"01/02/2006", false},
+	{"Jan 2 2006 15:04", true},
+	{"Jan 2 2006", false},
+	{"2 Jan 2006", false},
+	{"January 2 2006", false},
+	{"2 January 2006", false},
+}
+
+// parseDue reads a due value into a date (YYYY-MM-DD) and an optional
+// clock (HH:MM) in opts.Location. It knows "today" and "tomorrow".
+func parseDue(raw string, opts Options) (string, string, error) {
+	s := strings.TrimSpace(strings.ReplaceAll(raw, ",", ""))
+	switch strings.ToLower(s) {
+	case "":
+		return "", "", nil
+	case "today":
+		return opts.Now.Format(dateLayout), "", nil
+	case "tomorrow":
+		return opts.Now.AddDate(0, 0, 1).Format(dateLayout), "", nil
+	}
+	for _, l := range dateTimeLayouts {
+		t, err := time.ParseInLocation(l.layout, s, opts.Location)
+		if err != nil {
+			continue
+		}
+		t = t.In(opts.Location)
+		if !l.clock {
+			return t.Format(dateLayout), "", nil
+		}
+		return t.Format(dateLayout), t.Format(clockLayout), nil
+	}
+	return "", "", fmt.Errorf("unrecognised date %q", raw)
+}
+
+var phraseWeekdays = map[string]string{
+	"sunday": "SU", "sun": "SU",
+	"monday": "MO", "mon": "MO",
+	"tuesday": "TU", "tue": "TU", "tues": "TU",
+	"wednesday": "WE", "wed": "WE",
+	"thursday": "TH", "thu": "TH", "thurs": "TH",
+	"friday": "FR", "fri": "FR",
+	"saturday": "SA", "sat": "SA",
+}
+
+var phraseUnits = map[string]string{
+	"d": "daily", "day": "daily", "days": "daily",
+	"w": "weekly", "week": "weekly", "weeks": "weekly",
+	"m": "monthly", "month": "monthly", "months": "monthly",
+	"y": "yearly", "year": "yearly", "years": "yearly",
+}
+
+// ParseRecurrence reads a repeat rule as other tools write it: an RRULE
+// ("FREQ=WEEKLY;BYDAY=MO"), a Todoist-style phrase ("every 2 weeks",
+// "every monday, friday", "every! 3 days" for repeat-after-completion) or
+// a bare frequency ("weekly", "2w"). It returns an error for anything it
+// cannot represent.
+func ParseRecurrence(raw string) (*model.Recurrence, error) {
+	s := strings.ToLower(strings.TrimSpace(raw))
+	if s == "" {
+		return nil, nil
+	}
+	if strings.Contains(s, "freq=") {
+		return task.ParseRRULE(raw)
+	}
+
+	rec := &model.Recurrence{Interval: 1}
+	switch {
+	case strings.HasPrefix(s, "every!"):
+		rec.Mode = model.RecurrenceModeAfterCompletion
+		s = s[len("every!"):]
+	case strings.HasPrefix(s, "after"):
+		rec.Mode = model.RecurrenceModeAfterCompletion
+		s = s[len("after"):]
+	case strings.HasPrefix(s, "every"):
+		s = s[len("every"):]
+	}
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "other"))
+	if strings.HasPrefix(strings.TrimSpace(strings.ToLower(raw)), "every other") {
+		rec.Interval = 2
+	}
+
+	switch s {
+	case "day", "daily", "d":
+		rec.Type = "daily"
+	case "weekday", "weekdays", "workday", "workdays":
+		rec.Type = "weekly"
+		rec.ByDay = []string{"MO", "TU", "WE", "TH", "FR"}
+	case "weekend", "weekends":
+		rec.Type = "weekly"
+		rec.ByDay = []string{"SA", "SU"}
+	case "week", "weekly", "w":
+		rec.Type = "weekly"
+	case "biweekly", "fortnight", "fortnightly":
+		rec.Type, rec.Interval = "weekly", 2
+	case "month", "monthly", "m":
+		rec.Type = "monthly"
+	case "quarter", "quarterly":
+		rec.Type, rec.Interval = "monthly", 3
+	case "year", "yearly", "annually", "annual", "y":
+		rec.Type = "yearly"
+	default:
+		if !parseIntervalPhrase(s, rec) && !parseWeekdayPhrase(s, rec) {
+			return nil, fmt.Errorf("unsupported repeat rule %q", raw)
+		}
+	}
+	if rec.Mode == model.RecurrenceModeAfterCompletion && len(rec.ByDay) > 0 {
+		return nil, fmt.Errorf("unsupported repeat rule %q", raw)
+	}
+	task.NormalizeRecurrence(rec)
+	if err := task.ValidateRecurrence(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// parseIntervalPhrase reads "3 days", "2 weeks" and the short "2w".
+func parseIntervalPhrase(s string, rec *model.Recurrence) bool {
+	num, unit := s, ""
+	if n, u, ok := strings.Cut(s, " "); ok {
+		num, unit = n, strings.TrimSpace(u)
+	} else {
+		i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+		if i <= 0 {
+			return false
+		}
+		num, unit = s[:i], s[i:]
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n <= 0 {
+		return false
+	}
+	typ, ok := phraseUnits[unit]
+	if !ok {
+		return false
+	}
+	rec.Type, rec.Interval = typ, n
+	return true
+}
+
+// parseWeekdayPhrase reads "monday", "mon, thu" and "tue and fri".
+func parseWeekdayPhrase(s string, rec *model.Recurrence) bool {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	var days []string
+	for _, f := range fields {
+		if f == "and" {
+			continue
+		}
+		d, ok := phraseWeekdays[f]
+		if !ok {
+			return false
+		}
+		days = append(days, d)
+	}
+	if len(days) == 0 {
+		return false
+	}
+	rec.Type, rec.ByDay = "weekly", days
+	return true
+}
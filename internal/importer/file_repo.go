@@ -0,0 +1,196 @@
+package importer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+// maxJobsPerUser bounds the stored history; the oldest jobs are dropped.
+const maxJobsPerUser = 20
+
+type fileState struct {
+	Users map[string]map[model.ImportJobID]model.ImportJob `json:"users"`
+}
+
+type fileStore struct {
+	mu   sync.RWMutex
+	path string
+	s    fileState
+}
+
+// FileRepo is a persistent import-job repo scoped by user.
+type FileRepo struct {
+	store  *fileStore
+	userID string
+}
+
+func NewFileRepo(dataDir string) (*FileRepo, error) {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return nil, err
+	}
+	st := &fileStore{
+		path: filepath.Join(dataDir, "imports.json"),
+		s: fileState{
+			Users: map[string]map[model.ImportJobID]model.ImportJob{},
+		},
+	}
+	if err := st.load(); err != nil {
+		return nil, err
+	}
+	return &FileRepo{
+		store:  st,
+		userID: "default",
+	}, nil
+}
+
+func (s *fileStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.s.Users = map[string]map[model.ImportJobID]model.ImportJob{}
+			return nil
+		}
+		return err
+	}
+	var loaded fileState
+	if err := json.Unmarshal(b, &loaded); err != nil {
+		return err
+	}
+	if loaded.Users == nil {
+		loaded.Users = map[string]map[model.ImportJobID]model.ImportJob{}
+	}
+	for uid, m := range loaded.Users {
+		if m == nil {
+			loaded.Users[uid] = map[model.ImportJobID]model.ImportJob{}
+		}
+	}
+	s.s = loaded
+	return nil
+}
+
+func (s *fileStore) saveLocked() error {
+	b, err := json.MarshalIndent(s.s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o644)
+}
+
+func (r *FileRepo) ForUser(userID string) *FileRepo {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		userID = "default"
+	}
+	return &FileRepo{
+		store:  r.store,
+		userID: userID,
+	}
+}
+
+func (r *FileRepo) userMapLocked() map[model.ImportJobID]model.ImportJob {
+	m, ok := r.store.s.Users[r.userID]
+	if !ok || m == nil {
+		m = map[model.ImportJobID]model.ImportJob{}
+		r.store.s.Users[r.userID] = m
+	}
+	return m
+}
+
+func (r *FileRepo) Create(j model.ImportJob) (model.ImportJob, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	if strings.TrimSpace(string(j.ID)) == "" {
+		j.ID = newID("imp")
+	}
+	if j.CreatedAt.IsZero() {
+		j.CreatedAt = nowUTC()
+	}
+	if j.Status == "" {
+		j.Status = model.ImportJobPreview
+	}
+	m[j.ID] = j
+	pruneLocked(m)
+	if err := r.store.saveLocked(); err != nil {
+		return model.ImportJob{}, err
+	}
+	return j, nil
+}
+
+func (r *FileRepo) Get(id model.ImportJobID) (model.ImportJob, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	j, ok := r.store.s.Users[r.userID][id]
+	if !ok {
+		return model.ImportJob{}, ErrNotFound
+	}
+	return j, nil
+}
+
+func (r *FileRepo) Save(j model.ImportJob) (model.ImportJob, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	if _, ok := m[j.ID]; !ok {
+		return model.ImportJob{}, ErrNotFound
+	}
+	m[j.ID] = j
+	if err := r.store.saveLocked(); err != nil {
+		return model.ImportJob{}, err
+	}
+	return j, nil
+}
+
+func (r *FileRepo) List() ([]model.ImportJob, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	m := r.store.s.Users[r.userID]
+	out := make([]model.ImportJob, 0, len(m))
+	for _, j := range m {
+		out = append(out, j)
+	}
+	sortJobs(out)
+	return out, nil
+}
+
+// pruneLocked drops the oldest jobs beyond maxJobsPerUser.
+func pruneLocked(m map[model.ImportJobID]model.ImportJob) {
+	if len(m) <= maxJobsPerUser {
+		return
+	}
+	jobs := make([]model.ImportJob, 0, len(m))
+	for _, j := range m {
+		jobs = append(jobs, j)
+	}
+	sortJobs(jobs)
+	for _, j := range jobs[maxJobsPerUser:] {
+		delete(m, j.ID)
+	}
+}
+
+func sortJobs(js []model.ImportJob) {
+	sort.Slice(js, func(i, j int) bool {
+		if !js[i].CreatedAt.Equal(js[j].CreatedAt) {
+			return js[i].CreatedAt.After(js[j].CreatedAt)
+		}
+		return js[i].ID > js[j].ID
+	})
+}
+
+func nowUTC() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,365 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/task"
+	"donegeon/internal/webhook"
+)
+
+// maxImportBytes caps an uploaded export.
+const maxImportBytes = 10 << 20
+
+type Handler struct {
+	repo              Repo
+	repoResolver      func(*http.Request) Repo
+	taskRepoResolver  func(*http.Request) task.Repo
+	playerResolver    func(*http.Request) *player.FileRepo
+	pluginResolver    func(*http.Request) task.PluginRunner
+	publisherResolver func(*http.Request) webhook.Publisher
+	cfg               *config.Config
+}
+
+func NewHandler(repo Repo) *Handler {
+	return &Handler{repo: repo}
+}
+
+func (h *Handler) SetConfig(cfg *config.Config) {
+	h.cfg = cfg
+}
+
+func (h *Handler) SetRepoResolver(fn func(*http.Request) Repo) {
+	h.repoResolver = fn
+}
+
+func (h *Handler) SetTaskRepoResolver(fn func(*http.Request) task.Repo) {
+	h.taskRepoResolver = fn
+}
+
+func (h *Handler) SetPlayerResolver(fn func(*http.Request) *player.FileRepo) {
+	h.playerResolver = fn
+}
+
+// SetPluginResolver runs the requester's plugins on tasks an import
+// creates or undo archives, when they carry plugin cards.
+func (h *Handler) SetPluginResolver(fn func(*http.Request) task.PluginRunner) {
+	h.pluginResolver = fn
+}
+
+// SetPublisherResolver publishes task.created for each task an import
+// creates.
+func (h *Handler) SetPublisherResolver(fn func(*http.Request) webhook.Publisher) {
+	h.publisherResolver = fn
+}
+
+func (h *Handler) tasksCreated(r *http.Request, ts []model.Task) {
+	if h.publisherResolver == nil || len(ts) == 0 {
+		return
+	}
+	if p := h.publisherResolver(r); p != nil {
+		for _, t := range ts {
+			p.Publish(webhook.EventTaskCreated, t)
+		}
+	}
+}
+
+// tasksChanged tells the requester's plugins about tasks an import wrote.
+func (h *Handler) tasksChanged(r *http.Request, ts []model.Task) {
+	if h.pluginResolver == nil {
+		return
+	}
+	var runner task.PluginRunner
+	for _, t := range ts {
+		if len(task.PluginCards(t)) == 0 {
+			continue
+		}
+		if runner == nil {
+			if runner = h.pluginResolver(r); runner == nil {
+				return
+			}
+		}
+		runner.TaskChanged(t)
+	}
+}
+
+func (h *Handler) repoForRequest(r *http.Request) Repo {
+	if h.repoResolver != nil {
+		if repo := h.repoResolver(r); repo != nil {
+			return repo
+		}
+	}
+	return h.repo
+}
+
+// taskRepoForRequest tags writes as imports so task history shows where
+// they came from.
+func (h *Handler) taskRepoForRequest(r *http.Request) task.Repo {
+	if h.taskRepoResolver == nil {
+		return nil
+	}
+	repo := h.taskRepoResolver(r)
+	if repo == nil {
+		return nil
+	}
+	return repo.WithOrigin(task.SourceImport, "")
+}
+
+func (h *Handler) playerForRequest(r *http.Request) *player.FileRepo {
+	if h.playerResolver == nil {
+		return nil
+	}
+	return h.playerResolver(r)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeErr(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]any{"error": msg})
+}
+
+func decodeJSON(r *http.Request, out any) error {
+	return json.NewDecoder(r.Body).Decode(out)
+}
+
+func writeRepoErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrNothingToUndo):
+		writeErr(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrUnknownSource), errors.Is(err, ErrBadInput), errors.Is(err, ErrBadMapping):
+		writeErr(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotPreview), errors.Is(err, ErrNothingToApply):
+		writeErr(w, http.StatusConflict, err.Error())
+	default:
+		writeErr(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// jobSummary is a job as listed, without its rows.
+type jobSummary struct {
+	ID        model.ImportJobID `json:"id"`
+	Source    string            `json:"source"`
+	Status    string            `json:"status"`
+	Creates   int               `json:"creates"`
+	Skipped   int               `json:"skipped"`
+	CreatedAt time.Time         `json:"createdAt"`
+	AppliedAt *time.Time        `json:"appliedAt,omitempty"`
+	UndoneAt  *time.Time        `json:"undoneAt,omitempty"`
+}
+
+func summarize(j model.ImportJob) jobSummary {
+	return jobSummary{
+		ID:        j.ID,
+		Source:    j.Source,
+		Status:    j.Status,
+		Creates:   j.Creates,
+		Skipped:   j.Skipped,
+		CreatedAt: j.CreatedAt,
+		AppliedAt: j.AppliedAt,
+		UndoneAt:  j.UndoneAt,
+	}
+}
+
+// GET /api/import/sources
+func (h *Handler) Sources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"sources": Sources()})
+}
+
+type createJobRequest struct {
+	Source  string            `json:"source"`
+	Data    string            `json:"data"` // the export file's contents
+	Project string            `json:"project"`
+	Mapping map[string]string `json:"mapping"` // csv only: header -> field
+}
+
+// /api/import/jobs
+//
+// GET lists jobs, newest first. POST parses an export and stores a preview
+// job: the tasks it would create and the rows it skips, with reasons.
+// Nothing is written until the job is applied.
+func (h *Handler) Jobs(w http.ResponseWriter, r *http.Request) {
+	repo := h.repoForRequest(r)
+	switch r.Method {
+	case http.MethodGet:
+		jobs, err := repo.List()
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		out := make([]jobSummary, 0, len(jobs))
+		for _, j := range jobs {
+			out = append(out, summarize(j))
+		}
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		taskRepo := h.taskRepoForRequest(r)
+		if taskRepo == nil {
+			writeErr(w, http.StatusInternalServerError, "task repo unavailable")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+		var in createJobRequest
+		if err := decodeJSON(r, &in); err != nil {
+			writeErr(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		if strings.TrimSpace(in.Data) == "" {
+			writeErr(w, http.StatusBadRequest, "data is required")
+			return
+		}
+		playerRepo := h.playerForRequest(r)
+		loc := playerRepo.Location()
+		now := time.Now().In(loc)
+		source := strings.TrimSpace(in.Source)
+		rows, err := Parse(source, []byte(in.Data), Options{Mapping: in.Mapping, Location: loc, Now: now})
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		existing, err := taskRepo.List(task.ListFilter{})
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		planner := Planner{Source: source, Project: in.Project, Config: h.cfg, Player: playerRepo, Now: now}
+		job, err := repo.Create(planner.Plan(rows, existing))
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, job)
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// /api/import/jobs/{id}
+// /api/import/jobs/{id}/apply
+func (h *Handler) JobSub(w http.ResponseWriter, r *http.Request) {
+	repo := h.repoForRequest(r)
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/import/jobs/"), "/")
+	parts := strings.Split(path, "/")
+	if path == "" || len(parts) > 2 {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+	job, err := repo.Get(model.ImportJobID(parts[0]))
+	if err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+		return
+	}
+	if parts[1] != "apply" {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	taskRepo := h.taskRepoForRequest(r)
+	if taskRepo == nil {
+		writeErr(w, http.StatusInternalServerError, "task repo unavailable")
+		return
+	}
+	job, applyErr := Apply(taskRepo, job)
+	if errors.Is(applyErr, ErrNotPreview) || errors.Is(applyErr, ErrNothingToApply) {
+		writeRepoErr(w, applyErr)
+		return
+	}
+	created := make([]model.Task, 0, len(job.TaskIDs))
+	for _, row := range job.Rows {
+		if row.TaskID != "" && row.Task != nil {
+			created = append(created, *row.Task)
+		}
+	}
+	h.tasksCreated(r, created)
+	h.tasksChanged(r, created)
+	// Save even after a failed create so the tasks that were made can
+	// still be undone.
+	if applyErr != nil && len(job.TaskIDs) > 0 {
+		now := time.Now().UTC()
+		job.Status, job.AppliedAt = model.ImportJobApplied, &now
+	}
+	if _, err := repo.Save(job); err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+	if applyErr != nil {
+		writeRepoErr(w, applyErr)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// POST /api/import/undo
+//
+// Archives the tasks created by the most recent applied import. Tasks
+// edited since are left alone and listed under "kept".
+func (h *Handler) Undo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	repo := h.repoForRequest(r)
+	taskRepo := h.taskRepoForRequest(r)
+	if taskRepo == nil {
+		writeErr(w, http.StatusInternalServerError, "task repo unavailable")
+		return
+	}
+	job, err := LastApplied(repo)
+	if err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+	job, archived, kept, err := Undo(taskRepo, job)
+	if err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+	skipped := make(map[model.TaskID]bool, len(kept))
+	for _, k := range kept {
+		skipped[k.TaskID] = true
+	}
+	changed := make([]model.Task, 0, archived)
+	for _, id := range job.TaskIDs {
+		if skipped[id] {
+			continue
+		}
+		if t, err := taskRepo.Get(id); err == nil {
+			changed = append(changed, t)
+		}
+	}
+	h.tasksChanged(r, changed)
+	if _, err := repo.Save(job); err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"job":      summarize(job),
+		"archived": archived,
+		"kept":     kept,
+	})
+}
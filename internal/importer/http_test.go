@@ -0,0 +1,248 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+	"donegeon/internal/webhook"
+)
+
+func TestJobs_PreviewApplySkipReimportAndUndo(t *testing.T) {
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	tasks := task.NewMemoryRepo()
+	h := NewHandler(repo.ForUser("u-test"))
+	h.SetTaskRepoResolver(func(*http.Request) task.Repo { return tasks })
+	h.SetConfig(&config.Config{Modifiers: config.Modifiers{Types: []config.ModifierType{
+		{ID: "importance_seal", Effects: map[string]interface{}{"set_priority": "high"}},
+	}}})
+	do := func(method, path, body string, fn http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+		fn(rec, req)
+		return rec
+	}
+
+	export := `[
+		{"uuid":"a-1","description":"Water plants","project":"home.garden","tags":["chores"],"priority":"H","due":"20260320T000000Z","status":"pending","recur":"weekly","annotations":[{"description":"the ferns too"}]},
+		{"uuid":"a-2","description":"File taxes","priority":"M","status":"completed"},
+		{"uuid":"a-3","description":"Old thing","status":"deleted"},
+		{"uuid":"a-4","description":"Water plants","parent":"a-1","status":"pending"}
+	]`
+	body, _ := json.Marshal(map[string]any{"source": "taskwarrior", "data": export})
+	rec := do(http.MethodPost, "/api/import/jobs", string(body), h.Jobs)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var job model.ImportJob
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	if job.Status != model.ImportJobPreview || job.Creates != 2 || job.Skipped != 2 {
+		t.Fatalf("unexpected preview: %+v", job)
+	}
+	if job.Rows[2].Reason != "deleted" || job.Rows[3].Reason != "instance of a recurring task" {
+		t.Fatalf("unexpected skip reasons: %+v", job.Rows)
+	}
+	plants := job.Rows[0].Task
+	if plants.Project == nil || *plants.Project != "home.garden" || plants.Description != "the ferns too" ||
+		plants.Recurrence == nil || plants.Recurrence.Type != "weekly" ||
+		len(plants.Modifiers) != 1 || plants.Modifiers[0].DefID != "mod.importance_seal" {
+		t.Fatalf("unexpected mapped task: %+v", plants)
+	}
+	// No modifier sets medium, so the level survives as a tag.
+	if taxes := job.Rows[1].Task; !taxes.Done || len(taxes.Tags) != 1 || taxes.Tags[0] != "priority-medium" || len(job.Rows[1].Warnings) != 1 {
+		t.Fatalf("unexpected fallback for medium priority: %+v warnings=%v", taxes, job.Rows[1].Warnings)
+	}
+	if all, _ := tasks.List(task.ListFilter{}); len(all) != 0 {
+		t.Fatalf("preview must not write tasks, got %d", len(all))
+	}
+
+	rec = do(http.MethodPost, "/api/import/jobs/"+string(job.ID)+"/apply", "", h.JobSub)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on apply, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatalf("decode applied: %v", err)
+	}
+	if job.Status != model.ImportJobApplied || len(job.TaskIDs) != 2 {
+		t.Fatalf("unexpected applied job: %+v", job)
+	}
+	created, _ := tasks.Get(job.TaskIDs[0])
+	if created.ExternalRefs["taskwarrior"] != "a-1" {
+		t.Fatalf("expected external ref, got %+v", created.ExternalRefs)
+	}
+	history, _ := tasks.History(created.ID)
+	if len(history) == 0 || history[0].Source != task.SourceImport {
+		t.Fatalf("expected import-sourced history, got %+v", history)
+	}
+	if rec := do(http.MethodPost, "/api/import/jobs/"+string(job.ID)+"/apply", "", h.JobSub); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 applying twice, got %d", rec.Code)
+	}
+
+	// Importing the same export again only skips.
+	rec = do(http.MethodPost, "/api/import/jobs", string(body), h.Jobs)
+	var again model.ImportJob
+	_ = json.NewDecoder(rec.Body).Decode(&again)
+	if again.Creates != 0 || again.Rows[0].Reason != "already imported" {
+		t.Fatalf("expected re-import to skip, got %+v", again)
+	}
+	if rec := do(http.MethodPost, "/api/import/jobs/"+string(again.ID)+"/apply", "", h.JobSub); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 applying an empty job, got %d", rec.Code)
+	}
+
+	// Undo archives untouched tasks and keeps edited ones.
+	title := "File taxes (started)"
+	if _, err := tasks.Update(job.TaskIDs[1], task.Patch{Title: &title}); err != nil {
+		t.Fatalf("edit task: %v", err)
+	}
+	rec = do(http.MethodPost, "/api/import/undo", "", h.Undo)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on undo, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var undone struct {
+		Archived int    `json:"archived"`
+		Kept     []Kept `json:"kept"`
+	}
+	_ = json.NewDecoder(rec.Body).Decode(&undone)
+	if undone.Archived != 1 || len(undone.Kept) != 1 || undone.Kept[0].TaskID != job.TaskIDs[1] {
+		t.Fatalf("unexpected undo: %+v", undone)
+	}
+	if got, _ := tasks.Get(job.TaskIDs[0]); !got.Archived {
+		t.Fatalf("expected imported task archived")
+	}
+	if rec := do(http.MethodPost, "/api/import/undo", "", h.Undo); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 with nothing left to undo, got %d", rec.Code)
+	}
+}
+
+func TestParse_TodoistAndMappedCSV(t *testing.T) {
+	loc := time.UTC
+	opts := Options{Location: loc, Now: time.Date(2026, 3, 10, 9, 0, 0, 0, loc)}
+
+	todoist := "TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE\n" +
+		"section,Errands,,,,,,,,\n" +
+		"task,Buy milk @shopping,,1,1,me,,every! 3 days,en,UTC\n" +
+		"\n" +
+		"task,Dentist,,4,1,me,,Mar 14 2026 15:30,en,UTC\n"
+	rows, err := Parse("todoist_csv", []byte(todoist), opts)
+	if err != nil {
+		t.Fatalf("parse todoist csv: %v", err)
+	}
+	if len(rows) != 3 || rows[0].Skip != "section row" {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+	milk, dentist := rows[1].Record, rows[2].Record
+	if milk.Title != "Buy milk" || milk.Tags[0] != "shopping" || milk.Priority != "high" || milk.Due != "2026-03-10" || milk.Recurrence != "every! 3 days" {
+		t.Fatalf("unexpected milk: %+v", milk)
+	}
+	if dentist.Due != "2026-03-14" || dentist.DueTime != "15:30" || rows[2].Line != 5 {
+		t.Fatalf("unexpected dentist: %+v line=%d", dentist, rows[2].Line)
+	}
+
+	rec, err := ParseRecurrence(milk.Recurrence)
+	if err != nil || rec.Type != "daily" || rec.Interval != 3 || rec.Mode != model.RecurrenceModeAfterCompletion {
+		t.Fatalf("unexpected recurrence: %+v err=%v", rec, err)
+	}
+	if rec, err := ParseRecurrence("every mon, thu"); err != nil || rec.Type != "weekly" || len(rec.ByDay) != 2 {
+		t.Fatalf("unexpected weekday recurrence: %+v err=%v", rec, err)
+	}
+	if _, err := ParseRecurrence("every full moon"); err == nil {
+		t.Fatalf("expected unsupported phrase to fail")
+	}
+
+	generic := "Name,Where,Labels,When,Finished\nCall mum,family,phone;weekly,2026-03-12,yes\n"
+	opts.Mapping = map[string]string{"Where": FieldProject, "When": FieldDue}
+	rows, err = Parse("csv", []byte(generic), opts)
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	call := rows[0].Record
+	if call.Title != "Call mum" || call.Project != "family" || len(call.Tags) != 2 || call.Due != "2026-03-12" || !call.Done {
+		t.Fatalf("unexpected csv record: %+v", call)
+	}
+	opts.Mapping = map[string]string{"Where": "colour"}
+	if _, err := Parse("csv", []byte(generic), opts); err == nil {
+		t.Fatalf("expected bad mapping to fail")
+	}
+}
+
+type changedTasks []model.Task
+
+func (c *changedTasks) CardAttached(model.Task, string) {}
+func (c *changedTasks) TaskChanged(t model.Task)        { *c = append(*c, t) }
+
+func TestJobs_ApplyRunsPluginsOnCreatedTasks(t *testing.T) {
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	repo = repo.ForUser("u-test")
+	tasks := task.NewMemoryRepo()
+	var changed changedTasks
+	h := NewHandler(repo)
+	h.SetTaskRepoResolver(func(*http.Request) task.Repo { return tasks })
+	h.SetPluginResolver(func(*http.Request) task.PluginRunner { return &changed })
+
+	job, err := repo.Create(model.ImportJob{Source: "csv", Creates: 2, Rows: []model.ImportRow{
+		{Line: 1, Action: ActionCreate, Task: &model.Task{Title: "Synced", Modifiers: []model.TaskModifierSlot{{DefID: "mod.plugin_cal_sync"}}}},
+		{Line: 2, Action: ActionCreate, Task: &model.Task{Title: "Plain"}},
+	}})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.JobSub(rec, httptest.NewRequest(http.MethodPost, "/api/import/jobs/"+string(job.ID)+"/apply", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("apply: expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if len(changed) != 1 || changed[0].Title != "Synced" || changed[0].ID == "" {
+		t.Fatalf("expected the plugin to see only the task carrying its card, got %+v", changed)
+	}
+}
+
+type publishedEvents []model.Task
+
+func (p *publishedEvents) Publish(event string, data any) {
+	if t, ok := data.(model.Task); ok && event == webhook.EventTaskCreated {
+		*p = append(*p, t)
+	}
+}
+
+func TestJobs_ApplyPublishesTaskCreated(t *testing.T) {
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	repo = repo.ForUser("u-test")
+	tasks := task.NewMemoryRepo()
+	var created publishedEvents
+	h := NewHandler(repo)
+	h.SetTaskRepoResolver(func(*http.Request) task.Repo { return tasks })
+	h.SetPublisherResolver(func(*http.Request) webhook.Publisher { return &created })
+
+	job, err := repo.Create(model.ImportJob{Source: "csv", Creates: 2, Rows: []model.ImportRow{
+		{Line: 1, Action: ActionCreate, Task: &model.Task{Title: "Water plants"}},
+		{Line: 2, Action: ActionCreate, Task: &model.Task{Title: "Feed cat"}},
+	}})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.JobSub(rec, httptest.NewRequest(http.MethodPost, "/api/import/jobs/"+string(job.ID)+"/apply", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("apply: expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if len(created) != 2 || created[0].ID == "" || created[0].Title != "Water plants" || created[1].Title != "Feed cat" {
+		t.Fatalf("expected task.created for both new tasks, got %+v", created)
+	}
+}
@@ -0,0 +1,238 @@
+package importer
+
+import (
+	"strings"
+	"time"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/task"
+)
+
+// Row actions.
+const (
+	ActionCreate = "create"
+	ActionSkip   = "skip"
+)
+
+// Planner turns parsed rows into an import job preview.
+type Planner struct {
+	Source  string
+	Project string // for rows without a project; blank means the inbox
+	Config  *config.Config
+	Player  *player.FileRepo
+	Now     time.Time // in the player's location
+}
+
+// Plan maps each row onto a task to create, or a skip with its reason.
+// Rows whose external ID was imported from the same source before, or
+// repeats an earlier row, are skipped, so re-importing a file only adds
+// what is new.
+func (p Planner) Plan(rows []Row, existing []model.Task) model.ImportJob {
+	imported := importedRefs(p.Source, existing)
+	seen := map[string]bool{}
+
+	job := model.ImportJob{
+		Source:  p.Source,
+		Status:  model.ImportJobPreview,
+		Project: strings.TrimSpace(p.Project),
+		Rows:    make([]model.ImportRow, 0, len(rows)),
+	}
+	for _, row := range rows {
+		out := model.ImportRow{Line: row.Line, Action: ActionSkip, Reason: row.Skip}
+		rec := row.Record
+		switch {
+		case rec == nil:
+		case strings.TrimSpace(rec.Title) == "":
+			out.Reason = "no title"
+		case rec.ExternalID != "" && imported[rec.ExternalID]:
+			out.Reason = "already imported"
+		case rec.ExternalID != "" && seen[rec.ExternalID]:
+			out.Reason = "duplicate of an earlier row"
+		default:
+			if rec.ExternalID != "" {
+				seen[rec.ExternalID] = true
+			}
+			t, warnings := p.task(*rec)
+			out.Action, out.Reason, out.Task = ActionCreate, "", &t
+			out.Warnings = append(append([]string{}, rec.Warnings...), warnings...)
+		}
+		if out.Action == ActionCreate {
+			job.Creates++
+		} else {
+			if out.Reason == "" {
+				out.Reason = "unreadable row"
+			}
+			job.Skipped++
+		}
+		job.Rows = append(job.Rows, out)
+	}
+	return job
+}
+
+// task maps a record onto a new task and lists what had to be dropped.
+func (p Planner) task(rec Record) (model.Task, []string) {
+	var warnings []string
+	t := model.Task{
+		Title:       strings.TrimSpace(rec.Title),
+		Description: strings.TrimSpace(rec.Description),
+		Done:        rec.Done,
+		Tags:        task.NormalizeTags(rec.Tags),
+	}
+
+	project := strings.TrimSpace(rec.Project)
+	if project == "" {
+		project = p.Project
+	}
+	if project == "" {
+		project = "inbox"
+	}
+	t.Project = &project
+
+	if rec.Due != "" {
+		due := rec.Due
+		t.DueDate = &due
+		if clock := strings.TrimSpace(rec.DueTime); clock != "" {
+			if _, err := time.Parse("15:04", clock); err == nil {
+				t.DueTime = &clock
+			} else {
+				warnings = append(warnings, "due time dropped: unrecognised time "+clock)
+			}
+		}
+	}
+	if rec.Recurrence != "" {
+		rule, err := ParseRecurrence(rec.Recurrence)
+		if err != nil {
+			warnings = append(warnings, "recurrence dropped: "+err.Error())
+		} else if rule != nil {
+			t.Recurrence = rule
+			if t.DueDate == nil {
+				today := p.Now.Format(dateLayout)
+				t.DueDate = &today
+			}
+		}
+	}
+
+	if rec.Priority != "" && rec.Priority != "none" {
+		if defID, ok := task.PriorityModifier(p.Config, rec.Priority); ok {
+			t.Modifiers = []model.TaskModifierSlot{{DefID: defID}}
+		} else {
+			// Keep the information even when no card sets this level.
+			t.Tags = task.NormalizeTags(append(t.Tags, "priority-"+rec.Priority))
+			warnings = append(warnings, "no modifier sets priority "+rec.Priority+"; tagged instead")
+		}
+	}
+
+	if rec.ExternalID != "" {
+		t.ExternalRefs = map[string]string{p.Source: rec.ExternalID}
+	}
+	warnings = append(warnings, task.StripLockedFields(&t, p.Player)...)
+	return t, warnings
+}
+
+// importedRefs collects the external IDs already imported from source.
+func importedRefs(source string, tasks []model.Task) map[string]bool {
+	out := map[string]bool{}
+	for _, t := range tasks {
+		if ref := t.ExternalRefs[source]; ref != "" {
+			out[ref] = true
+		}
+	}
+	return out
+}
+
+// Apply creates the job's planned tasks. Rows whose external ID has been
+// imported since the preview are skipped instead.
+func Apply(repo task.Repo, job model.ImportJob) (model.ImportJob, error) {
+	if job.Status != model.ImportJobPreview {
+		return job, ErrNotPreview
+	}
+	if job.Creates == 0 {
+		return job, ErrNothingToApply
+	}
+	existing, err := repo.List(task.ListFilter{})
+	if err != nil {
+		return job, err
+	}
+	imported := importedRefs(job.Source, existing)
+
+	for i := range job.Rows {
+		row := &job.Rows[i]
+		if row.Action != ActionCreate || row.Task == nil {
+			continue
+		}
+		if ref := row.Task.ExternalRefs[job.Source]; ref != "" && imported[ref] {
+			row.Action, row.Reason = ActionSkip, "already imported"
+			job.Creates--
+			job.Skipped++
+			continue
+		}
+		t, err := repo.Create(*row.Task)
+		if err != nil {
+			return job, err
+		}
+		row.TaskID, row.Task = t.ID, &t
+		job.TaskIDs = append(job.TaskIDs, t.ID)
+	}
+	now := nowUTC()
+	job.Status, job.AppliedAt = model.ImportJobApplied, &now
+	return job, nil
+}
+
+// Kept is a created task that undo left alone.
+type Kept struct {
+	TaskID model.TaskID `json:"taskId"`
+	Reason string       `json:"reason"`
+}
+
+// Undo archives the tasks an applied job created. Tasks edited since the
+// import (or already archived) are kept and reported, so undo never
+// throws away work done on them.
+func Undo(repo task.Repo, job model.ImportJob) (model.ImportJob, int, []Kept, error) {
+	if job.Status != model.ImportJobApplied {
+		return job, 0, nil, ErrNothingToUndo
+	}
+	archived, kept := 0, []Kept{}
+	yes, first := true, int64(1)
+	for _, id := range job.TaskIDs {
+		t, err := repo.Get(id)
+		if err != nil {
+			kept = append(kept, Kept{TaskID: id, Reason: "task no longer exists"})
+			continue
+		}
+		switch {
+		case t.Archived:
+			kept = append(kept, Kept{TaskID: id, Reason: "already archived"})
+			continue
+		case t.Revision != first:
+			kept = append(kept, Kept{TaskID: id, Reason: "changed since the import"})
+			continue
+		}
+		if _, err := repo.Update(id, task.Patch{Archived: &yes, IfRevision: &first}); err != nil {
+			if err == task.ErrRevisionConflict {
+				kept = append(kept, Kept{TaskID: id, Reason: "changed since the import"})
+				continue
+			}
+			return job, archived, kept, err
+		}
+		archived++
+	}
+	now := nowUTC()
+	job.Status, job.UndoneAt = model.ImportJobUndone, &now
+	return job, archived, kept, nil
+}
+
+// LastApplied returns the newest job that is still applied.
+func LastApplied(repo Repo) (model.ImportJob, error) {
+	jobs, err := repo.List()
+	if err != nil {
+		return model.ImportJob{}, err
+	}
+	for _, j := range jobs {
+		if j.Status == model.ImportJobApplied {
+			return j, nil
+		}
+	}
+	return model.ImportJob{}, ErrNothingToUndo
+}
@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Record is one task read from another tool, before it is mapped onto
+// model.Task.
+type Record struct {
+	ExternalID  string
+	Title       string
+	Description string
+	Project     string
+	Tags        []string
+	Priority    string // none | low | medium | high
+	Due         string // YYYY-MM-DD
+	DueTime     string // HH:MM
+	Recurrence  string // a phrase ("every 2 weeks", "weekly") or an RRULE
+	Done        bool
+
+	// Warnings lists values the parser had to drop.
+	Warnings []string
+}
+
+// Row is one input row: a record, or the reason it was skipped.
+type Row struct {
+	Line   int
+	Record *Record
+	Skip   string
+}
+
+// Options tune a parse.
+type Options struct {
+	// Mapping maps CSV headers to record fields (title, description,
+	// project, tags, priority, due, due_time, recurrence, done, id). Only
+	// the generic CSV parser reads it.
+	Mapping map[string]string
+
+	// Location and Now resolve relative and floating dates.
+	Location *time.Location
+	Now      time.Time
+}
+
+// Parser reads one export format.
+type Parser interface {
+	Parse(data []byte, opts Options) ([]Row, error)
+}
+
+// ParserFunc adapts a function to Parser.
+type ParserFunc func(data []byte, opts Options) ([]Row, error)
+
+func (f ParserFunc) Parse(data []byte, opts Options) ([]Row, error) {
+	return f(data, opts)
+}
+
+var parsers = map[string]Parser{}
+
+// Register makes a parser available as an import source. It panics on a
+// duplicate name, like http.Handle.
+func Register(source string, p Parser) {
+	if _, dup := parsers[source]; dup {
+		panic("importer: duplicate source " + source)
+	}
+	parsers[source] = p
+}
+
+// Sources lists the registered source names.
+func Sources() []string {
+	out := make([]string, 0, len(parsers))
+	for name := range parsers {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Parse runs the named source's parser.
+func Parse(source string, data []byte, opts Options) ([]Row, error) {
+	p, ok := parsers[strings.TrimSpace(source)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q (want one of %s)", ErrUnknownSource, source, strings.Join(Sources(), ", "))
+	}
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	opts.Now = opts.Now.In(opts.Location)
+	return p.Parse(data, opts)
+}
+
+func init() {
+	Register("todoist_csv", ParserFunc(parseTodoistCSV))
+	Register("todoist_json", ParserFunc(parseTodoistJSON))
+	Register("taskwarrior", ParserFunc(parseTaskwarrior))
+	Register("csv", ParserFunc(parseGenericCSV))
+}
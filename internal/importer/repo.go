@@ -0,0 +1,34 @@
+package importer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+
+	"donegeon/internal/model"
+)
+
+var (
+	ErrNotFound       = errors.New("import job not found")
+	ErrUnknownSource  = errors.New("unknown import source")
+	ErrBadInput       = errors.New("could not read import file")
+	ErrBadMapping     = errors.New("invalid column mapping")
+	ErrNotPreview     = errors.New("import job was already applied")
+	ErrNothingToUndo  = errors.New("no applied import to undo")
+	ErrNothingToApply = errors.New("import job has no rows to create")
+)
+
+type Repo interface {
+	Create(j model.ImportJob) (model.ImportJob, error)
+	Get(id model.ImportJobID) (model.ImportJob, error)
+	// Save replaces a stored job.
+	Save(j model.ImportJob) (model.ImportJob, error)
+	// List returns the user's jobs, newest first.
+	List() ([]model.ImportJob, error)
+}
+
+func newID(prefix string) model.ImportJobID {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return model.ImportJobID(prefix + "_" + hex.EncodeToString(b[:]))
+}
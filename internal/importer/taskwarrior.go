@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// twStampLayout is Taskwarrior's export date format (always UTC).
+const twStampLayout = "20060102T150405Z"
+
+type twTask struct {
+	UUID        string   `json:"uuid"`
+	Description string   `json:"description"`
+	Project     string   `json:"project"`
+	Tags        []string `json:"tags"`
+	Priority    string   `json:"priority"`
+	Due         string   `json:"due"`
+	Status      string   `json:"status"`
+	Recur       string   `json:"recur"`
+	Parent      string   `json:"parent"`
+	Annotations []struct {
+		Description string `json:"description"`
+	} `json:"annotations"`
+}
+
+// parseTaskwarrior reads the JSON array written by "task export". Deleted
+// tasks and the generated instances of a recurring task are skipped; the
+// recurring template is imported once with its rule. Annotations become
+// the description, and dotted projects ("home.garden") keep their name.
+func parseTaskwarrior(data []byte, opts Options) ([]Row, error) {
+	var tasks []twTask
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadInput, err)
+	}
+	rows := make([]Row, 0, len(tasks))
+	for i, t := range tasks {
+		row := Row{Line: i + 1}
+		switch {
+		case t.Status == "deleted":
+			row.Skip = "deleted"
+		case t.Parent != "":
+			row.Skip = "instance of a recurring task"
+		}
+		if row.Skip != "" {
+			rows = append(rows, row)
+			continue
+		}
+
+		notes := make([]string, 0, len(t.Annotations))
+		for _, a := range t.Annotations {
+			if s := strings.TrimSpace(a.Description); s != "" {
+				notes = append(notes, s)
+			}
+		}
+		r := &Record{
+			ExternalID:  t.UUID,
+			Title:       strings.TrimSpace(t.Description),
+			Description: strings.Join(notes, "\n"),
+			Project:     t.Project,
+			Tags:        t.Tags,
+			Recurrence:  t.Recur,
+			Done:        t.Status == "completed",
+		}
+		switch strings.ToUpper(t.Priority) {
+		case "H":
+			r.Priority = "high"
+		case "M":
+			r.Priority = "medium"
+		case "L":
+			r.Priority = "low"
+		}
+		if t.Due != "" {
+			due, err := time.Parse(twStampLayout, t.Due)
+			if err != nil {
+				r.Warnings = append(r.Warnings, fmt.Sprintf("due date dropped: unrecognised date %q", t.Due))
+			} else {
+				due = due.In(opts.Location)
+				r.Due = due.Format(dateLayout)
+				// Taskwarrior stores date-only dues as local midnight.
+				if due.Hour() != 0 || due.Minute() != 0 {
+					r.DueTime = due.Format(clockLayout)
+				}
+			}
+		}
+		row.Record = r
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,243 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseTodoistCSV reads Todoist's per-project CSV export (TYPE, CONTENT,
+// DESCRIPTION, PRIORITY, INDENT, AUTHOR, RESPONSIBLE, DATE, ...). Only
+// "task" rows become tasks; sections and notes are reported as skipped.
+// Labels are written inline in CONTENT as "@label". The export has no
+// project column, so rows land in the job's project.
+func parseTodoistCSV(data []byte, opts Options) ([]Row, error) {
+	header, records, lines, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	col := func(rec []string, name string) string {
+		if i, ok := header[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+	if _, ok := header["content"]; !ok {
+		return nil, fmt.Errorf("%w: missing CONTENT column", ErrBadInput)
+	}
+
+	rows := make([]Row, 0, len(records))
+	for i, rec := range records {
+		row := Row{Line: lines[i]}
+		switch typ := strings.ToLower(col(rec, "type")); typ {
+		case "task", "":
+		default:
+			row.Skip = typ + " row"
+			rows = append(rows, row)
+			continue
+		}
+		title, tags := splitInlineLabels(col(rec, "content"))
+		r := &Record{
+			Title:       title,
+			Description: col(rec, "description"),
+			Tags:        tags,
+		}
+		// The CSV export numbers priorities as the app shows them: p1 is
+		// the most urgent.
+		switch col(rec, "priority") {
+		case "1":
+			r.Priority = "high"
+		case "2":
+			r.Priority = "medium"
+		case "3":
+			r.Priority = "low"
+		}
+		applyTodoistDate(r, col(rec, "date"), "", opts)
+		row.Record = r
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// todoistBackup covers the Sync API dump (items) and the REST export
+// (tasks).
+type todoistBackup struct {
+	Projects []struct {
+		ID   json.RawMessage `json:"id"`
+		Name string          `json:"name"`
+	} `json:"projects"`
+	Items []todoistItem `json:"items"`
+	Tasks []todoistItem `json:"tasks"`
+}
+
+type todoistItem struct {
+	ID          json.RawMessage `json:"id"`
+	Content     string          `json:"content"`
+	Description string          `json:"description"`
+	ProjectID   json.RawMessage `json:"project_id"`
+	Labels      []string        `json:"labels"`
+	Priority    int             `json:"priority"`
+	Checked     any             `json:"checked"`
+	IsCompleted bool            `json:"is_completed"`
+	IsDeleted   any             `json:"is_deleted"`
+	Due         *struct {
+		Date        string `json:"date"`
+		String      string `json:"string"`
+		IsRecurring bool   `json:"is_recurring"`
+	} `json:"due"`
+}
+
+// parseTodoistJSON reads a Todoist JSON backup. Project IDs are resolved
+// to names through the backup's projects list.
+func parseTodoistJSON(data []byte, opts Options) ([]Row, error) {
+	var b todoistBackup
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadInput, err)
+	}
+	projects := make(map[string]string, len(b.Projects))
+	for _, p := range b.Projects {
+		projects[rawID(p.ID)] = p.Name
+	}
+	items := append(b.Items, b.Tasks...)
+
+	rows := make([]Row, 0, len(items))
+	for i, it := range items {
+		row := Row{Line: i + 1}
+		if truthy(it.IsDeleted) {
+			row.Skip = "deleted"
+			rows = append(rows, row)
+			continue
+		}
+		r := &Record{
+			ExternalID:  rawID(it.ID),
+			Title:       strings.TrimSpace(it.Content),
+			Description: strings.TrimSpace(it.Description),
+			Project:     projects[rawID(it.ProjectID)],
+			Tags:        it.Labels,
+			Done:        truthy(it.Checked) || it.IsCompleted,
+		}
+		// The API counts the other way round from the app: 4 is p1.
+		switch it.Priority {
+		case 4:
+			r.Priority = "high"
+		case 3:
+			r.Priority = "medium"
+		case 2:
+			r.Priority = "low"
+		}
+		if it.Due != nil {
+			phrase := ""
+			if it.Due.IsRecurring {
+				phrase = it.Due.String
+			}
+			applyTodoistDate(r, it.Due.Date, phrase, opts)
+		}
+		row.Record = r
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// applyTodoistDate fills the due date and recurrence from a Todoist date.
+// A recurring date is a phrase ("every monday"); its first occurrence is
+// due today unless an explicit date is given too.
+func applyTodoistDate(r *Record, date, phrase string, opts Options) {
+	if phrase == "" && strings.HasPrefix(strings.ToLower(strings.TrimSpace(date)), "every") {
+		phrase, date = date, ""
+	}
+	if phrase != "" {
+		r.Recurrence = phrase
+		if date == "" {
+			r.Due = opts.Now.Format(dateLayout)
+		}
+	}
+	if date == "" {
+		return
+	}
+	d, clock, err := parseDue(date, opts)
+	if err != nil {
+		r.Warnings = append(r.Warnings, "due date dropped: "+err.Error())
+		return
+	}
+	r.Due, r.DueTime = d, clock
+}
+
+// splitInlineLabels pulls "@label" words out of a Todoist title.
+func splitInlineLabels(content string) (string, []string) {
+	var (
+		words []string
+		tags  []string
+	)
+	for _, w := range strings.Fields(content) {
+		if len(w) > 1 && strings.HasPrefix(w, "@") {
+			tags = append(tags, w[1:])
+			continue
+		}
+		words = append(words, w)
+	}
+	return strings.Join(words, " "), tags
+}
+
+// rawID reads an ID that may be a JSON number or string.
+func rawID(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// truthy reads a flag that older exports write as 0/1.
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		b, _ := strconv.ParseBool(x)
+		return b
+	}
+	return false
+}
+
+// readCSV returns the lowercased header positions, the data records and
+// the file line each record starts on.
+func readCSV(data []byte) (map[string]int, [][]string, []int, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	rd := csv.NewReader(bytes.NewReader(data))
+	rd.FieldsPerRecord = -1
+	rd.LazyQuotes = true
+	var (
+		header  map[string]int
+		records [][]string
+		lines   []int
+	)
+	for {
+		rec, err := rd.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%w: %v", ErrBadInput, err)
+		}
+		line, _ := rd.FieldPos(0)
+		if header == nil {
+			header = make(map[string]int, len(rec))
+			for i, h := range rec {
+				header[strings.ToLower(strings.TrimSpace(h))] = i
+			}
+			continue
+		}
+		records = append(records, rec)
+		lines = append(lines, line)
+	}
+	if header == nil {
+		return nil, nil, nil, fmt.Errorf("%w: empty file", ErrBadInput)
+	}
+	return header, records, lines, nil
+}
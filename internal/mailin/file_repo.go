@@ -0,0 +1,191 @@
+package mailin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+var ErrNotFound = errors.New("attachment not found")
+
+type fileState struct {
+	Users map[string]map[model.AttachmentID]model.Attachment `json:"users"`
+}
+
+type fileStore struct {
+	mu   sync.RWMutex
+	path string
+	dir  string // attachment bodies, one directory per user
+	s    fileState
+}
+
+// FileRepo keeps attachment metadata in attachments.json and the files
+// themselves under files/{user}/{id}, scoped by user.
+type FileRepo struct {
+	store  *fileStore
+	userID string
+}
+
+func NewFileRepo(dataDir string) (*FileRepo, error) {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return nil, err
+	}
+	st := &fileStore{
+		path: filepath.Join(dataDir, "attachments.json"),
+		dir:  filepath.Join(dataDir, "files"),
+		s: fileState{
+			Users: map[string]map[model.AttachmentID]model.Attachment{},
+		},
+	}
+	if err := st.load(); err != nil {
+		return nil, err
+	}
+	return &FileRepo{
+		store:  st,
+		userID: "default",
+	}, nil
+}
+
+func (s *fileStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var loaded fileState
+	if err := json.Unmarshal(b, &loaded); err != nil {
+		return err
+	}
+	if loaded.Users == nil {
+		loaded.Users = map[string]map[model.AttachmentID]model.Attachment{}
+	}
+	for uid, m := range loaded.Users {
+		if m == nil {
+			loaded.Users[uid] = map[model.AttachmentID]model.Attachment{}
+		}
+	}
+	s.s = loaded
+	return nil
+}
+
+func (s *fileStore) saveLocked() error {
+	b, err := json.MarshalIndent(s.s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o644)
+}
+
+func (r *FileRepo) ForUser(userID string) *FileRepo {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		userID = "default"
+	}
+	return &FileRepo{
+		store:  r.store,
+		userID: userID,
+	}
+}
+
+func (r *FileRepo) userMapLocked() map[model.AttachmentID]model.Attachment {
+	m, ok := r.store.s.Users[r.userID]
+	if !ok || m == nil {
+		m = map[model.AttachmentID]model.Attachment{}
+		r.store.s.Users[r.userID] = m
+	}
+	return m
+}
+
+// filePath is where an attachment's body is stored. User IDs and
+// attachment IDs are generated, so neither can climb out of dir.
+func (r *FileRepo) filePath(id model.AttachmentID) string {
+	return filepath.Join(r.store.dir, filepath.Base(r.userID), filepath.Base(string(id)))
+}
+
+// Add stores an attachment's file and records it against taskID.
+func (r *FileRepo) Add(taskID model.TaskID, a Attachment) (model.Attachment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	meta := model.Attachment{
+		ID:          model.AttachmentID(newID("att")),
+		TaskID:      taskID,
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		Size:        int64(len(a.Data)),
+		CreatedAt:   nowUTC(),
+	}
+	if meta.ContentType == "" {
+		meta.ContentType = "application/octet-stream"
+	}
+	path := r.filePath(meta.ID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return model.Attachment{}, err
+	}
+	if err := os.WriteFile(path, a.Data, 0o644); err != nil {
+		return model.Attachment{}, err
+	}
+	r.userMapLocked()[meta.ID] = meta
+	if err := r.store.saveLocked(); err != nil {
+		_ = os.Remove(path)
+		return model.Attachment{}, err
+	}
+	return meta, nil
+}
+
+// Get returns an attachment and the path of its file.
+func (r *FileRepo) Get(id model.AttachmentID) (model.Attachment, string, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	a, ok := r.store.s.Users[r.userID][id]
+	if !ok {
+		return model.Attachment{}, "", ErrNotFound
+	}
+	return a, r.filePath(id), nil
+}
+
+// List returns a task's attachments, or every attachment when taskID is
+// empty, oldest first.
+func (r *FileRepo) List(taskID model.TaskID) ([]model.Attachment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	out := make([]model.Attachment, 0)
+	for _, a := range r.store.s.Users[r.userID] {
+		if taskID == "" || a.TaskID == taskID {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func newID(prefix string) string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return prefix + "_" + hex.EncodeToString(b[:])
+}
+
+func nowUTC() time.Time {
+	return time.Now().UTC()
+}
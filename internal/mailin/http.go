@@ -0,0 +1,101 @@
+package mailin
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"donegeon/internal/model"
+)
+
+type Handler struct {
+	repo         *FileRepo
+	repoResolver func(*http.Request) *FileRepo
+}
+
+func NewHandler(repo *FileRepo) *Handler {
+	return &Handler{repo: repo}
+}
+
+func (h *Handler) SetRepoResolver(fn func(*http.Request) *FileRepo) {
+	h.repoResolver = fn
+}
+
+func (h *Handler) repoForRequest(r *http.Request) *FileRepo {
+	if h.repoResolver != nil {
+		if repo := h.repoResolver(r); repo != nil {
+			return repo
+		}
+	}
+	return h.repo
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeErr(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]any{"error": msg})
+}
+
+// GET /api/attachments?taskId=
+//
+// Lists attachments, all of them or one task's.
+func (h *Handler) Root(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	taskID := model.TaskID(strings.TrimSpace(r.URL.Query().Get("taskId")))
+	list, err := h.repoForRequest(r).List(taskID)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// GET /api/attachments/{id}
+//
+// Downloads an attachment. Files are always served as downloads so a
+// mailed HTML file cannot run in the app's origin.
+func (h *Handler) Sub(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/attachments/"), "/")
+	if id == "" || strings.Contains(id, "/") {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+	a, path, err := h.repoForRequest(r).Get(model.AttachmentID(id))
+	if errors.Is(err, ErrNotFound) {
+		writeErr(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		writeErr(w, http.StatusNotFound, ErrNotFound.Error())
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+	http.ServeContent(w, r, "", stat.ModTime(), f)
+}
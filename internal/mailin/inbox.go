@@ -0,0 +1,141 @@
+package mailin
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+	"donegeon/internal/webhook"
+)
+
+// maxDescriptionBytes trims long mail bodies; the start of a thread is
+// what matters for a task.
+const maxDescriptionBytes = 32 << 10
+
+// Inbox takes mail for a recipient token.
+type Inbox interface {
+	// Accepts reports whether mail to token is delivered anywhere, so
+	// unknown recipients are refused before the message is sent.
+	Accepts(token string) bool
+	Deliver(token string, msg Message) error
+}
+
+// TaskInbox turns each message into an inbox task for the user whose
+// capture token it was sent to, and keeps its attachments.
+type TaskInbox struct {
+	authenticate      func(token string) (userID string, ok bool)
+	repoResolver      func(userID string) task.Repo
+	publisherResolver func(userID string) webhook.Publisher
+	attachments       *FileRepo
+	logger            *log.Logger
+}
+
+func NewTaskInbox(attachments *FileRepo, authenticate func(token string) (string, bool), repoResolver func(userID string) task.Repo) *TaskInbox {
+	return &TaskInbox{
+		authenticate: authenticate,
+		repoResolver: repoResolver,
+		attachments:  attachments,
+		logger:       log.Default(),
+	}
+}
+
+func (in *TaskInbox) SetPublisherResolver(fn func(userID string) webhook.Publisher) {
+	in.publisherResolver = fn
+}
+
+func (in *TaskInbox) SetLogger(logger *log.Logger) {
+	in.logger = logger
+}
+
+func (in *TaskInbox) Accepts(token string) bool {
+	_, ok := in.authenticate(token)
+	return ok
+}
+
+// Deliver creates the task. Attachments that cannot be saved are logged
+// and skipped rather than failing the delivery, which would make the
+// sender retry and duplicate the task.
+func (in *TaskInbox) Deliver(token string, msg Message) error {
+	userID, ok := in.authenticate(token)
+	if !ok {
+		return fmt.Errorf("unknown recipient")
+	}
+	repo := in.repoResolver(userID)
+	if repo == nil {
+		return fmt.Errorf("no task repo for user %s", userID)
+	}
+
+	inbox := "inbox"
+	t := model.Task{
+		Title:       messageTitle(msg),
+		Description: messageDescription(msg),
+		Project:     &inbox,
+	}
+	created, err := repo.WithOrigin(task.SourceEmail, "").Create(t)
+	if err != nil {
+		return err
+	}
+
+	attachments := in.attachments.ForUser(userID)
+	for _, a := range msg.Attachments {
+		if _, err := attachments.Add(created.ID, a); err != nil && in.logger != nil {
+			in.logger.Printf("[mail] task %s: save attachment %q: %v", created.ID, a.Filename, err)
+		}
+	}
+	if in.publisherResolver != nil {
+		if p := in.publisherResolver(userID); p != nil {
+			p.Publish(webhook.EventTaskCreated, created)
+		}
+	}
+	return nil
+}
+
+// messageTitle is the subject without forwarding prefixes, falling back to
+// the first line of the body.
+func messageTitle(msg Message) string {
+	title := strings.Join(strings.Fields(msg.Subject), " ")
+	for {
+		lower := strings.ToLower(title)
+		cut := ""
+		for _, prefix := range []string{"fwd:", "fw:"} {
+			if strings.HasPrefix(lower, prefix) {
+				cut = prefix
+			}
+		}
+		if cut == "" {
+			break
+		}
+		title = strings.TrimSpace(title[len(cut):])
+	}
+	if title == "" {
+		first, _, _ := strings.Cut(msg.Body, "\n")
+		title = strings.Join(strings.Fields(first), " ")
+	}
+	if title == "" {
+		title = "(no subject)"
+	}
+	return title
+}
+
+// messageDescription is the body, trimmed to size, with the names of any
+// attachments listed at the end.
+func messageDescription(msg Message) string {
+	body := msg.Body
+	if len(body) > maxDescriptionBytes {
+		body = strings.ToValidUTF8(body[:maxDescriptionBytes], "") + "\n…"
+	}
+	if len(msg.Attachments) == 0 {
+		return body
+	}
+	names := make([]string, 0, len(msg.Attachments))
+	for _, a := range msg.Attachments {
+		names = append(names, a.Filename)
+	}
+	list := "Attachments: " + strings.Join(names, ", ")
+	if body == "" {
+		return list
+	}
+	return body + "\n\n" + list
+}
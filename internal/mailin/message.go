@@ -0,0 +1,215 @@
+package mailin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxParts bounds how many MIME parts one message may have.
+const maxParts = 100
+
+// Message is the part of an email that becomes a task.
+type Message struct {
+	From        string
+	Subject     string
+	Body        string // plain text; HTML-only mail is reduced to text
+	Attachments []Attachment
+}
+
+// Attachment is a file that came with a message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+var errTooManyParts = errors.New("too many MIME parts")
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// charsetReader is asked for charsets other than UTF-8, ISO-8859-1 and
+// US-ASCII; those are read as bytes, which keeps ASCII intact.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	return input, nil
+}
+
+// ParseMessage reads an RFC 5322 message with its MIME parts.
+func ParseMessage(raw []byte) (Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return Message{}, err
+	}
+	var out Message
+	if subject, err := wordDecoder.DecodeHeader(m.Header.Get("Subject")); err == nil {
+		out.Subject = subject
+	} else {
+		out.Subject = m.Header.Get("Subject")
+	}
+	if from, err := m.Header.AddressList("From"); err == nil && len(from) > 0 {
+		out.From = from[0].Address
+	}
+
+	var plain, htmlBody string
+	parts := 0
+	var walk func(header mailHeader, body io.Reader) error
+	walk = func(header mailHeader, body io.Reader) error {
+		parts++
+		if parts > maxParts {
+			return errTooManyParts
+		}
+		mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+		if err != nil {
+			mediaType, params = "text/plain", map[string]string{}
+		}
+		if strings.HasPrefix(mediaType, "multipart/") {
+			mr := multipart.NewReader(body, params["boundary"])
+			for {
+				p, err := mr.NextPart()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if err := walk(p.Header, p); err != nil {
+					return err
+				}
+			}
+		}
+
+		data, err := io.ReadAll(decodeTransfer(header.Get("Content-Transfer-Encoding"), body))
+		if err != nil {
+			return err
+		}
+		if name := attachmentName(header, params); name != "" {
+			out.Attachments = append(out.Attachments, Attachment{Filename: name, ContentType: mediaType, Data: data})
+			return nil
+		}
+		switch mediaType {
+		case "text/plain":
+			if plain == "" {
+				plain = string(data)
+			}
+		case "text/html":
+			if htmlBody == "" {
+				htmlBody = string(data)
+			}
+		}
+		return nil
+	}
+	if err := walk(mailHeader(m.Header), m.Body); err != nil {
+		return Message{}, err
+	}
+
+	out.Body = plain
+	if strings.TrimSpace(out.Body) == "" && htmlBody != "" {
+		out.Body = htmlToText(htmlBody)
+	}
+	out.Body = strings.TrimSpace(strings.ReplaceAll(out.Body, "\r\n", "\n"))
+	return out, nil
+}
+
+// mailHeader is what both mail.Header and textproto.MIMEHeader offer.
+type mailHeader interface {
+	Get(key string) string
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, newlineStripper{body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// newlineStripper drops the line breaks base64 bodies are wrapped with.
+type newlineStripper struct{ r io.Reader }
+
+func (n newlineStripper) Read(p []byte) (int, error) {
+	for {
+		c, err := n.r.Read(p)
+		k := 0
+		for _, b := range p[:c] {
+			if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+				p[k] = b
+				k++
+			}
+		}
+		if k > 0 || err != nil {
+			return k, err
+		}
+	}
+}
+
+// attachmentName is the part's file name if it is an attachment: marked
+// as one or carrying a file name.
+func attachmentName(header mailHeader, ctParams map[string]string) string {
+	disposition, dParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	name := dParams["filename"]
+	if name == "" {
+		name = ctParams["name"]
+	}
+	if decoded, err := wordDecoder.DecodeHeader(name); err == nil {
+		name = decoded
+	}
+	name = safeFilename(name)
+	if disposition == "attachment" && name == "" {
+		name = "attachment"
+	}
+	if disposition == "inline" && ctParams["name"] == "" && dParams["filename"] == "" {
+		return ""
+	}
+	return name
+}
+
+// safeFilename keeps the base name and drops characters that are awkward
+// in paths and headers.
+func safeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == '"' || r == '/' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == ".." {
+		return ""
+	}
+	if len(name) > 200 {
+		name = name[len(name)-200:]
+	}
+	return name
+}
+
+var (
+	htmlBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>|</tr>|</h[1-6]>`)
+	htmlDrop   = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)>`)
+	htmlTags   = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLines = regexp.MustCompile(`\n[ \t]*\n(\s*\n)+`)
+)
+
+// htmlToText is a rough text version of an HTML body, enough for a task
+// description.
+func htmlToText(s string) string {
+	s = htmlDrop.ReplaceAllString(s, "")
+	s = htmlBreaks.ReplaceAllString(s, "\n")
+	s = htmlTags.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	return blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
@@ -0,0 +1,348 @@
+// Package mailin is a small inbound SMTP server that turns mail sent to
+// <capture-token>@<domain> into inbox tasks. It speaks just enough SMTP
+// for a forwarding MTA or a local client: no TLS, no AUTH, no relaying.
+// The token in the address is the credential, and the sender allowlist
+// narrows who can use it.
+package mailin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxBytes      = 10 << 20
+	defaultMaxRecipients = 20
+	commandTimeout       = 5 * time.Minute
+	maxLineBytes         = 4096
+)
+
+// Config is the listener's settings.
+type Config struct {
+	Addr   string // e.g. ":2525"; empty turns the listener off
+	Domain string // accepted recipient domain; empty accepts any
+	// Allow lists the sender addresses ("me@example.com") and domains
+	// ("example.com" or "@example.com") mail is taken from. Empty allows
+	// any sender.
+	Allow    []string
+	MaxBytes int64 // largest message accepted
+}
+
+// ConfigFromEnv reads DONEGEON_SMTP_ADDR, DONEGEON_SMTP_DOMAIN,
+// DONEGEON_SMTP_ALLOW (comma-separated) and DONEGEON_SMTP_MAX_BYTES. ok is
+// false when no address is set, which leaves the listener off.
+func ConfigFromEnv() (cfg Config, ok bool) {
+	cfg.Addr = strings.TrimSpace(os.Getenv("DONEGEON_SMTP_ADDR"))
+	cfg.Domain = strings.TrimSpace(os.Getenv("DONEGEON_SMTP_DOMAIN"))
+	for _, a := range strings.Split(os.Getenv("DONEGEON_SMTP_ALLOW"), ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			cfg.Allow = append(cfg.Allow, a)
+		}
+	}
+	if n, err := strconv.ParseInt(strings.TrimSpace(os.Getenv("DONEGEON_SMTP_MAX_BYTES")), 10, 64); err == nil && n > 0 {
+		cfg.MaxBytes = n
+	}
+	return cfg, cfg.Addr != ""
+}
+
+// Server is the SMTP listener. Set its Inbox before serving.
+type Server struct {
+	cfg    Config
+	inbox  Inbox
+	logger *log.Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func NewServer(cfg Config, logger *log.Logger) *Server {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultMaxBytes
+	}
+	cfg.Domain = strings.TrimSpace(cfg.Domain)
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Server{cfg: cfg, logger: logger}
+}
+
+func (s *Server) SetInbox(inbox Inbox) {
+	s.inbox = inbox
+}
+
+// Addr is the address being listened on, once serving.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln until Close. It returns nil after Close.
+func (s *Server) Serve(ln net.Listener) error {
+	if s.inbox == nil {
+		return errors.New("mailin: no inbox set")
+	}
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// Close stops accepting and waits for open sessions to finish.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	ln := s.listener
+	s.mu.Unlock()
+	var err error
+	if ln != nil {
+		err = ln.Close()
+	}
+	s.wg.Wait()
+	return err
+}
+
+// session is one SMTP conversation's envelope state.
+type session struct {
+	from       string
+	recipients []string // tokens
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	// Each command may read at most maxLineBytes (and DATA the message
+	// limit) so a client cannot make a line grow without bound.
+	limited := &io.LimitedReader{R: conn}
+	tp := &textproto.Conn{
+		Reader: *textproto.NewReader(bufio.NewReader(limited)),
+		Writer: *textproto.NewWriter(bufio.NewWriter(conn)),
+	}
+	host := s.cfg.Domain
+	if host == "" {
+		host = "localhost"
+	}
+	reply := func(code int, msg string) bool {
+		_ = conn.SetWriteDeadline(time.Now().Add(commandTimeout))
+		return tp.PrintfLine("%d %s", code, msg) == nil
+	}
+
+	var sess session
+	if !reply(220, host+" Donegeon ESMTP ready") {
+		return
+	}
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(commandTimeout))
+		limited.N = maxLineBytes
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			sess = session{}
+			reply(250, host)
+		case "EHLO":
+			sess = session{}
+			_ = tp.PrintfLine("250-%s", host)
+			_ = tp.PrintfLine("250-SIZE %d", s.cfg.MaxBytes)
+			_ = tp.PrintfLine("250-8BITMIME")
+			reply(250, "SMTPUTF8")
+		case "MAIL":
+			addr, params, ok := pathArg(arg, "FROM:")
+			switch {
+			case !ok:
+				reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+			case sess.from != "":
+				reply(503, "5.5.1 Sender already given")
+			case tooBig(params, s.cfg.MaxBytes):
+				reply(552, "5.3.4 Message too big")
+			case !s.allowed(addr):
+				s.logger.Printf("[mail] rejected sender %q", addr)
+				reply(550, "5.7.1 Sender not allowed")
+			default:
+				sess.from = addr
+				if sess.from == "" {
+					sess.from = "<>"
+				}
+				reply(250, "2.1.0 OK")
+			}
+		case "RCPT":
+			addr, _, ok := pathArg(arg, "TO:")
+			token, okDomain := s.recipientToken(addr)
+			switch {
+			case !ok:
+				reply(501, "5.5.4 Syntax: RCPT TO:<address>")
+			case sess.from == "":
+				reply(503, "5.5.1 Need MAIL first")
+			case len(sess.recipients) >= defaultMaxRecipients:
+				reply(452, "4.5.3 Too many recipients")
+			case !okDomain || !s.inbox.Accepts(token):
+				reply(550, "5.1.1 No such mailbox")
+			default:
+				sess.recipients = append(sess.recipients, token)
+				reply(250, "2.1.5 OK")
+			}
+		case "DATA":
+			if len(sess.recipients) == 0 {
+				reply(503, "5.5.1 Need RCPT first")
+				continue
+			}
+			if !reply(354, "End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			_ = conn.SetReadDeadline(time.Now().Add(commandTimeout))
+			limited.N = 2*s.cfg.MaxBytes + maxLineBytes
+			raw, err := io.ReadAll(io.LimitReader(tp.DotReader(), s.cfg.MaxBytes+1))
+			if err != nil {
+				return
+			}
+			if int64(len(raw)) > s.cfg.MaxBytes {
+				// Drain the rest so the reply lines up with the client.
+				_, _ = io.Copy(io.Discard, tp.DotReader())
+				reply(552, "5.3.4 Message too big")
+			} else {
+				code, msg := s.deliver(sess, raw)
+				reply(code, msg)
+			}
+			sess = session{}
+		case "RSET":
+			sess = session{}
+			reply(250, "2.0.0 OK")
+		case "NOOP":
+			reply(250, "2.0.0 OK")
+		case "VRFY":
+			reply(252, "2.5.0 Cannot verify")
+		case "QUIT":
+			reply(221, "2.0.0 Bye")
+			return
+		default:
+			reply(502, "5.5.2 Command not implemented")
+		}
+	}
+}
+
+// deliver parses the message and hands it to the inbox once per
+// recipient. It succeeds if any recipient took it.
+func (s *Server) deliver(sess session, raw []byte) (int, string) {
+	msg, err := ParseMessage(raw)
+	if err != nil {
+		return 554, "5.6.0 Could not parse message"
+	}
+	if msg.From == "" && sess.from != "<>" {
+		msg.From = sess.from
+	}
+	delivered := 0
+	for _, token := range sess.recipients {
+		if err := s.inbox.Deliver(token, msg); err != nil {
+			s.logger.Printf("[mail] deliver from %s: %v", sess.from, err)
+			continue
+		}
+		delivered++
+	}
+	if delivered == 0 {
+		return 451, "4.3.0 Could not deliver, try again later"
+	}
+	return 250, fmt.Sprintf("2.0.0 Queued as %d task(s)", delivered)
+}
+
+// pathArg reads "FROM:<addr> PARAMS" (or TO:), tolerating a space after
+// the colon and a missing pair of angle brackets.
+func pathArg(arg, prefix string) (addr string, params []string, ok bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	addr = strings.TrimSuffix(strings.TrimPrefix(fields[0], "<"), ">")
+	return addr, fields[1:], true
+}
+
+// tooBig reports whether a MAIL SIZE= parameter is over the limit.
+func tooBig(params []string, max int64) bool {
+	for _, p := range params {
+		k, v, _ := strings.Cut(p, "=")
+		if strings.EqualFold(k, "SIZE") {
+			n, err := strconv.ParseInt(v, 10, 64)
+			return err == nil && n > max
+		}
+	}
+	return false
+}
+
+// allowed reports whether mail from addr is accepted.
+func (s *Server) allowed(addr string) bool {
+	if len(s.cfg.Allow) == 0 {
+		return true
+	}
+	addr = strings.ToLower(addr)
+	_, domain, _ := strings.Cut(addr, "@")
+	for _, a := range s.cfg.Allow {
+		a = strings.ToLower(strings.TrimSpace(a))
+		switch {
+		case strings.Contains(strings.TrimPrefix(a, "@"), "@"):
+			if a == addr {
+				return true
+			}
+		case domain != "" && strings.TrimPrefix(a, "@") == domain:
+			return true
+		}
+	}
+	return false
+}
+
+// recipientToken is the token in token@domain. Tokens are case-sensitive,
+// so the local part is kept as sent.
+func (s *Server) recipientToken(addr string) (string, bool) {
+	at := strings.LastIndex(addr, "@")
+	if at <= 0 {
+		return "", false
+	}
+	if s.cfg.Domain != "" && !strings.EqualFold(addr[at+1:], s.cfg.Domain) {
+		return "", false
+	}
+	return addr[:at], true
+}
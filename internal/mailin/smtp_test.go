@@ -0,0 +1,120 @@
+package mailin
+
+import (
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"donegeon/internal/task"
+)
+
+const testToken = "tok-Secret_1"
+
+func startServer(t *testing.T, cfg Config) (string, *task.MemoryRepo, *FileRepo) {
+	t.Helper()
+	taskRepo := task.NewMemoryRepo()
+	attachments, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	inbox := NewTaskInbox(attachments,
+		func(token string) (string, bool) { return "u-mail", token == testToken },
+		func(string) task.Repo { return taskRepo })
+	inbox.SetLogger(log.New(io.Discard, "", 0))
+
+	srv := NewServer(cfg, log.New(io.Discard, "", 0))
+	srv.SetInbox(inbox)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return ln.Addr().String(), taskRepo, attachments.ForUser("u-mail")
+}
+
+const forwarded = "From: Me <me@example.com>\r\n" +
+	"To: " + testToken + "@tasks.example.com\r\n" +
+	"Subject: =?UTF-8?Q?Fwd:_Invoice_=E2=82=AC42?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=XYZ\r\n" +
+	"\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Please pay by Friday.\r\n" +
+	".leading dot survives\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: application/pdf; name=\"invoice.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"../invoice.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0x\r\nLjQK\r\n" +
+	"--XYZ--\r\n"
+
+func TestSMTP_ForwardedMailBecomesTaskWithAttachment(t *testing.T) {
+	addr, taskRepo, attachments := startServer(t, Config{Domain: "tasks.example.com", Allow: []string{"example.com"}})
+
+	if err := smtp.SendMail(addr, nil, "me@example.com", []string{testToken + "@tasks.example.com"}, []byte(forwarded)); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	tasks, _ := taskRepo.List(task.ListFilter{})
+	if len(tasks) != 1 {
+		t.Fatalf("expected one task, got %d", len(tasks))
+	}
+	got := tasks[0]
+	if got.Title != "Invoice €42" || *got.Project != "inbox" {
+		t.Fatalf("unexpected task %q in %v", got.Title, *got.Project)
+	}
+	want := "Please pay by Friday.\n.leading dot survives\n\nAttachments: invoice.pdf"
+	if got.Description != want {
+		t.Fatalf("unexpected description %q", got.Description)
+	}
+	history, _ := taskRepo.History(got.ID)
+	if len(history) == 0 || history[0].Source != task.SourceEmail {
+		t.Fatalf("expected email recorded as the source, got %+v", history)
+	}
+
+	list, _ := attachments.List(got.ID)
+	if len(list) != 1 || list[0].Filename != "invoice.pdf" || list[0].ContentType != "application/pdf" {
+		t.Fatalf("unexpected attachments %+v", list)
+	}
+	h := NewHandler(attachments)
+	rec := httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodGet, "/api/attachments/"+string(list[0].ID), nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "%PDF-1.4\n" ||
+		!strings.HasPrefix(rec.Header().Get("Content-Disposition"), "attachment") {
+		t.Fatalf("unexpected download %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
+
+func TestSMTP_RejectsUnknownSendersAndRecipients(t *testing.T) {
+	addr, taskRepo, _ := startServer(t, Config{Domain: "tasks.example.com", Allow: []string{"me@example.com"}})
+	msg := []byte("Subject: hi\r\n\r\nbody\r\n")
+
+	cases := []struct {
+		from, to string
+	}{
+		{"stranger@example.com", testToken + "@tasks.example.com"},
+		{"me@example.com", "wrong-token@tasks.example.com"},
+		{"me@example.com", testToken + "@elsewhere.example.com"},
+	}
+	for _, c := range cases {
+		err := smtp.SendMail(addr, nil, c.from, []string{c.to}, msg)
+		if err == nil || !strings.HasPrefix(err.Error(), "550") {
+			t.Fatalf("expected 550 for %s -> %s, got %v", c.from, c.to, err)
+		}
+	}
+	if tasks, _ := taskRepo.List(task.ListFilter{}); len(tasks) != 0 {
+		t.Fatalf("expected no tasks, got %d", len(tasks))
+	}
+
+	if err := smtp.SendMail(addr, nil, "ME@example.com", []string{testToken + "@TASKS.example.com"}, msg); err != nil {
+		t.Fatalf("expected allowed sender to deliver: %v", err)
+	}
+}
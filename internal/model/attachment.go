@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type AttachmentID string
+
+// Attachment is a file kept with a task, such as one that came with a
+// forwarded email. The file itself is stored outside the JSON state.
+type Attachment struct {
+	ID          AttachmentID `json:"id"`
+	TaskID      TaskID       `json:"taskId"`
+	Filename    string       `json:"filename"`
+	ContentType string       `json:"contentType"`
+	Size        int64        `json:"size"`
+	CreatedAt   time.Time    `json:"createdAt"`
+}
@@ -10,13 +10,63 @@ type Blueprint struct {
 	Description   string      `json:"description"`
 	ModifierSlots []string    `json:"modifierSlots,omitempty"`
 	Steps         []string    `json:"steps,omitempty"`
-	CreatedAt     time.Time   `json:"createdAt"`
-	UpdatedAt     time.Time   `json:"updatedAt"`
+	// Variables are filled in at instantiation and substituted wherever
+	// title, description, steps or due say {{name}}.
+	Variables []BlueprintVariable `json:"variables,omitempty"`
+	// Due sets the top task's due date: YYYY-MM-DD, an offset from the day
+	// of instantiation ("+3d", "+2w") or a {{variable}}.
+	Due       string    `json:"due,omitempty"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// BlueprintVersion is a snapshot of a blueprint as it was before an edit.
+type BlueprintVersion struct {
+	Version       int                 `json:"version"`
+	Title         string              `json:"title"`
+	Description   string              `json:"description"`
+	ModifierSlots []string            `json:"modifierSlots,omitempty"`
+	Steps         []string            `json:"steps,omitempty"`
+	Variables     []BlueprintVariable `json:"variables,omitempty"`
+	Due           string              `json:"due,omitempty"`
+	SavedAt       time.Time           `json:"savedAt"`
+}
+
+// BlueprintLibraryEntry is a blueprint shared into a team's library. The
+// team is identified by its owner's user ID.
+type BlueprintLibraryEntry struct {
+	ID          string    `json:"id"`
+	TeamOwnerID string    `json:"teamOwnerId"`
+	SharedBy    string    `json:"sharedBy"`
+	Blueprint   Blueprint `json:"blueprint"`
+	SharedAt    time.Time `json:"sharedAt"`
+}
+
+// Blueprint variable types.
+const (
+	BlueprintVarString = "string"
+	BlueprintVarDate   = "date"   // YYYY-MM-DD
+	BlueprintVarOffset = "offset" // "+3d" / "+2w", substituted as a date
+	BlueprintVarChoice = "choice" // one of Choices
+)
+
+// BlueprintVariable is a placeholder a blueprint asks for when instantiated.
+type BlueprintVariable struct {
+	Name    string   `json:"name"`
+	Label   string   `json:"label,omitempty"`
+	Type    string   `json:"type"`
+	Choices []string `json:"choices,omitempty"`
+	// Default is used when no value is given; without one the value is
+	// required.
+	Default string `json:"default,omitempty"`
 }
 
 type BlueprintUpsert struct {
-	Title         string   `json:"title"`
-	Description   string   `json:"description"`
-	ModifierSlots []string `json:"modifierSlots,omitempty"`
-	Steps         []string `json:"steps,omitempty"`
+	Title         string              `json:"title"`
+	Description   string              `json:"description"`
+	ModifierSlots []string            `json:"modifierSlots,omitempty"`
+	Steps         []string            `json:"steps,omitempty"`
+	Variables     []BlueprintVariable `json:"variables,omitempty"`
+	Due           string              `json:"due,omitempty"`
 }
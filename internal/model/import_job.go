@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+type ImportJobID string
+
+// Import job statuses.
+const (
+	ImportJobPreview = "preview" // parsed, nothing written yet
+	ImportJobApplied = "applied"
+	ImportJobUndone  = "undone"
+)
+
+// ImportJob is one run of a task importer (Todoist, Taskwarrior, CSV, ...).
+// It is created as a preview and only writes tasks once applied.
+type ImportJob struct {
+	ID        ImportJobID `json:"id"`
+	Source    string      `json:"source"`
+	Status    string      `json:"status"`
+	Project   string      `json:"project,omitempty"` // for rows without one
+	Rows      []ImportRow `json:"rows"`
+	Creates   int         `json:"creates"`
+	Skipped   int         `json:"skipped"`
+	TaskIDs   []TaskID    `json:"taskIds,omitempty"` // tasks created on apply
+	CreatedAt time.Time   `json:"createdAt"`
+	AppliedAt *time.Time  `json:"appliedAt,omitempty"`
+	UndoneAt  *time.Time  `json:"undoneAt,omitempty"`
+}
+
+// ImportRow is the plan (and, once applied, the outcome) for one input row.
+type ImportRow struct {
+	Line     int      `json:"line"`
+	Action   string   `json:"action"`           // create | skip
+	Reason   string   `json:"reason,omitempty"` // why it was skipped
+	Warnings []string `json:"warnings,omitempty"`
+	Task     *Task    `json:"task,omitempty"` // the task to create
+	TaskID   TaskID   `json:"taskId,omitempty"`
+}
@@ -23,6 +23,7 @@ type PluginManifest struct {
 	CardIcon     string       `json:"cardIcon"`
 	InstallCost  int          `json:"installCost"`
 	Capabilities []string     `json:"capabilities,omitempty"`
+	Endpoint     string       `json:"endpoint,omitempty"` // called by plugin.Runtime; community plugins only
 	Source       PluginSource `json:"source"`
 	CreatedAt    time.Time    `json:"createdAt"`
 	UpdatedAt    time.Time    `json:"updatedAt"`
@@ -33,6 +34,9 @@ type InstalledPlugin struct {
 	Source      PluginSource `json:"source"`
 	InstalledAt time.Time    `json:"installedAt"`
 	Enabled     bool         `json:"enabled"`
+	// Secret signs the runtime's calls to this install; it is only shown
+	// when the plugin is installed.
+	Secret string `json:"secret,omitempty"`
 }
 
 type PluginMarketplaceItem struct {
@@ -45,3 +49,31 @@ type InstalledPluginDetail struct {
 	InstalledAt time.Time `json:"installedAt"`
 	Enabled     bool      `json:"enabled"`
 }
+
+type PluginRunID string
+
+// Plugin run statuses.
+const (
+	PluginRunPending   = "pending" // waiting for its first or next attempt
+	PluginRunSucceeded = "succeeded"
+	PluginRunFailed    = "failed" // out of attempts, or rejected
+)
+
+// PluginRun is one call of an installed plugin for a task, with its
+// attempts so far and what was done with the plugin's answer.
+type PluginRun struct {
+	ID            PluginRunID `json:"id"`
+	PluginID      PluginID    `json:"pluginId"`
+	TaskID        TaskID      `json:"taskId"`
+	Trigger       string      `json:"trigger"`
+	Payload       string      `json:"payload"`
+	Status        string      `json:"status"`
+	Attempts      int         `json:"attempts"`
+	ResponseCode  int         `json:"responseCode,omitempty"`
+	Error         string      `json:"error,omitempty"`
+	Applied       []string    `json:"applied,omitempty"` // results written to the task
+	Skipped       []string    `json:"skipped,omitempty"` // results outside the plugin's capabilities
+	CreatedAt     time.Time   `json:"createdAt"`
+	LastAttemptAt *time.Time  `json:"lastAttemptAt,omitempty"`
+	NextAttemptAt *time.Time  `json:"nextAttemptAt,omitempty"`
+}
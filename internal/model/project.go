@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+type ProjectID string
+
+// Project statuses.
+const (
+	ProjectStatusActive   = "active"
+	ProjectStatusOnHold   = "on_hold"
+	ProjectStatusDone     = "done"
+	ProjectStatusArchived = "archived"
+)
+
+// Project groups tasks. Tasks still refer to their project by name
+// (Task.Project), so renaming a project rewrites its tasks.
+type Project struct {
+	ID          ProjectID  `json:"id"`
+	Name        string     `json:"name"`
+	Color       string     `json:"color,omitempty"` // e.g. "#4f7cac"
+	Icon        string     `json:"icon,omitempty"`  // short emoji or icon key
+	Description string     `json:"description,omitempty"`
+	Status      string     `json:"status"`
+	ParentID    *ProjectID `json:"parentId,omitempty"`
+	SortOrder   int        `json:"sortOrder"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+type ProjectUpsert struct {
+	Name        string     `json:"name"`
+	Color       string     `json:"color,omitempty"`
+	Icon        string     `json:"icon,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	ParentID    *ProjectID `json:"parentId,omitempty"`
+	SortOrder   int        `json:"sortOrder"`
+}
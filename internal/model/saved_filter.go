@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+type SavedFilterID string
+
+// FilterQuery is the stored form of a task list filter.
+type FilterQuery struct {
+	Status   string   `json:"status,omitempty"`  // e.g. "due_today,overdue"
+	Project  string   `json:"project,omitempty"` // "inbox" | "projects" | "<name>"
+	Tags     []string `json:"tags,omitempty"`    // all must match
+	Live     *bool    `json:"live,omitempty"`
+	Deferred string   `json:"deferred,omitempty"`
+}
+
+// SavedFilter is a named, reusable task view ("smart list").
+type SavedFilter struct {
+	ID        SavedFilterID `json:"id"`
+	Name      string        `json:"name"`
+	Icon      string        `json:"icon,omitempty"`
+	Query     FilterQuery   `json:"query"`
+	Sort      string        `json:"sort,omitempty"`    // due | updated | created | title
+	GroupBy   string        `json:"groupBy,omitempty"` // "" | project | tag | status
+	ShowCount bool          `json:"showCount"`         // sidebar count badge
+	Shared    bool          `json:"shared"`            // visible to the owner's team
+	SortOrder int           `json:"sortOrder"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+type SavedFilterUpsert struct {
+	Name      string      `json:"name"`
+	Icon      string      `json:"icon,omitempty"`
+	Query     FilterQuery `json:"query"`
+	Sort      string      `json:"sort,omitempty"`
+	GroupBy   string      `json:"groupBy,omitempty"`
+	ShowCount bool        `json:"showCount"`
+	Shared    bool        `json:"shared"`
+	SortOrder int         `json:"sortOrder"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Tag holds per-user metadata for a tag name. Tasks carry tags as plain
+// strings (Task.Tags); a Tag record only exists once a tag has a color.
+type Tag struct {
+	Name      string    `json:"name"`
+	Color     string    `json:"color,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TagSummary is a tag as listed by /api/tags.
+type TagSummary struct {
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+	Count int    `json:"count"` // tasks carrying the tag
+	Open  int    `json:"open"`  // of which not done
+}
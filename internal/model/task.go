@@ -6,9 +6,27 @@ import (
 
 type TaskID string
 
+// Recurrence modes.
+const (
+	RecurrenceModeSchedule        = "schedule"         // repeat on the rule's calendar
+	RecurrenceModeAfterCompletion = "after_completion" // repeat N units after the last completion
+)
+
+// Recurrence is an RRULE subset (FREQ, INTERVAL, BYDAY, BYMONTHDAY, BYSETPOS,
+// COUNT, UNTIL) plus a completion-based mode.
 type Recurrence struct {
-	Type     string `json:"type"`
-	Interval int    `json:"interval"`
+	Type       string   `json:"type"`
+	Interval   int      `json:"interval"`
+	Mode       string   `json:"mode,omitempty"`
+	ByDay      []string `json:"byDay,omitempty"`      // e.g. "TU", "-1FR", "2MO"
+	ByMonthDay []int    `json:"byMonthDay,omitempty"` // 1..31 or -31..-1
+	BySetPos   []int    `json:"bySetPos,omitempty"`
+	Count      int      `json:"count,omitempty"`
+	Until      *string  `json:"until,omitempty"` // YYYY-MM-DD, inclusive
+
+	// Occurrences counts how many times the task has been respawned; COUNT is
+	// enforced against it.
+	Occurrences int `json:"occurrences,omitempty"`
 }
 
 type Task struct {
@@ -19,9 +37,13 @@ type Task struct {
 	Project     *string  `json:"project,omitempty"`
 	Tags        []string `json:"tags,omitempty"`
 	Live        bool     `json:"live,omitempty"`
+	Archived    bool     `json:"archived,omitempty"` // hidden from listings unless asked for
+	ParentID    *TaskID  `json:"parentId,omitempty"` // set on the step tasks of a blueprint
 
 	Modifiers          []TaskModifierSlot `json:"modifiers,omitempty"`
 	DueDate            *string            `json:"dueDate,omitempty"`
+	DueTime            *string            `json:"dueTime,omitempty"`   // HH:MM in the owner's timezone
+	StartDate          *string            `json:"startDate,omitempty"` // hidden from the inbox until this day
 	NextAction         bool               `json:"nextAction"`
 	Recurrence         *Recurrence        `json:"recurrence,omitempty"`
 	AssignedVillagerID *string            `json:"assignedVillagerId,omitempty"`
@@ -33,6 +55,21 @@ type Task struct {
 	HabitStreak        int                `json:"habitStreak,omitempty"`
 	LastCompletedDate  *string            `json:"lastCompletedDate,omitempty"`
 
+	// EstimateMinutes is the user's guess; TrackedSeconds is the total of the
+	// task's time entries, kept in sync by the time tracker.
+	EstimateMinutes int   `json:"estimateMinutes,omitempty"`
+	TrackedSeconds  int64 `json:"trackedSeconds,omitempty"`
+
+	// ExternalRefs maps an import source (e.g. "ics") to the task's ID
+	// there, so re-importing updates instead of duplicating.
+	ExternalRefs map[string]string `json:"externalRefs,omitempty"`
+
+	// Revision increases on every write; it backs the task ETag.
+	Revision int64 `json:"revision"`
+
+	// CommentCount is computed on read.
+	CommentCount int `json:"commentCount,omitempty"`
+
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
@@ -50,6 +87,39 @@ type TaskUpsert struct {
 	Tags        []string           `json:"tags,omitempty"`
 	Modifiers   []TaskModifierSlot `json:"modifiers,omitempty"`
 	DueDate     *string            `json:"dueDate,omitempty"`
+	DueTime     *string            `json:"dueTime,omitempty"`
+	StartDate   *string            `json:"startDate,omitempty"`
 	NextAction  bool               `json:"nextAction"`
 	Recurrence  *Recurrence        `json:"recurrence,omitempty"`
+
+	EstimateMinutes int `json:"estimateMinutes,omitempty"`
+}
+
+// TaskActivity is one append-only entry in a task's history.
+type TaskActivity struct {
+	ID      string            `json:"id"`
+	TaskID  TaskID            `json:"taskId"`
+	At      time.Time         `json:"at"`
+	Actor   string            `json:"actor,omitempty"`
+	Source  string            `json:"source"` // api | board | day_tick | plugin
+	Action  string            `json:"action"` // created | updated | completed | reopened | recurred | processed
+	Changes []TaskFieldChange `json:"changes,omitempty"`
+}
+
+type TaskFieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from,omitempty"`
+	To    any    `json:"to,omitempty"`
+}
+
+// TaskComment is a Markdown comment in a task's thread.
+type TaskComment struct {
+	ID         string    `json:"id"`
+	TaskID     TaskID    `json:"taskId"`
+	AuthorID   string    `json:"authorId"`
+	AuthorName string    `json:"authorName,omitempty"`
+	Body       string    `json:"body"` // Markdown
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	Edited     bool      `json:"edited,omitempty"`
 }
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+type TimeEntryID string
+
+// Time entry sources.
+const (
+	TimeSourceTimer   = "timer"   // started and stopped with the timer
+	TimeSourceManual  = "manual"  // entered by hand
+	TimeSourceProcess = "process" // logged by /api/tasks/{id}/process
+)
+
+// TimeEntry is a span of work on a task. End is nil while the timer runs.
+type TimeEntry struct {
+	ID        TimeEntryID `json:"id"`
+	TaskID    TaskID      `json:"taskId"`
+	Start     time.Time   `json:"start"`
+	End       *time.Time  `json:"end,omitempty"`
+	Note      string      `json:"note,omitempty"`
+	Source    string      `json:"source"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+// Running reports whether the entry is an active timer.
+func (e TimeEntry) Running() bool {
+	return e.End == nil
+}
+
+// Seconds returns the entry length, measuring a running timer up to now.
+func (e TimeEntry) Seconds(now time.Time) int64 {
+	end := now
+	if e.End != nil {
+		end = *e.End
+	}
+	if !end.After(e.Start) {
+		return 0
+	}
+	return int64(end.Sub(e.Start) / time.Second)
+}
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+type WebhookID string
+
+// Webhook is a user's subscription to domain events. Events lists the event
+// names it receives; empty means all of them.
+type Webhook struct {
+	ID          WebhookID `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active"`
+	// Secret signs every payload; it is only shown when the webhook is
+	// created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending" // waiting for its first or next attempt
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed" // out of attempts
+)
+
+// WebhookDelivery is one event sent to one webhook, with its attempts so
+// far.
+type WebhookDelivery struct {
+	ID            string     `json:"id"`
+	WebhookID     WebhookID  `json:"webhookId"`
+	EventID       string     `json:"eventId"`
+	Event         string     `json:"event"`
+	Payload       string     `json:"payload"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	ResponseCode  int        `json:"responseCode,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	LastAttemptAt *time.Time `json:"lastAttemptAt,omitempty"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+}
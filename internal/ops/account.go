@@ -0,0 +1,57 @@
+package ops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"donegeon/internal/account"
+)
+
+// ExportAccount writes userID's account archive under dataDir to outPath.
+func ExportAccount(dataDir, userID, outPath string) error {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return fmt.Errorf("userID is required")
+	}
+	st, err := account.OpenStores(dataDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	if err := account.Export(f, st, userID, time.Now()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ImportAccount restores an account archive into userID under dataDir.
+// The account must be new or empty.
+//
+// Like restore, run it while the server is stopped: the server keeps its
+// own copy of every store in memory.
+func ImportAccount(dataDir, userID, archivePath string) (account.Summary, error) {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return account.Summary{}, fmt.Errorf("userID is required")
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return account.Summary{}, err
+	}
+	defer f.Close()
+	st, err := account.OpenStores(dataDir)
+	if err != nil {
+		return account.Summary{}, err
+	}
+	return account.Import(f, st, userID)
+}
@@ -0,0 +1,106 @@
+package ops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"donegeon/internal/blueprint"
+	"donegeon/internal/model"
+)
+
+// BlueprintLoadResult reports what one pack file added.
+type BlueprintLoadResult struct {
+	Path       string
+	Blueprints []model.Blueprint
+}
+
+// LoadBlueprintPacks reads blueprint files (or directories of .yaml, .yml and
+// .json files) and creates their blueprints for userID under dataDir. When
+// teamOwnerID is set each blueprint is also shared into that team's library.
+// Every file is decoded and validated before anything is written.
+//
+// Like restore, run it while the server is stopped: the server keeps its own
+// copy of the blueprint store in memory.
+func LoadBlueprintPacks(dataDir, userID, teamOwnerID string, paths []string) ([]BlueprintLoadResult, error) {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return nil, fmt.Errorf("userID is required")
+	}
+	files, err := blueprintPackFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no blueprint files found")
+	}
+
+	decoded := make([]BlueprintLoadResult, 0, len(files))
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		_, bs, err := blueprint.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		decoded = append(decoded, BlueprintLoadResult{Path: path, Blueprints: bs})
+	}
+
+	base, err := blueprint.NewFileRepo(filepath.Join(dataDir, "blueprints"))
+	if err != nil {
+		return nil, err
+	}
+	repo := base.ForUser(userID)
+	out := make([]BlueprintLoadResult, 0, len(decoded))
+	for _, pack := range decoded {
+		res := BlueprintLoadResult{Path: pack.Path}
+		for _, b := range pack.Blueprints {
+			created, err := repo.Create(b)
+			if err != nil {
+				return out, fmt.Errorf("%s: %w", pack.Path, err)
+			}
+			if teamOwnerID != "" {
+				if _, err := repo.ShareToLibrary(teamOwnerID, created); err != nil {
+					return out, fmt.Errorf("%s: %w", pack.Path, err)
+				}
+			}
+			res.Blueprints = append(res.Blueprints, created)
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+func blueprintPackFiles(paths []string) ([]string, error) {
+	var out []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			out = append(out, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		var found []string
+		for _, e := range entries {
+			switch strings.ToLower(filepath.Ext(e.Name())) {
+			case ".yaml", ".yml", ".json":
+				if !e.IsDir() {
+					found = append(found, filepath.Join(p, e.Name()))
+				}
+			}
+		}
+		sort.Strings(found)
+		out = append(out, found...)
+	}
+	return out, nil
+}
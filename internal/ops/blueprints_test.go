@@ -0,0 +1,70 @@
+package ops
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"donegeon/internal/blueprint"
+)
+
+func TestLoadBlueprintPacks_LoadsDirectoryAndSharesToTeam(t *testing.T) {
+	dataDir := t.TempDir()
+	packDir := t.TempDir()
+	writeFile(t, filepath.Join(packDir, "onboarding.yaml"), `version: 1
+name: Onboarding
+blueprints:
+  - title: Onboard {{name}}
+    steps: [Laptop, Accounts]
+    variables:
+      - name: name
+        type: string
+`)
+	writeFile(t, filepath.Join(packDir, "review.json"), `{"version":1,"blueprints":[{"title":"Weekly review"}]}`)
+	writeFile(t, filepath.Join(packDir, "notes.txt"), "ignored")
+
+	results, err := LoadBlueprintPacks(dataDir, "usr_1", "usr_1", []string{packDir})
+	if err != nil {
+		t.Fatalf("load packs: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 pack results, got %d", len(results))
+	}
+
+	repo, err := blueprint.NewFileRepo(filepath.Join(dataDir, "blueprints"))
+	if err != nil {
+		t.Fatalf("open blueprint repo: %v", err)
+	}
+	list, _ := repo.ForUser("usr_1").List()
+	if len(list) != 2 {
+		t.Fatalf("expected 2 blueprints, got %d", len(list))
+	}
+	lib, _ := repo.Library()
+	if len(lib) != 2 || lib[0].TeamOwnerID != "usr_1" {
+		t.Fatalf("expected 2 library entries for usr_1, got %+v", lib)
+	}
+}
+
+func TestLoadBlueprintPacks_InvalidFileWritesNothing(t *testing.T) {
+	dataDir := t.TempDir()
+	packDir := t.TempDir()
+	writeFile(t, filepath.Join(packDir, "a.yaml"), "version: 1\nblueprints:\n  - title: Fine\n")
+	writeFile(t, filepath.Join(packDir, "b.yaml"), "version: 1\nblueprints:\n  - title: Hi {{who}}\n")
+
+	if _, err := LoadBlueprintPacks(dataDir, "usr_1", "", []string{packDir}); err == nil {
+		t.Fatal("expected error for undeclared variable")
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "blueprints")); err == nil {
+		repo, _ := blueprint.NewFileRepo(filepath.Join(dataDir, "blueprints"))
+		if list, _ := repo.ForUser("usr_1").List(); len(list) != 0 {
+			t.Fatalf("expected nothing loaded, got %d", len(list))
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
@@ -133,10 +133,11 @@ func cloneTeamMembers(src []TeamMember) []TeamMember {
 	out := make([]TeamMember, 0, len(src))
 	for _, m := range src {
 		out = append(out, TeamMember{
-			Email:     m.Email,
-			Role:      m.Role,
-			Status:    m.Status,
-			InvitedAt: m.InvitedAt,
+			Email:      m.Email,
+			Role:       m.Role,
+			Status:     m.Status,
+			InvitedAt:  m.InvitedAt,
+			AcceptedAt: m.AcceptedAt,
 		})
 	}
 	return out
@@ -148,6 +149,7 @@ func cloneProfile(src PlayerProfile) PlayerProfile {
 		Avatar:                src.Avatar,
 		OnboardingCompleted:   src.OnboardingCompleted,
 		OnboardingCompletedAt: src.OnboardingCompletedAt,
+		Timezone:              src.Timezone,
 		Team: TeamProfile{
 			ID:      src.Team.ID,
 			Name:    src.Team.Name,
@@ -176,6 +178,20 @@ func (r *FileRepo) GetState() UserState {
 	return cloneUserState(us)
 }
 
+// ReplaceState overwrites the user's whole state, as account import does.
+// The team ID is re-derived when blank.
+func (r *FileRepo) ReplaceState(us UserState) (UserState, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	us = normalizeUserState(cloneUserState(us))
+	us.Profile = normalizeProfile(us.Profile, r.userID)
+	r.store.s.Users[r.userID] = us
+	if err := r.store.saveLocked(); err != nil {
+		return UserState{}, err
+	}
+	return cloneUserState(us), nil
+}
+
 func (r *FileRepo) AddLoot(kind string, amount int) (UserState, error) {
 	if amount <= 0 {
 		return r.GetState(), nil
@@ -702,6 +718,136 @@ func (r *FileRepo) UpdateTeam(teamName, teamAvatar string) (PlayerProfile, UserS
 	return cloneProfile(us.Profile), cloneUserState(us), nil
 }
 
+// UpdateProfile changes the fields that are set; nil leaves a field alone.
+func (r *FileRepo) UpdateProfile(displayName, avatar, timezone *string) (PlayerProfile, error) {
+	if timezone != nil {
+		tz, err := normalizeTimezone(*timezone)
+		if err != nil {
+			return PlayerProfile{}, err
+		}
+		timezone = &tz
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	us := r.userStateLocked()
+	if displayName != nil {
+		us.Profile.DisplayName = normalizeDisplayName(*displayName)
+	}
+	if avatar != nil {
+		us.Profile.Avatar = normalizeAvatar(*avatar)
+	}
+	if timezone != nil {
+		us.Profile.Timezone = *timezone
+	}
+	us.Profile = normalizeProfile(us.Profile, r.userID)
+	r.store.s.Users[r.userID] = us
+	if err := r.store.saveLocked(); err != nil {
+		return PlayerProfile{}, err
+	}
+	return cloneProfile(us.Profile), nil
+}
+
+// Location returns the user's configured time zone, falling back to the
+// server's local zone. It is safe to call on a nil repo.
+func (r *FileRepo) Location() *time.Location {
+	if r == nil {
+		return time.Local
+	}
+	tz := r.GetProfile().Timezone
+	if tz == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+func normalizeTimezone(tz string) (string, error) {
+	tz = strings.TrimSpace(tz)
+	if tz == "" {
+		return "", nil
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return "", ErrInvalidTimezone
+	}
+	return tz, nil
+}
+
+// HasActiveTeamMember reports whether email is on this user's team and has
+// accepted the invite. Only active members can see the owner's shared data.
+func (r *FileRepo) HasActiveTeamMember(email string) bool {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return false
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	us := r.userStateLocked()
+	for _, m := range us.Profile.Team.Members {
+		if strings.EqualFold(strings.TrimSpace(m.Email), email) {
+			return m.Status == TeamMemberActive
+		}
+	}
+	return false
+}
+
+// TeamInvitations lists the teams that have invited email and are waiting
+// for an answer.
+func (r *FileRepo) TeamInvitations(email string) []TeamInvitation {
+	email = strings.TrimSpace(email)
+	out := make([]TeamInvitation, 0)
+	if email == "" {
+		return out
+	}
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for ownerID, us := range r.store.s.Users {
+		if ownerID == r.userID {
+			continue
+		}
+		for _, m := range us.Profile.Team.Members {
+			if strings.EqualFold(strings.TrimSpace(m.Email), email) && m.Status != TeamMemberActive {
+				out = append(out, TeamInvitation{
+					OwnerID:   ownerID,
+					TeamName:  us.Profile.Team.Name,
+					InvitedAt: m.InvitedAt,
+				})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].InvitedAt.Before(out[j].InvitedAt) })
+	return out
+}
+
+// AcceptTeamInvite marks email's invite to this user's team as accepted. It
+// reports false when email was never invited.
+func (r *FileRepo) AcceptTeamInvite(email string) (bool, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return false, nil
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	us := r.userStateLocked()
+	for i, m := range us.Profile.Team.Members {
+		if !strings.EqualFold(strings.TrimSpace(m.Email), email) {
+			continue
+		}
+		if m.Status == TeamMemberActive {
+			return true, nil
+		}
+		now := time.Now().UTC()
+		us.Profile.Team.Members[i].Status = TeamMemberActive
+		us.Profile.Team.Members[i].AcceptedAt = &now
+		r.store.s.Users[r.userID] = us
+		return true, r.store.saveLocked()
+	}
+	return false, nil
+}
+
 func (r *FileRepo) InviteTeamMember(email string) (bool, PlayerProfile, UserState, error) {
 	email = strings.ToLower(strings.TrimSpace(email))
 	if email == "" {
@@ -726,7 +872,7 @@ func (r *FileRepo) InviteTeamMember(email string) (bool, PlayerProfile, UserStat
 	us.Profile.Team.Members = append(us.Profile.Team.Members, TeamMember{
 		Email:     email,
 		Role:      "member",
-		Status:    "invited",
+		Status:    TeamMemberInvited,
 		InvitedAt: time.Now().UTC(),
 	})
 	us.Profile = normalizeProfile(us.Profile, r.userID)
@@ -5,10 +5,14 @@ import (
 	"net/http"
 	"net/mail"
 	"strings"
+
+	"donegeon/internal/webhook"
 )
 
 type Handler struct {
-	repoResolver func(*http.Request) *FileRepo
+	repoResolver      func(*http.Request) *FileRepo
+	publisherResolver func(*http.Request) webhook.Publisher
+	emailResolver     func(*http.Request) string
 }
 
 func NewHandler() *Handler {
@@ -19,6 +23,25 @@ func (h *Handler) SetRepoResolver(fn func(*http.Request) *FileRepo) {
 	h.repoResolver = fn
 }
 
+// SetPublisherResolver sends feature.unlocked events to the requester's
+// webhooks.
+func (h *Handler) SetPublisherResolver(fn func(*http.Request) webhook.Publisher) {
+	h.publisherResolver = fn
+}
+
+// SetEmailResolver gives the requester's sign-in email, which team
+// invites are addressed to.
+func (h *Handler) SetEmailResolver(fn func(*http.Request) string) {
+	h.emailResolver = fn
+}
+
+func (h *Handler) emailForRequest(r *http.Request) string {
+	if h.emailResolver == nil {
+		return ""
+	}
+	return strings.TrimSpace(h.emailResolver(r))
+}
+
 func (h *Handler) repoForRequest(r *http.Request) *FileRepo {
 	if h.repoResolver == nil {
 		return nil
@@ -100,6 +123,15 @@ func (h *Handler) Unlock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !already && h.publisherResolver != nil {
+		if p := h.publisherResolver(r); p != nil {
+			p.Publish(webhook.EventFeatureUnlocked, map[string]any{
+				"feature": feature,
+				"cost":    cost,
+			})
+		}
+	}
+
 	writeJSON(w, http.StatusOK, map[string]any{
 		"ok":      true,
 		"already": already,
@@ -111,9 +143,9 @@ func (h *Handler) Unlock(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GET /api/player/profile
+// GET/PATCH /api/player/profile
 func (h *Handler) Profile(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	if r.Method != http.MethodGet && r.Method != http.MethodPatch {
 		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
@@ -122,8 +154,34 @@ func (h *Handler) Profile(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, http.StatusInternalServerError, "player repository unavailable")
 		return
 	}
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"profile": repo.GetProfile(),
+		})
+		return
+	}
+
+	var in struct {
+		DisplayName *string `json:"displayName"`
+		Avatar      *string `json:"avatar"`
+		Timezone    *string `json:"timezone"`
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		writeErr(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	profile, err := repo.UpdateProfile(in.DisplayName, in.Avatar, in.Timezone)
+	if err == ErrInvalidTimezone {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "could not update profile")
+		return
+	}
 	writeJSON(w, http.StatusOK, map[string]any{
-		"profile": repo.GetProfile(),
+		"ok":      true,
+		"profile": profile,
 	})
 }
 
@@ -235,3 +293,63 @@ func (h *Handler) TeamInvite(w http.ResponseWriter, r *http.Request) {
 		"profile": profile,
 	})
 }
+
+// GET /api/player/team/invitations
+//
+// Lists the teams waiting for the requester to accept their invite.
+func (h *Handler) TeamInvitations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	repo := h.repoForRequest(r)
+	if repo == nil {
+		writeErr(w, http.StatusInternalServerError, "player repository unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, repo.TeamInvitations(h.emailForRequest(r)))
+}
+
+// POST /api/player/team/accept { ownerId }
+//
+// Accepts the requester's invite to ownerId's team. Until then the owner's
+// shared tasks, filters and blueprints stay hidden from them.
+func (h *Handler) TeamAccept(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	repo := h.repoForRequest(r)
+	if repo == nil {
+		writeErr(w, http.StatusInternalServerError, "player repository unavailable")
+		return
+	}
+
+	var in struct {
+		OwnerID string `json:"ownerId"`
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		writeErr(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	ownerID := strings.TrimSpace(in.OwnerID)
+	if ownerID == "" {
+		writeErr(w, http.StatusBadRequest, `missing field "ownerId"`)
+		return
+	}
+	email := h.emailForRequest(r)
+	if email == "" || ownerID == repo.userID {
+		writeErr(w, http.StatusNotFound, "no invitation")
+		return
+	}
+	ok, err := repo.ForUser(ownerID).AcceptTeamInvite(email)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "could not accept invitation")
+		return
+	}
+	if !ok {
+		writeErr(w, http.StatusNotFound, "no invitation")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "ownerId": ownerID})
+}
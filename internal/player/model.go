@@ -1,6 +1,11 @@
 package player
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidTimezone = errors.New("unknown timezone")
 
 const (
 	LootCoin           = "coin"
@@ -56,11 +61,25 @@ type VillagerProgress struct {
 	Perks []string `json:"perks,omitempty"`
 }
 
+// Team member statuses. Invited members get no access until they accept.
+const (
+	TeamMemberInvited = "invited"
+	TeamMemberActive  = "active"
+)
+
 type TeamMember struct {
-	Email     string    `json:"email"`
-	Role      string    `json:"role"`
-	Status    string    `json:"status"`
-	InvitedAt time.Time `json:"invitedAt,omitempty"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	Status     string     `json:"status"`
+	InvitedAt  time.Time  `json:"invitedAt,omitempty"`
+	AcceptedAt *time.Time `json:"acceptedAt,omitempty"`
+}
+
+// TeamInvitation is an open invite to someone else's team.
+type TeamInvitation struct {
+	OwnerID   string    `json:"ownerId"`
+	TeamName  string    `json:"teamName"`
+	InvitedAt time.Time `json:"invitedAt"`
 }
 
 type TeamProfile struct {
@@ -76,6 +95,10 @@ type PlayerProfile struct {
 	OnboardingCompleted   bool        `json:"onboardingCompleted"`
 	OnboardingCompletedAt time.Time   `json:"onboardingCompletedAt,omitempty"`
 	Team                  TeamProfile `json:"team"`
+
+	// Timezone is an IANA name (e.g. "America/Chicago"); empty means the
+	// server's local zone.
+	Timezone string `json:"timezone,omitempty"`
 }
 
 type StateResponse struct {
@@ -183,7 +206,7 @@ func normalizeProfile(in PlayerProfile, userID string) PlayerProfile {
 			m.Role = "member"
 		}
 		if m.Status == "" {
-			m.Status = "invited"
+			m.Status = TeamMemberInvited
 		}
 		members = append(members, m)
 	}
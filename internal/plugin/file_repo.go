@@ -15,6 +15,7 @@ import (
 type userState struct {
 	Installed map[model.PluginID]model.InstalledPlugin `json:"installed"`
 	Community map[model.PluginID]model.PluginManifest  `json:"community"`
+	Runs      []model.PluginRun                        `json:"runs,omitempty"`
 }
 
 type fileState struct {
@@ -168,6 +169,7 @@ func cloneManifest(m model.PluginManifest) model.PluginManifest {
 		CardIcon:     m.CardIcon,
 		InstallCost:  m.InstallCost,
 		Capabilities: append([]string{}, m.Capabilities...),
+		Endpoint:     m.Endpoint,
 		Source:       m.Source,
 		CreatedAt:    m.CreatedAt,
 		UpdatedAt:    m.UpdatedAt,
@@ -180,6 +182,7 @@ func cloneInstall(in model.InstalledPlugin) model.InstalledPlugin {
 		Source:      in.Source,
 		InstalledAt: in.InstalledAt,
 		Enabled:     in.Enabled,
+		Secret:      in.Secret,
 	}
 }
 
@@ -324,6 +327,7 @@ func (r *FileRepo) Install(id model.PluginID) (bool, model.InstalledPlugin, mode
 		Source:      manifest.Source,
 		InstalledAt: time.Now().UTC(),
 		Enabled:     true,
+		Secret:      newSecret(),
 	}
 	user.Installed[id] = install
 	r.store.s.Users[r.userID] = user
@@ -351,3 +355,61 @@ func (r *FileRepo) Uninstall(id model.PluginID) (bool, error) {
 	}
 	return true, nil
 }
+
+// UserSnapshot is the user's installed plugins and the community manifests
+// they registered, for account export and import.
+type UserSnapshot struct {
+	Installed []model.InstalledPlugin `json:"installed"`
+	Community []model.PluginManifest  `json:"community,omitempty"`
+}
+
+func (r *FileRepo) Snapshot() UserSnapshot {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	st := normalizeUserState(r.store.s.Users[r.userID])
+	out := UserSnapshot{
+		Installed: make([]model.InstalledPlugin, 0, len(st.Installed)),
+		Community: make([]model.PluginManifest, 0, len(st.Community)),
+	}
+	for _, in := range st.Installed {
+		out.Installed = append(out.Installed, cloneInstall(in))
+	}
+	for _, m := range st.Community {
+		out.Community = append(out.Community, cloneManifest(m))
+	}
+	sort.Slice(out.Installed, func(i, j int) bool { return out.Installed[i].PluginID < out.Installed[j].PluginID })
+	sort.Slice(out.Community, func(i, j int) bool { return out.Community[i].ID < out.Community[j].ID })
+	return out
+}
+
+// Restore replaces the user's plugins with snap. Invalid manifests, and
+// installs of plugins that neither the core set nor snap's manifests
+// provide, are dropped. Every install gets a new signing secret.
+func (r *FileRepo) Restore(snap UserSnapshot) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	st := normalizeUserState(userState{})
+	for _, m := range snap.Community {
+		m = cloneManifest(m)
+		normalizeManifest(&m)
+		if validateManifest(m) != nil {
+			continue
+		}
+		m.Source = model.PluginSourceCommunity
+		st.Community[m.ID] = m
+	}
+	for _, in := range snap.Installed {
+		_, core := r.corePlugins[in.PluginID]
+		_, community := st.Community[in.PluginID]
+		if !core && !community {
+			continue
+		}
+		in = cloneInstall(in)
+		in.Secret = newSecret()
+		st.Installed[in.PluginID] = in
+	}
+	r.store.s.Users[r.userID] = normalizeUserState(st)
+	return r.store.saveLocked()
+}
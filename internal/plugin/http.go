@@ -9,10 +9,12 @@ import (
 
 	"donegeon/internal/model"
 	"donegeon/internal/player"
+	"donegeon/internal/webhook"
 )
 
 type Handler struct {
 	repo           Repo
+	guard          webhook.Guard
 	repoResolver   func(*http.Request) Repo
 	playerResolver func(*http.Request) *player.FileRepo
 }
@@ -25,6 +27,12 @@ func (h *Handler) SetRepoResolver(fn func(*http.Request) Repo) {
 	h.repoResolver = fn
 }
 
+// SetGuard sets which hosts plugin endpoints may point at. By default
+// private, loopback and link-local addresses are refused.
+func (h *Handler) SetGuard(g webhook.Guard) {
+	h.guard = g
+}
+
 func (h *Handler) SetPlayerResolver(fn func(*http.Request) *player.FileRepo) {
 	h.playerResolver = fn
 }
@@ -158,10 +166,20 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if ep := strings.TrimSpace(manifest.Endpoint); ep != "" {
+		if err := h.guard.CheckURL(r.Context(), ep); errors.Is(err, webhook.ErrBlockedHost) {
+			writeErr(w, http.StatusBadRequest, err.Error())
+			return
+		} else if err != nil {
+			writeErr(w, http.StatusBadRequest, ErrPluginBadEndpoint.Error())
+			return
+		}
+	}
+
 	created, err := repo.Register(manifest)
 	if err != nil {
 		switch {
-		case errors.Is(err, ErrPluginMissingID), errors.Is(err, ErrPluginMissingName), errors.Is(err, ErrPluginMissingCardDef), errors.Is(err, ErrPluginInvalid):
+		case errors.Is(err, ErrPluginMissingID), errors.Is(err, ErrPluginMissingName), errors.Is(err, ErrPluginMissingCardDef), errors.Is(err, ErrPluginInvalid), errors.Is(err, ErrPluginBadEndpoint):
 			writeErr(w, http.StatusBadRequest, err.Error())
 		case errors.Is(err, ErrPluginIDConflict):
 			writeErr(w, http.StatusConflict, err.Error())
@@ -254,17 +272,22 @@ func (h *Handler) Install(w http.ResponseWriter, r *http.Request) {
 		coinBalance = playerRepo.GetState().Loot[player.LootCoin]
 	}
 
+	installed := map[string]any{
+		"plugin":      installedManifest,
+		"installedAt": install.InstalledAt,
+		"enabled":     install.Enabled,
+	}
+	// The signing secret is shown once, to whoever installs the plugin.
+	if !already && installedManifest.Endpoint != "" {
+		installed["secret"] = install.Secret
+	}
 	writeJSON(w, http.StatusOK, map[string]any{
 		"ok":           true,
 		"already":      already,
 		"coinsCharged": coinsCharged,
 		"coinBalance":  coinBalance,
-		"installed": map[string]any{
-			"plugin":      installedManifest,
-			"installedAt": install.InstalledAt,
-			"enabled":     install.Enabled,
-		},
-		"state": state,
+		"installed":    installed,
+		"state":        state,
 	})
 }
 
@@ -307,3 +330,34 @@ func (h *Handler) Uninstall(w http.ResponseWriter, r *http.Request) {
 		"state":   state,
 	})
 }
+
+// GET /api/plugins/runs?pluginId=
+//
+// Lists an installed plugin's runs, newest first.
+func (h *Handler) Runs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	repo := h.repoForRequest(r)
+	id := model.PluginID(strings.TrimSpace(r.URL.Query().Get("pluginId")))
+	if id == "" {
+		writeErr(w, http.StatusBadRequest, "missing query \"pluginId\"")
+		return
+	}
+	installed, err := repo.IsInstalled(id)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !installed {
+		writeErr(w, http.StatusNotFound, "plugin not installed")
+		return
+	}
+	runs, err := repo.Runs(id)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
@@ -124,6 +124,33 @@ func TestInstallPluginInsufficientCoins(t *testing.T) {
 	}
 }
 
+func TestRegister_RefusesInternalEndpoints(t *testing.T) {
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new plugin repo: %v", err)
+	}
+	h := NewHandler(repo.ForUser("u-plugin"))
+
+	for _, ep := range []string{"http://169.254.169.254/latest/meta-data", "http://127.0.0.1:6379/", "http://192.168.1.10/hook"} {
+		req := jsonReq(http.MethodPost, "/api/plugins/register", map[string]any{
+			"manifest": map[string]any{
+				"id":        "sneaky",
+				"name":      "Sneaky",
+				"cardDefId": "mod.plugin_sneaky",
+				"endpoint":  ep,
+			},
+		})
+		rec := httptest.NewRecorder()
+		h.Register(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("register %s expected 400, got %d body=%s", ep, rec.Code, rec.Body.String())
+		}
+	}
+	if _, _, err := repo.ForUser("u-plugin").Installed("sneaky"); err == nil {
+		t.Fatalf("refused plugin was registered")
+	}
+}
+
 func jsonReq(method, path string, body any) *http.Request {
 	b, _ := json.Marshal(body)
 	req := httptest.NewRequest(method, path, bytes.NewReader(b))
@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"errors"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
@@ -17,6 +18,7 @@ var (
 	ErrPluginMissingID      = errors.New("plugin id is required")
 	ErrPluginMissingName    = errors.New("plugin name is required")
 	ErrPluginMissingCardDef = errors.New("plugin card def id is required")
+	ErrPluginBadEndpoint    = errors.New("plugin endpoint must be an absolute http or https URL")
 )
 
 type Repo interface {
@@ -27,6 +29,8 @@ type Repo interface {
 	Register(manifest model.PluginManifest) (model.PluginManifest, error)
 	Install(id model.PluginID) (already bool, install model.InstalledPlugin, manifest model.PluginManifest, err error)
 	Uninstall(id model.PluginID) (removed bool, err error)
+	// Runs returns an installed plugin's run log, newest first.
+	Runs(id model.PluginID) ([]model.PluginRun, error)
 }
 
 func normalizeManifest(m *model.PluginManifest) {
@@ -36,6 +40,7 @@ func normalizeManifest(m *model.PluginManifest) {
 	m.Provider = strings.TrimSpace(m.Provider)
 	m.Category = strings.TrimSpace(strings.ToLower(m.Category))
 	m.Version = strings.TrimSpace(m.Version)
+	m.Endpoint = strings.TrimSpace(m.Endpoint)
 	if m.Version == "" {
 		m.Version = "1.0.0"
 	}
@@ -97,6 +102,12 @@ func validateManifest(m model.PluginManifest) error {
 	if !strings.HasPrefix(strings.ToLower(m.CardDefID), "mod.plugin_") {
 		return ErrPluginInvalid
 	}
+	if m.Endpoint != "" {
+		u, err := url.Parse(m.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrPluginBadEndpoint
+		}
+	}
 	return nil
 }
 
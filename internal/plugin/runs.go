@@ -0,0 +1,168 @@
+package plugin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+// maxRunsPerUser bounds the run log; the oldest finished runs are dropped.
+const maxRunsPerUser = 200
+
+func newID(prefix string) string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return prefix + "_" + hex.EncodeToString(b[:])
+}
+
+// newSecret is a random signing secret for one install.
+func newSecret() string {
+	var b [24]byte
+	_, _ = rand.Read(b[:])
+	return "plsec_" + hex.EncodeToString(b[:])
+}
+
+// ByCard returns the enabled install whose plugin provides cardDefID, with
+// its manifest.
+func (r *FileRepo) ByCard(cardDefID string) (model.InstalledPlugin, model.PluginManifest, error) {
+	cardDefID = strings.TrimSpace(strings.ToLower(cardDefID))
+	installed, err := r.ListInstalled()
+	if err != nil {
+		return model.InstalledPlugin{}, model.PluginManifest{}, err
+	}
+	for _, in := range installed {
+		if !in.Enabled {
+			continue
+		}
+		m, err := r.GetMarketplace(in.PluginID)
+		if err == nil && m.CardDefID == cardDefID {
+			return in, m, nil
+		}
+	}
+	return model.InstalledPlugin{}, model.PluginManifest{}, ErrPluginNotFound
+}
+
+// Installed returns an installed plugin with its manifest. Installs made
+// before plugins had secrets are given one here.
+func (r *FileRepo) Installed(id model.PluginID) (model.InstalledPlugin, model.PluginManifest, error) {
+	m, err := r.GetMarketplace(id)
+	if err != nil {
+		return model.InstalledPlugin{}, model.PluginManifest{}, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	user := r.userStateLocked()
+	in, ok := user.Installed[m.ID]
+	if !ok {
+		return model.InstalledPlugin{}, model.PluginManifest{}, ErrPluginNotFound
+	}
+	if in.Secret == "" {
+		in.Secret = newSecret()
+		user.Installed[m.ID] = in
+		if err := r.store.saveLocked(); err != nil {
+			return model.InstalledPlugin{}, model.PluginManifest{}, err
+		}
+	}
+	return cloneInstall(in), m, nil
+}
+
+// AddRun logs a new run.
+func (r *FileRepo) AddRun(run model.PluginRun) (model.PluginRun, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if strings.TrimSpace(string(run.ID)) == "" {
+		run.ID = model.PluginRunID(newID("run"))
+	}
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = time.Now().UTC()
+	}
+	if run.Status == "" {
+		run.Status = model.PluginRunPending
+	}
+	user := r.userStateLocked()
+	user.Runs = pruneRuns(append(user.Runs, run))
+	r.store.s.Users[r.userID] = user
+	if err := r.store.saveLocked(); err != nil {
+		return model.PluginRun{}, err
+	}
+	return run, nil
+}
+
+// SaveRun records an attempt on a logged run. A run pruned from the log in
+// the meantime is not brought back.
+func (r *FileRepo) SaveRun(run model.PluginRun) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user := r.userStateLocked()
+	for i := range user.Runs {
+		if user.Runs[i].ID == run.ID {
+			user.Runs[i] = run
+			return r.store.saveLocked()
+		}
+	}
+	return nil
+}
+
+// Runs returns a plugin's run log, newest first.
+func (r *FileRepo) Runs(id model.PluginID) ([]model.PluginRun, error) {
+	id = model.PluginID(slugify(string(id)))
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	out := make([]model.PluginRun, 0)
+	for _, run := range r.store.s.Users[r.userID].Runs {
+		if run.PluginID == id {
+			out = append(out, run)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+// pendingRun is a run still waiting for an attempt, with the user it
+// belongs to.
+type pendingRun struct {
+	userID string
+	run    model.PluginRun
+}
+
+// pendingRuns returns every user's unfinished runs.
+func (r *FileRepo) pendingRuns() []pendingRun {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	out := make([]pendingRun, 0)
+	for uid, st := range r.store.s.Users {
+		for _, run := range st.Runs {
+			if run.Status == model.PluginRunPending {
+				out = append(out, pendingRun{userID: uid, run: run})
+			}
+		}
+	}
+	return out
+}
+
+// pruneRuns drops the oldest finished runs beyond maxRunsPerUser. Pending
+// ones are kept until they finish.
+func pruneRuns(log []model.PluginRun) []model.PluginRun {
+	extra := len(log) - maxRunsPerUser
+	if extra <= 0 {
+		return log
+	}
+	out := make([]model.PluginRun, 0, len(log))
+	for _, run := range log {
+		if extra > 0 && run.Status != model.PluginRunPending {
+			extra--
+			continue
+		}
+		out = append(out, run)
+	}
+	return out
+}
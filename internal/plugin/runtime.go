@@ -0,0 +1,447 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+	"donegeon/internal/webhook"
+)
+
+const (
+	defaultRunAttempts   = 4
+	defaultRunBackoff    = 2 * time.Second
+	defaultRunMaxBackoff = 5 * time.Minute
+	runTimeout           = 10 * time.Second
+	maxResponseBytes     = 64 << 10
+)
+
+// Run triggers.
+const (
+	TriggerCardAttached = "card.attached" // the plugin's card was stacked on the task
+	TriggerTaskChanged  = "task.changed"  // a task carrying the card was created or edited
+)
+
+// Headers sent with each call, next to webhook.SignatureHeader.
+const (
+	TriggerHeader = "X-Donegeon-Plugin-Trigger"
+	RunHeader     = "X-Donegeon-Plugin-Run"
+)
+
+// Payload is the body POSTed to a plugin's endpoint. Task holds only the
+// fields the plugin's capabilities need.
+type Payload struct {
+	RunID        string         `json:"runId"`
+	PluginID     string         `json:"pluginId"`
+	Trigger      string         `json:"trigger"`
+	Capabilities []string       `json:"capabilities"`
+	Task         map[string]any `json:"task"`
+	CreatedAt    time.Time      `json:"createdAt"`
+}
+
+// Response is what a plugin answers with. An empty body means no results.
+type Response struct {
+	Results []Result `json:"results"`
+}
+
+// Result is one change a plugin asks for:
+//
+//	{"type":"comment","body":"..."}
+//	{"type":"external_ref","value":"evt_123"}
+//	{"type":"complete"}
+//	{"type":"update","title":"...","description":"..."}
+type Result struct {
+	Type        string  `json:"type"`
+	Body        string  `json:"body,omitempty"`
+	Value       string  `json:"value,omitempty"`
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// resultCapabilities lists, per result type, the capabilities any one of
+// which lets a plugin return it.
+var resultCapabilities = map[string][]string{
+	"comment":      {"task.comment", "issue.link"},
+	"external_ref": {"calendar.create_event", "calendar.update_event", "task.sync", "issue.link"},
+	"complete":     {"task.complete", "issue.transition"},
+	"update":       {"task.sync"},
+}
+
+// Runtime calls the endpoints of installed community plugins when their
+// cards are attached to a task or such a task changes, and applies what
+// they answer within their capabilities. Every call is logged as a run
+// before its first attempt and retried like a webhook delivery; pending
+// retries live in timers, so call Resume after a restart.
+type Runtime struct {
+	repo             *FileRepo
+	client           *http.Client
+	taskRepoResolver func(userID string) task.Repo
+	completer        Completer
+
+	MaxAttempts int
+	Backoff     time.Duration // before the first retry; doubles after each attempt
+	MaxBackoff  time.Duration
+
+	wg sync.WaitGroup
+}
+
+func NewRuntime(repo *FileRepo) *Runtime {
+	return &Runtime{
+		repo:        repo,
+		client:      webhook.Guard{}.Client(runTimeout),
+		MaxAttempts: defaultRunAttempts,
+		Backoff:     defaultRunBackoff,
+		MaxBackoff:  defaultRunMaxBackoff,
+	}
+}
+
+// SetGuard sets which hosts plugin calls may reach; see webhook.Guard.
+func (rt *Runtime) SetGuard(g webhook.Guard) {
+	rt.client = g.Client(runTimeout)
+}
+
+// SetClient replaces the HTTP client used for calls.
+func (rt *Runtime) SetClient(c *http.Client) {
+	rt.client = c
+}
+
+// SetTaskRepoResolver sets where results are written for a user. Without
+// it runs are still made, but their results are skipped.
+func (rt *Runtime) SetTaskRepoResolver(fn func(userID string) task.Repo) {
+	rt.taskRepoResolver = fn
+}
+
+// Completer marks a task done for userID through repo under the usual
+// completion rules and side effects, returning why it refused if it did.
+type Completer func(userID string, repo task.Repo, id model.TaskID) error
+
+// SetCompleter sets how "complete" results are applied. Without it they
+// are skipped: a bare done flag would bypass the completion rules.
+func (rt *Runtime) SetCompleter(fn Completer) {
+	rt.completer = fn
+}
+
+// ForUser is the task.PluginRunner for userID's plugins.
+func (rt *Runtime) ForUser(userID string) task.PluginRunner {
+	return userRunner{rt: rt, userID: userID, repo: rt.repo.ForUser(userID)}
+}
+
+type userRunner struct {
+	rt     *Runtime
+	userID string
+	repo   *FileRepo
+}
+
+func (u userRunner) CardAttached(t model.Task, cardDefID string) {
+	u.run(t, cardDefID, TriggerCardAttached)
+}
+
+func (u userRunner) TaskChanged(t model.Task) {
+	seen := map[string]bool{}
+	for _, card := range task.PluginCards(t) {
+		if !seen[card] {
+			seen[card] = true
+			u.run(t, card, TriggerTaskChanged)
+		}
+	}
+}
+
+func (u userRunner) run(t model.Task, cardDefID, trigger string) {
+	in, m, err := u.repo.ByCard(cardDefID)
+	if err != nil || m.Endpoint == "" {
+		return
+	}
+	run, err := u.rt.enqueue(u.repo, in.PluginID, m, t, trigger)
+	if err != nil {
+		return
+	}
+	u.rt.schedule(u.userID, run, 0)
+}
+
+// Resume schedules every pending run in the log, at its next attempt time
+// or right away when that has passed.
+func (rt *Runtime) Resume() {
+	now := time.Now().UTC()
+	for _, p := range rt.repo.pendingRuns() {
+		delay := time.Duration(0)
+		if next := p.run.NextAttemptAt; next != nil && next.After(now) {
+			delay = next.Sub(now)
+		}
+		rt.schedule(p.userID, p.run, delay)
+	}
+}
+
+// Wait blocks until no run is in flight or scheduled.
+func (rt *Runtime) Wait() {
+	rt.wg.Wait()
+}
+
+func (rt *Runtime) enqueue(repo *FileRepo, id model.PluginID, m model.PluginManifest, t model.Task, trigger string) (model.PluginRun, error) {
+	runID := model.PluginRunID(newID("run"))
+	body, err := json.Marshal(Payload{
+		RunID:        string(runID),
+		PluginID:     string(id),
+		Trigger:      trigger,
+		Capabilities: m.Capabilities,
+		Task:         scopedTask(t, id, m.Capabilities),
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return model.PluginRun{}, err
+	}
+	return repo.AddRun(model.PluginRun{
+		ID:       runID,
+		PluginID: id,
+		TaskID:   t.ID,
+		Trigger:  trigger,
+		Payload:  string(body),
+	})
+}
+
+func (rt *Runtime) schedule(userID string, run model.PluginRun, delay time.Duration) {
+	rt.wg.Add(1)
+	time.AfterFunc(delay, func() {
+		defer rt.wg.Done()
+		rt.attempt(userID, run)
+	})
+}
+
+// attempt makes one call, applies the answer, logs the run, and schedules
+// the retry if one is due.
+func (rt *Runtime) attempt(userID string, run model.PluginRun) model.PluginRun {
+	repo := rt.repo.ForUser(userID)
+	now := time.Now().UTC()
+	run.NextAttemptAt = nil
+	in, m, err := repo.Installed(run.PluginID)
+	switch {
+	case err != nil:
+		run.Status = model.PluginRunFailed
+		run.Error = "plugin was uninstalled"
+		_ = repo.SaveRun(run)
+		return run
+	case !in.Enabled || m.Endpoint == "":
+		run.Status = model.PluginRunFailed
+		run.Error = "plugin is disabled"
+		_ = repo.SaveRun(run)
+		return run
+	}
+
+	run.Attempts++
+	run.LastAttemptAt = &now
+	code, body, retry, err := rt.send(m.Endpoint, in.Secret, run)
+	run.ResponseCode = code
+	run.Error = ""
+	switch {
+	case err == nil:
+		results, perr := parseResults(body)
+		if perr != nil {
+			run.Status = model.PluginRunFailed
+			run.Error = perr.Error()
+			break
+		}
+		run.Status = model.PluginRunSucceeded
+		run.Applied, run.Skipped = rt.apply(userID, m, run.TaskID, results)
+	case retry && run.Attempts < rt.MaxAttempts:
+		run.Status = model.PluginRunPending
+		run.Error = err.Error()
+		next := now.Add(rt.backoff(run.Attempts))
+		run.NextAttemptAt = &next
+	default:
+		run.Status = model.PluginRunFailed
+		run.Error = err.Error()
+	}
+	_ = repo.SaveRun(run)
+	if run.NextAttemptAt != nil {
+		rt.schedule(userID, run, run.NextAttemptAt.Sub(now))
+	}
+	return run
+}
+
+// send posts the payload, signed with the install's secret. retry reports
+// whether a failure is worth trying again: network errors, timeouts, 429
+// and 5xx are; other statuses mean the plugin refused the run.
+func (rt *Runtime) send(endpoint, secret string, run model.PluginRun) (code int, body []byte, retry bool, err error) {
+	payload := []byte(run.Payload)
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Donegeon-Plugin/1")
+	req.Header.Set(TriggerHeader, run.Trigger)
+	req.Header.Set(RunHeader, string(run.ID))
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, payload))
+
+	resp, err := rt.client.Do(req)
+	if errors.Is(err, webhook.ErrBlockedHost) {
+		return 0, nil, false, err
+	}
+	if err != nil {
+		return 0, nil, true, err
+	}
+	defer resp.Body.Close()
+	body, err = io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return resp.StatusCode, nil, true, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, body, false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode >= 500
+	return resp.StatusCode, nil, retry, fmt.Errorf("plugin answered %d", resp.StatusCode)
+}
+
+// parseResults reads a plugin's answer. A malformed one fails the run
+// without a retry; the plugin would likely answer the same again.
+func parseResults(body []byte) ([]Result, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+	var resp Response
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return resp.Results, nil
+}
+
+// apply writes the results the plugin's capabilities allow to the task, as
+// SourcePlugin, and returns what was applied and what was skipped and why.
+// Completions go through the completer, so they follow the same rules as
+// any other.
+func (rt *Runtime) apply(userID string, m model.PluginManifest, taskID model.TaskID, results []Result) (applied, skipped []string) {
+	var repo task.Repo
+	if rt.taskRepoResolver != nil {
+		if r := rt.taskRepoResolver(userID); r != nil {
+			repo = r.WithOrigin(task.SourcePlugin, string(m.ID))
+		}
+	}
+	for _, res := range results {
+		typ := strings.TrimSpace(strings.ToLower(res.Type))
+		needs, known := resultCapabilities[typ]
+		switch {
+		case !known:
+			skipped = append(skipped, fmt.Sprintf("%s: unknown result type", res.Type))
+			continue
+		case !slices.ContainsFunc(needs, func(c string) bool { return slices.Contains(m.Capabilities, c) }):
+			skipped = append(skipped, fmt.Sprintf("%s: needs one of %s", typ, strings.Join(needs, ", ")))
+			continue
+		case repo == nil:
+			skipped = append(skipped, typ+": no task store")
+			continue
+		}
+		if err := rt.applyResult(userID, repo, m, taskID, typ, res); err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", typ, err))
+			continue
+		}
+		applied = append(applied, typ)
+	}
+	return applied, skipped
+}
+
+var (
+	errEmptyResult = errors.New("nothing to apply")
+	errNoCompleter = errors.New("completion is not available")
+)
+
+func (rt *Runtime) applyResult(userID string, repo task.Repo, m model.PluginManifest, taskID model.TaskID, typ string, res Result) error {
+	var err error
+	switch typ {
+	case "comment":
+		body := strings.TrimSpace(res.Body)
+		if body == "" {
+			return errEmptyResult
+		}
+		_, err = repo.AddComment(taskID, model.TaskComment{
+			AuthorID:   "plugin:" + string(m.ID),
+			AuthorName: m.Name,
+			Body:       body,
+		})
+	case "external_ref":
+		// An empty value clears the plugin's reference.
+		_, err = repo.Update(taskID, task.Patch{
+			ExternalRefs: map[string]string{string(m.ID): strings.TrimSpace(res.Value)},
+		})
+	case "complete":
+		if rt.completer == nil {
+			return errNoCompleter
+		}
+		err = rt.completer(userID, repo, taskID)
+	case "update":
+		if res.Title == nil && res.Description == nil {
+			return errEmptyResult
+		}
+		var patch task.Patch
+		if res.Description != nil {
+			patch.Description = task.Some(*res.Description)
+		}
+		if res.Title != nil {
+			title := strings.TrimSpace(*res.Title)
+			if title == "" {
+				return errors.New("title cannot be empty")
+			}
+			patch.Title = &title
+		}
+		_, err = repo.Update(taskID, patch)
+	}
+	return err
+}
+
+// scopedTask is the part of t a plugin with the given capabilities sees.
+// Every plugin gets the task's ID and title, and the reference it stored
+// on the task, if any.
+func scopedTask(t model.Task, id model.PluginID, caps []string) map[string]any {
+	out := map[string]any{
+		"id":    t.ID,
+		"title": t.Title,
+	}
+	if ref, ok := t.ExternalRefs[string(id)]; ok {
+		out["externalRef"] = ref
+	}
+	schedule := func() {
+		out["dueDate"] = t.DueDate
+		out["dueTime"] = t.DueTime
+		out["startDate"] = t.StartDate
+		out["recurrence"] = t.Recurrence
+	}
+	for _, c := range caps {
+		switch {
+		case strings.HasPrefix(c, "calendar."):
+			schedule()
+			out["done"] = t.Done
+		case c == "task.sync":
+			schedule()
+			out["description"] = t.Description
+			out["done"] = t.Done
+			out["project"] = t.Project
+			out["tags"] = t.Tags
+		case c == "task.complete":
+			out["done"] = t.Done
+		case strings.HasPrefix(c, "issue."):
+			out["description"] = t.Description
+		}
+	}
+	return out
+}
+
+// backoff is the delay after the given number of attempts.
+func (rt *Runtime) backoff(attempts int) time.Duration {
+	delay := rt.Backoff
+	for i := 1; i < attempts && delay < rt.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if rt.MaxBackoff > 0 && delay > rt.MaxBackoff {
+		delay = rt.MaxBackoff
+	}
+	return delay
+}
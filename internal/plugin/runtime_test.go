@@ -0,0 +1,260 @@
+package plugin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/task"
+	"donegeon/internal/webhook"
+)
+
+type pluginCall struct {
+	trigger   string
+	signature string
+	body      []byte
+}
+
+// newPluginServer answers each call with the next of codes (200 after
+// they run out) and, on 2xx, with answer.
+func newPluginServer(t *testing.T, codes []int, answer string) (*httptest.Server, func() []pluginCall) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []pluginCall
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, pluginCall{r.Header.Get(TriggerHeader), r.Header.Get(webhook.SignatureHeader), body})
+		code := http.StatusOK
+		if len(got) <= len(codes) {
+			code = codes[len(got)-1]
+		}
+		w.WriteHeader(code)
+		if code < 300 {
+			_, _ = io.WriteString(w, answer)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []pluginCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]pluginCall(nil), got...)
+	}
+}
+
+func newTestRuntime(t *testing.T, endpoint string, caps []string) (*Runtime, *FileRepo, model.InstalledPlugin, task.Repo) {
+	t.Helper()
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new plugin repo: %v", err)
+	}
+	repo = repo.ForUser("u-runtime")
+	if _, err := repo.Register(model.PluginManifest{
+		ID:           "cal_sync",
+		Name:         "Cal Sync",
+		Capabilities: caps,
+		Endpoint:     endpoint,
+	}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	_, install, _, err := repo.Install("cal_sync")
+	if err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	tasks := task.NewMemoryRepo()
+	rt := NewRuntime(repo)
+	rt.Backoff = time.Millisecond
+	rt.MaxBackoff = 5 * time.Millisecond
+	rt.SetGuard(webhook.Guard{Allow: []string{"127.0.0.1"}}) // test servers listen on loopback
+	rt.SetTaskRepoResolver(func(string) task.Repo { return tasks })
+	return rt, repo, install, tasks
+}
+
+func TestRuntime_SignsScopedPayloadRetriesAndAppliesAllowedResults(t *testing.T) {
+	answer := `{"results":[
+		{"type":"comment","body":"Added to your calendar."},
+		{"type":"external_ref","value":"evt_42"},
+		{"type":"complete"},
+		{"type":"update","title":"Renamed"}
+	]}`
+	srv, calls := newPluginServer(t, []int{http.StatusServiceUnavailable}, answer)
+	rt, repo, install, tasks := newTestRuntime(t, srv.URL, []string{"task.comment", "calendar.create_event"})
+
+	due := "2026-10-20"
+	tk, err := tasks.Create(model.Task{Title: "Dentist", Description: "bring forms", DueDate: &due})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	rt.ForUser("u-runtime").CardAttached(tk, "mod.plugin_cal_sync")
+	rt.Wait()
+
+	got := calls()
+	if len(got) != 2 {
+		t.Fatalf("expected a retry after 503, got %d calls", len(got))
+	}
+	for _, c := range got {
+		if c.trigger != TriggerCardAttached {
+			t.Fatalf("trigger header = %q", c.trigger)
+		}
+		if !webhook.Verify(install.Secret, c.body, c.signature) {
+			t.Fatalf("signature did not verify with the install secret")
+		}
+	}
+	var payload Payload
+	if err := json.Unmarshal(got[0].body, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if payload.Task["title"] != "Dentist" || payload.Task["dueDate"] != due {
+		t.Fatalf("payload task missing scoped fields: %+v", payload.Task)
+	}
+	if _, ok := payload.Task["description"]; ok {
+		t.Fatalf("calendar plugin should not see the description: %+v", payload.Task)
+	}
+
+	updated, err := tasks.Get(tk.ID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if updated.ExternalRefs["cal_sync"] != "evt_42" {
+		t.Fatalf("external ref not stored: %+v", updated.ExternalRefs)
+	}
+	if updated.Done || updated.Title != "Dentist" {
+		t.Fatalf("results outside the plugin's capabilities were applied: %+v", updated)
+	}
+	comments, _ := tasks.Comments(tk.ID)
+	if len(comments) != 1 || comments[0].AuthorName != "Cal Sync" {
+		t.Fatalf("expected the plugin's comment, got %+v", comments)
+	}
+
+	runs, err := repo.Runs("cal_sync")
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("expected one run, got %d (%v)", len(runs), err)
+	}
+	run := runs[0]
+	if run.Status != model.PluginRunSucceeded || run.Attempts != 2 || run.TaskID != tk.ID {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	if len(run.Applied) != 2 || len(run.Skipped) != 2 {
+		t.Fatalf("expected 2 applied and 2 skipped, got %v / %v", run.Applied, run.Skipped)
+	}
+}
+
+func TestRuntime_BadResponseFailsWithoutRetry(t *testing.T) {
+	srv, calls := newPluginServer(t, nil, "not json")
+	rt, repo, _, tasks := newTestRuntime(t, srv.URL, []string{"task.comment"})
+
+	tk, err := tasks.Create(model.Task{
+		Title:     "Plan trip",
+		Modifiers: []model.TaskModifierSlot{{DefID: "mod.plugin_cal_sync"}},
+	})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	rt.ForUser("u-runtime").TaskChanged(tk)
+	rt.Wait()
+
+	if n := len(calls()); n != 1 {
+		t.Fatalf("expected one call, got %d", n)
+	}
+	runs, _ := repo.Runs("cal_sync")
+	if len(runs) != 1 || runs[0].Status != model.PluginRunFailed || runs[0].Trigger != TriggerTaskChanged {
+		t.Fatalf("unexpected runs: %+v", runs)
+	}
+}
+
+func TestRuntime_RefusesInternalEndpointsAtDialTime(t *testing.T) {
+	srv, calls := newPluginServer(t, nil, `{"results":[]}`)
+	rt, repo, _, tasks := newTestRuntime(t, srv.URL, []string{"task.comment"})
+	rt.SetGuard(webhook.Guard{})
+
+	tk, err := tasks.Create(model.Task{Title: "Plan trip"})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	rt.ForUser("u-runtime").CardAttached(tk, "mod.plugin_cal_sync")
+	rt.Wait()
+
+	if n := len(calls()); n != 0 {
+		t.Fatalf("expected no call to a loopback endpoint, got %d", n)
+	}
+	runs, _ := repo.Runs("cal_sync")
+	if len(runs) != 1 || runs[0].Status != model.PluginRunFailed || runs[0].Attempts != 1 {
+		t.Fatalf("expected one failed, unretried run, got %+v", runs)
+	}
+}
+
+type publishedEvents struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (p *publishedEvents) Publish(event string, _ any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+}
+
+func TestRuntime_CompleteFollowsTheCompletionRules(t *testing.T) {
+	srv, _ := newPluginServer(t, nil, `{"results":[{"type":"complete"}]}`)
+	rt, repo, _, tasks := newTestRuntime(t, srv.URL, []string{"task.complete"})
+
+	players, err := player.NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new player repo: %v", err)
+	}
+	playerRepo := players.ForUser("u-runtime")
+	cfg := &config.Config{}
+	cfg.Tasks.Processing.CompletionRequiresAssignedVillager = true
+	th := task.NewHandler(tasks)
+	th.SetConfig(cfg)
+	pub := &publishedEvents{}
+	rt.SetCompleter(func(userID string, repo task.Repo, id model.TaskID) error {
+		_, err := th.Complete(repo, playerRepo, pub, id)
+		return err
+	})
+
+	tk, err := tasks.Create(model.Task{Title: "Water plants"})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	runner := rt.ForUser("u-runtime")
+	runner.CardAttached(tk, "mod.plugin_cal_sync")
+	rt.Wait()
+	if got, _ := tasks.Get(tk.ID); got.Done {
+		t.Fatalf("plugin completed a task without an assigned villager")
+	}
+	runs, _ := repo.Runs("cal_sync")
+	if len(runs) != 1 || len(runs[0].Applied) != 0 || len(runs[0].Skipped) != 1 {
+		t.Fatalf("expected the completion to be skipped, got %+v", runs)
+	}
+
+	villager := "v-1"
+	if _, err := tasks.Update(tk.ID, task.Patch{AssignedVillagerID: task.Some(villager)}); err != nil {
+		t.Fatalf("assign villager: %v", err)
+	}
+	runner.CardAttached(tk, "mod.plugin_cal_sync")
+	rt.Wait()
+
+	got, _ := tasks.Get(tk.ID)
+	if !got.Done || got.CompletionCount != 1 {
+		t.Fatalf("expected the task completed with habit progress, got %+v", got)
+	}
+	if n := playerRepo.GetState().Metrics[player.MetricTasksCompleted]; n != 1 {
+		t.Fatalf("expected tasks_completed 1, got %d", n)
+	}
+	if len(pub.events) != 1 || pub.events[0] != webhook.EventTaskCompleted {
+		t.Fatalf("expected one task.completed event, got %v", pub.events)
+	}
+	if h, _ := tasks.History(tk.ID); h[len(h)-1].Source != task.SourcePlugin {
+		t.Fatalf("expected the completion recorded as the plugin's, got %+v", h[len(h)-1])
+	}
+}
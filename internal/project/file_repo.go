@@ -0,0 +1,335 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+type fileState struct {
+	Users map[string]map[model.ProjectID]model.Project `json:"users"`
+	// Migrated marks the users whose free-text task projects were turned
+	// into entities.
+	Migrated map[string]bool `json:"migrated,omitempty"`
+}
+
+type fileStore struct {
+	mu   sync.RWMutex
+	path string
+	s    fileState
+}
+
+// FileRepo is a persistent project repo scoped by user.
+type FileRepo struct {
+	store  *fileStore
+	userID string
+}
+
+func NewFileRepo(dataDir string) (*FileRepo, error) {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return nil, err
+	}
+	st := &fileStore{
+		path: filepath.Join(dataDir, "projects.json"),
+		s: fileState{
+			Users: map[string]map[model.ProjectID]model.Project{},
+		},
+	}
+	if err := st.load(); err != nil {
+		return nil, err
+	}
+	return &FileRepo{
+		store:  st,
+		userID: "default",
+	}, nil
+}
+
+func (s *fileStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.s.Users = map[string]map[model.ProjectID]model.Project{}
+			return nil
+		}
+		return err
+	}
+	var loaded fileState
+	if err := json.Unmarshal(b, &loaded); err != nil {
+		return err
+	}
+	if loaded.Users == nil {
+		loaded.Users = map[string]map[model.ProjectID]model.Project{}
+	}
+	for uid, m := range loaded.Users {
+		if m == nil {
+			loaded.Users[uid] = map[model.ProjectID]model.Project{}
+		}
+	}
+	s.s = loaded
+	return nil
+}
+
+func (s *fileStore) saveLocked() error {
+	b, err := json.MarshalIndent(s.s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o644)
+}
+
+func (r *FileRepo) ForUser(userID string) *FileRepo {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		userID = "default"
+	}
+	return &FileRepo{
+		store:  r.store,
+		userID: userID,
+	}
+}
+
+func (r *FileRepo) userMapLocked() map[model.ProjectID]model.Project {
+	m, ok := r.store.s.Users[r.userID]
+	if !ok || m == nil {
+		m = map[model.ProjectID]model.Project{}
+		r.store.s.Users[r.userID] = m
+	}
+	return m
+}
+
+// checkLocked validates p against its siblings: unique names and a parent
+// chain that exists and never loops back to p.
+func checkLocked(m map[model.ProjectID]model.Project, p model.Project) error {
+	if err := validateProject(p); err != nil {
+		return err
+	}
+	key := nameKey(p.Name)
+	for id, other := range m {
+		if id != p.ID && nameKey(other.Name) == key {
+			return ErrNameTaken
+		}
+	}
+	seen := map[model.ProjectID]bool{p.ID: true}
+	for parent := p.ParentID; parent != nil; {
+		if seen[*parent] {
+			return ErrInvalidParent
+		}
+		seen[*parent] = true
+		next, ok := m[*parent]
+		if !ok {
+			return ErrNotFound
+		}
+		parent = next.ParentID
+	}
+	return nil
+}
+
+func (r *FileRepo) Create(p model.Project) (model.Project, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	if strings.TrimSpace(string(p.ID)) == "" {
+		p.ID = newID("prj")
+	}
+	normalizeProject(&p)
+	if err := checkLocked(m, p); err != nil {
+		return model.Project{}, err
+	}
+	p.CreatedAt = p.CreatedAt.UTC()
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = nowUTC()
+	}
+	p.UpdatedAt = nowUTC()
+	m[p.ID] = p
+	if err := r.store.saveLocked(); err != nil {
+		return model.Project{}, err
+	}
+	return p, nil
+}
+
+func (r *FileRepo) Get(id model.ProjectID) (model.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	p, ok := r.store.s.Users[r.userID][id]
+	if !ok {
+		return model.Project{}, ErrNotFound
+	}
+	return p, nil
+}
+
+func (r *FileRepo) GetByName(name string) (model.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	key := nameKey(name)
+	for _, p := range r.store.s.Users[r.userID] {
+		if nameKey(p.Name) == key {
+			return p, nil
+		}
+	}
+	return model.Project{}, ErrNotFound
+}
+
+func (r *FileRepo) Update(id model.ProjectID, patch Patch) (model.Project, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	p, ok := m[id]
+	if !ok {
+		return model.Project{}, ErrNotFound
+	}
+	applyPatch(&p, patch)
+	normalizeProject(&p)
+	if err := checkLocked(m, p); err != nil {
+		return model.Project{}, err
+	}
+	p.UpdatedAt = nowUTC()
+	m[id] = p
+	if err := r.store.saveLocked(); err != nil {
+		return model.Project{}, err
+	}
+	return p, nil
+}
+
+func (r *FileRepo) Delete(id model.ProjectID) (model.Project, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	p, ok := m[id]
+	if !ok {
+		return model.Project{}, ErrNotFound
+	}
+	delete(m, id)
+	now := nowUTC()
+	for cid, child := range m {
+		if child.ParentID != nil && *child.ParentID == id {
+			child.ParentID = p.ParentID
+			child.UpdatedAt = now
+			m[cid] = child
+		}
+	}
+	if err := r.store.saveLocked(); err != nil {
+		return model.Project{}, err
+	}
+	return p, nil
+}
+
+func (r *FileRepo) List() ([]model.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	m := r.store.s.Users[r.userID]
+	out := make([]model.Project, 0, len(m))
+	for _, p := range m {
+		out = append(out, p)
+	}
+	sortProjects(out)
+	return out, nil
+}
+
+func (r *FileRepo) EnsureNames(names []string) ([]model.Project, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	created := r.ensureNamesLocked(names)
+	if len(created) == 0 {
+		return created, nil
+	}
+	if err := r.store.saveLocked(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *FileRepo) ensureNamesLocked(names []string) []model.Project {
+	m := r.userMapLocked()
+	existing := map[string]bool{}
+	for _, p := range m {
+		existing[nameKey(p.Name)] = true
+	}
+	created := []model.Project{}
+	now := nowUTC()
+	for _, name := range names {
+		name = normalizeName(name)
+		key := nameKey(name)
+		if name == "" || key == InboxName || existing[key] {
+			continue
+		}
+		existing[key] = true
+		p := model.Project{
+			ID:        newID("prj"),
+			Name:      name,
+			Status:    model.ProjectStatusActive,
+			SortOrder: len(m),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		m[p.ID] = p
+		created = append(created, p)
+	}
+	return created
+}
+
+// MigrateTaskProjects creates entities for the free-text project names on
+// each user's tasks. A user is migrated once: the projects and the done
+// flag are saved together, and later calls skip them.
+func (r *FileRepo) MigrateTaskProjects(userIDs []string, tasksFor func(userID string) ([]model.Task, error)) error {
+	for _, uid := range userIDs {
+		r.store.mu.RLock()
+		done := r.store.s.Migrated[uid]
+		r.store.mu.RUnlock()
+		if done {
+			continue
+		}
+		tasks, err := tasksFor(uid)
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(tasks))
+		for _, t := range tasks {
+			if t.Project != nil {
+				names = append(names, *t.Project)
+			}
+		}
+
+		r.store.mu.Lock()
+		user := r.ForUser(uid)
+		user.ensureNamesLocked(names)
+		if r.store.s.Migrated == nil {
+			r.store.s.Migrated = map[string]bool{}
+		}
+		r.store.s.Migrated[user.userID] = true
+		err = r.store.saveLocked()
+		r.store.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortProjects(ps []model.Project) {
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].SortOrder != ps[j].SortOrder {
+			return ps[i].SortOrder < ps[j].SortOrder
+		}
+		return strings.ToLower(ps[i].Name) < strings.ToLower(ps[j].Name)
+	})
+}
+
+func nowUTC() time.Time {
+	return time.Now().UTC()
+}
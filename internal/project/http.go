@@ -0,0 +1,259 @@
+package project
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/task"
+)
+
+type Handler struct {
+	repo             Repo
+	repoResolver     func(*http.Request) Repo
+	taskRepoResolver func(*http.Request) task.Repo
+	playerResolver   func(*http.Request) *player.FileRepo
+}
+
+func NewHandler(repo Repo) *Handler {
+	return &Handler{repo: repo}
+}
+
+func (h *Handler) SetRepoResolver(fn func(*http.Request) Repo) {
+	h.repoResolver = fn
+}
+
+// SetTaskRepoResolver lets the handler rename task project names and
+// compute progress.
+func (h *Handler) SetTaskRepoResolver(fn func(*http.Request) task.Repo) {
+	h.taskRepoResolver = fn
+}
+
+func (h *Handler) SetPlayerResolver(fn func(*http.Request) *player.FileRepo) {
+	h.playerResolver = fn
+}
+
+func (h *Handler) repoForRequest(r *http.Request) Repo {
+	if h.repoResolver != nil {
+		if repo := h.repoResolver(r); repo != nil {
+			return repo
+		}
+	}
+	return h.repo
+}
+
+func (h *Handler) taskRepoForRequest(r *http.Request) task.Repo {
+	if h.taskRepoResolver == nil {
+		return nil
+	}
+	return h.taskRepoResolver(r)
+}
+
+func (h *Handler) locationFor(r *http.Request) *time.Location {
+	if h.playerResolver == nil {
+		return time.Local
+	}
+	return h.playerResolver(r).Location()
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeErr(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]any{"error": msg})
+}
+
+func decodeJSON(r *http.Request, out any) error {
+	return json.NewDecoder(r.Body).Decode(out)
+}
+
+func writeRepoErr(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrNotFound:
+		writeErr(w, http.StatusNotFound, err.Error())
+	case ErrNameTaken:
+		writeErr(w, http.StatusConflict, err.Error())
+	case ErrNameRequired, ErrReservedName, ErrInvalidStatus, ErrInvalidColor, ErrInvalidParent:
+		writeErr(w, http.StatusBadRequest, err.Error())
+	default:
+		writeErr(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// tasksForRequest lists every task of the user (nil without a task repo).
+func (h *Handler) tasksForRequest(r *http.Request) (task.Repo, []model.Task, error) {
+	taskRepo := h.taskRepoForRequest(r)
+	if taskRepo == nil {
+		return nil, nil, nil
+	}
+	tasks, err := taskRepo.List(task.ListFilter{Status: "all"})
+	return taskRepo, tasks, err
+}
+
+// /api/projects
+func (h *Handler) Root(w http.ResponseWriter, r *http.Request) {
+	repo := h.repoForRequest(r)
+	switch r.Method {
+	case http.MethodGet:
+		items, err := repo.List()
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if status := strings.TrimSpace(r.URL.Query().Get("status")); status != "" {
+			filtered := make([]model.Project, 0, len(items))
+			for _, p := range items {
+				if p.Status == status {
+					filtered = append(filtered, p)
+				}
+			}
+			items = filtered
+		}
+		writeJSON(w, http.StatusOK, items)
+		return
+	case http.MethodPost:
+		var in model.ProjectUpsert
+		if err := decodeJSON(r, &in); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad json")
+			return
+		}
+		out, err := repo.Create(newProjectFromUpsert(in))
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, out)
+		return
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+}
+
+// /api/projects/{id}
+// /api/projects/{id}/progress
+func (h *Handler) Sub(w http.ResponseWriter, r *http.Request) {
+	repo := h.repoForRequest(r)
+	tail := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/projects/"), "/")
+	if tail == "" {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+	parts := strings.Split(tail, "/")
+	id := model.ProjectID(parts[0])
+
+	if len(parts) == 2 && parts[1] == "progress" {
+		if r.Method != http.MethodGet {
+			writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		p, err := repo.Get(id)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		_, tasks, err := h.tasksForRequest(r)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, buildProgress(p, tasks, time.Now(), h.locationFor(r)))
+		return
+	}
+	if len(parts) != 1 {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		p, err := repo.Get(id)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, p)
+		return
+	case http.MethodPatch:
+		var patch Patch
+		if err := decodeJSON(r, &patch); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad json")
+			return
+		}
+		before, err := repo.Get(id)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		after, err := repo.Update(id, patch)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		renamed := 0
+		if after.Name != before.Name {
+			renamed, err = h.retagTasks(r, before.Name, &after.Name)
+			if err != nil {
+				writeErr(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"project":      after,
+			"tasksUpdated": renamed,
+		})
+		return
+	case http.MethodDelete:
+		p, err := repo.Delete(id)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		moved, err := h.retagTasks(r, p.Name, nil)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"ok":         true,
+			"tasksMoved": moved,
+		})
+		return
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+}
+
+// retagTasks points every task in project `from` at `to` (nil = inbox).
+func (h *Handler) retagTasks(r *http.Request, from string, to *string) (int, error) {
+	taskRepo, tasks, err := h.tasksForRequest(r)
+	if err != nil || taskRepo == nil {
+		return 0, err
+	}
+	next := InboxName
+	if to != nil {
+		next = *to
+	}
+	updates := make([]task.BatchUpdate, 0)
+	for _, t := range tasks {
+		if t.Project == nil || nameKey(*t.Project) != nameKey(from) {
+			continue
+		}
+		updates = append(updates, task.BatchUpdate{ID: t.ID, Patch: task.Patch{Project: task.Some(next)}})
+	}
+	if len(updates) == 0 {
+		return 0, nil
+	}
+	// One write: either every task moves or none does.
+	if _, err := taskRepo.UpdateMany(updates); err != nil {
+		return 0, err
+	}
+	return len(updates), nil
+}
@@ -0,0 +1,92 @@
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+func TestRoot_MigratesTaskProjectsAndRenameUpdatesTasks(t *testing.T) {
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	repo = repo.ForUser("u-project")
+	tasks := task.NewMemoryRepo()
+	h := NewHandler(repo)
+	h.SetTaskRepoResolver(func(*http.Request) task.Repo { return tasks })
+
+	home, inbox := "home", "inbox"
+	a, _ := tasks.Create(model.Task{Title: "Fix sink", Project: &home})
+	b, _ := tasks.Create(model.Task{Title: "Paint fence", Project: &home, Done: true})
+	_, _ = tasks.Create(model.Task{Title: "Loose end", Project: &inbox})
+
+	migrate := func() error {
+		return repo.MigrateTaskProjects([]string{"u-project"}, func(string) ([]model.Task, error) {
+			return tasks.List(task.ListFilter{Status: "all"})
+		})
+	}
+	if err := migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.Root(rec, httptest.NewRequest(http.MethodGet, "/api/projects", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var list []model.Project
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "home" || list[0].Status != model.ProjectStatusActive {
+		t.Fatalf("expected migrated home project, got %+v", list)
+	}
+	id := list[0].ID
+
+	// The migration runs once per user: a new free-text name is left alone.
+	garden := "garden"
+	_, _ = tasks.Create(model.Task{Title: "Weed beds", Project: &garden})
+	if err := migrate(); err != nil {
+		t.Fatalf("migrate again: %v", err)
+	}
+	if all, _ := repo.List(); len(all) != 1 {
+		t.Fatalf("expected the migration to run once, got %+v", all)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Root(rec, httptest.NewRequest(http.MethodPost, "/api/projects", bytes.NewReader([]byte(`{"name":"Home"}`))))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate name, got %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodPatch, "/api/projects/"+string(id), bytes.NewReader([]byte(`{"name":"House","color":"#4f7cac"}`))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	for _, tid := range []model.TaskID{a.ID, b.ID} {
+		got, _ := tasks.Get(tid)
+		if got.Project == nil || *got.Project != "House" {
+			t.Fatalf("expected task %s moved to House, got %v", tid, got.Project)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodGet, "/api/projects/"+string(id)+"/progress", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var progress Progress
+	if err := json.NewDecoder(rec.Body).Decode(&progress); err != nil {
+		t.Fatalf("decode progress: %v", err)
+	}
+	if progress.Total != 2 || progress.Done != 1 || progress.Percent != 50 {
+		t.Fatalf("unexpected progress: %+v", progress)
+	}
+}
@@ -0,0 +1,46 @@
+package project
+
+import (
+	"time"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+// Progress summarizes the tasks filed under a project.
+type Progress struct {
+	ProjectID       model.ProjectID `json:"projectId"`
+	Name            string          `json:"name"`
+	Total           int             `json:"total"`
+	Done            int             `json:"done"`
+	Pending         int             `json:"pending"`
+	Overdue         int             `json:"overdue"`
+	Percent         int             `json:"percent"`
+	EstimateMinutes int             `json:"estimateMinutes"`
+	TrackedSeconds  int64           `json:"trackedSeconds"`
+}
+
+func buildProgress(p model.Project, tasks []model.Task, now time.Time, loc *time.Location) Progress {
+	out := Progress{ProjectID: p.ID, Name: p.Name}
+	key := nameKey(p.Name)
+	for _, t := range tasks {
+		if t.Project == nil || nameKey(*t.Project) != key {
+			continue
+		}
+		out.Total++
+		out.EstimateMinutes += t.EstimateMinutes
+		out.TrackedSeconds += t.TrackedSeconds
+		if t.Done {
+			out.Done++
+			continue
+		}
+		out.Pending++
+		if task.IsOverdue(t, now, loc) {
+			out.Overdue++
+		}
+	}
+	if out.Total > 0 {
+		out.Percent = out.Done * 100 / out.Total
+	}
+	return out
+}
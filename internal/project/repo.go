@@ -0,0 +1,147 @@
+package project
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+var (
+	ErrNotFound      = errors.New("project not found")
+	ErrNameRequired  = errors.New("name is required")
+	ErrNameTaken     = errors.New("a project with that name already exists")
+	ErrReservedName  = errors.New("inbox is not a project")
+	ErrInvalidStatus = errors.New("status must be active, on_hold, done or archived")
+	ErrInvalidColor  = errors.New("color must be a hex value like #4f7cac")
+	ErrInvalidParent = errors.New("parent project would create a cycle")
+)
+
+// InboxName is the pseudo-project tasks default to; it never gets an entity.
+const InboxName = "inbox"
+
+// Patch represents a partial update.
+// nil pointer => "no change"; ParentID "" moves the project to the top level.
+type Patch struct {
+	Name        *string          `json:"name,omitempty"`
+	Color       *string          `json:"color,omitempty"`
+	Icon        *string          `json:"icon,omitempty"`
+	Description *string          `json:"description,omitempty"`
+	Status      *string          `json:"status,omitempty"`
+	ParentID    *model.ProjectID `json:"parentId,omitempty"`
+	SortOrder   *int             `json:"sortOrder,omitempty"`
+}
+
+type Repo interface {
+	Create(p model.Project) (model.Project, error)
+	Get(id model.ProjectID) (model.Project, error)
+	GetByName(name string) (model.Project, error)
+	Update(id model.ProjectID, patch Patch) (model.Project, error)
+	// Delete removes a project; its children move up to its parent.
+	Delete(id model.ProjectID) (model.Project, error)
+	List() ([]model.Project, error)
+
+	// EnsureNames creates active projects for names that have none yet,
+	// which is how free-text task projects migrate into entities.
+	EnsureNames(names []string) ([]model.Project, error)
+}
+
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func newID(prefix string) model.ProjectID {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return model.ProjectID(prefix + "_" + hex.EncodeToString(b[:]))
+}
+
+func normalizeName(name string) string {
+	name = strings.TrimSpace(name)
+	if len(name) > 120 {
+		name = name[:120]
+	}
+	return name
+}
+
+func nameKey(name string) string {
+	return strings.ToLower(normalizeName(name))
+}
+
+func normalizeProject(p *model.Project) {
+	p.Name = normalizeName(p.Name)
+	p.Color = strings.ToLower(strings.TrimSpace(p.Color))
+	p.Icon = strings.TrimSpace(p.Icon)
+	if len(p.Icon) > 16 {
+		p.Icon = p.Icon[:16]
+	}
+	p.Description = strings.TrimSpace(p.Description)
+	p.Status = strings.ToLower(strings.TrimSpace(p.Status))
+	if p.Status == "" {
+		p.Status = model.ProjectStatusActive
+	}
+	if p.ParentID != nil && strings.TrimSpace(string(*p.ParentID)) == "" {
+		p.ParentID = nil
+	}
+}
+
+func validateProject(p model.Project) error {
+	if p.Name == "" {
+		return ErrNameRequired
+	}
+	if nameKey(p.Name) == InboxName {
+		return ErrReservedName
+	}
+	switch p.Status {
+	case model.ProjectStatusActive, model.ProjectStatusOnHold, model.ProjectStatusDone, model.ProjectStatusArchived:
+	default:
+		return ErrInvalidStatus
+	}
+	if p.Color != "" && !hexColor.MatchString(p.Color) {
+		return ErrInvalidColor
+	}
+	return nil
+}
+
+func applyPatch(p *model.Project, patch Patch) {
+	if patch.Name != nil {
+		p.Name = *patch.Name
+	}
+	if patch.Color != nil {
+		p.Color = *patch.Color
+	}
+	if patch.Icon != nil {
+		p.Icon = *patch.Icon
+	}
+	if patch.Description != nil {
+		p.Description = *patch.Description
+	}
+	if patch.Status != nil {
+		p.Status = *patch.Status
+	}
+	if patch.ParentID != nil {
+		parent := *patch.ParentID
+		p.ParentID = &parent
+	}
+	if patch.SortOrder != nil {
+		p.SortOrder = *patch.SortOrder
+	}
+}
+
+func newProjectFromUpsert(u model.ProjectUpsert) model.Project {
+	now := time.Now().UTC()
+	return model.Project{
+		ID:          newID("prj"),
+		Name:        u.Name,
+		Color:       u.Color,
+		Icon:        u.Icon,
+		Description: u.Description,
+		Status:      u.Status,
+		ParentID:    u.ParentID,
+		SortOrder:   u.SortOrder,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"donegeon/internal/config"
 	"donegeon/internal/model"
 	"donegeon/internal/player"
 	"donegeon/internal/task"
@@ -41,6 +42,7 @@ type StateResponse struct {
 type Handler struct {
 	taskRepoResolver func(*http.Request) task.Repo
 	playerResolver   func(*http.Request) *player.FileRepo
+	cfg              *config.Config
 }
 
 func NewHandler() *Handler {
@@ -55,15 +57,49 @@ func (h *Handler) SetPlayerResolver(fn func(*http.Request) *player.FileRepo) {
 	h.playerResolver = fn
 }
 
-func (h *Handler) tasksForRequest(r *http.Request) ([]model.Task, error) {
+// SetConfig lets review reports show task priorities.
+func (h *Handler) SetConfig(cfg *config.Config) {
+	h.cfg = cfg
+}
+
+func (h *Handler) tasksForRequest(r *http.Request) (task.Repo, []model.Task, error) {
 	if h.taskRepoResolver == nil {
-		return []model.Task{}, nil
+		return nil, []model.Task{}, nil
 	}
 	repo := h.taskRepoResolver(r)
 	if repo == nil {
-		return []model.Task{}, nil
+		return nil, []model.Task{}, nil
 	}
-	return repo.List(task.ListFilter{Status: "all"})
+	ts, err := repo.List(task.ListFilter{Status: "all"})
+	return repo, ts, err
+}
+
+// completionTimes returns when t was completed according to its activity
+// log, at most once per day. Tasks with no logged completion (created done,
+// or older than the log) fall back to UpdatedAt.
+func completionTimes(repo task.Repo, t model.Task, loc *time.Location) []time.Time {
+	var entries []model.TaskActivity
+	if repo != nil {
+		entries, _ = repo.History(t.ID)
+	}
+	out := make([]time.Time, 0)
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if e.Action != "completed" {
+			continue
+		}
+		at := e.At.In(loc)
+		day := at.Format("2006-01-02")
+		if seen[day] {
+			continue
+		}
+		seen[day] = true
+		out = append(out, at)
+	}
+	if len(out) == 0 && t.Done {
+		out = append(out, t.UpdatedAt.In(loc))
+	}
+	return out
 }
 
 func (h *Handler) playerStateForRequest(r *http.Request) player.UserState {
@@ -77,6 +113,15 @@ func (h *Handler) playerStateForRequest(r *http.Request) player.UserState {
 	return repo.GetState()
 }
 
+// locationForRequest is the user's timezone, so quest windows roll over at
+// their midnight.
+func (h *Handler) locationForRequest(r *http.Request) *time.Location {
+	if h.playerResolver == nil {
+		return time.Local
+	}
+	return h.playerResolver(r).Location()
+}
+
 func writeJSON(w http.ResponseWriter, code int, v any) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(code)
@@ -89,13 +134,13 @@ func (h *Handler) State(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	allTasks, err := h.tasksForRequest(r)
+	taskRepo, allTasks, err := h.tasksForRequest(r)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 		return
 	}
 	playerState := h.playerStateForRequest(r)
-	now := time.Now().In(time.Local)
+	now := time.Now().In(h.locationForRequest(r))
 
 	doneToday := 0
 	doneThisWeek := 0
@@ -111,35 +156,24 @@ func (h *Handler) State(w http.ResponseWriter, r *http.Request) {
 		if !t.Done && t.AssignedVillagerID != nil && strings.TrimSpace(*t.AssignedVillagerID) != "" {
 			assignedPending++
 		}
-		if !t.Done {
-			continue
-		}
-		updated := t.UpdatedAt.In(time.Local)
-		if isSameLocalDay(updated, now) {
-			doneToday++
-		}
-		if isWithinWindow(updated, weekStart, weekEnd) {
-			doneThisWeek++
-		}
-		if isWithinWindow(updated, monthStart, monthEnd) {
-			doneThisMonth++
-		}
-		if isWithinWindow(updated, seasonStart, seasonEnd) {
-			doneThisSeason++
+		for _, completed := range completionTimes(taskRepo, t, now.Location()) {
+			if isSameLocalDay(completed, now) {
+				doneToday++
+			}
+			if isWithinWindow(completed, weekStart, weekEnd) {
+				doneThisWeek++
+			}
+			if isWithinWindow(completed, monthStart, monthEnd) {
+				doneThisMonth++
+			}
+			if isWithinWindow(completed, seasonStart, seasonEnd) {
+				doneThisSeason++
+			}
 		}
 	}
 
-	deckOpens := 0
-	for _, n := range playerState.DeckOpens {
-		deckOpens += n
-	}
-	metrics := playerState.Metrics
-	tasksCompleted := metrics[player.MetricTasksCompleted]
-	if tasksCompleted < doneThisSeason {
-		tasksCompleted = doneThisSeason
-	}
-	zombiesCleared := metrics[player.MetricZombiesCleared]
-	overrunLevel := metrics[player.MetricOverrunLevel]
+	review := yearlyReviewFor(playerState, doneThisSeason)
+	overrunLevel := review.OverrunLevel
 
 	resp := StateResponse{
 		Daily: []QuestItem{
@@ -149,22 +183,16 @@ func (h *Handler) State(w http.ResponseWriter, r *http.Request) {
 		},
 		Weekly: []QuestItem{
 			quest("WQ_Complete5", "Complete 5 Tasks", "Weekly", doneThisWeek, 5, "Build momentum through the week."),
-			quest("WQ_Open3Decks", "Open 3 Decks", "Weekly", deckOpens, 3, "Draw new cards to expand options."),
+			quest("WQ_Open3Decks", "Open 3 Decks", "Weekly", review.DeckOpens, 3, "Draw new cards to expand options."),
 		},
 		Monthly: []QuestItem{
 			quest("MQ_Complete20", "Complete 20 Tasks", "Monthly", doneThisMonth, 20, "Sustain consistency this month."),
 		},
 		Seasonal: []QuestItem{
 			quest("SQ_Complete60", "Complete 60 Tasks", "Seasonal", doneThisSeason, 60, "Finish a full seasonal arc."),
-			quest("SQ_Clear10Zombies", "Clear 10 Zombies", "Seasonal", zombiesCleared, 10, "Stay ahead of overdue pressure."),
-		},
-		YearlyReview: YearlyReview{
-			TasksCompleted: tasksCompleted,
-			ZombiesCleared: zombiesCleared,
-			DeckOpens:      deckOpens,
-			OverrunLevel:   overrunLevel,
-			Title:          yearlyTitle(tasksCompleted, zombiesCleared),
+			quest("SQ_Clear10Zombies", "Clear 10 Zombies", "Seasonal", review.ZombiesCleared, 10, "Stay ahead of overdue pressure."),
 		},
+		YearlyReview: review,
 	}
 
 	sort.Slice(resp.Daily, func(i, j int) bool { return resp.Daily[i].ID < resp.Daily[j].ID })
@@ -203,6 +231,28 @@ func boolToProgress(v bool) int {
 	return 0
 }
 
+// yearlyReviewFor reads the review from the player's lifetime metrics.
+// minCompleted covers completions the metric missed (tasks done before it
+// was tracked).
+func yearlyReviewFor(st player.UserState, minCompleted int) YearlyReview {
+	deckOpens := 0
+	for _, n := range st.DeckOpens {
+		deckOpens += n
+	}
+	tasksCompleted := st.Metrics[player.MetricTasksCompleted]
+	if tasksCompleted < minCompleted {
+		tasksCompleted = minCompleted
+	}
+	zombiesCleared := st.Metrics[player.MetricZombiesCleared]
+	return YearlyReview{
+		TasksCompleted: tasksCompleted,
+		ZombiesCleared: zombiesCleared,
+		DeckOpens:      deckOpens,
+		OverrunLevel:   st.Metrics[player.MetricOverrunLevel],
+		Title:          yearlyTitle(tasksCompleted, zombiesCleared),
+	}
+}
+
 func yearlyTitle(tasksCompleted, zombiesCleared int) string {
 	switch {
 	case tasksCompleted >= 250 && zombiesCleared >= 25:
@@ -238,6 +288,11 @@ func timeWindowMonth(now time.Time) (time.Time, time.Time) {
 	return start, start.AddDate(0, 1, 0)
 }
 
+func timeWindowYear(now time.Time) (time.Time, time.Time) {
+	start := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+	return start, start.AddDate(1, 0, 0)
+}
+
 func timeWindowSeason(now time.Time) (time.Time, time.Time) {
 	month := int(now.Month())
 	seasonStartMonth := ((month-1)/3)*3 + 1
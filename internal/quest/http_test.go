@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"donegeon/internal/model"
@@ -98,3 +99,66 @@ func TestState_MethodNotAllowed(t *testing.T) {
 		t.Fatalf("expected 405, got %d", rec.Code)
 	}
 }
+
+func TestReview_WeeklyReportAsJSONAndMarkdown(t *testing.T) {
+	taskRepo := task.NewMemoryRepo()
+	playerRepo, err := player.NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new player repo: %v", err)
+	}
+	playerRepo = playerRepo.ForUser("u-review")
+
+	home := "home"
+	done := true
+	for _, title := range []string{"Fix the tap", "Hang shelves"} {
+		created, err := taskRepo.Create(model.Task{Title: title, Project: &home})
+		if err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+		if _, err := taskRepo.Update(created.ID, task.Patch{Done: &done}); err != nil {
+			t.Fatalf("complete task: %v", err)
+		}
+	}
+	if _, err := taskRepo.Create(model.Task{Title: "Still open", Project: &home}); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	h := NewHandler()
+	h.SetTaskRepoResolver(func(_ *http.Request) task.Repo { return taskRepo })
+	h.SetPlayerResolver(func(_ *http.Request) *player.FileRepo { return playerRepo })
+
+	rec := httptest.NewRecorder()
+	h.Review(rec, httptest.NewRequest(http.MethodGet, "/api/quests/review", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out Review
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatalf("decode review: %v", err)
+	}
+	if out.Period != PeriodWeek || out.TasksCompleted != 2 || len(out.Completed) != 2 ||
+		len(out.Projects) != 1 || out.Projects[0].Completed != 2 || out.YearlyReview != nil {
+		t.Fatalf("unexpected weekly review: %+v", out)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Review(rec, httptest.NewRequest(http.MethodGet, "/api/quests/review?period=year&format=markdown", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Header().Get("Content-Type"), "text/markdown") {
+		t.Fatalf("expected markdown, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, want := range []string{" in review\n", "- Tasks completed: 2\n", "- Title earned: Awakening\n", "- [x] Fix the tap\n"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in report:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "Still open") {
+		t.Fatalf("expected open task left out:\n%s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Review(rec, httptest.NewRequest(http.MethodGet, "/api/quests/review?period=decade", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown period, got %d", rec.Code)
+	}
+}
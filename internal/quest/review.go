@@ -0,0 +1,192 @@
+package quest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+// Review periods.
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+)
+
+type ProjectCount struct {
+	Project   string `json:"project"`
+	Completed int    `json:"completed"`
+}
+
+// Review is what got done in one week, month or year. Tasks repeated in the
+// period count once per completion but are listed once.
+type Review struct {
+	Period         string         `json:"period"`
+	Start          string         `json:"start"` // first day, in the user's timezone
+	End            string         `json:"end"`   // last day
+	Title          string         `json:"title"`
+	TasksCompleted int            `json:"tasksCompleted"`
+	Projects       []ProjectCount `json:"projects"`
+	YearlyReview   *YearlyReview  `json:"yearlyReview,omitempty"` // year only
+	Completed      []model.Task   `json:"completed"`
+}
+
+// buildReview collects the tasks completed in the period around now. The
+// listed copies are marked done as of their last completion in the period.
+func buildReview(period string, now time.Time, repo task.Repo, tasks []model.Task) Review {
+	var start, end time.Time
+	switch period {
+	case PeriodMonth:
+		start, end = timeWindowMonth(now)
+	case PeriodYear:
+		start, end = timeWindowYear(now)
+	default:
+		period = PeriodWeek
+		start, end = timeWindowWeek(now)
+	}
+	out := Review{
+		Period:    period,
+		Start:     start.Format("2006-01-02"),
+		End:       end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Completed: []model.Task{},
+	}
+
+	byProject := map[string]int{}
+	for _, t := range tasks {
+		var last time.Time
+		n := 0
+		for _, at := range completionTimes(repo, t, now.Location()) {
+			if !isWithinWindow(at, start, end) {
+				continue
+			}
+			n++
+			if at.After(last) {
+				last = at
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		out.TasksCompleted += n
+		project := "inbox"
+		if t.Project != nil && strings.TrimSpace(*t.Project) != "" {
+			project = *t.Project
+		}
+		byProject[project] += n
+		t.Done = true
+		t.UpdatedAt = last
+		out.Completed = append(out.Completed, t)
+	}
+	sort.SliceStable(out.Completed, func(i, j int) bool {
+		return out.Completed[i].UpdatedAt.Before(out.Completed[j].UpdatedAt)
+	})
+	out.Projects = make([]ProjectCount, 0, len(byProject))
+	for p, n := range byProject {
+		out.Projects = append(out.Projects, ProjectCount{Project: p, Completed: n})
+	}
+	sort.Slice(out.Projects, func(i, j int) bool {
+		if out.Projects[i].Completed != out.Projects[j].Completed {
+			return out.Projects[i].Completed > out.Projects[j].Completed
+		}
+		return out.Projects[i].Project < out.Projects[j].Project
+	})
+
+	switch period {
+	case PeriodMonth:
+		out.Title = start.Format("January 2006")
+	case PeriodYear:
+		out.Title = strconv.Itoa(start.Year()) + " in review"
+	default:
+		out.Title = "Week of " + out.Start
+	}
+	return out
+}
+
+// markdownIntro is the report's summary, written above the task list.
+func (rv Review) markdownIntro() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "_%s – %s_\n\n", rv.Start, rv.End)
+	fmt.Fprintf(&b, "- Tasks completed: %d\n", rv.TasksCompleted)
+	if rv.YearlyReview != nil {
+		fmt.Fprintf(&b, "- Title earned: %s\n", rv.YearlyReview.Title)
+		fmt.Fprintf(&b, "- Zombies cleared: %d\n", rv.YearlyReview.ZombiesCleared)
+		fmt.Fprintf(&b, "- Decks opened: %d\n", rv.YearlyReview.DeckOpens)
+	}
+	for _, p := range rv.Projects {
+		fmt.Fprintf(&b, "- %s: %d\n", p.Project, p.Completed)
+	}
+	return b.String()
+}
+
+// GET /api/quests/review?period=week&date=2026-10-12&format=markdown&template=checklist
+//
+// What got done in the week, month or year containing date (today by
+// default). format=json (default) returns the Review; markdown and todotxt
+// render it as a shareable report, with template choosing the Markdown
+// layout as for /api/tasks/export.
+func (h *Handler) Review(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	period := strings.ToLower(strings.TrimSpace(q.Get("period")))
+	switch period {
+	case "":
+		period = PeriodWeek
+	case PeriodWeek, PeriodMonth, PeriodYear:
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "period must be week, month or year"})
+		return
+	}
+	format := strings.ToLower(strings.TrimSpace(q.Get("format")))
+	var export task.TextExport
+	if format != "" && format != "json" {
+		var err error
+		if export, err = task.ParseTextExport(format, q.Get("template")); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+	}
+
+	loc := h.locationForRequest(r)
+	now := time.Now().In(loc)
+	if raw := strings.TrimSpace(q.Get("date")); raw != "" {
+		d, err := time.ParseInLocation("2006-01-02", raw, loc)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "date must be YYYY-MM-DD"})
+			return
+		}
+		now = d
+	}
+
+	taskRepo, allTasks, err := h.tasksForRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	review := buildReview(period, now, taskRepo, allTasks)
+	if period == PeriodYear {
+		yr := yearlyReviewFor(h.playerStateForRequest(r), review.TasksCompleted)
+		review.YearlyReview = &yr
+	}
+
+	if export.Format == "" {
+		writeJSON(w, http.StatusOK, review)
+		return
+	}
+	export.Title = review.Title
+	export.Intro = review.markdownIntro()
+	export.Config = h.cfg
+	export.Now = now
+	w.Header().Set("Content-Type", export.ContentType())
+	w.Header().Set("Content-Disposition", `inline; filename="`+export.Filename("review-"+review.Start)+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(task.RenderTasks(review.Completed, export)))
+}
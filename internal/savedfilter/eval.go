@@ -0,0 +1,90 @@
+package savedfilter
+
+import (
+	"sort"
+	"time"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+// Group is one bucket of an evaluated filter.
+type Group struct {
+	Key   string       `json:"key"`
+	Tasks []model.Task `json:"tasks"`
+}
+
+// statusOrder is the fixed bucket order for GroupByStatus.
+var statusOrder = []string{"overdue", "due_today", "upcoming", "no_due", "done"}
+
+// Evaluate runs a saved filter against a task repo. cfg supplies priority
+// levels for SortPriority and may be nil.
+func Evaluate(repo task.Repo, f model.SavedFilter, cfg *config.Config, now time.Time, loc *time.Location) ([]model.Task, []Group, error) {
+	tasks, err := repo.List(ListFilter(f.Query, loc))
+	if err != nil {
+		return nil, nil, err
+	}
+	task.SortTasks(tasks, f.Sort, cfg)
+	if f.GroupBy == "" {
+		return tasks, nil, nil
+	}
+	return tasks, groupTasks(tasks, f.GroupBy, now, loc), nil
+}
+
+func groupTasks(tasks []model.Task, groupBy string, now time.Time, loc *time.Location) []Group {
+	byKey := map[string][]model.Task{}
+	add := func(key string, t model.Task) {
+		byKey[key] = append(byKey[key], t)
+	}
+	today := task.Today(now, loc)
+	for _, t := range tasks {
+		switch groupBy {
+		case GroupByProject:
+			key := "inbox"
+			if t.Project != nil && *t.Project != "" {
+				key = *t.Project
+			}
+			add(key, t)
+		case GroupByTag:
+			if len(t.Tags) == 0 {
+				add("", t)
+			}
+			for _, tag := range t.Tags {
+				add(tag, t)
+			}
+		case GroupByStatus:
+			switch {
+			case t.Done:
+				add("done", t)
+			case task.IsOverdue(t, now, loc):
+				add("overdue", t)
+			case t.DueDate == nil:
+				add("no_due", t)
+			case *t.DueDate == today:
+				add("due_today", t)
+			default:
+				add("upcoming", t)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(byKey))
+	if groupBy == GroupByStatus {
+		for _, k := range statusOrder {
+			if _, ok := byKey[k]; ok {
+				keys = append(keys, k)
+			}
+		}
+	} else {
+		for k := range byKey {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+	out := make([]Group, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, Group{Key: k, Tasks: byKey[k]})
+	}
+	return out
+}
@@ -0,0 +1,220 @@
+package savedfilter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+type fileState struct {
+	Users map[string]map[model.SavedFilterID]model.SavedFilter `json:"users"`
+}
+
+type fileStore struct {
+	mu   sync.RWMutex
+	path string
+	s    fileState
+}
+
+// FileRepo is a persistent saved-filter repo scoped by user.
+type FileRepo struct {
+	store  *fileStore
+	userID string
+}
+
+func NewFileRepo(dataDir string) (*FileRepo, error) {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return nil, err
+	}
+	st := &fileStore{
+		path: filepath.Join(dataDir, "filters.json"),
+		s: fileState{
+			Users: map[string]map[model.SavedFilterID]model.SavedFilter{},
+		},
+	}
+	if err := st.load(); err != nil {
+		return nil, err
+	}
+	return &FileRepo{
+		store:  st,
+		userID: "default",
+	}, nil
+}
+
+func (s *fileStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.s.Users = map[string]map[model.SavedFilterID]model.SavedFilter{}
+			return nil
+		}
+		return err
+	}
+	var loaded fileState
+	if err := json.Unmarshal(b, &loaded); err != nil {
+		return err
+	}
+	if loaded.Users == nil {
+		loaded.Users = map[string]map[model.SavedFilterID]model.SavedFilter{}
+	}
+	for uid, m := range loaded.Users {
+		if m == nil {
+			loaded.Users[uid] = map[model.SavedFilterID]model.SavedFilter{}
+		}
+	}
+	s.s = loaded
+	return nil
+}
+
+func (s *fileStore) saveLocked() error {
+	b, err := json.MarshalIndent(s.s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o644)
+}
+
+func (r *FileRepo) ForUser(userID string) *FileRepo {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		userID = "default"
+	}
+	return &FileRepo{
+		store:  r.store,
+		userID: userID,
+	}
+}
+
+func (r *FileRepo) userMapLocked() map[model.SavedFilterID]model.SavedFilter {
+	m, ok := r.store.s.Users[r.userID]
+	if !ok || m == nil {
+		m = map[model.SavedFilterID]model.SavedFilter{}
+		r.store.s.Users[r.userID] = m
+	}
+	return m
+}
+
+func (r *FileRepo) Create(f model.SavedFilter) (model.SavedFilter, error) {
+	normalizeFilter(&f)
+	if err := validateFilter(f); err != nil {
+		return model.SavedFilter{}, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	if strings.TrimSpace(string(f.ID)) == "" {
+		f.ID = newID("flt")
+	}
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = nowUTC()
+	}
+	f.UpdatedAt = nowUTC()
+	m[f.ID] = f
+	if err := r.store.saveLocked(); err != nil {
+		return model.SavedFilter{}, err
+	}
+	return f, nil
+}
+
+func (r *FileRepo) Get(id model.SavedFilterID) (model.SavedFilter, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	f, ok := r.store.s.Users[r.userID][id]
+	if !ok {
+		return model.SavedFilter{}, ErrNotFound
+	}
+	return f, nil
+}
+
+func (r *FileRepo) Update(id model.SavedFilterID, p Patch) (model.SavedFilter, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	f, ok := m[id]
+	if !ok {
+		return model.SavedFilter{}, ErrNotFound
+	}
+	applyPatch(&f, p)
+	normalizeFilter(&f)
+	if err := validateFilter(f); err != nil {
+		return model.SavedFilter{}, err
+	}
+	f.UpdatedAt = nowUTC()
+	m[id] = f
+	if err := r.store.saveLocked(); err != nil {
+		return model.SavedFilter{}, err
+	}
+	return f, nil
+}
+
+func (r *FileRepo) Delete(id model.SavedFilterID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	if _, ok := m[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m, id)
+	return r.store.saveLocked()
+}
+
+func (r *FileRepo) List() ([]model.SavedFilter, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	m := r.store.s.Users[r.userID]
+	out := make([]model.SavedFilter, 0, len(m))
+	for _, f := range m {
+		out = append(out, f)
+	}
+	sortFilters(out)
+	return out, nil
+}
+
+func (r *FileRepo) Shared() ([]Owned, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	out := []Owned{}
+	for uid, m := range r.store.s.Users {
+		for _, f := range m {
+			if f.Shared {
+				out = append(out, Owned{OwnerID: uid, Filter: f})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].OwnerID != out[j].OwnerID {
+			return out[i].OwnerID < out[j].OwnerID
+		}
+		return out[i].Filter.Name < out[j].Filter.Name
+	})
+	return out, nil
+}
+
+func sortFilters(fs []model.SavedFilter) {
+	sort.Slice(fs, func(i, j int) bool {
+		if fs[i].SortOrder != fs[j].SortOrder {
+			return fs[i].SortOrder < fs[j].SortOrder
+		}
+		return strings.ToLower(fs[i].Name) < strings.ToLower(fs[j].Name)
+	})
+}
+
+func nowUTC() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,292 @@
+package savedfilter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/task"
+)
+
+type Handler struct {
+	repo             Repo
+	repoResolver     func(*http.Request) Repo
+	taskRepoResolver func(*http.Request) task.Repo
+	playerResolver   func(*http.Request) *player.FileRepo
+	shareResolver    func(*http.Request, string) bool
+	cfg              *config.Config
+}
+
+// NewHandler takes the unscoped repo; it is used to find filters that
+// teammates share.
+func NewHandler(repo Repo) *Handler {
+	return &Handler{repo: repo}
+}
+
+func (h *Handler) SetConfig(cfg *config.Config) {
+	h.cfg = cfg
+}
+
+func (h *Handler) SetRepoResolver(fn func(*http.Request) Repo) {
+	h.repoResolver = fn
+}
+
+func (h *Handler) SetTaskRepoResolver(fn func(*http.Request) task.Repo) {
+	h.taskRepoResolver = fn
+}
+
+func (h *Handler) SetPlayerResolver(fn func(*http.Request) *player.FileRepo) {
+	h.playerResolver = fn
+}
+
+// SetShareResolver reports whether the requester may use filters shared by
+// ownerID (i.e. they are on the owner's team).
+func (h *Handler) SetShareResolver(fn func(r *http.Request, ownerID string) bool) {
+	h.shareResolver = fn
+}
+
+func (h *Handler) repoForRequest(r *http.Request) Repo {
+	if h.repoResolver != nil {
+		if repo := h.repoResolver(r); repo != nil {
+			return repo
+		}
+	}
+	return h.repo
+}
+
+func (h *Handler) taskRepoForRequest(r *http.Request) task.Repo {
+	if h.taskRepoResolver == nil {
+		return nil
+	}
+	return h.taskRepoResolver(r)
+}
+
+func (h *Handler) locationFor(r *http.Request) *time.Location {
+	if h.playerResolver == nil {
+		return time.Local
+	}
+	return h.playerResolver(r).Location()
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeErr(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]any{"error": msg})
+}
+
+func decodeJSON(r *http.Request, out any) error {
+	return json.NewDecoder(r.Body).Decode(out)
+}
+
+func writeRepoErr(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrNotFound:
+		writeErr(w, http.StatusNotFound, err.Error())
+	case ErrNameRequired, ErrInvalidSort, ErrInvalidGroupBy:
+		writeErr(w, http.StatusBadRequest, err.Error())
+	default:
+		writeErr(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// filterView is a filter as listed; OwnerID marks one shared by a teammate.
+type filterView struct {
+	model.SavedFilter
+	Count   *int   `json:"count,omitempty"`
+	OwnerID string `json:"ownerId,omitempty"`
+}
+
+// sharedWith lists the teammates' shared filters the requester can use.
+func (h *Handler) sharedWith(r *http.Request) ([]Owned, error) {
+	if h.shareResolver == nil {
+		return nil, nil
+	}
+	all, err := h.repo.Shared()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Owned, 0, len(all))
+	for _, o := range all {
+		if h.shareResolver(r, o.OwnerID) {
+			out = append(out, o)
+		}
+	}
+	return out, nil
+}
+
+// find returns one of the requester's filters, or a teammate's shared one
+// (with its owner ID).
+func (h *Handler) find(r *http.Request, id model.SavedFilterID) (model.SavedFilter, string, error) {
+	f, err := h.repoForRequest(r).Get(id)
+	if err != ErrNotFound {
+		return f, "", err
+	}
+	shared, err := h.sharedWith(r)
+	if err != nil {
+		return model.SavedFilter{}, "", err
+	}
+	for _, o := range shared {
+		if o.Filter.ID == id {
+			return o.Filter, o.OwnerID, nil
+		}
+	}
+	return model.SavedFilter{}, "", ErrNotFound
+}
+
+func (h *Handler) count(r *http.Request, f model.SavedFilter) *int {
+	taskRepo := h.taskRepoForRequest(r)
+	if !f.ShowCount || taskRepo == nil {
+		return nil
+	}
+	tasks, err := taskRepo.List(ListFilter(f.Query, h.locationFor(r)))
+	if err != nil {
+		return nil
+	}
+	n := len(tasks)
+	return &n
+}
+
+// /api/filters
+func (h *Handler) Root(w http.ResponseWriter, r *http.Request) {
+	repo := h.repoForRequest(r)
+	switch r.Method {
+	case http.MethodGet:
+		items, err := repo.List()
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		shared, err := h.sharedWith(r)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		out := make([]filterView, 0, len(items)+len(shared))
+		for _, f := range items {
+			out = append(out, filterView{SavedFilter: f, Count: h.count(r, f)})
+		}
+		for _, o := range shared {
+			out = append(out, filterView{SavedFilter: o.Filter, Count: h.count(r, o.Filter), OwnerID: o.OwnerID})
+		}
+		writeJSON(w, http.StatusOK, out)
+		return
+	case http.MethodPost:
+		var in model.SavedFilterUpsert
+		if err := decodeJSON(r, &in); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad json")
+			return
+		}
+		out, err := repo.Create(newFilterFromUpsert(in))
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, out)
+		return
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+}
+
+// /api/filters/{id}
+// /api/filters/{id}/tasks
+func (h *Handler) Sub(w http.ResponseWriter, r *http.Request) {
+	repo := h.repoForRequest(r)
+	tail := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/filters/"), "/")
+	if tail == "" {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+	parts := strings.Split(tail, "/")
+	id := model.SavedFilterID(parts[0])
+
+	if len(parts) == 2 && parts[1] == "tasks" {
+		if r.Method != http.MethodGet {
+			writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		f, _, err := h.find(r, id)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		taskRepo := h.taskRepoForRequest(r)
+		if taskRepo == nil {
+			writeErr(w, http.StatusInternalServerError, "task repository unavailable")
+			return
+		}
+		tasks, groups, err := Evaluate(taskRepo, f, h.cfg, time.Now(), h.locationFor(r))
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		resp := map[string]any{
+			"filter": f,
+			"count":  len(tasks),
+			"tasks":  tasks,
+		}
+		if groups != nil {
+			resp["groups"] = groups
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	if len(parts) != 1 {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		f, owner, err := h.find(r, id)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, filterView{SavedFilter: f, Count: h.count(r, f), OwnerID: owner})
+		return
+	case http.MethodPatch:
+		var p Patch
+		if err := decodeJSON(r, &p); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad json")
+			return
+		}
+		f, err := repo.Update(id, p)
+		if err != nil {
+			h.writeOwnerOnly(w, r, id, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, f)
+		return
+	case http.MethodDelete:
+		if err := repo.Delete(id); err != nil {
+			h.writeOwnerOnly(w, r, id, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+		return
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+}
+
+// writeOwnerOnly turns a not-found on a teammate's shared filter into 403.
+func (h *Handler) writeOwnerOnly(w http.ResponseWriter, r *http.Request, id model.SavedFilterID, err error) {
+	if err == ErrNotFound {
+		if _, owner, ferr := h.find(r, id); ferr == nil && owner != "" {
+			writeErr(w, http.StatusForbidden, "only the owner can change a shared filter")
+			return
+		}
+	}
+	writeRepoErr(w, err)
+}
@@ -0,0 +1,95 @@
+package savedfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+func TestSub_EvaluatesGroupsAndSharesWithTeam(t *testing.T) {
+	base, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	owner, teammate := base.ForUser("u-owner"), base.ForUser("u-mate")
+	tasks := task.NewMemoryRepo()
+
+	asUser := map[string]Repo{"owner": owner, "mate": teammate}
+	h := NewHandler(base)
+	h.SetRepoResolver(func(r *http.Request) Repo { return asUser[r.Header.Get("X-Test-User")] })
+	h.SetTaskRepoResolver(func(*http.Request) task.Repo { return tasks })
+	h.SetShareResolver(func(r *http.Request, ownerID string) bool {
+		return r.Header.Get("X-Test-User") == "mate" && ownerID == "u-owner"
+	})
+	do := func(user, method, path, body string, fn http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("X-Test-User", user)
+		rec := httptest.NewRecorder()
+		fn(rec, req)
+		return rec
+	}
+
+	home, work := "home", "work"
+	_, _ = tasks.Create(model.Task{Title: "Zebra", Project: &home, Tags: []string{"errand"}})
+	_, _ = tasks.Create(model.Task{Title: "Apple", Project: &work, Tags: []string{"Errand"}})
+	_, _ = tasks.Create(model.Task{Title: "Untagged", Project: &home})
+
+	rec := do("owner", http.MethodPost, "/api/filters", `{"name":"Errands","query":{"tags":["errand"]},"sort":"bogus"}`, h.Root)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad sort, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	rec = do("owner", http.MethodPost, "/api/filters", `{"name":"Errands","query":{"tags":["errand"]},"sort":"title","groupBy":"project","showCount":true,"shared":true}`, h.Root)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var created model.SavedFilter
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode created: %v", err)
+	}
+
+	rec = do("owner", http.MethodGet, "/api/filters/"+string(created.ID)+"/tasks", "", h.Sub)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var evaluated struct {
+		Count  int          `json:"count"`
+		Tasks  []model.Task `json:"tasks"`
+		Groups []Group      `json:"groups"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&evaluated); err != nil {
+		t.Fatalf("decode evaluation: %v", err)
+	}
+	if evaluated.Count != 2 || evaluated.Tasks[0].Title != "Apple" || evaluated.Tasks[1].Title != "Zebra" {
+		t.Fatalf("unexpected tasks: %+v", evaluated)
+	}
+	if len(evaluated.Groups) != 2 || evaluated.Groups[0].Key != "home" || evaluated.Groups[1].Key != "work" {
+		t.Fatalf("unexpected groups: %+v", evaluated.Groups)
+	}
+
+	rec = do("mate", http.MethodGet, "/api/filters", "", h.Root)
+	var listed []struct {
+		model.SavedFilter
+		Count   *int   `json:"count"`
+		OwnerID string `json:"ownerId"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(listed) != 1 || listed[0].OwnerID != "u-owner" || listed[0].Count == nil || *listed[0].Count != 2 {
+		t.Fatalf("expected shared filter with count for teammate, got %+v", listed)
+	}
+
+	rec = do("mate", http.MethodPatch, "/api/filters/"+string(created.ID), `{"name":"Mine now"}`, h.Sub)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for teammate edit, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	rec = do("owner", http.MethodDelete, "/api/filters/"+string(created.ID), "", h.Sub)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
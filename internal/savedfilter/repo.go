@@ -0,0 +1,148 @@
+package savedfilter
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+var (
+	ErrNotFound       = errors.New("filter not found")
+	ErrNameRequired   = errors.New("name is required")
+	ErrInvalidSort    = errors.New("sort must be due, created, updated, priority or title")
+	ErrInvalidGroupBy = errors.New("groupBy must be project, tag or status")
+)
+
+// Group orders accepted by SavedFilter.GroupBy.
+const (
+	GroupByProject = "project"
+	GroupByTag     = "tag"
+	GroupByStatus  = "status"
+)
+
+// Patch represents a partial update.
+// nil pointer => "no change"
+type Patch struct {
+	Name      *string            `json:"name,omitempty"`
+	Icon      *string            `json:"icon,omitempty"`
+	Query     *model.FilterQuery `json:"query,omitempty"`
+	Sort      *string            `json:"sort,omitempty"`
+	GroupBy   *string            `json:"groupBy,omitempty"`
+	ShowCount *bool              `json:"showCount,omitempty"`
+	Shared    *bool              `json:"shared,omitempty"`
+	SortOrder *int               `json:"sortOrder,omitempty"`
+}
+
+// Owned is a filter together with the user who saved it.
+type Owned struct {
+	OwnerID string
+	Filter  model.SavedFilter
+}
+
+type Repo interface {
+	Create(f model.SavedFilter) (model.SavedFilter, error)
+	Get(id model.SavedFilterID) (model.SavedFilter, error)
+	Update(id model.SavedFilterID, patch Patch) (model.SavedFilter, error)
+	Delete(id model.SavedFilterID) error
+	List() ([]model.SavedFilter, error)
+
+	// Shared lists every user's shared filters; callers check team access.
+	Shared() ([]Owned, error)
+}
+
+func newID(prefix string) model.SavedFilterID {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return model.SavedFilterID(prefix + "_" + hex.EncodeToString(b[:]))
+}
+
+func normalizeFilter(f *model.SavedFilter) {
+	f.Name = strings.TrimSpace(f.Name)
+	if len(f.Name) > 80 {
+		f.Name = f.Name[:80]
+	}
+	f.Icon = strings.TrimSpace(f.Icon)
+	f.Sort = strings.ToLower(strings.TrimSpace(f.Sort))
+	f.GroupBy = strings.ToLower(strings.TrimSpace(f.GroupBy))
+	f.Query.Status = strings.ToLower(strings.ReplaceAll(f.Query.Status, " ", ""))
+	f.Query.Project = strings.TrimSpace(f.Query.Project)
+	f.Query.Tags = task.NormalizeTags(f.Query.Tags)
+	f.Query.Deferred = strings.ToLower(strings.TrimSpace(f.Query.Deferred))
+}
+
+func validateFilter(f model.SavedFilter) error {
+	if f.Name == "" {
+		return ErrNameRequired
+	}
+	if f.Sort != "" && !task.IsSortOrder(f.Sort) {
+		return ErrInvalidSort
+	}
+	switch f.GroupBy {
+	case "", GroupByProject, GroupByTag, GroupByStatus:
+	default:
+		return ErrInvalidGroupBy
+	}
+	return nil
+}
+
+func applyPatch(f *model.SavedFilter, p Patch) {
+	if p.Name != nil {
+		f.Name = *p.Name
+	}
+	if p.Icon != nil {
+		f.Icon = *p.Icon
+	}
+	if p.Query != nil {
+		f.Query = *p.Query
+	}
+	if p.Sort != nil {
+		f.Sort = *p.Sort
+	}
+	if p.GroupBy != nil {
+		f.GroupBy = *p.GroupBy
+	}
+	if p.ShowCount != nil {
+		f.ShowCount = *p.ShowCount
+	}
+	if p.Shared != nil {
+		f.Shared = *p.Shared
+	}
+	if p.SortOrder != nil {
+		f.SortOrder = *p.SortOrder
+	}
+}
+
+func newFilterFromUpsert(u model.SavedFilterUpsert) model.SavedFilter {
+	now := time.Now().UTC()
+	return model.SavedFilter{
+		ID:        newID("flt"),
+		Name:      u.Name,
+		Icon:      u.Icon,
+		Query:     u.Query,
+		Sort:      u.Sort,
+		GroupBy:   u.GroupBy,
+		ShowCount: u.ShowCount,
+		Shared:    u.Shared,
+		SortOrder: u.SortOrder,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// ListFilter converts a stored query into a task repo filter.
+func ListFilter(q model.FilterQuery, loc *time.Location) task.ListFilter {
+	return task.ListFilter{
+		Status:   q.Status,
+		Project:  q.Project,
+		Tags:     append([]string{}, q.Tags...),
+		Live:     q.Live,
+		Deferred: q.Deferred,
+		Archived: "exclude",
+		Location: loc,
+	}
+}
@@ -10,15 +10,26 @@ import (
 	"strings"
 	"time"
 
+	"donegeon/internal/account"
 	"donegeon/internal/auth"
 	"donegeon/internal/blueprint"
 	"donegeon/internal/board"
+	"donegeon/internal/caldav"
+	"donegeon/internal/capture"
 	"donegeon/internal/config"
 	"donegeon/internal/httpmw"
+	"donegeon/internal/importer"
+	"donegeon/internal/mailin"
+	"donegeon/internal/model"
 	"donegeon/internal/player"
 	"donegeon/internal/plugin"
+	"donegeon/internal/project"
 	"donegeon/internal/quest"
+	"donegeon/internal/savedfilter"
+	"donegeon/internal/tag"
 	"donegeon/internal/task"
+	"donegeon/internal/timetrack"
+	"donegeon/internal/webhook"
 	"donegeon/static"
 	"donegeon/ui/page"
 
@@ -31,6 +42,9 @@ type Options struct {
 	StaticDir     string
 	UseDiskStatic bool
 	Logger        *log.Logger
+	// Mail, when set, is wired to create tasks from inbound mail; the
+	// caller runs its listener.
+	Mail *mailin.Server
 }
 
 func NewHandler(opts Options) (http.Handler, error) {
@@ -78,6 +92,36 @@ func NewHandler(opts Options) (http.Handler, error) {
 	mux.HandleFunc("/api/auth/verify-otp", authHandler.VerifyOTP)
 	mux.HandleFunc("/api/auth/session", authHandler.Session)
 	mux.HandleFunc("/api/auth/logout", authHandler.Logout)
+	mux.Handle("/api/auth/tokens", authService.RequireAPI(http.HandlerFunc(authHandler.Tokens)))
+	mux.Handle("/api/auth/tokens/", authService.RequireAPI(http.HandlerFunc(authHandler.TokensSub)))
+
+	webhookRepo, err := webhook.NewFileRepo(filepath.Join(opts.DataDir, "webhooks"))
+	if err != nil {
+		return nil, err
+	}
+	outboundGuard := webhook.GuardFromEnv()
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo)
+	webhookDispatcher.SetGuard(outboundGuard)
+	webhookDispatcher.Resume()
+	webhookHandler := webhook.NewHandler(webhookRepo, webhookDispatcher)
+	webhookHandler.SetGuard(outboundGuard)
+	webhookHandler.SetRepoResolver(func(r *http.Request) *webhook.FileRepo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return webhookRepo
+		}
+		return webhookRepo.ForUser(u.ID)
+	})
+	mux.Handle("/api/webhooks", authService.RequireAPI(http.HandlerFunc(webhookHandler.Root)))
+	mux.Handle("/api/webhooks/", authService.RequireAPI(http.HandlerFunc(webhookHandler.Sub)))
+	mux.Handle("/api/webhooks/events", authService.RequireAPI(http.HandlerFunc(webhookHandler.EventsList)))
+	publisherFor := func(r *http.Request) webhook.Publisher {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return nil
+		}
+		return webhookDispatcher.ForUser(u.ID)
+	}
 
 	playerRepo, err := player.NewFileRepo(filepath.Join(opts.DataDir, "player"))
 	if err != nil {
@@ -91,18 +135,29 @@ func NewHandler(opts Options) (http.Handler, error) {
 		}
 		return playerRepo.ForUser(u.ID)
 	})
+	playerHandler.SetPublisherResolver(publisherFor)
+	playerHandler.SetEmailResolver(func(r *http.Request) string {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return ""
+		}
+		return u.Email
+	})
 	mux.Handle("/api/player/state", authService.RequireAPI(http.HandlerFunc(playerHandler.State)))
 	mux.Handle("/api/player/unlock", authService.RequireAPI(http.HandlerFunc(playerHandler.Unlock)))
 	mux.Handle("/api/player/profile", authService.RequireAPI(http.HandlerFunc(playerHandler.Profile)))
 	mux.Handle("/api/player/onboarding/complete", authService.RequireAPI(http.HandlerFunc(playerHandler.CompleteOnboarding)))
 	mux.Handle("/api/player/team", authService.RequireAPI(http.HandlerFunc(playerHandler.Team)))
 	mux.Handle("/api/player/team/invite", authService.RequireAPI(http.HandlerFunc(playerHandler.TeamInvite)))
+	mux.Handle("/api/player/team/invitations", authService.RequireAPI(http.HandlerFunc(playerHandler.TeamInvitations)))
+	mux.Handle("/api/player/team/accept", authService.RequireAPI(http.HandlerFunc(playerHandler.TeamAccept)))
 
 	pluginRepo, err := plugin.NewFileRepo(filepath.Join(opts.DataDir, "plugins"))
 	if err != nil {
 		return nil, err
 	}
 	pluginHandler := plugin.NewHandler(pluginRepo)
+	pluginHandler.SetGuard(outboundGuard)
 	pluginHandler.SetRepoResolver(func(r *http.Request) plugin.Repo {
 		u, ok := auth.UserFromContext(r.Context())
 		if !ok {
@@ -121,6 +176,16 @@ func NewHandler(opts Options) (http.Handler, error) {
 	mux.Handle("/api/plugins/register", authService.RequireAPI(http.HandlerFunc(pluginHandler.Register)))
 	mux.Handle("/api/plugins/install", authService.RequireAPI(http.HandlerFunc(pluginHandler.Install)))
 	mux.Handle("/api/plugins/uninstall", authService.RequireAPI(http.HandlerFunc(pluginHandler.Uninstall)))
+	mux.Handle("/api/plugins/runs", authService.RequireAPI(http.HandlerFunc(pluginHandler.Runs)))
+	pluginRuntime := plugin.NewRuntime(pluginRepo)
+	pluginRuntime.SetGuard(outboundGuard)
+	pluginRunnerFor := func(r *http.Request) task.PluginRunner {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return nil
+		}
+		return pluginRuntime.ForUser(u.ID)
+	}
 
 	taskFileRepo, err := task.NewFileRepo(filepath.Join(opts.DataDir, "tasks"))
 	if err != nil {
@@ -142,9 +207,291 @@ func NewHandler(opts Options) (http.Handler, error) {
 		}
 		return playerRepo.ForUser(u.ID)
 	})
+	taskHandler.SetActorResolver(func(r *http.Request) task.Actor {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return task.Actor{}
+		}
+		name := strings.TrimSpace(playerRepo.ForUser(u.ID).GetProfile().DisplayName)
+		if name == "" {
+			name = u.Email
+		}
+		return task.Actor{ID: u.ID, Name: name}
+	})
+	taskHandler.SetSharedRepoResolver(func(r *http.Request, id model.TaskID) task.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return nil
+		}
+		ownerID, ok := taskFileRepo.OwnerOf(id)
+		if !ok || ownerID == u.ID || !playerRepo.ForUser(ownerID).HasActiveTeamMember(u.Email) {
+			return nil
+		}
+		return taskFileRepo.ForUser(ownerID)
+	})
+	timeRepo, err := timetrack.NewFileRepo(filepath.Join(opts.DataDir, "time"))
+	if err != nil {
+		return nil, err
+	}
+	taskHandler.SetWorkLoggerResolver(func(r *http.Request) task.WorkLogger {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return timeRepo
+		}
+		return timeRepo.ForUser(u.ID)
+	})
+	taskHandler.SetPublisherResolver(publisherFor)
+	taskHandler.SetPluginResolver(pluginRunnerFor)
+	pluginRuntime.SetTaskRepoResolver(func(userID string) task.Repo {
+		return taskFileRepo.ForUser(userID)
+	})
+	pluginRuntime.SetCompleter(func(userID string, repo task.Repo, id model.TaskID) error {
+		_, err := taskHandler.Complete(repo, playerRepo.ForUser(userID), webhookDispatcher.ForUser(userID), id)
+		return err
+	})
+	pluginRuntime.Resume()
 	mux.Handle("/api/tasks", authService.RequireAPI(http.HandlerFunc(taskHandler.TasksRoot)))
 	mux.Handle("/api/tasks/", authService.RequireAPI(http.HandlerFunc(taskHandler.TasksSub)))
 	mux.Handle("/api/tasks/live", authService.RequireAPI(http.HandlerFunc(taskHandler.TasksLive)))
+	mux.Handle("/api/tasks/bulk", authService.RequireAPI(http.HandlerFunc(taskHandler.TasksBulk)))
+	mux.Handle("/api/tasks/export", authService.RequireAPI(http.HandlerFunc(taskHandler.TasksExport)))
+	mux.Handle("/api/tasks/import/ics", authService.RequireAPI(http.HandlerFunc(taskHandler.ImportICS)))
+	mux.Handle("/api/calendar/feed.ics", authService.RequireToken(auth.ScopeCalendarFeed, http.HandlerFunc(taskHandler.CalendarFeed)))
+
+	caldavHandler := caldav.NewHandler(taskFileRepo)
+	caldavHandler.SetPluginResolver(pluginRunnerFor)
+	caldavHandler.SetPublisherResolver(publisherFor)
+	caldavHandler.SetUpdater(taskHandler.Apply)
+	caldavHandler.SetRepoResolver(func(r *http.Request) task.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return taskFileRepo
+		}
+		return taskFileRepo.ForUser(u.ID).WithOrigin(task.SourceCalDAV, "")
+	})
+	caldavHandler.SetPlayerResolver(func(r *http.Request) *player.FileRepo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return playerRepo
+		}
+		return playerRepo.ForUser(u.ID)
+	})
+	mux.Handle(caldav.Prefix, authService.RequireBasicToken(auth.ScopeCalDAV, "Donegeon CalDAV", http.HandlerFunc(caldavHandler.DAV)))
+	mux.Handle("/.well-known/caldav", http.RedirectHandler(caldav.Prefix, http.StatusMovedPermanently))
+
+	captureHandler := capture.NewHandler()
+	captureHandler.SetConfig(opts.Config)
+	captureHandler.SetRepoResolver(func(r *http.Request) task.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return nil
+		}
+		return taskFileRepo.ForUser(u.ID)
+	})
+	captureHandler.SetPlayerResolver(func(r *http.Request) *player.FileRepo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return playerRepo
+		}
+		return playerRepo.ForUser(u.ID)
+	})
+	captureHandler.SetKeyResolver(func(r *http.Request) string {
+		t, _ := auth.APITokenFromContext(r.Context())
+		return t.ID
+	})
+	captureHandler.SetPublisherResolver(publisherFor)
+	mux.Handle("/api/capture/", authService.RequirePathToken(auth.ScopeCapture, "/api/capture/", http.HandlerFunc(captureHandler.Capture)))
+
+	attachmentRepo, err := mailin.NewFileRepo(filepath.Join(opts.DataDir, "mail"))
+	if err != nil {
+		return nil, err
+	}
+	attachmentHandler := mailin.NewHandler(attachmentRepo)
+	attachmentHandler.SetRepoResolver(func(r *http.Request) *mailin.FileRepo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return attachmentRepo
+		}
+		return attachmentRepo.ForUser(u.ID)
+	})
+	mux.Handle("/api/attachments", authService.RequireAPI(http.HandlerFunc(attachmentHandler.Root)))
+	mux.Handle("/api/attachments/", authService.RequireAPI(http.HandlerFunc(attachmentHandler.Sub)))
+	if opts.Mail != nil {
+		// Mail goes to <capture token>@<domain>, so the same tokens (and
+		// revoking them) cover both capture paths.
+		inbox := mailin.NewTaskInbox(attachmentRepo,
+			func(token string) (string, bool) {
+				u, _, ok := authService.AuthenticateAPIToken(token, auth.ScopeCapture, time.Now())
+				return u.ID, ok
+			},
+			func(userID string) task.Repo {
+				return taskFileRepo.ForUser(userID)
+			})
+		inbox.SetPublisherResolver(func(userID string) webhook.Publisher {
+			return webhookDispatcher.ForUser(userID)
+		})
+		inbox.SetLogger(opts.Logger)
+		opts.Mail.SetInbox(inbox)
+	}
+
+	timeHandler := timetrack.NewHandler(timeRepo)
+	timeHandler.SetRepoResolver(func(r *http.Request) timetrack.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return timeRepo
+		}
+		return timeRepo.ForUser(u.ID)
+	})
+	timeHandler.SetTaskRepoResolver(func(r *http.Request) task.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return taskFileRepo
+		}
+		return taskFileRepo.ForUser(u.ID)
+	})
+	mux.Handle("/api/time/entries", authService.RequireAPI(http.HandlerFunc(timeHandler.Entries)))
+	mux.Handle("/api/time/entries/", authService.RequireAPI(http.HandlerFunc(timeHandler.EntriesSub)))
+	mux.Handle("/api/time/timer", authService.RequireAPI(http.HandlerFunc(timeHandler.Timer)))
+	mux.Handle("/api/time/timer/start", authService.RequireAPI(http.HandlerFunc(timeHandler.TimerStart)))
+	mux.Handle("/api/time/timer/stop", authService.RequireAPI(http.HandlerFunc(timeHandler.TimerStop)))
+	mux.Handle("/api/time/report", authService.RequireAPI(http.HandlerFunc(timeHandler.Report)))
+
+	projectRepo, err := project.NewFileRepo(filepath.Join(opts.DataDir, "projects"))
+	if err != nil {
+		return nil, err
+	}
+	// Tasks from before project entities keep their names as free text;
+	// give them entities once per user.
+	if err := projectRepo.MigrateTaskProjects(taskFileRepo.UserIDs(), func(userID string) ([]model.Task, error) {
+		return taskFileRepo.ForUser(userID).List(task.ListFilter{Status: "all"})
+	}); err != nil {
+		return nil, err
+	}
+	// From then on, any task write that names a project (API, board,
+	// imports, capture, CalDAV, blueprints, plugins) gets it an entity.
+	taskFileRepo.OnProjects(func(userID string, names []string) {
+		if _, err := projectRepo.ForUser(userID).EnsureNames(names); err != nil {
+			opts.Logger.Printf("[projects] ensure %q for %s: %v", names, userID, err)
+		}
+	})
+	projectHandler := project.NewHandler(projectRepo)
+	projectHandler.SetRepoResolver(func(r *http.Request) project.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return projectRepo
+		}
+		return projectRepo.ForUser(u.ID)
+	})
+	projectHandler.SetTaskRepoResolver(func(r *http.Request) task.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return taskFileRepo
+		}
+		return taskFileRepo.ForUser(u.ID)
+	})
+	projectHandler.SetPlayerResolver(func(r *http.Request) *player.FileRepo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return playerRepo
+		}
+		return playerRepo.ForUser(u.ID)
+	})
+	mux.Handle("/api/projects", authService.RequireAPI(http.HandlerFunc(projectHandler.Root)))
+	mux.Handle("/api/projects/", authService.RequireAPI(http.HandlerFunc(projectHandler.Sub)))
+
+	tagRepo, err := tag.NewFileRepo(filepath.Join(opts.DataDir, "tags"))
+	if err != nil {
+		return nil, err
+	}
+	tagHandler := tag.NewHandler(tagRepo)
+	tagHandler.SetRepoResolver(func(r *http.Request) tag.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return tagRepo
+		}
+		return tagRepo.ForUser(u.ID)
+	})
+	tagHandler.SetTaskRepoResolver(func(r *http.Request) task.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return taskFileRepo
+		}
+		return taskFileRepo.ForUser(u.ID)
+	})
+	mux.Handle("/api/tags", authService.RequireAPI(http.HandlerFunc(tagHandler.Root)))
+	mux.Handle("/api/tags/merge", authService.RequireAPI(http.HandlerFunc(tagHandler.Merge)))
+	mux.Handle("/api/tags/", authService.RequireAPI(http.HandlerFunc(tagHandler.Sub)))
+
+	filterRepo, err := savedfilter.NewFileRepo(filepath.Join(opts.DataDir, "filters"))
+	if err != nil {
+		return nil, err
+	}
+	filterHandler := savedfilter.NewHandler(filterRepo)
+	filterHandler.SetConfig(opts.Config)
+	filterHandler.SetRepoResolver(func(r *http.Request) savedfilter.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return filterRepo
+		}
+		return filterRepo.ForUser(u.ID)
+	})
+	filterHandler.SetTaskRepoResolver(func(r *http.Request) task.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return taskFileRepo
+		}
+		return taskFileRepo.ForUser(u.ID)
+	})
+	filterHandler.SetPlayerResolver(func(r *http.Request) *player.FileRepo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return playerRepo
+		}
+		return playerRepo.ForUser(u.ID)
+	})
+	filterHandler.SetShareResolver(func(r *http.Request, ownerID string) bool {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok || ownerID == u.ID {
+			return false
+		}
+		return playerRepo.ForUser(ownerID).HasActiveTeamMember(u.Email)
+	})
+	mux.Handle("/api/filters", authService.RequireAPI(http.HandlerFunc(filterHandler.Root)))
+	mux.Handle("/api/filters/", authService.RequireAPI(http.HandlerFunc(filterHandler.Sub)))
+
+	importRepo, err := importer.NewFileRepo(filepath.Join(opts.DataDir, "imports"))
+	if err != nil {
+		return nil, err
+	}
+	importHandler := importer.NewHandler(importRepo)
+	importHandler.SetPluginResolver(pluginRunnerFor)
+	importHandler.SetPublisherResolver(publisherFor)
+	importHandler.SetConfig(opts.Config)
+	importHandler.SetRepoResolver(func(r *http.Request) importer.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return importRepo
+		}
+		return importRepo.ForUser(u.ID)
+	})
+	importHandler.SetTaskRepoResolver(func(r *http.Request) task.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return taskFileRepo
+		}
+		return taskFileRepo.ForUser(u.ID)
+	})
+	importHandler.SetPlayerResolver(func(r *http.Request) *player.FileRepo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return playerRepo
+		}
+		return playerRepo.ForUser(u.ID)
+	})
+	mux.Handle("/api/import/sources", authService.RequireAPI(http.HandlerFunc(importHandler.Sources)))
+	mux.Handle("/api/import/jobs", authService.RequireAPI(http.HandlerFunc(importHandler.Jobs)))
+	mux.Handle("/api/import/jobs/", authService.RequireAPI(http.HandlerFunc(importHandler.JobSub)))
+	mux.Handle("/api/import/undo", authService.RequireAPI(http.HandlerFunc(importHandler.Undo)))
 
 	blueprintRepo, err := blueprint.NewFileRepo(filepath.Join(opts.DataDir, "blueprints"))
 	if err != nil {
@@ -158,6 +505,34 @@ func NewHandler(opts Options) (http.Handler, error) {
 		}
 		return blueprintRepo.ForUser(u.ID)
 	})
+	blueprintHandler.SetTaskRepoResolver(func(r *http.Request) task.Repo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return taskFileRepo
+		}
+		return taskFileRepo.ForUser(u.ID)
+	})
+	blueprintHandler.SetPlayerResolver(func(r *http.Request) *player.FileRepo {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return playerRepo
+		}
+		return playerRepo.ForUser(u.ID)
+	})
+	blueprintHandler.SetTeamResolver(func(r *http.Request, team string) (string, bool) {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return "", false
+		}
+		ownerID := strings.TrimPrefix(team, "team_")
+		if ownerID == "" || ownerID == u.ID {
+			return u.ID, true
+		}
+		return ownerID, playerRepo.ForUser(ownerID).HasActiveTeamMember(u.Email)
+	})
+	blueprintHandler.SetConfig(opts.Config)
+	blueprintHandler.SetPublisherResolver(publisherFor)
+	blueprintHandler.SetPluginResolver(pluginRunnerFor)
 	mux.Handle("/api/blueprints", authService.RequireAPI(http.HandlerFunc(blueprintHandler.Root)))
 	mux.Handle("/api/blueprints/", authService.RequireAPI(http.HandlerFunc(blueprintHandler.Sub)))
 
@@ -176,7 +551,9 @@ func NewHandler(opts Options) (http.Handler, error) {
 		}
 		return playerRepo.ForUser(u.ID)
 	})
+	questHandler.SetConfig(opts.Config)
 	mux.Handle("/api/quests/state", authService.RequireAPI(http.HandlerFunc(questHandler.State)))
+	mux.Handle("/api/quests/review", authService.RequireAPI(http.HandlerFunc(questHandler.Review)))
 
 	boardRepo, err := board.NewFileRepo(filepath.Join(opts.DataDir, "boards"))
 	if err != nil {
@@ -192,7 +569,7 @@ func NewHandler(opts Options) (http.Handler, error) {
 		if boardName == "" {
 			boardName = "default"
 		}
-		return "user_" + u.ID + "__" + boardName
+		return board.UserBoardID(u.ID, boardName)
 	})
 	boardHandler.SetTaskRepoResolver(func(r *http.Request) task.Repo {
 		u, ok := auth.UserFromContext(r.Context())
@@ -208,9 +585,37 @@ func NewHandler(opts Options) (http.Handler, error) {
 		}
 		return playerRepo.ForUser(u.ID)
 	})
+	boardHandler.SetPublisherResolver(publisherFor)
+	boardHandler.SetPluginResolver(pluginRunnerFor)
 	mux.Handle("/api/board/state", authService.RequireAPI(http.HandlerFunc(boardHandler.GetState)))
 	mux.Handle("/api/board/cmd", authService.RequireAPI(http.HandlerFunc(boardHandler.Command)))
 
+	accountHandler := account.NewHandler(account.Stores{
+		Auth:        authRepo,
+		Tasks:       taskFileRepo,
+		Boards:      boardRepo,
+		Players:     playerRepo,
+		Blueprints:  blueprintRepo,
+		Plugins:     pluginRepo,
+		Projects:    projectRepo,
+		Tags:        tagRepo,
+		Filters:     filterRepo,
+		Time:        timeRepo,
+		Attachments: attachmentRepo,
+		Webhooks:    webhookRepo,
+		Imports:     importRepo,
+	})
+	accountHandler.SetUserResolver(func(r *http.Request) string {
+		u, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return ""
+		}
+		return u.ID
+	})
+	mux.Handle("/api/account/export", authService.RequireAPI(http.HandlerFunc(accountHandler.Export)))
+	mux.Handle("/api/account/import", authService.RequireAPI(http.HandlerFunc(accountHandler.Import)))
+	blueprintHandler.SetBoardCommand(boardHandler.RunCommand)
+
 	mux.Handle("/api/config", authService.RequireAPI(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		enc := json.NewEncoder(w)
@@ -0,0 +1,196 @@
+package tag
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+type fileState struct {
+	Users map[string]map[string]model.Tag `json:"users"`
+}
+
+type fileStore struct {
+	mu   sync.RWMutex
+	path string
+	s    fileState
+}
+
+// FileRepo is a persistent tag metadata repo scoped by user.
+type FileRepo struct {
+	store  *fileStore
+	userID string
+}
+
+func NewFileRepo(dataDir string) (*FileRepo, error) {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return nil, err
+	}
+	st := &fileStore{
+		path: filepath.Join(dataDir, "tags.json"),
+		s: fileState{
+			Users: map[string]map[string]model.Tag{},
+		},
+	}
+	if err := st.load(); err != nil {
+		return nil, err
+	}
+	return &FileRepo{
+		store:  st,
+		userID: "default",
+	}, nil
+}
+
+func (s *fileStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.s.Users = map[string]map[string]model.Tag{}
+			return nil
+		}
+		return err
+	}
+	var loaded fileState
+	if err := json.Unmarshal(b, &loaded); err != nil {
+		return err
+	}
+	if loaded.Users == nil {
+		loaded.Users = map[string]map[string]model.Tag{}
+	}
+	for uid, m := range loaded.Users {
+		if m == nil {
+			loaded.Users[uid] = map[string]model.Tag{}
+		}
+	}
+	s.s = loaded
+	return nil
+}
+
+func (s *fileStore) saveLocked() error {
+	b, err := json.MarshalIndent(s.s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o644)
+}
+
+func (r *FileRepo) ForUser(userID string) *FileRepo {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		userID = "default"
+	}
+	return &FileRepo{
+		store:  r.store,
+		userID: userID,
+	}
+}
+
+func (r *FileRepo) userMapLocked() map[string]model.Tag {
+	m, ok := r.store.s.Users[r.userID]
+	if !ok || m == nil {
+		m = map[string]model.Tag{}
+		r.store.s.Users[r.userID] = m
+	}
+	return m
+}
+
+func (r *FileRepo) List() ([]model.Tag, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	m := r.store.s.Users[r.userID]
+	out := make([]model.Tag, 0, len(m))
+	for _, t := range m {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (r *FileRepo) Get(name string) (model.Tag, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	t, ok := r.store.s.Users[r.userID][name]
+	if !ok {
+		return model.Tag{}, ErrNotFound
+	}
+	return t, nil
+}
+
+func (r *FileRepo) SetColor(name, color string) (model.Tag, error) {
+	if name == "" {
+		return model.Tag{}, ErrNameRequired
+	}
+	color, err := normalizeColor(color)
+	if err != nil {
+		return model.Tag{}, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	now := nowUTC()
+	t, ok := m[name]
+	if !ok {
+		t = model.Tag{Name: name, CreatedAt: now}
+	}
+	t.Color = color
+	t.UpdatedAt = now
+	m[name] = t
+	if err := r.store.saveLocked(); err != nil {
+		return model.Tag{}, err
+	}
+	return t, nil
+}
+
+func (r *FileRepo) Rename(from, to string) error {
+	if to == "" {
+		return ErrNameRequired
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	src, ok := m[from]
+	if !ok || from == to {
+		return nil
+	}
+	delete(m, from)
+	dst, exists := m[to]
+	if !exists {
+		dst = src
+		dst.Name = to
+	} else if dst.Color == "" {
+		dst.Color = src.Color
+	}
+	dst.UpdatedAt = nowUTC()
+	m[to] = dst
+	return r.store.saveLocked()
+}
+
+func (r *FileRepo) Delete(name string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	if _, ok := m[name]; !ok {
+		return nil
+	}
+	delete(m, name)
+	return r.store.saveLocked()
+}
+
+func nowUTC() time.Time {
+	return time.Now().UTC()
+}
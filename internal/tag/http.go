@@ -0,0 +1,270 @@
+package tag
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+type Handler struct {
+	repo             Repo
+	repoResolver     func(*http.Request) Repo
+	taskRepoResolver func(*http.Request) task.Repo
+}
+
+func NewHandler(repo Repo) *Handler {
+	return &Handler{repo: repo}
+}
+
+func (h *Handler) SetRepoResolver(fn func(*http.Request) Repo) {
+	h.repoResolver = fn
+}
+
+func (h *Handler) SetTaskRepoResolver(fn func(*http.Request) task.Repo) {
+	h.taskRepoResolver = fn
+}
+
+func (h *Handler) repoForRequest(r *http.Request) Repo {
+	if h.repoResolver != nil {
+		if repo := h.repoResolver(r); repo != nil {
+			return repo
+		}
+	}
+	return h.repo
+}
+
+func (h *Handler) taskRepoForRequest(r *http.Request) task.Repo {
+	if h.taskRepoResolver == nil {
+		return nil
+	}
+	return h.taskRepoResolver(r)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeErr(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]any{"error": msg})
+}
+
+func decodeJSON(r *http.Request, out any) error {
+	return json.NewDecoder(r.Body).Decode(out)
+}
+
+// /api/tags
+func (h *Handler) Root(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	meta, err := h.repoForRequest(r).List()
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	byName := map[string]*model.TagSummary{}
+	for _, t := range meta {
+		byName[t.Name] = &model.TagSummary{Name: t.Name, Color: t.Color}
+	}
+	if taskRepo := h.taskRepoForRequest(r); taskRepo != nil {
+		tasks, err := taskRepo.List(task.ListFilter{Status: "all"})
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, t := range tasks {
+			for _, name := range t.Tags {
+				s, ok := byName[name]
+				if !ok {
+					s = &model.TagSummary{Name: name}
+					byName[name] = s
+				}
+				s.Count++
+				if !t.Done {
+					s.Open++
+				}
+			}
+		}
+	}
+
+	out := make([]model.TagSummary, 0, len(byName))
+	for _, s := range byName {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	writeJSON(w, http.StatusOK, out)
+}
+
+// /api/tags/merge  { "from": ["@Home", "home"], "into": "@home" }
+func (h *Handler) Merge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var in struct {
+		From []string `json:"from"`
+		Into string   `json:"into"`
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad json")
+		return
+	}
+	into := task.NormalizeTag(in.Into)
+	if into == "" || len(in.From) == 0 {
+		writeErr(w, http.StatusBadRequest, "from and into are required")
+		return
+	}
+	updated, err := h.retag(r, in.From, into)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tag":          into,
+		"tasksUpdated": updated,
+	})
+}
+
+// /api/tags/{name}
+func (h *Handler) Sub(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/api/tags/"))
+	if name == "" {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+	repo := h.repoForRequest(r)
+
+	switch r.Method {
+	case http.MethodPatch:
+		var in struct {
+			Name  *string `json:"name"`
+			Color *string `json:"color"`
+		}
+		if err := decodeJSON(r, &in); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad json")
+			return
+		}
+		current := name
+		updated := 0
+		if in.Name != nil {
+			to := task.NormalizeTag(*in.Name)
+			if to == "" {
+				writeErr(w, http.StatusBadRequest, ErrNameRequired.Error())
+				return
+			}
+			var err error
+			// Renaming onto an existing tag merges the two.
+			if updated, err = h.retag(r, []string{name}, to); err != nil {
+				writeErr(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			current = to
+		}
+		if in.Color != nil {
+			if _, err := repo.SetColor(current, *in.Color); err != nil {
+				if err == ErrInvalidColor {
+					writeErr(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				writeErr(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		out := model.Tag{Name: current}
+		if t, err := repo.Get(current); err == nil {
+			out = t
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"tag":          out,
+			"tasksUpdated": updated,
+		})
+		return
+	case http.MethodDelete:
+		updated, err := h.retag(r, []string{name}, "")
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"ok":           true,
+			"tasksUpdated": updated,
+		})
+		return
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+}
+
+// retag replaces the `from` tags (exact names, so legacy spellings can be
+// targeted) with `into` on every task and moves their metadata; an empty
+// `into` removes them.
+func (h *Handler) retag(r *http.Request, from []string, into string) (int, error) {
+	fromSet := map[string]bool{}
+	for _, f := range from {
+		if f = strings.TrimSpace(f); f != "" {
+			fromSet[f] = true
+		}
+	}
+
+	updated := 0
+	if taskRepo := h.taskRepoForRequest(r); taskRepo != nil {
+		tasks, err := taskRepo.List(task.ListFilter{Status: "all"})
+		if err != nil {
+			return 0, err
+		}
+		updates := make([]task.BatchUpdate, 0)
+		for _, t := range tasks {
+			next := make([]string, 0, len(t.Tags))
+			changed := false
+			for _, tg := range t.Tags {
+				if !fromSet[tg] {
+					next = append(next, tg)
+					continue
+				}
+				changed = true
+				if into != "" {
+					next = append(next, into)
+				}
+			}
+			if !changed {
+				continue
+			}
+			updates = append(updates, task.BatchUpdate{ID: t.ID, Patch: task.Patch{Tags: task.Some(next)}})
+		}
+		if len(updates) > 0 {
+			// One write: either every task is retagged or none is.
+			if _, err := taskRepo.UpdateMany(updates); err != nil {
+				return 0, err
+			}
+		}
+		updated = len(updates)
+	}
+
+	repo := h.repoForRequest(r)
+	for f := range fromSet {
+		var err error
+		if into == "" {
+			err = repo.Delete(f)
+		} else {
+			err = repo.Rename(f, into)
+		}
+		if err != nil {
+			return updated, err
+		}
+	}
+	return updated, nil
+}
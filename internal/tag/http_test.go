@@ -0,0 +1,92 @@
+package tag
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+func TestSub_RenameMergesAndDeleteStripsTasks(t *testing.T) {
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	repo = repo.ForUser("u-tag")
+	tasks := task.NewMemoryRepo()
+	h := NewHandler(repo)
+	h.SetTaskRepoResolver(func(*http.Request) task.Repo { return tasks })
+
+	a, _ := tasks.Create(model.Task{Title: "Groceries", Tags: []string{" @Home ", "errand"}})
+	b, _ := tasks.Create(model.Task{Title: "Laundry", Tags: []string{"house"}})
+	if got := a.Tags; len(got) != 2 || got[0] != "@home" {
+		t.Fatalf("expected tags normalized on create, got %v", got)
+	}
+
+	rec := httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodPatch, "/api/tags/house", bytes.NewReader([]byte(`{"name":"@Home","color":"#AA3300"}`))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got, _ := tasks.Get(b.ID); len(got.Tags) != 1 || got.Tags[0] != "@home" {
+		t.Fatalf("expected house renamed to @home, got %v", got.Tags)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Root(rec, httptest.NewRequest(http.MethodGet, "/api/tags", nil))
+	var list []model.TagSummary
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "@home" || list[0].Count != 2 || list[0].Color != "#aa3300" {
+		t.Fatalf("unexpected tag list: %+v", list)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodDelete, "/api/tags/errand", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got, _ := tasks.Get(a.ID); len(got.Tags) != 1 || got.Tags[0] != "@home" {
+		t.Fatalf("expected errand removed, got %v", got.Tags)
+	}
+}
+
+// failingBatch refuses batch writes, as a store that cannot save would.
+type failingBatch struct {
+	task.Repo
+}
+
+func (failingBatch) UpdateMany([]task.BatchUpdate) ([]model.Task, error) {
+	return nil, errors.New("disk full")
+}
+
+func TestSub_RenameRetagsEveryTaskOrNone(t *testing.T) {
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	repo = repo.ForUser("u-tag")
+	tasks := task.NewMemoryRepo()
+	h := NewHandler(repo)
+	h.SetTaskRepoResolver(func(*http.Request) task.Repo { return failingBatch{tasks} })
+
+	a, _ := tasks.Create(model.Task{Title: "Groceries", Tags: []string{"errand"}})
+	b, _ := tasks.Create(model.Task{Title: "Post office", Tags: []string{"errand"}})
+
+	rec := httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodPatch, "/api/tags/errand", bytes.NewReader([]byte(`{"name":"out"}`))))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	for _, id := range []model.TaskID{a.ID, b.ID} {
+		if got, _ := tasks.Get(id); len(got.Tags) != 1 || got.Tags[0] != "errand" {
+			t.Fatalf("expected no task retagged after a failed write, got %v", got.Tags)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package tag
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"donegeon/internal/model"
+)
+
+var (
+	ErrNotFound     = errors.New("tag not found")
+	ErrNameRequired = errors.New("tag name is required")
+	ErrInvalidColor = errors.New("color must be a hex value like #4f7cac")
+)
+
+// Repo stores tag metadata (colors). Which tasks carry a tag is owned by the
+// task repo.
+type Repo interface {
+	List() ([]model.Tag, error)
+	Get(name string) (model.Tag, error)
+	// SetColor creates the tag record if needed; "" clears the color.
+	SetColor(name, color string) (model.Tag, error)
+	// Rename moves metadata to a new name. An existing target keeps its
+	// color unless it has none.
+	Rename(from, to string) error
+	Delete(name string) error
+}
+
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func normalizeColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color != "" && !hexColor.MatchString(color) {
+		return "", ErrInvalidColor
+	}
+	return color, nil
+}
@@ -0,0 +1,129 @@
+package task
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+// Activity sources recorded on task history entries.
+const (
+	SourceAPI     = "api"
+	SourceBoard   = "board"
+	SourceDayTick = "day_tick"
+	SourcePlugin  = "plugin"
+	SourceImport  = "import"
+	SourceCalDAV  = "caldav"
+	SourceCapture = "capture"
+	SourceEmail   = "email"
+)
+
+// activityOrigin is who/what a scoped repo attributes writes to.
+type activityOrigin struct {
+	source string
+	actor  string
+}
+
+func (o activityOrigin) withDefaults(defaultActor string) activityOrigin {
+	if strings.TrimSpace(o.source) == "" {
+		o.source = SourceAPI
+	}
+	if strings.TrimSpace(o.actor) == "" {
+		o.actor = defaultActor
+	}
+	return o
+}
+
+// historyIgnoredFields are bookkeeping fields that never produce diffs.
+var historyIgnoredFields = map[string]bool{
+	"id":           true,
+	"createdAt":    true,
+	"updatedAt":    true,
+	"revision":     true,
+	"live":         true,
+	"commentCount": true,
+
+	// Time entries keep their own log.
+	"trackedSeconds": true,
+}
+
+// diffTasks returns field-level changes between two task snapshots, keyed by
+// their JSON names so the history reads like the API.
+func diffTasks(before, after model.Task) []model.TaskFieldChange {
+	normalizeTask(&before)
+	normalizeTask(&after)
+	b := taskFieldMap(before)
+	a := taskFieldMap(after)
+
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+
+	out := make([]model.TaskFieldChange, 0)
+	for k := range keys {
+		if historyIgnoredFields[k] {
+			continue
+		}
+		if reflect.DeepEqual(b[k], a[k]) {
+			continue
+		}
+		out = append(out, model.TaskFieldChange{Field: k, From: b[k], To: a[k]})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	return out
+}
+
+func taskFieldMap(t model.Task) map[string]any {
+	out := map[string]any{}
+	buf, err := json.Marshal(t)
+	if err != nil {
+		return out
+	}
+	_ = json.Unmarshal(buf, &out)
+	return out
+}
+
+func activityAction(before, after model.Task, source string) string {
+	switch {
+	case !before.Done && after.Done:
+		return "completed"
+	case before.Done && !after.Done && source == SourceDayTick:
+		return "recurred"
+	case before.Done && !after.Done:
+		return "reopened"
+	case after.ProcessedCount > before.ProcessedCount:
+		return "processed"
+	default:
+		return "updated"
+	}
+}
+
+// newActivity builds the history entry for a write, or ok=false when the
+// write changed nothing worth recording.
+func newActivity(origin activityOrigin, before, after model.Task, created bool, at time.Time) (model.TaskActivity, bool) {
+	changes := diffTasks(before, after)
+	action := "created"
+	if !created {
+		if len(changes) == 0 {
+			return model.TaskActivity{}, false
+		}
+		action = activityAction(before, after, origin.source)
+	}
+	return model.TaskActivity{
+		ID:      string(newID("act")),
+		TaskID:  after.ID,
+		At:      at,
+		Actor:   origin.actor,
+		Source:  origin.source,
+		Action:  action,
+		Changes: changes,
+	}, true
+}
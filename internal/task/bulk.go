@@ -0,0 +1,248 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"donegeon/internal/model"
+	"donegeon/internal/webhook"
+)
+
+// maxBulkTasks caps how many tasks one bulk request may touch.
+const maxBulkTasks = 500
+
+// Bulk actions; each expands to a per-task patch.
+const (
+	BulkComplete  = "complete"
+	BulkReopen    = "reopen"
+	BulkArchive   = "archive"
+	BulkUnarchive = "unarchive"
+	BulkAddTag    = "add_tag"
+	BulkRemoveTag = "remove_tag"
+)
+
+// bulkRequest selects tasks by ids or filter and changes them with either a
+// patch (same shape as PATCH /api/tasks/{id}) or an action.
+type bulkRequest struct {
+	IDs    []model.TaskID  `json:"ids"`
+	Filter *bulkFilter     `json:"filter"`
+	Patch  json.RawMessage `json:"patch"`
+	Action string          `json:"action"`
+	Tag    string          `json:"tag"` // for add_tag / remove_tag
+}
+
+type bulkFilter struct {
+	Status   string   `json:"status"`
+	Project  string   `json:"project"`
+	Tags     []string `json:"tags"`
+	Live     *bool    `json:"live"`
+	Deferred string   `json:"deferred"`
+	Archived string   `json:"archived"`
+}
+
+// bulkResult reports what happened (or would have happened) to one task.
+type bulkResult struct {
+	ID     model.TaskID `json:"id"`
+	Status string       `json:"status"` // updated | skipped | failed
+	Error  string       `json:"error,omitempty"`
+	Task   *model.Task  `json:"task,omitempty"`
+}
+
+// /api/tasks/bulk
+//
+// The whole batch is all or nothing: if any task fails the unlock, modifier
+// or villager rules, nothing is changed and the response lists each task's
+// outcome with the first failure's status code.
+func (h *Handler) TasksBulk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, 405, "method not allowed")
+		return
+	}
+	repo := h.repoForRequest(r)
+	playerRepo := h.playerForRequest(r)
+
+	var in bulkRequest
+	if err := decodeJSON(r, &in); err != nil {
+		writeErr(w, 400, "bad json")
+		return
+	}
+	hasPatch := len(in.Patch) > 0 && string(in.Patch) != "null"
+	in.Action = strings.ToLower(strings.TrimSpace(in.Action))
+	if hasPatch == (in.Action != "") {
+		writeErr(w, 400, "exactly one of patch or action is required")
+		return
+	}
+	if (len(in.IDs) > 0) == (in.Filter != nil) {
+		writeErr(w, 400, "exactly one of ids or filter is required")
+		return
+	}
+	if len(in.IDs) > maxBulkTasks {
+		writeErr(w, 400, fmt.Sprintf("bulk updates are limited to %d tasks", maxBulkTasks))
+		return
+	}
+
+	var base Patch
+	switch in.Action {
+	case "":
+		if err := json.Unmarshal(in.Patch, &base); err != nil {
+			writeErr(w, 400, "bad json")
+			return
+		}
+		if err := h.validatePatch(&base); err != nil {
+			writeErr(w, 400, err.Error())
+			return
+		}
+	case BulkComplete, BulkReopen:
+		done := in.Action == BulkComplete
+		base.Done = &done
+	case BulkArchive, BulkUnarchive:
+		archived := in.Action == BulkArchive
+		base.Archived = &archived
+	case BulkAddTag, BulkRemoveTag:
+		in.Tag = NormalizeTag(in.Tag)
+		if in.Tag == "" {
+			writeErr(w, 400, "tag is required for "+in.Action)
+			return
+		}
+	default:
+		writeErr(w, 400, "unknown action: "+in.Action)
+		return
+	}
+
+	tasks, err := h.bulkSelect(repo, in, r)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			writeErr(w, 404, err.Error())
+			return
+		}
+		writeErr(w, 500, err.Error())
+		return
+	}
+	if len(tasks) > maxBulkTasks {
+		writeErr(w, 400, fmt.Sprintf("bulk updates are limited to %d tasks", maxBulkTasks))
+		return
+	}
+
+	results := make([]bulkResult, len(tasks))
+	updates := make([]BatchUpdate, 0, len(tasks))
+	updateIdx := make([]int, 0, len(tasks))
+	completes := make([]bool, 0, len(tasks))
+	var fx patchEffects
+	var failed *patchError
+	for i, cur := range tasks {
+		results[i] = bulkResult{ID: cur.ID, Status: "updated"}
+		p := base
+		switch in.Action {
+		case BulkAddTag:
+			if hasTag(cur, in.Tag) {
+				results[i].Status = "skipped"
+				continue
+			}
+			p.Tags = Some(append(append([]string{}, cur.Tags...), in.Tag))
+		case BulkRemoveTag:
+			if !hasTag(cur, in.Tag) {
+				results[i].Status = "skipped"
+				continue
+			}
+			next := make([]string, 0, len(cur.Tags))
+			for _, tg := range cur.Tags {
+				if NormalizeTag(tg) != in.Tag {
+					next = append(next, tg)
+				}
+			}
+			p.Tags = Some(next)
+		}
+		// Pin the revision we planned against so the side effects below
+		// match what is actually stored.
+		rev := cur.Revision
+		p.IfRevision = &rev
+
+		p, taskFx, perr := h.planPatch(repo, playerRepo, cur.ID, p)
+		if perr != nil {
+			results[i].Status = "failed"
+			results[i].Error = perr.msg
+			if failed == nil {
+				failed = perr
+			}
+			continue
+		}
+		fx = fx.add(taskFx)
+		updates = append(updates, BatchUpdate{ID: cur.ID, Patch: p})
+		updateIdx = append(updateIdx, i)
+		completes = append(completes, taskFx.completed > 0)
+	}
+	if failed != nil {
+		writeJSON(w, failed.code, map[string]any{
+			"error":   "no tasks were changed: " + failed.msg,
+			"results": results,
+		})
+		return
+	}
+
+	updated, err := repo.UpdateMany(updates)
+	if err == ErrTooManyMods {
+		writeErr(w, 400, err.Error())
+		return
+	}
+	if err == ErrRevisionConflict || err == ErrNotFound {
+		writeErr(w, 409, "tasks changed during the bulk update; nothing was changed, please retry")
+		return
+	}
+	if err != nil {
+		writeErr(w, 500, err.Error())
+		return
+	}
+	for j, t := range updated {
+		t := t
+		results[updateIdx[j]].Task = &t
+	}
+	fx.apply(playerRepo)
+	for j, t := range updated {
+		if completes[j] {
+			h.publish(r, webhook.EventTaskCompleted, t)
+		}
+		h.taskChanged(r, t)
+	}
+
+	writeJSON(w, 200, map[string]any{
+		"updated": len(updated),
+		"results": results,
+	})
+}
+
+// bulkSelect resolves the request's selector to tasks, de-duplicating ids so
+// a task is never patched (or counted as completed) twice.
+func (h *Handler) bulkSelect(repo Repo, in bulkRequest, r *http.Request) ([]model.Task, error) {
+	if in.Filter != nil {
+		f := in.Filter
+		if f.Archived == "" {
+			f.Archived = "exclude"
+		}
+		return repo.List(ListFilter{
+			Status:   f.Status,
+			Project:  f.Project,
+			Tags:     f.Tags,
+			Live:     f.Live,
+			Deferred: f.Deferred,
+			Archived: f.Archived,
+			Location: h.locationFor(r),
+		})
+	}
+	seen := make(map[model.TaskID]bool, len(in.IDs))
+	out := make([]model.Task, 0, len(in.IDs))
+	for _, id := range in.IDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		t, err := repo.Get(id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package task
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GET /api/calendar/feed.ics?token=...&project=home&tag=errand&component=vtodo&done=1
+//
+// A subscribable calendar of every dated task. It is authenticated with a
+// calendar.feed API token rather than the session cookie, so calendar apps
+// can poll it. project and tag narrow the feed; done tasks are left out
+// unless done=1.
+func (h *Handler) CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeErr(w, 405, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	component := strings.ToLower(strings.TrimSpace(q.Get("component")))
+	switch component {
+	case "", ICSEvent:
+		component = ICSEvent
+	case ICSTodo:
+	default:
+		writeErr(w, 400, "component must be vevent or vtodo")
+		return
+	}
+	status := "pending"
+	switch strings.ToLower(strings.TrimSpace(q.Get("done"))) {
+	case "1", "true", "yes":
+		status = ""
+	}
+
+	loc := h.locationFor(r)
+	project := strings.TrimSpace(q.Get("project"))
+	tasks, err := h.repoForRequest(r).List(ListFilter{
+		Status:   status,
+		Project:  project,
+		Tags:     q["tag"],
+		Deferred: "include",
+		Archived: "exclude",
+		Location: loc,
+	})
+	if err != nil {
+		writeErr(w, 500, err.Error())
+		return
+	}
+
+	name := "Donegeon"
+	if project != "" && project != "any" {
+		name += " – " + project
+	}
+	ics := BuildCalendarFeedICS(tasks, CalendarFeed{Name: name, Component: component}, time.Now().In(loc))
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="donegeon.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write([]byte(ics))
+	}
+}
@@ -2,16 +2,29 @@ package task
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"donegeon/internal/model"
 )
 
-const icsDateLayout = "20060102"
+const (
+	icsDateLayout  = "20060102"
+	icsStampLayout = "20060102T150405Z"
+)
+
+// Calendar component kinds for the feed.
+const (
+	ICSEvent = "vevent"
+	ICSTodo  = "vtodo"
+)
 
 // BuildTaskCalendarICS builds a simple iCalendar event for a task.
 // A due date is required so the exported event has a concrete start date.
+// Dates are read in now's location; a due time makes it a timed event
+// lasting the task's estimate (30 minutes by default).
 func BuildTaskCalendarICS(t model.Task, now time.Time) (string, error) {
 	dueRaw := ""
 	if t.DueDate != nil {
@@ -20,12 +33,84 @@ func BuildTaskCalendarICS(t model.Task, now time.Time) (string, error) {
 	if dueRaw == "" {
 		return "", fmt.Errorf("task due date required for calendar export")
 	}
-
-	due, err := time.ParseInLocation("2006-01-02", dueRaw, time.Local)
-	if err != nil {
+	if _, err := time.ParseInLocation("2006-01-02", dueRaw, now.Location()); err != nil {
 		return "", fmt.Errorf("task due date must be YYYY-MM-DD")
 	}
-	end := due.AddDate(0, 0, 1)
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Donegeon//Task Export//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+	}
+	event, _ := taskComponent(t, ICSEvent, now)
+	lines = append(lines, event...)
+	lines = append(lines, "END:VCALENDAR")
+	return joinICSLines(lines), nil
+}
+
+// CalendarFeed describes a subscribable calendar of many tasks.
+type CalendarFeed struct {
+	Name      string
+	Component string // ICSEvent (default) or ICSTodo
+}
+
+// BuildCalendarFeedICS renders every task with a valid due date as one
+// component. UIDs are stable per task and SEQUENCE follows the task's
+// revision, so subscribed clients update events in place.
+func BuildCalendarFeedICS(ts []model.Task, feed CalendarFeed, now time.Time) string {
+	name := strings.TrimSpace(feed.Name)
+	if name == "" {
+		name = "Donegeon"
+	}
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Donegeon//Task Feed//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + escapeICSText(name),
+		"X-WR-TIMEZONE:" + now.Location().String(),
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+		"X-PUBLISHED-TTL:PT1H",
+	}
+	for _, t := range ts {
+		if t.DueDate == nil {
+			continue
+		}
+		if comp, ok := taskComponent(t, feed.Component, now); ok {
+			lines = append(lines, comp...)
+		}
+	}
+	lines = append(lines, "END:VCALENDAR")
+	return joinICSLines(lines)
+}
+
+// taskComponent renders t as a VEVENT or VTODO. ok is false when an event
+// has no usable due date; to-dos may be undated.
+func taskComponent(t model.Task, kind string, now time.Time) ([]string, bool) {
+	var start, end string
+	if t.DueDate != nil {
+		if due, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(*t.DueDate), now.Location()); err == nil {
+			// Date-only by default; a due time makes it timed.
+			start = ";VALUE=DATE:" + due.Format(icsDateLayout)
+			end = ";VALUE=DATE:" + due.AddDate(0, 0, 1).Format(icsDateLayout)
+		}
+	}
+	if start == "" && kind != ICSTodo {
+		return nil, false
+	}
+	if start != "" && t.DueTime != nil {
+		if at, ok := DueAt(t, now.Location()); ok {
+			length := 30 * time.Minute
+			if t.EstimateMinutes > 0 {
+				length = time.Duration(t.EstimateMinutes) * time.Minute
+			}
+			start = ":" + at.UTC().Format(icsStampLayout)
+			end = ":" + at.Add(length).UTC().Format(icsStampLayout)
+		}
+	}
 
 	title := strings.TrimSpace(t.Title)
 	if title == "" {
@@ -33,57 +118,122 @@ func BuildTaskCalendarICS(t model.Task, now time.Time) (string, error) {
 	}
 	desc := strings.TrimSpace(t.Description)
 
-	uid := fmt.Sprintf("task-%s@donegeon", strings.TrimSpace(string(t.ID)))
-	if strings.TrimSpace(string(t.ID)) == "" {
+	uid := TaskUID(t)
+	if uid == "" {
 		uid = fmt.Sprintf("task-export-%d@donegeon", now.UnixNano())
 	}
+	stamp := now
+	if !t.UpdatedAt.IsZero() {
+		stamp = t.UpdatedAt
+	}
 
+	component := "VEVENT"
+	if kind == ICSTodo {
+		component = "VTODO"
+	}
 	lines := []string{
-		"BEGIN:VCALENDAR",
-		"VERSION:2.0",
-		"PRODID:-//Donegeon//Task Export//EN",
-		"CALSCALE:GREGORIAN",
-		"METHOD:PUBLISH",
-		"BEGIN:VEVENT",
+		"BEGIN:" + component,
 		"UID:" + escapeICSText(uid),
-		"DTSTAMP:" + now.UTC().Format("20060102T150405Z"),
+		"DTSTAMP:" + stamp.UTC().Format(icsStampLayout),
 		"SUMMARY:" + escapeICSText(title),
-		"DTSTART;VALUE=DATE:" + due.Format(icsDateLayout),
-		"DTEND;VALUE=DATE:" + end.Format(icsDateLayout),
+	}
+	if t.Revision > 0 {
+		lines = append(lines,
+			"SEQUENCE:"+strconv.FormatInt(t.Revision-1, 10),
+			"LAST-MODIFIED:"+stamp.UTC().Format(icsStampLayout),
+		)
+	}
+	if kind == ICSTodo {
+		// RRULE needs a DTSTART to count from; otherwise DUE alone is
+		// what to-do apps expect.
+		if start != "" {
+			if t.Recurrence != nil {
+				lines = append(lines, "DTSTART"+start)
+			}
+			lines = append(lines, "DUE"+start)
+		}
+		if t.Done {
+			lines = append(lines, "STATUS:COMPLETED", "COMPLETED:"+stamp.UTC().Format(icsStampLayout))
+		} else {
+			lines = append(lines, "STATUS:NEEDS-ACTION")
+		}
+	} else {
+		lines = append(lines, "DTSTART"+start, "DTEND"+end)
 	}
 	if desc != "" {
 		lines = append(lines, "DESCRIPTION:"+escapeICSText(desc))
 	}
-	if rrule := recurrenceToICSRRULE(t.Recurrence); rrule != "" {
+	if len(t.Tags) > 0 {
+		tags := make([]string, 0, len(t.Tags))
+		for _, tg := range t.Tags {
+			tags = append(tags, escapeICSText(tg))
+		}
+		lines = append(lines, "CATEGORIES:"+strings.Join(tags, ","))
+	}
+	if rrule := RecurrenceToRRULE(t.Recurrence, t.DueDate); rrule != "" {
 		lines = append(lines, "RRULE:"+rrule)
 	}
-	lines = append(lines, "END:VEVENT", "END:VCALENDAR", "")
-
-	return strings.Join(lines, "\r\n"), nil
+	lines = append(lines, "END:"+component)
+	return lines, true
 }
 
-func recurrenceToICSRRULE(rec *model.Recurrence) string {
-	if rec == nil {
-		return ""
+// TaskUID is the iCalendar UID for t: the one it was imported with, or one
+// derived from its ID. It is blank for a task without an ID.
+func TaskUID(t model.Task) string {
+	if uid := t.ExternalRefs[ExternalRefICS]; uid != "" {
+		return uid
 	}
-	interval := rec.Interval
-	if interval <= 0 {
-		interval = 1
+	if id := strings.TrimSpace(string(t.ID)); id != "" {
+		return "task-" + id + "@donegeon"
 	}
+	return ""
+}
+
+// BuildTaskTodoICS wraps t as a single VTODO calendar object, as served to
+// CalDAV clients. Unlike BuildTaskCalendarICS it does not need a due date.
+func BuildTaskTodoICS(t model.Task, now time.Time) string {
+	todo, _ := taskComponent(t, ICSTodo, now)
+	lines := append([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Donegeon//Tasks//EN",
+	}, todo...)
+	return joinICSLines(append(lines, "END:VCALENDAR"))
+}
 
-	freq := ""
-	switch strings.ToUpper(strings.TrimSpace(rec.Type)) {
-	case "DAILY":
-		freq = "DAILY"
-	case "WEEKLY":
-		freq = "WEEKLY"
-	case "MONTHLY":
-		freq = "MONTHLY"
-	default:
-		return ""
+// joinICSLines folds each content line and joins them with CRLF, ending
+// with a trailing CRLF.
+func joinICSLines(lines []string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICSLine(line))
+		b.WriteString("\r\n")
 	}
+	return b.String()
+}
 
-	return fmt.Sprintf("FREQ=%s;INTERVAL=%d", freq, interval)
+// foldICSLine splits a content line into 75-octet pieces joined by CRLF and
+// a space (RFC 5545 section 3.1), never splitting a UTF-8 sequence.
+func foldICSLine(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+	var b strings.Builder
+	width := limit
+	for len(line) > width {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines lose one octet to the leading space.
+		width = limit - 1
+	}
+	b.WriteString(line)
+	return b.String()
 }
 
 func escapeICSText(s string) string {
@@ -0,0 +1,74 @@
+package task
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+var (
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrCommentEmpty    = errors.New("comment body required")
+	ErrCommentTooLong  = errors.New("comment body too long")
+)
+
+const maxCommentBodyLen = 20000
+
+func normalizeCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", ErrCommentEmpty
+	}
+	if len(body) > maxCommentBodyLen {
+		return "", ErrCommentTooLong
+	}
+	return body, nil
+}
+
+func newComment(id model.TaskID, c model.TaskComment) (model.TaskComment, error) {
+	body, err := normalizeCommentBody(c.Body)
+	if err != nil {
+		return model.TaskComment{}, err
+	}
+	now := time.Now()
+	c.ID = string(newID("cmt"))
+	c.TaskID = id
+	c.Body = body
+	c.AuthorID = strings.TrimSpace(c.AuthorID)
+	c.AuthorName = strings.TrimSpace(c.AuthorName)
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	c.Edited = false
+	return c, nil
+}
+
+// editComment replaces the body of comments[commentID] in place.
+func editComment(comments []model.TaskComment, commentID, body string) (model.TaskComment, error) {
+	body, err := normalizeCommentBody(body)
+	if err != nil {
+		return model.TaskComment{}, err
+	}
+	for i := range comments {
+		if comments[i].ID != commentID {
+			continue
+		}
+		if comments[i].Body != body {
+			comments[i].Body = body
+			comments[i].UpdatedAt = time.Now()
+			comments[i].Edited = true
+		}
+		return comments[i], nil
+	}
+	return model.TaskComment{}, ErrCommentNotFound
+}
+
+func removeComment(comments []model.TaskComment, commentID string) ([]model.TaskComment, error) {
+	for i := range comments {
+		if comments[i].ID == commentID {
+			return append(comments[:i:i], comments[i+1:]...), nil
+		}
+	}
+	return comments, ErrCommentNotFound
+}
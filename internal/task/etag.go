@@ -0,0 +1,71 @@
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"donegeon/internal/model"
+)
+
+// TaskETag renders a task revision as a strong entity tag.
+func TaskETag(t model.Task) string {
+	return `"` + strconv.FormatInt(t.Revision, 10) + `"`
+}
+
+// etagMatches evaluates an If-Match / If-None-Match header value against
+// etag. Weak tags only match when weak is allowed (If-None-Match).
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+			etag = strings.TrimPrefix(etag, "W/")
+		}
+		if candidate != "" && candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writePreconditionFailed answers a stale If-Match with the server copy.
+func writePreconditionFailed(w http.ResponseWriter, cur model.Task) {
+	w.Header().Set("ETag", TaskETag(cur))
+	writeJSON(w, 412, map[string]any{
+		"error":   ErrRevisionConflict.Error(),
+		"current": cur,
+	})
+}
+
+// writeTaskJSON writes a single task with its ETag.
+func writeTaskJSON(w http.ResponseWriter, code int, t model.Task) {
+	w.Header().Set("ETag", TaskETag(t))
+	writeJSON(w, code, t)
+}
+
+// writeListJSON writes a list response with a weak ETag over its body and
+// honors If-None-Match.
+func writeListJSON(w http.ResponseWriter, r *http.Request, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeErr(w, 500, err.Error())
+		return
+	}
+	sum := sha256.Sum256(b)
+	etag := `W/"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag, true) {
+		w.WriteHeader(304)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(200)
+	_, _ = w.Write(append(b, '\n'))
+}
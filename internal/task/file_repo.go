@@ -17,8 +17,10 @@ type fileState struct {
 }
 
 type userTaskState struct {
-	Tasks     map[model.TaskID]model.Task `json:"tasks"`
-	LiveIndex map[model.TaskID]bool       `json:"liveIndex"`
+	Tasks     map[model.TaskID]model.Task           `json:"tasks"`
+	LiveIndex map[model.TaskID]bool                 `json:"liveIndex"`
+	History   map[model.TaskID][]model.TaskActivity `json:"history,omitempty"`
+	Comments  map[model.TaskID][]model.TaskComment  `json:"comments,omitempty"`
 }
 
 func newFileState() fileState {
@@ -31,13 +33,16 @@ func newUserTaskState() userTaskState {
 	return userTaskState{
 		Tasks:     map[model.TaskID]model.Task{},
 		LiveIndex: map[model.TaskID]bool{},
+		History:   map[model.TaskID][]model.TaskActivity{},
+		Comments:  map[model.TaskID][]model.TaskComment{},
 	}
 }
 
 type fileStore struct {
-	mu   sync.RWMutex
-	path string
-	s    fileState
+	mu         sync.RWMutex
+	path       string
+	s          fileState
+	onProjects func(userID string, names []string)
 }
 
 // FileRepo is a persistent task repository.
@@ -45,6 +50,7 @@ type fileStore struct {
 type FileRepo struct {
 	store  *fileStore
 	userID string
+	origin activityOrigin
 }
 
 func NewFileRepo(dataDir string) (*FileRepo, error) {
@@ -91,6 +97,12 @@ func (s *fileStore) load() error {
 		if us.LiveIndex == nil {
 			us.LiveIndex = map[model.TaskID]bool{}
 		}
+		if us.History == nil {
+			us.History = map[model.TaskID][]model.TaskActivity{}
+		}
+		if us.Comments == nil {
+			us.Comments = map[model.TaskID][]model.TaskComment{}
+		}
 		loaded.Users[uid] = us
 	}
 	s.s = loaded
@@ -116,6 +128,33 @@ func (r *FileRepo) ForUser(userID string) *FileRepo {
 	}
 }
 
+// OnProjects registers fn to hear the project names each write sets, for
+// every user of the store, so project entities follow free-text task
+// projects whichever path wrote the task.
+func (r *FileRepo) OnProjects(fn func(userID string, names []string)) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.onProjects = fn
+}
+
+func (r *FileRepo) projectsSetLocked(names []string) {
+	if r.store.onProjects != nil && len(names) > 0 {
+		r.store.onProjects(r.userID, names)
+	}
+}
+
+// UserIDs lists the users that have task data, sorted.
+func (r *FileRepo) UserIDs() []string {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	out := make([]string, 0, len(r.store.s.Users))
+	for uid := range r.store.s.Users {
+		out = append(out, uid)
+	}
+	sort.Strings(out)
+	return out
+}
+
 func (r *FileRepo) userStateLocked() userTaskState {
 	us, ok := r.store.s.Users[r.userID]
 	if !ok {
@@ -129,10 +168,202 @@ func (r *FileRepo) userStateLocked() userTaskState {
 	if us.LiveIndex == nil {
 		us.LiveIndex = map[model.TaskID]bool{}
 	}
+	if us.History == nil {
+		us.History = map[model.TaskID][]model.TaskActivity{}
+	}
+	if us.Comments == nil {
+		us.Comments = map[model.TaskID][]model.TaskComment{}
+	}
 	r.store.s.Users[r.userID] = us
 	return us
 }
 
+// UserSnapshot is everything the task store keeps for one user. Account
+// export and import move it between users and deployments.
+type UserSnapshot struct {
+	Tasks    []model.Task                          `json:"tasks"`
+	Live     []model.TaskID                        `json:"live,omitempty"`
+	History  map[model.TaskID][]model.TaskActivity `json:"history,omitempty"`
+	Comments map[model.TaskID][]model.TaskComment  `json:"comments,omitempty"`
+}
+
+// Snapshot copies the user's tasks (archived ones included), live flags,
+// history and comments. Tasks are sorted by creation time.
+func (r *FileRepo) Snapshot() UserSnapshot {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	us := r.store.s.Users[r.userID]
+	out := UserSnapshot{
+		Tasks:    make([]model.Task, 0, len(us.Tasks)),
+		History:  make(map[model.TaskID][]model.TaskActivity, len(us.History)),
+		Comments: make(map[model.TaskID][]model.TaskComment, len(us.Comments)),
+	}
+	for _, t := range us.Tasks {
+		out.Tasks = append(out.Tasks, t)
+	}
+	sort.Slice(out.Tasks, func(i, j int) bool {
+		if !out.Tasks[i].CreatedAt.Equal(out.Tasks[j].CreatedAt) {
+			return out.Tasks[i].CreatedAt.Before(out.Tasks[j].CreatedAt)
+		}
+		return out.Tasks[i].ID < out.Tasks[j].ID
+	})
+	for id, live := range us.LiveIndex {
+		if live {
+			out.Live = append(out.Live, id)
+		}
+	}
+	sort.Slice(out.Live, func(i, j int) bool { return out.Live[i] < out.Live[j] })
+	for id, h := range us.History {
+		out.History[id] = append([]model.TaskActivity{}, h...)
+	}
+	for id, c := range us.Comments {
+		out.Comments[id] = append([]model.TaskComment{}, c...)
+	}
+	return out
+}
+
+// Restore replaces the user's task state with snap as given: IDs,
+// revisions and timestamps are kept, so callers remap IDs first.
+func (r *FileRepo) Restore(snap UserSnapshot) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	us := newUserTaskState()
+	for _, t := range snap.Tasks {
+		normalizeTask(&t)
+		us.Tasks[t.ID] = t
+	}
+	for _, id := range snap.Live {
+		if _, ok := us.Tasks[id]; ok {
+			us.LiveIndex[id] = true
+		}
+	}
+	for id, h := range snap.History {
+		if _, ok := us.Tasks[id]; ok {
+			us.History[id] = append([]model.TaskActivity{}, h...)
+		}
+	}
+	for id, c := range snap.Comments {
+		if _, ok := us.Tasks[id]; ok {
+			us.Comments[id] = append([]model.TaskComment{}, c...)
+		}
+	}
+	r.store.s.Users[r.userID] = us
+	return r.store.saveLocked()
+}
+
+// OwnerOf reports which user holds task id.
+func (r *FileRepo) OwnerOf(id model.TaskID) (string, bool) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for uid, us := range r.store.s.Users {
+		if _, ok := us.Tasks[id]; ok {
+			return uid, true
+		}
+	}
+	return "", false
+}
+
+func (r *FileRepo) Comments(id model.TaskID) ([]model.TaskComment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	us, ok := r.store.s.Users[r.userID]
+	if !ok || us.Tasks == nil {
+		return nil, ErrNotFound
+	}
+	if _, ok := us.Tasks[id]; !ok {
+		return nil, ErrNotFound
+	}
+	return append([]model.TaskComment{}, us.Comments[id]...), nil
+}
+
+func (r *FileRepo) AddComment(id model.TaskID, c model.TaskComment) (model.TaskComment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	us := r.userStateLocked()
+	if _, ok := us.Tasks[id]; !ok {
+		return model.TaskComment{}, ErrNotFound
+	}
+	c, err := newComment(id, c)
+	if err != nil {
+		return model.TaskComment{}, err
+	}
+	us.Comments[id] = append(us.Comments[id], c)
+	r.writeUserStateLocked(us)
+	if err := r.store.saveLocked(); err != nil {
+		return model.TaskComment{}, err
+	}
+	return c, nil
+}
+
+func (r *FileRepo) UpdateComment(id model.TaskID, commentID, body string) (model.TaskComment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	us := r.userStateLocked()
+	if _, ok := us.Tasks[id]; !ok {
+		return model.TaskComment{}, ErrNotFound
+	}
+	c, err := editComment(us.Comments[id], commentID, body)
+	if err != nil {
+		return model.TaskComment{}, err
+	}
+	r.writeUserStateLocked(us)
+	if err := r.store.saveLocked(); err != nil {
+		return model.TaskComment{}, err
+	}
+	return c, nil
+}
+
+func (r *FileRepo) DeleteComment(id model.TaskID, commentID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	us := r.userStateLocked()
+	if _, ok := us.Tasks[id]; !ok {
+		return ErrNotFound
+	}
+	next, err := removeComment(us.Comments[id], commentID)
+	if err != nil {
+		return err
+	}
+	us.Comments[id] = next
+	r.writeUserStateLocked(us)
+	return r.store.saveLocked()
+}
+
+func (r *FileRepo) WithOrigin(source, actor string) Repo {
+	return &FileRepo{
+		store:  r.store,
+		userID: r.userID,
+		origin: activityOrigin{source: source, actor: actor},
+	}
+}
+
+func (r *FileRepo) History(id model.TaskID) ([]model.TaskActivity, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	us, ok := r.store.s.Users[r.userID]
+	if !ok || us.Tasks == nil {
+		return nil, ErrNotFound
+	}
+	if _, ok := us.Tasks[id]; !ok {
+		return nil, ErrNotFound
+	}
+	return append([]model.TaskActivity{}, us.History[id]...), nil
+}
+
+func (r *FileRepo) recordLocked(us userTaskState, before, after model.Task, created bool) {
+	if entry, ok := newActivity(r.origin.withDefaults(r.userID), before, after, created, after.UpdatedAt); ok {
+		us.History[after.ID] = append(us.History[after.ID], entry)
+	}
+}
+
 func (r *FileRepo) writeUserStateLocked(us userTaskState) {
 	r.store.s.Users[r.userID] = us
 }
@@ -143,16 +374,20 @@ func (r *FileRepo) SyncLive(taskIDs []model.TaskID) error {
 
 	us := r.userStateLocked()
 
-	next := make(map[model.TaskID]bool, len(taskIDs))
+	want := make(map[model.TaskID]bool, len(taskIDs))
 	for _, id := range taskIDs {
-		if id == "" {
-			continue
+		if id != "" {
+			want[id] = true
 		}
-		if t, ok := us.Tasks[id]; ok && !t.Done {
-			next[id] = true
+	}
+	for id := range us.LiveIndex {
+		if !want[id] {
+			setLiveLocked(us.Tasks, us.LiveIndex, id, false)
 		}
 	}
-	us.LiveIndex = next
+	for id := range want {
+		setLiveLocked(us.Tasks, us.LiveIndex, id, true)
+	}
 	r.writeUserStateLocked(us)
 	return r.store.saveLocked()
 }
@@ -162,22 +397,10 @@ func (r *FileRepo) SetLive(id model.TaskID, live bool) error {
 	defer r.store.mu.Unlock()
 
 	us := r.userStateLocked()
-	t, ok := us.Tasks[id]
-	if !ok {
+	if _, ok := us.Tasks[id]; !ok {
 		return ErrNotFound
 	}
-
-	if t.Done {
-		delete(us.LiveIndex, id)
-		r.writeUserStateLocked(us)
-		return r.store.saveLocked()
-	}
-
-	if live {
-		us.LiveIndex[id] = true
-	} else {
-		delete(us.LiveIndex, id)
-	}
+	setLiveLocked(us.Tasks, us.LiveIndex, id, live)
 	r.writeUserStateLocked(us)
 	return r.store.saveLocked()
 }
@@ -192,13 +415,19 @@ func (r *FileRepo) Create(t model.Task) (model.Task, error) {
 	t.ID = newID("task")
 	t.CreatedAt = now
 	t.UpdatedAt = now
+	t.Revision = 1
+	t.Tags = NormalizeTags(t.Tags)
 	normalizeTask(&t)
 
 	us.Tasks[t.ID] = t
+	r.recordLocked(us, model.Task{}, t, true)
 	r.writeUserStateLocked(us)
 	if err := r.store.saveLocked(); err != nil {
 		return model.Task{}, err
 	}
+	if t.Project != nil {
+		r.projectsSetLocked([]string{*t.Project})
+	}
 	return t, nil
 }
 
@@ -215,6 +444,8 @@ func (r *FileRepo) Get(id model.TaskID) (model.Task, error) {
 		return model.Task{}, ErrNotFound
 	}
 	normalizeTask(&t)
+	t.Live = isLive(us.LiveIndex, t)
+	t.CommentCount = len(us.Comments[id])
 	return t, nil
 }
 
@@ -227,22 +458,95 @@ func (r *FileRepo) Update(id model.TaskID, p Patch) (model.Task, error) {
 	if !ok {
 		return model.Task{}, ErrNotFound
 	}
+	normalizeTask(&t)
+	if err := checkRevision(t, p); err != nil {
+		return model.Task{}, err
+	}
+	before := t
 	if err := applyPatch(&t, p); err != nil {
 		return model.Task{}, err
 	}
-	if t.Done {
+	if t.Done || t.Archived {
 		us.LiveIndex[t.ID] = false
 	}
 	t.UpdatedAt = time.Now()
+	t.Revision++
 	normalizeTask(&t)
 	us.Tasks[id] = t
+	r.recordLocked(us, before, t, false)
 	r.writeUserStateLocked(us)
 	if err := r.store.saveLocked(); err != nil {
 		return model.Task{}, err
 	}
+	if p.Project.Set && t.Project != nil {
+		r.projectsSetLocked([]string{*t.Project})
+	}
+	t.Live = isLive(us.LiveIndex, t)
+	t.CommentCount = len(us.Comments[id])
 	return t, nil
 }
 
+func (r *FileRepo) UpdateMany(updates []BatchUpdate) ([]model.Task, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	us := r.userStateLocked()
+	// Stage every change first so a failure leaves the store untouched.
+	staged := make(map[model.TaskID]model.Task, len(updates))
+	befores := make(map[model.TaskID]model.Task, len(updates))
+	order := make([]model.TaskID, 0, len(updates))
+	for _, u := range updates {
+		t, ok := staged[u.ID]
+		if !ok {
+			if t, ok = us.Tasks[u.ID]; !ok {
+				return nil, ErrNotFound
+			}
+			normalizeTask(&t)
+			befores[u.ID] = t
+			order = append(order, u.ID)
+		}
+		if err := checkRevision(t, u.Patch); err != nil {
+			return nil, err
+		}
+		if err := applyPatch(&t, u.Patch); err != nil {
+			return nil, err
+		}
+		staged[u.ID] = t
+	}
+
+	now := time.Now()
+	for _, id := range order {
+		t := staged[id]
+		if t.Done || t.Archived {
+			us.LiveIndex[id] = false
+		}
+		t.UpdatedAt = now
+		t.Revision++
+		normalizeTask(&t)
+		us.Tasks[id] = t
+		r.recordLocked(us, befores[id], t, false)
+		staged[id] = t
+	}
+	r.writeUserStateLocked(us)
+	if err := r.store.saveLocked(); err != nil {
+		return nil, err
+	}
+
+	out := make([]model.Task, 0, len(updates))
+	var projects []string
+	for _, u := range updates {
+		t := staged[u.ID]
+		t.Live = isLive(us.LiveIndex, t)
+		t.CommentCount = len(us.Comments[u.ID])
+		out = append(out, t)
+		if u.Patch.Project.Set && t.Project != nil {
+			projects = append(projects, *t.Project)
+		}
+	}
+	r.projectsSetLocked(projects)
+	return out, nil
+}
+
 func (r *FileRepo) List(filter ListFilter) ([]model.Task, error) {
 	r.store.mu.RLock()
 	defer r.store.mu.RUnlock()
@@ -252,8 +556,7 @@ func (r *FileRepo) List(filter ListFilter) ([]model.Task, error) {
 		return []model.Task{}, nil
 	}
 
-	today := time.Now().Format("2006-01-02")
-	status := strings.ToLower(strings.TrimSpace(filter.Status))
+	now := time.Now()
 	projectFilter := strings.TrimSpace(filter.Project)
 	projectFilterLower := strings.ToLower(projectFilter)
 
@@ -261,12 +564,9 @@ func (r *FileRepo) List(filter ListFilter) ([]model.Task, error) {
 	for _, t0 := range us.Tasks {
 		t := t0
 		normalizeTask(&t)
+		t.CommentCount = len(us.Comments[t.ID])
 
-		if t.Done {
-			t.Live = false
-		} else {
-			t.Live = us.LiveIndex[t.ID] && !t.Done
-		}
+		t.Live = isLive(us.LiveIndex, t)
 
 		if filter.Live != nil && t.Live != *filter.Live {
 			continue
@@ -292,53 +592,21 @@ func (r *FileRepo) List(filter ListFilter) ([]model.Task, error) {
 			}
 		}
 
-		switch status {
-		case "", "all":
-		case "pending":
-			if t.Done {
-				continue
-			}
-		case "done":
-			if !t.Done {
-				continue
-			}
-		case "due_today":
-			if t.Done || t.DueDate == nil || *t.DueDate != today {
-				continue
-			}
-		case "overdue":
-			if t.Done || t.DueDate == nil || *t.DueDate >= today {
-				continue
-			}
-		case "upcoming":
-			if t.Done || t.DueDate == nil || *t.DueDate <= today {
-				continue
-			}
+		if !matchesFilter(t, filter, now) {
+			continue
 		}
 
 		out = append(out, t)
 	}
 
 	sort.Slice(out, func(i, j int) bool {
-		di, dj := out[i].DueDate, out[j].DueDate
-		switch {
-		case di == nil && dj == nil:
-			return out[i].UpdatedAt.After(out[j].UpdatedAt)
-		case di == nil:
-			return false
-		case dj == nil:
-			return true
-		case *di != *dj:
-			return *di < *dj
-		default:
-			return out[i].UpdatedAt.After(out[j].UpdatedAt)
-		}
+		return dueLess(out[i], out[j])
 	})
 
 	return out, nil
 }
 
 func (r *FileRepo) SetModifiers(id model.TaskID, mods []model.TaskModifierSlot) (model.Task, error) {
-	p := Patch{Modifiers: &mods}
+	p := Patch{Modifiers: Some(mods)}
 	return r.Update(id, p)
 }
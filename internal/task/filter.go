@@ -0,0 +1,230 @@
+package task
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+)
+
+// Sort orders accepted by SortTasks.
+const (
+	SortDue      = "due"      // due soonest first, undated last (default)
+	SortUpdated  = "updated"  // most recently updated first
+	SortCreated  = "created"  // newest first
+	SortTitle    = "title"    // A-Z
+	SortPriority = "priority" // highest priority first, then due
+)
+
+// IsSortOrder reports whether by is one of the Sort* orders.
+func IsSortOrder(by string) bool {
+	switch by {
+	case SortDue, SortUpdated, SortCreated, SortTitle, SortPriority:
+		return true
+	}
+	return false
+}
+
+// matchesFilter applies the status, deferred, archived and tag parts of a
+// ListFilter.
+// Status may list several values separated by commas; any one matching is
+// enough ("due_today,overdue").
+func matchesFilter(t model.Task, filter ListFilter, now time.Time) bool {
+	today := Today(now, filter.Location)
+
+	deferred := IsDeferred(t, today)
+	switch strings.ToLower(strings.TrimSpace(filter.Deferred)) {
+	case "include":
+	case "only":
+		if !deferred {
+			return false
+		}
+	case "exclude":
+		if deferred {
+			return false
+		}
+	default:
+		if deferred && strings.EqualFold(strings.TrimSpace(filter.Project), "inbox") {
+			return false
+		}
+	}
+
+	if filter.ParentID != "" && (t.ParentID == nil || *t.ParentID != filter.ParentID) {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(filter.Archived)) {
+	case "only":
+		if !t.Archived {
+			return false
+		}
+	case "exclude":
+		if t.Archived {
+			return false
+		}
+	}
+
+	for _, want := range filter.Tags {
+		if !hasTag(t, want) {
+			return false
+		}
+	}
+
+	statuses := strings.Split(strings.ToLower(filter.Status), ",")
+	for _, status := range statuses {
+		if matchesStatus(t, strings.TrimSpace(status), today, now, filter.Location) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesStatus(t model.Task, status, today string, now time.Time, loc *time.Location) bool {
+	switch status {
+	case "pending":
+		return !t.Done
+	case "done":
+		return t.Done
+	case "due_today":
+		return !t.Done && t.DueDate != nil && *t.DueDate == today
+	case "overdue":
+		return IsOverdue(t, now, loc)
+	case "upcoming":
+		return !t.Done && t.DueDate != nil && *t.DueDate > today
+	default:
+		// "", "all" and unknown values match everything
+		return true
+	}
+}
+
+func hasTag(t model.Task, tag string) bool {
+	tag = NormalizeTag(tag)
+	for _, have := range t.Tags {
+		if NormalizeTag(have) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SortTasks orders tasks in place; unknown orders fall back to SortDue. cfg
+// supplies the priority levels and may be nil. Ties are broken by ID, so
+// the order is total and a page cursor can resume from a sort key alone.
+func SortTasks(ts []model.Task, by string, cfg *config.Config) {
+	by = sortOrder(by)
+	keys := make(map[model.TaskID]sortKey, len(ts))
+	for _, t := range ts {
+		keys[t.ID] = keyOf(t, by, cfg)
+	}
+	sort.SliceStable(ts, func(i, j int) bool {
+		return keyLess(by, keys[ts[i].ID], keys[ts[j].ID])
+	})
+}
+
+// sortOrder maps unknown orders to SortDue.
+func sortOrder(by string) string {
+	if !IsSortOrder(by) {
+		return SortDue
+	}
+	return by
+}
+
+// sortKey holds the fields a sort order compares. Only the ones the order
+// uses are filled in.
+type sortKey struct {
+	Rank    int          `json:"r,omitempty"`
+	Due     *string      `json:"d,omitempty"`
+	Updated time.Time    `json:"u,omitzero"`
+	Created time.Time    `json:"c,omitzero"`
+	Title   string       `json:"t,omitempty"`
+	ID      model.TaskID `json:"i"`
+}
+
+func keyOf(t model.Task, by string, cfg *config.Config) sortKey {
+	k := sortKey{ID: t.ID}
+	switch by {
+	case SortPriority:
+		k.Rank = PriorityRank(cfg, Priority(cfg, t.Modifiers))
+		k.Due, k.Updated = t.DueDate, t.UpdatedAt
+	case SortUpdated:
+		k.Updated = t.UpdatedAt
+	case SortCreated:
+		k.Created = t.CreatedAt
+	case SortTitle:
+		k.Title = strings.ToLower(t.Title)
+	default:
+		k.Due, k.Updated = t.DueDate, t.UpdatedAt
+	}
+	return k
+}
+
+// keyLess orders two keys under by, falling back to the ID.
+func keyLess(by string, a, b sortKey) bool {
+	switch by {
+	case SortPriority:
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		if c := dueCompare(a, b); c != 0 {
+			return c < 0
+		}
+	case SortUpdated:
+		if !a.Updated.Equal(b.Updated) {
+			return a.Updated.After(b.Updated)
+		}
+	case SortCreated:
+		if !a.Created.Equal(b.Created) {
+			return a.Created.After(b.Created)
+		}
+	case SortTitle:
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+	default:
+		if c := dueCompare(a, b); c != 0 {
+			return c < 0
+		}
+	}
+	return a.ID < b.ID
+}
+
+// dueCompare is dueLess over sort keys: -1 when a sorts first, 1 when b
+// does, 0 on a tie.
+func dueCompare(a, b sortKey) int {
+	switch {
+	case a.Due == nil && b.Due != nil:
+		return 1
+	case a.Due != nil && b.Due == nil:
+		return -1
+	case a.Due != nil && *a.Due != *b.Due:
+		if *a.Due < *b.Due {
+			return -1
+		}
+		return 1
+	case !a.Updated.Equal(b.Updated):
+		if a.Updated.After(b.Updated) {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// dueLess sorts due soonest first (undated last), then updated desc.
+func dueLess(a, b model.Task) bool {
+	da, db := a.DueDate, b.DueDate
+	switch {
+	case da == nil && db == nil:
+		return a.UpdatedAt.After(b.UpdatedAt)
+	case da == nil:
+		return false
+	case db == nil:
+		return true
+	case *da != *db:
+		return *da < *db
+	default:
+		return a.UpdatedAt.After(b.UpdatedAt)
+	}
+}
@@ -21,9 +21,10 @@ type HabitProgressResult struct {
 }
 
 // BuildHabitCompletionUpdate computes task habit progression for a completion event.
-// It is idempotent per task per local day to avoid toggling done/undone inflating counts.
+// It is idempotent per task per day to avoid toggling done/undone inflating counts.
+// Days are counted in completedAt's location, so pass it in the user's timezone.
 func BuildHabitCompletionUpdate(cur model.Task, completedAt time.Time) (Patch, HabitProgressResult) {
-	now := completedAt.In(time.Local)
+	now := completedAt
 	today := now.Format("2006-01-02")
 	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
 
@@ -74,7 +75,7 @@ func BuildHabitCompletionUpdate(cur model.Task, completedAt time.Time) (Patch, H
 	if counted {
 		delta := nextCount - cur.CompletionCount
 		patch.CompletionCountDelta = &delta
-		patch.LastCompletedDate = &today
+		patch.LastCompletedDate = Some(today)
 	}
 
 	return patch, HabitProgressResult{
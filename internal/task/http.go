@@ -4,19 +4,38 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"donegeon/internal/config"
 	"donegeon/internal/model"
 	"donegeon/internal/player"
+	"donegeon/internal/webhook"
 )
 
 type Handler struct {
-	repo           Repo
-	repoResolver   func(*http.Request) Repo
-	playerResolver func(*http.Request) *player.FileRepo
-	cfg            *config.Config
+	repo               Repo
+	repoResolver       func(*http.Request) Repo
+	playerResolver     func(*http.Request) *player.FileRepo
+	actorResolver      func(*http.Request) Actor
+	sharedRepoResolver func(*http.Request, model.TaskID) Repo
+	workLogResolver    func(*http.Request) WorkLogger
+	publisherResolver  func(*http.Request) webhook.Publisher
+	pluginResolver     func(*http.Request) PluginRunner
+	cfg                *config.Config
+}
+
+// WorkLogger records a finished work session against a task. The time
+// tracker implements it so /process can log sessions.
+type WorkLogger interface {
+	LogWork(taskID model.TaskID, start, end time.Time, note string) error
+}
+
+// Actor identifies the signed-in user behind a request.
+type Actor struct {
+	ID   string
+	Name string
 }
 
 func NewHandler(repo Repo) *Handler {
@@ -31,10 +50,69 @@ func (h *Handler) SetPlayerResolver(fn func(*http.Request) *player.FileRepo) {
 	h.playerResolver = fn
 }
 
+func (h *Handler) SetActorResolver(fn func(*http.Request) Actor) {
+	h.actorResolver = fn
+}
+
+// SetSharedRepoResolver resolves the owner's repo for a task another user
+// shares with the requester (team members); it returns nil otherwise.
+func (h *Handler) SetSharedRepoResolver(fn func(*http.Request, model.TaskID) Repo) {
+	h.sharedRepoResolver = fn
+}
+
+func (h *Handler) SetWorkLoggerResolver(fn func(*http.Request) WorkLogger) {
+	h.workLogResolver = fn
+}
+
+// SetPublisherResolver sends task.created and task.completed events to the
+// requester's webhooks.
+func (h *Handler) SetPublisherResolver(fn func(*http.Request) webhook.Publisher) {
+	h.publisherResolver = fn
+}
+
 func (h *Handler) SetConfig(cfg *config.Config) {
 	h.cfg = cfg
 }
 
+func (h *Handler) actorForRequest(r *http.Request) Actor {
+	if h.actorResolver == nil {
+		return Actor{}
+	}
+	return h.actorResolver(r)
+}
+
+func (h *Handler) sharedRepoFor(r *http.Request, id model.TaskID) Repo {
+	if h.sharedRepoResolver == nil {
+		return nil
+	}
+	return h.sharedRepoResolver(r, id)
+}
+
+func (h *Handler) workLoggerFor(r *http.Request) WorkLogger {
+	if h.workLogResolver == nil {
+		return nil
+	}
+	return h.workLogResolver(r)
+}
+
+func (h *Handler) publish(r *http.Request, event string, data any) {
+	if p := h.publisherForRequest(r); p != nil {
+		p.Publish(event, data)
+	}
+}
+
+func (h *Handler) publisherForRequest(r *http.Request) webhook.Publisher {
+	if h.publisherResolver == nil {
+		return nil
+	}
+	return h.publisherResolver(r)
+}
+
+// locationFor is the requesting user's timezone (server local by default).
+func (h *Handler) locationFor(r *http.Request) *time.Location {
+	return h.playerForRequest(r).Location()
+}
+
 func (h *Handler) repoForRequest(r *http.Request) Repo {
 	if h.repoResolver != nil {
 		if repo := h.repoResolver(r); repo != nil {
@@ -140,6 +218,27 @@ func allowsRecurrenceViaModifier(mods []model.TaskModifierSlot) bool {
 	return hasAnyModifier(mods, "mod.recurring", "mod.recurring_contract")
 }
 
+// normalizeRecurrenceInput canonicalizes rec in place and rejects rules the
+// engine or the configured recurrence types do not support.
+func (h *Handler) normalizeRecurrenceInput(rec *model.Recurrence) error {
+	if rec == nil {
+		return nil
+	}
+	NormalizeRecurrence(rec)
+	if err := ValidateRecurrence(rec); err != nil {
+		return err
+	}
+	if h.cfg == nil || len(h.cfg.Tasks.Recurrence.Supported) == 0 {
+		return nil
+	}
+	for _, typ := range h.cfg.Tasks.Recurrence.Supported {
+		if strings.EqualFold(strings.TrimSpace(typ), rec.Type) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: type %q is not enabled", ErrInvalidRecurrence, rec.Type)
+}
+
 func isUnlocked(repo *player.FileRepo, feature string) bool {
 	if repo == nil {
 		return true
@@ -217,6 +316,26 @@ func (h *Handler) villagerMaxStaminaFor(repo *player.FileRepo, villagerID string
 	return max
 }
 
+// listFilterFromQuery reads the list filters shared by GET /api/tasks and
+// its exports. Archived tasks are left out unless asked for.
+func (h *Handler) listFilterFromQuery(r *http.Request) ListFilter {
+	q := r.URL.Query()
+	filter := ListFilter{
+		Status:   q.Get("status"),
+		Project:  q.Get("project"),
+		Live:     parseBoolPtr(q.Get("live")),
+		Tags:     q["tag"],
+		ParentID: model.TaskID(q.Get("parent")),
+		Deferred: q.Get("deferred"),
+		Archived: q.Get("archived"),
+		Location: h.locationFor(r),
+	}
+	if filter.Archived == "" {
+		filter.Archived = "exclude"
+	}
+	return filter
+}
+
 // /api/tasks  (collection)
 func (h *Handler) TasksRoot(w http.ResponseWriter, r *http.Request) {
 	repo := h.repoForRequest(r)
@@ -225,17 +344,49 @@ func (h *Handler) TasksRoot(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		q := r.URL.Query()
-		filter := ListFilter{
-			Status:  q.Get("status"),
-			Project: q.Get("project"),
-			Live:    parseBoolPtr(q.Get("live")),
+		filter := h.listFilterFromQuery(r)
+		by := strings.ToLower(strings.TrimSpace(q.Get("sort")))
+		if by != "" && !IsSortOrder(by) {
+			writeErr(w, 400, "sort must be due, created, updated, priority or title")
+			return
+		}
+		limit := 0
+		if raw := strings.TrimSpace(q.Get("limit")); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxListLimit {
+				writeErr(w, 400, fmt.Sprintf("limit must be between 1 and %d", maxListLimit))
+				return
+			}
+			limit = n
 		}
 		ts, err := repo.List(filter)
 		if err != nil {
 			writeErr(w, 500, err.Error())
 			return
 		}
-		writeJSON(w, 200, ts)
+		// Always sort, even in the default order, so the order is total
+		// and page cursors resume from their sort key.
+		SortTasks(ts, by, h.cfg)
+		total := len(ts)
+		ts, next, err := paginate(ts, by, h.cfg, limit, q.Get("cursor"))
+		if err != nil {
+			writeErr(w, 400, err.Error())
+			return
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		if next != "" {
+			w.Header().Set("X-Next-Cursor", next)
+		}
+		if fields := parseFields(q.Get("fields")); fields != nil {
+			lean, err := projectFields(ts, fields)
+			if err != nil {
+				writeErr(w, 500, err.Error())
+				return
+			}
+			writeListJSON(w, r, lean)
+			return
+		}
+		writeListJSON(w, r, ts)
 		return
 
 	case http.MethodPost:
@@ -244,6 +395,23 @@ func (h *Handler) TasksRoot(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, 400, "bad json")
 			return
 		}
+		if err := h.normalizeRecurrenceInput(in.Recurrence); err != nil {
+			writeErr(w, 400, err.Error())
+			return
+		}
+		if in.EstimateMinutes < 0 {
+			writeErr(w, 400, "estimateMinutes must be >= 0")
+			return
+		}
+		if err := ValidateSchedule(in.DueDate, in.DueTime, in.StartDate); err != nil {
+			writeErr(w, 400, err.Error())
+			return
+		}
+		if in.DueTime != nil && strings.TrimSpace(*in.DueTime) != "" &&
+			(in.DueDate == nil || strings.TrimSpace(*in.DueDate) == "") {
+			writeErr(w, 400, ErrDueTimeNoDate.Error())
+			return
+		}
 		if in.DueDate != nil &&
 			strings.TrimSpace(*in.DueDate) != "" &&
 			!isUnlocked(playerRepo, player.FeatureTaskDueDate) &&
@@ -264,6 +432,7 @@ func (h *Handler) TasksRoot(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		in.Project = normalizeProject(in.Project)
+		in.Tags = append(in.Tags, ModifierTags(h.cfg, in.Modifiers)...)
 
 		t, err := repo.Create(model.Task{
 			Title:       in.Title,
@@ -273,8 +442,12 @@ func (h *Handler) TasksRoot(w http.ResponseWriter, r *http.Request) {
 			Tags:        in.Tags,
 			Modifiers:   in.Modifiers,
 			DueDate:     in.DueDate,
+			DueTime:     in.DueTime,
+			StartDate:   in.StartDate,
 			NextAction:  in.NextAction,
 			Recurrence:  in.Recurrence,
+
+			EstimateMinutes: in.EstimateMinutes,
 		})
 		if err != nil {
 			if err == ErrTooManyMods {
@@ -285,7 +458,9 @@ func (h *Handler) TasksRoot(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		writeJSON(w, 201, t)
+		h.publish(r, webhook.EventTaskCreated, t)
+		h.taskChanged(r, t)
+		writeTaskJSON(w, 201, t)
 		return
 
 	default:
@@ -322,32 +497,35 @@ func (h *Handler) TasksSub(w http.ResponseWriter, r *http.Request) {
 				writeErr(w, 500, err.Error())
 				return
 			}
-			writeJSON(w, 200, t)
+			w.Header().Set("Accept-Patch", MergePatchContentType)
+			if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, TaskETag(t), true) {
+				w.Header().Set("ETag", TaskETag(t))
+				w.WriteHeader(304)
+				return
+			}
+			writeTaskJSON(w, 200, t)
 			return
 
 		case http.MethodPatch:
-			var p Patch
-			if err := decodeJSON(r, &p); err != nil {
-				writeErr(w, 400, "bad json")
+			p, err := decodePatch(r, repo, model.TaskID(id))
+			if err == ErrNotFound {
+				writeErr(w, 404, "not found")
 				return
 			}
-			var (
-				cur       model.Task
-				needCur   bool
-				curLoaded bool
-			)
-			if p.Modifiers == nil && (p.DueDate != nil || p.NextAction != nil || p.Recurrence != nil) {
-				needCur = true
+			if err == errNullTitle {
+				writeErr(w, 400, err.Error())
+				return
 			}
-			if p.Done != nil && *p.Done {
-				needCur = true
+			if err != nil {
+				writeErr(w, 400, "bad json")
+				return
 			}
-			if p.Done != nil && *p.Done && h.completionRequiresAssignedVillager() {
-				needCur = true
+			if err := h.validatePatch(&p); err != nil {
+				writeErr(w, 400, err.Error())
+				return
 			}
-			if needCur {
-				var err error
-				cur, err = repo.Get(model.TaskID(id))
+			if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+				cur, err := repo.Get(model.TaskID(id))
 				if err == ErrNotFound {
 					writeErr(w, 404, "not found")
 					return
@@ -356,64 +534,18 @@ func (h *Handler) TasksSub(w http.ResponseWriter, r *http.Request) {
 					writeErr(w, 500, err.Error())
 					return
 				}
-				curLoaded = true
-			}
-
-			effectiveMods := []model.TaskModifierSlot{}
-			if p.Modifiers != nil {
-				effectiveMods = *p.Modifiers
-			} else if curLoaded {
-				effectiveMods = cur.Modifiers
-			}
-
-			if p.DueDate != nil &&
-				strings.TrimSpace(*p.DueDate) != "" &&
-				!isUnlocked(playerRepo, player.FeatureTaskDueDate) &&
-				!allowsDueDateViaModifier(effectiveMods) {
-				denyLockedFeature(w, player.FeatureTaskDueDate)
-				return
-			}
-			if p.NextAction != nil &&
-				*p.NextAction &&
-				!isUnlocked(playerRepo, player.FeatureTaskNextAction) &&
-				!allowsNextActionViaModifier(effectiveMods) {
-				denyLockedFeature(w, player.FeatureTaskNextAction)
-				return
-			}
-			if p.Recurrence != nil &&
-				!isUnlocked(playerRepo, player.FeatureTaskRecurrence) &&
-				!allowsRecurrenceViaModifier(effectiveMods) {
-				denyLockedFeature(w, player.FeatureTaskRecurrence)
-				return
-			}
-			if p.Done != nil && *p.Done && h.completionRequiresAssignedVillager() {
-				if !curLoaded {
-					var err error
-					cur, err = repo.Get(model.TaskID(id))
-					if err == ErrNotFound {
-						writeErr(w, 404, "not found")
-						return
-					}
-					if err != nil {
-						writeErr(w, 500, err.Error())
-						return
-					}
-				}
-				if cur.AssignedVillagerID == nil || strings.TrimSpace(*cur.AssignedVillagerID) == "" {
-					writeErr(w, 400, "task completion requires an assigned villager")
+				if !etagMatches(ifMatch, TaskETag(cur), false) {
+					writePreconditionFailed(w, cur)
 					return
 				}
+				// Re-checked inside the repo so a concurrent write still loses.
+				rev := cur.Revision
+				p.IfRevision = &rev
 			}
-			habitBonusCoin := 0
-			justCompleted := p.Done != nil && *p.Done && curLoaded && !cur.Done
-			if justCompleted {
-				habitPatch, habitResult := BuildHabitCompletionUpdate(cur, time.Now())
-				p.CompletionCountDelta = habitPatch.CompletionCountDelta
-				p.Habit = habitPatch.Habit
-				p.HabitTier = habitPatch.HabitTier
-				p.HabitStreak = habitPatch.HabitStreak
-				p.LastCompletedDate = habitPatch.LastCompletedDate
-				habitBonusCoin = habitResult.BonusCoin
+			p, fx, perr := h.planPatch(repo, playerRepo, model.TaskID(id), p)
+			if perr != nil {
+				writeErr(w, perr.code, perr.msg)
+				return
 			}
 
 			t, err := repo.Update(model.TaskID(id), p)
@@ -421,6 +553,14 @@ func (h *Handler) TasksSub(w http.ResponseWriter, r *http.Request) {
 				writeErr(w, 400, err.Error())
 				return
 			}
+			if err == ErrRevisionConflict {
+				if cur, gerr := repo.Get(model.TaskID(id)); gerr == nil {
+					writePreconditionFailed(w, cur)
+					return
+				}
+				writeErr(w, 412, err.Error())
+				return
+			}
 			if err == ErrNotFound {
 				writeErr(w, 404, "not found")
 				return
@@ -429,13 +569,12 @@ func (h *Handler) TasksSub(w http.ResponseWriter, r *http.Request) {
 				writeErr(w, 500, err.Error())
 				return
 			}
-			if habitBonusCoin > 0 && playerRepo != nil {
-				_, _ = playerRepo.AddLoot(player.LootCoin, habitBonusCoin)
-			}
-			if justCompleted && playerRepo != nil {
-				_, _, _ = playerRepo.IncrementMetric(player.MetricTasksCompleted, 1)
+			fx.apply(playerRepo)
+			if fx.completed > 0 {
+				h.publish(r, webhook.EventTaskCompleted, t)
 			}
-			writeJSON(w, 200, t)
+			h.taskChanged(r, t)
+			writeTaskJSON(w, 200, t)
 			return
 
 		default:
@@ -458,7 +597,7 @@ func (h *Handler) TasksSub(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			ics, err := BuildTaskCalendarICS(t, time.Now())
+			ics, err := BuildTaskCalendarICS(t, time.Now().In(h.locationFor(r)))
 			if err != nil {
 				writeErr(w, 400, err.Error())
 				return
@@ -475,6 +614,31 @@ func (h *Handler) TasksSub(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// /api/tasks/{id}/comments[/{commentId}]
+	if len(parts) >= 2 && len(parts) <= 3 && parts[1] == "comments" {
+		h.taskComments(w, r, repo, model.TaskID(id), parts[2:])
+		return
+	}
+
+	// /api/tasks/{id}/history
+	if len(parts) == 2 && parts[1] == "history" {
+		if r.Method != http.MethodGet {
+			writeErr(w, 405, "method not allowed")
+			return
+		}
+		entries, err := repo.History(model.TaskID(id))
+		if err == ErrNotFound {
+			writeErr(w, 404, "not found")
+			return
+		}
+		if err != nil {
+			writeErr(w, 500, err.Error())
+			return
+		}
+		writeJSON(w, 200, entries)
+		return
+	}
+
 	// /api/tasks/{id}/live
 	if len(parts) == 2 && parts[1] == "live" {
 		switch r.Method {
@@ -524,10 +688,17 @@ func (h *Handler) TasksSub(w http.ResponseWriter, r *http.Request) {
 		case http.MethodPost:
 			var in struct {
 				MarkDone bool `json:"markDone"`
+				// Minutes optionally logs a work session ending now.
+				Minutes int    `json:"minutes"`
+				Note    string `json:"note"`
 			}
 			if r.Body != nil {
 				_ = decodeJSON(r, &in)
 			}
+			if in.Minutes < 0 || in.Minutes > 24*60 {
+				writeErr(w, 400, "minutes must be between 0 and 1440")
+				return
+			}
 
 			cur, err := repo.Get(model.TaskID(id))
 			if err == ErrNotFound {
@@ -569,6 +740,10 @@ func (h *Handler) TasksSub(w http.ResponseWriter, r *http.Request) {
 				WorkedToday:         &worked,
 				ProcessedCountDelta: &inc,
 			}
+			if in.Minutes > 0 {
+				secs := int64(in.Minutes) * 60
+				patch.TrackedSecondsDelta = &secs
+			}
 			habitBonusCoin := 0
 			justCompleted := false
 			if in.MarkDone {
@@ -576,7 +751,7 @@ func (h *Handler) TasksSub(w http.ResponseWriter, r *http.Request) {
 				patch.Done = &done
 				if !cur.Done {
 					justCompleted = true
-					habitPatch, habitResult := BuildHabitCompletionUpdate(cur, time.Now())
+					habitPatch, habitResult := BuildHabitCompletionUpdate(cur, time.Now().In(h.locationFor(r)))
 					patch.CompletionCountDelta = habitPatch.CompletionCountDelta
 					patch.Habit = habitPatch.Habit
 					patch.HabitTier = habitPatch.HabitTier
@@ -595,6 +770,13 @@ func (h *Handler) TasksSub(w http.ResponseWriter, r *http.Request) {
 				writeErr(w, 500, err.Error())
 				return
 			}
+			if in.Minutes > 0 {
+				if logger := h.workLoggerFor(r); logger != nil {
+					end := time.Now().UTC()
+					start := end.Add(-time.Duration(in.Minutes) * time.Minute)
+					_ = logger.LogWork(updated.ID, start, end, strings.TrimSpace(in.Note))
+				}
+			}
 			if habitBonusCoin > 0 {
 				if pRepo := h.playerForRequest(r); pRepo != nil {
 					_, _ = pRepo.AddLoot(player.LootCoin, habitBonusCoin)
@@ -604,6 +786,8 @@ func (h *Handler) TasksSub(w http.ResponseWriter, r *http.Request) {
 				if pRepo := h.playerForRequest(r); pRepo != nil {
 					_, _, _ = pRepo.IncrementMetric(player.MetricTasksCompleted, 1)
 				}
+				h.publish(r, webhook.EventTaskCompleted, updated)
+				h.taskChanged(r, updated)
 			}
 
 			writeJSON(w, 200, map[string]any{
@@ -0,0 +1,134 @@
+package task
+
+import (
+	"net/http"
+
+	"donegeon/internal/model"
+)
+
+// /api/tasks/{id}/comments and /api/tasks/{id}/comments/{commentId}
+func (h *Handler) taskComments(w http.ResponseWriter, r *http.Request, repo Repo, id model.TaskID, rest []string) {
+	actor := h.actorForRequest(r)
+
+	// Team members reach the owner's thread through the shared resolver.
+	isOwner := true
+	if _, err := repo.Get(id); err == ErrNotFound {
+		shared := h.sharedRepoFor(r, id)
+		if shared == nil {
+			writeErr(w, 404, "not found")
+			return
+		}
+		repo, isOwner = shared, false
+	} else if err != nil {
+		writeErr(w, 500, err.Error())
+		return
+	}
+
+	if len(rest) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			comments, err := repo.Comments(id)
+			if err != nil {
+				writeCommentErr(w, err)
+				return
+			}
+			writeJSON(w, 200, comments)
+			return
+
+		case http.MethodPost:
+			var in struct {
+				Body string `json:"body"`
+			}
+			if err := decodeJSON(r, &in); err != nil {
+				writeErr(w, 400, "bad json")
+				return
+			}
+			c, err := repo.AddComment(id, model.TaskComment{
+				AuthorID:   actor.ID,
+				AuthorName: actor.Name,
+				Body:       in.Body,
+			})
+			if err != nil {
+				writeCommentErr(w, err)
+				return
+			}
+			writeJSON(w, 201, c)
+			return
+
+		default:
+			writeErr(w, 405, "method not allowed")
+			return
+		}
+	}
+
+	commentID := rest[0]
+	comments, err := repo.Comments(id)
+	if err != nil {
+		writeCommentErr(w, err)
+		return
+	}
+	var existing *model.TaskComment
+	for i := range comments {
+		if comments[i].ID == commentID {
+			existing = &comments[i]
+			break
+		}
+	}
+	if existing == nil {
+		writeErr(w, 404, ErrCommentNotFound.Error())
+		return
+	}
+	// Comments without an author (plugins, anonymous writes) can only be
+	// removed by the task owner.
+	isAuthor := actor.ID != "" && existing.AuthorID == actor.ID
+
+	switch r.Method {
+	case http.MethodPatch:
+		if !isAuthor {
+			writeErr(w, 403, "only the author can edit a comment")
+			return
+		}
+		var in struct {
+			Body string `json:"body"`
+		}
+		if err := decodeJSON(r, &in); err != nil {
+			writeErr(w, 400, "bad json")
+			return
+		}
+		c, err := repo.UpdateComment(id, commentID, in.Body)
+		if err != nil {
+			writeCommentErr(w, err)
+			return
+		}
+		writeJSON(w, 200, c)
+		return
+
+	case http.MethodDelete:
+		// Task owners moderate their own threads.
+		if !isAuthor && !isOwner {
+			writeErr(w, 403, "only the author or task owner can delete a comment")
+			return
+		}
+		if err := repo.DeleteComment(id, commentID); err != nil {
+			writeCommentErr(w, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+		return
+
+	default:
+		writeErr(w, 405, "method not allowed")
+		return
+	}
+}
+
+func writeCommentErr(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrNotFound, ErrCommentNotFound:
+		writeErr(w, 404, err.Error())
+	case ErrCommentEmpty, ErrCommentTooLong:
+		writeErr(w, 400, err.Error())
+	default:
+		writeErr(w, 500, err.Error())
+	}
+}
@@ -0,0 +1,57 @@
+package task
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GET /api/tasks/export?format=markdown&template=status&project=home&sort=due
+//
+// Renders the filtered task list as Markdown or todo.txt. It takes the same
+// filters and sort as GET /api/tasks; template picks the Markdown layout
+// (project, checklist or status) and title sets its heading. download=1
+// asks the browser to save the file.
+func (h *Handler) TasksExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, 405, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	export, err := ParseTextExport(q.Get("format"), q.Get("template"))
+	if err != nil {
+		writeErr(w, 400, err.Error())
+		return
+	}
+	by := strings.ToLower(strings.TrimSpace(q.Get("sort")))
+	if by != "" && !IsSortOrder(by) {
+		writeErr(w, 400, "sort must be due, created, updated, priority or title")
+		return
+	}
+
+	filter := h.listFilterFromQuery(r)
+	ts, err := h.repoForRequest(r).List(filter)
+	if err != nil {
+		writeErr(w, 500, err.Error())
+		return
+	}
+	if by == "" {
+		by = SortDue
+	}
+	SortTasks(ts, by, h.cfg)
+
+	export.Title = strings.TrimSpace(q.Get("title"))
+	export.Config = h.cfg
+	export.Now = time.Now().In(filter.Location)
+	body := RenderTasks(ts, export)
+
+	disposition := "inline"
+	switch strings.ToLower(strings.TrimSpace(q.Get("download"))) {
+	case "1", "true", "yes":
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", export.ContentType())
+	w.Header().Set("Content-Disposition", disposition+`; filename="`+export.Filename("tasks")+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(body))
+}
@@ -7,10 +7,13 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"donegeon/internal/config"
 	"donegeon/internal/model"
 	"donegeon/internal/player"
+	"donegeon/internal/webhook"
 )
 
 func newTaskHandlerForTests(t *testing.T, requireAssigned bool) (*Handler, *MemoryRepo, *player.FileRepo) {
@@ -124,7 +127,7 @@ func TestTasksSub_CompletionRequiresAssignedVillager(t *testing.T) {
 	}
 
 	assigned := "villager_stack_1"
-	if _, err := repo.Update(created.ID, Patch{AssignedVillagerID: &assigned}); err != nil {
+	if _, err := repo.Update(created.ID, Patch{AssignedVillagerID: Some(assigned)}); err != nil {
 		t.Fatalf("assign villager: %v", err)
 	}
 
@@ -155,7 +158,7 @@ func TestTasksSub_ProcessRequiresAssignmentAndUpdatesProgress(t *testing.T) {
 	}
 
 	assigned := "villager_stack_2"
-	if _, err := repo.Update(created.ID, Patch{AssignedVillagerID: &assigned}); err != nil {
+	if _, err := repo.Update(created.ID, Patch{AssignedVillagerID: Some(assigned)}); err != nil {
 		t.Fatalf("assign villager: %v", err)
 	}
 
@@ -209,7 +212,7 @@ func TestTasksSub_ProcessConsumesStaminaUntilDepleted(t *testing.T) {
 		t.Fatalf("create task: %v", err)
 	}
 	assigned := "villager_stack_stamina"
-	if _, err := repo.Update(created.ID, Patch{AssignedVillagerID: &assigned}); err != nil {
+	if _, err := repo.Update(created.ID, Patch{AssignedVillagerID: Some(assigned)}); err != nil {
 		t.Fatalf("assign villager: %v", err)
 	}
 
@@ -254,7 +257,7 @@ func TestTasksSub_ToggleDonePreservesTaskDetails(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create task: %v", err)
 	}
-	if _, err := repo.Update(created.ID, Patch{Project: &inbox}); err != nil {
+	if _, err := repo.Update(created.ID, Patch{Project: Some(inbox)}); err != nil {
 		t.Fatalf("set inbox project: %v", err)
 	}
 
@@ -349,7 +352,8 @@ func TestTasksRoot_CreateLockedFieldsAllowedWithAttachedModifier(t *testing.T) {
 func TestTasksSub_DoneTransitionUpdatesHabitProgress(t *testing.T) {
 	h, repo, playerRepo := newTaskHandlerForTests(t, false)
 	inbox := "inbox"
-	yesterday := "2026-02-06"
+	// streak days are counted in the player's timezone
+	yesterday := time.Now().In(playerRepo.Location()).AddDate(0, 0, -1).Format("2006-01-02")
 	created, err := repo.Create(model.Task{
 		Title:             "Daily standup",
 		Project:           &inbox,
@@ -524,3 +528,807 @@ func TestTasksSub_CalendarExportRequiresDueDate(t *testing.T) {
 		t.Fatalf("expected due-date-required error, got body=%s", rec.Body.String())
 	}
 }
+
+func TestTasksSub_PatchNullClearsAndMergePatchMerges(t *testing.T) {
+	h, repo, _ := newTaskHandlerForTests(t, false)
+	inbox := "inbox"
+	due := "2026-02-12"
+	until := "2026-12-31"
+	created, err := repo.Create(model.Task{
+		Title:     "Rent",
+		Project:   &inbox,
+		DueDate:   &due,
+		Modifiers: []model.TaskModifierSlot{{DefID: "mod.recurring"}},
+		Recurrence: &model.Recurrence{
+			Type:     "monthly",
+			Interval: 1,
+			Until:    &until,
+		},
+	})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/tasks/"+string(created.ID),
+		strings.NewReader(`{"recurrence":{"interval":2,"until":null},"dueDate":null}`))
+	req.Header.Set("Content-Type", MergePatchContentType)
+	rec := httptest.NewRecorder()
+	h.TasksSub(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for merge patch, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	got, err := repo.Get(created.ID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if got.Recurrence == nil || got.Recurrence.Type != "monthly" || got.Recurrence.Interval != 2 || got.Recurrence.Until != nil {
+		t.Fatalf("expected recurrence merged, got %+v", got.Recurrence)
+	}
+	if got.DueDate != nil {
+		t.Fatalf("expected null to clear due date, got %v", *got.DueDate)
+	}
+
+	rec = httptest.NewRecorder()
+	h.TasksSub(rec, jsonReq(http.MethodPatch, "/api/tasks/"+string(created.ID), map[string]any{
+		"recurrence": nil,
+	}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 clearing recurrence, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	got, err = repo.Get(created.ID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if got.Recurrence != nil {
+		t.Fatalf("expected recurrence cleared, got %+v", got.Recurrence)
+	}
+	if got.Project == nil || *got.Project != inbox || got.Title != "Rent" {
+		t.Fatalf("expected absent fields untouched, got %+v", got)
+	}
+}
+
+func TestTasksSub_MergePatchNullDescriptionClearsAndNullTitleFails(t *testing.T) {
+	h, repo, _ := newTaskHandlerForTests(t, false)
+	created, err := repo.Create(model.Task{Title: "Rent", Description: "Due on the first"})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/tasks/"+string(created.ID), strings.NewReader(body))
+		req.Header.Set("Content-Type", MergePatchContentType)
+		rec := httptest.NewRecorder()
+		h.TasksSub(rec, req)
+		return rec
+	}
+
+	if rec := patch(`{"description":null}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 clearing description, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got, _ := repo.Get(created.ID); got.Description != "" || got.Title != "Rent" {
+		t.Fatalf("expected null to clear only the description, got %+v", got)
+	}
+
+	rec := patch(`{"title":null}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "title cannot be null") {
+		t.Fatalf("expected 400 for a null title, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got, _ := repo.Get(created.ID); got.Title != "Rent" {
+		t.Fatalf("expected the title kept, got %q", got.Title)
+	}
+}
+
+func TestTasksSub_IfMatchRejectsStaleRevision(t *testing.T) {
+	h, repo, _ := newTaskHandlerForTests(t, false)
+	inbox := "inbox"
+	created, err := repo.Create(model.Task{Title: "Draft", Project: &inbox})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.TasksSub(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/"+string(created.ID), nil))
+	etag := rec.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("expected ETag \"1\", got %q", etag)
+	}
+
+	// A board edit lands first and bumps the revision.
+	title := "Board title"
+	if _, err := repo.Update(created.ID, Patch{Title: &title}); err != nil {
+		t.Fatalf("board update: %v", err)
+	}
+
+	req := jsonReq(http.MethodPatch, "/api/tasks/"+string(created.ID), map[string]any{"title": "Stale"})
+	req.Header.Set("If-Match", etag)
+	rec = httptest.NewRecorder()
+	h.TasksSub(rec, req)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var conflict struct {
+		Current model.Task `json:"current"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &conflict); err != nil {
+		t.Fatalf("decode conflict: %v", err)
+	}
+	if conflict.Current.Title != "Board title" || conflict.Current.Revision != 2 {
+		t.Fatalf("expected current server copy, got %+v", conflict.Current)
+	}
+
+	req = jsonReq(http.MethodPatch, "/api/tasks/"+string(created.ID), map[string]any{"title": "Fresh"})
+	req.Header.Set("If-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	h.TasksSub(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with fresh ETag, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("ETag"); got != `"3"` {
+		t.Fatalf("expected ETag \"3\" after update, got %q", got)
+	}
+}
+
+func TestTasksSub_HistoryRecordsSourcesAndDiffs(t *testing.T) {
+	h, repo, _ := newTaskHandlerForTests(t, false)
+
+	rec := httptest.NewRecorder()
+	h.TasksRoot(rec, jsonReq(http.MethodPost, "/api/tasks", map[string]any{"title": "Write notes"}))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var created model.Task
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode created: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.TasksSub(rec, jsonReq(http.MethodPatch, "/api/tasks/"+string(created.ID), map[string]any{"title": "Write meeting notes"}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	done := true
+	if _, err := repo.WithOrigin(SourceBoard, "").Update(created.ID, Patch{Done: &done}); err != nil {
+		t.Fatalf("board complete: %v", err)
+	}
+	// No-op writes leave no entry.
+	if _, err := repo.Update(created.ID, Patch{Done: &done}); err != nil {
+		t.Fatalf("no-op update: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.TasksSub(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/"+string(created.ID)+"/history", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var entries []model.TaskActivity
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 history entries, got %+v", entries)
+	}
+	if entries[0].Action != "created" || entries[0].Source != SourceAPI {
+		t.Fatalf("expected api create entry, got %+v", entries[0])
+	}
+	if entries[1].Action != "updated" || len(entries[1].Changes) != 1 || entries[1].Changes[0].Field != "title" ||
+		entries[1].Changes[0].From != "Write notes" || entries[1].Changes[0].To != "Write meeting notes" {
+		t.Fatalf("expected title diff, got %+v", entries[1])
+	}
+	if entries[2].Action != "completed" || entries[2].Source != SourceBoard {
+		t.Fatalf("expected board completion entry, got %+v", entries[2])
+	}
+}
+
+func TestTasksRoot_ScheduleFiltersUseUserTimezone(t *testing.T) {
+	h, repo, playerRepo := newTaskHandlerForTests(t, false)
+	tz := "Pacific/Kiritimati" // UTC+14, so "today" differs from the server's for most of the day
+	if _, err := playerRepo.UpdateProfile(nil, nil, &tz); err != nil {
+		t.Fatalf("set timezone: %v", err)
+	}
+	loc := playerRepo.Location()
+	inbox := "inbox"
+	today := Today(time.Now(), loc)
+	tomorrow := time.Now().In(loc).AddDate(0, 0, 1).Format("2006-01-02")
+	midnight := "00:00"
+
+	dueToday, _ := repo.Create(model.Task{Title: "Due today", Project: &inbox, DueDate: &today})
+	pastDue, _ := repo.Create(model.Task{Title: "Due at midnight", Project: &inbox, DueDate: &today, DueTime: &midnight})
+	deferred, _ := repo.Create(model.Task{Title: "Later", Project: &inbox, StartDate: &tomorrow})
+
+	list := func(query string) map[model.TaskID]bool {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.TasksRoot(rec, httptest.NewRequest(http.MethodGet, "/api/tasks?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+		}
+		var out []model.Task
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		ids := map[model.TaskID]bool{}
+		for _, task := range out {
+			ids[task.ID] = true
+		}
+		return ids
+	}
+
+	if got := list("status=overdue"); !got[pastDue.ID] || got[dueToday.ID] {
+		t.Fatalf("expected only the timed task overdue, got %v", got)
+	}
+	if got := list("status=due_today"); !got[pastDue.ID] || !got[dueToday.ID] {
+		t.Fatalf("expected both tasks due today, got %v", got)
+	}
+	if got := list("project=inbox"); got[deferred.ID] {
+		t.Fatalf("expected deferred task hidden from inbox")
+	}
+	if got := list("project=inbox&deferred=include"); !got[deferred.ID] {
+		t.Fatalf("expected deferred task with deferred=include")
+	}
+}
+
+func TestTasksSub_ContextFilterModifierAddsTags(t *testing.T) {
+	h, repo, _ := newTaskHandlerForTests(t, false)
+	h.cfg.Modifiers.Types = append(h.cfg.Modifiers.Types, config.ModifierType{
+		ID:      "context_filter",
+		Effects: map[string]interface{}{"add_tags": []any{"@Context"}},
+	})
+	created, err := repo.Create(model.Task{Title: "Call plumber", Tags: []string{"Home"}})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.TasksSub(rec, jsonReq(http.MethodPatch, "/api/tasks/"+string(created.ID), map[string]any{
+		"modifiers": []any{map[string]any{"defId": "mod.context_filter"}},
+	}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	got, _ := repo.Get(created.ID)
+	if strings.Join(got.Tags, ",") != "home,@context" {
+		t.Fatalf("expected normalized tags plus @context, got %v", got.Tags)
+	}
+}
+
+func TestTasksRoot_PaginatesSortsByPriorityAndProjectsFields(t *testing.T) {
+	h, repo, _ := newTaskHandlerForTests(t, false)
+	h.cfg.Modifiers.Types = append(h.cfg.Modifiers.Types, config.ModifierType{
+		ID:      "test_urgent",
+		Effects: map[string]interface{}{"set_priority": "high"},
+	})
+	a, _ := repo.Create(model.Task{Title: "A"})
+	b, _ := repo.Create(model.Task{Title: "B", Modifiers: []model.TaskModifierSlot{{DefID: "mod.test_urgent"}}})
+	c, _ := repo.Create(model.Task{Title: "C"})
+
+	get := func(query string) (*httptest.ResponseRecorder, []map[string]any) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.TasksRoot(rec, httptest.NewRequest(http.MethodGet, "/api/tasks?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 for %q, got %d body=%s", query, rec.Code, rec.Body.String())
+		}
+		var out []map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return rec, out
+	}
+
+	rec, page := get("sort=priority&limit=2&fields=title")
+	if rec.Header().Get("X-Total-Count") != "3" || rec.Header().Get("X-Next-Cursor") == "" {
+		t.Fatalf("expected total and next cursor headers, got %v", rec.Header())
+	}
+	if len(page) != 2 || page[0]["id"] != string(b.ID) || page[0]["title"] != "B" {
+		t.Fatalf("expected prioritized task first, got %v", page)
+	}
+	if _, ok := page[0]["description"]; ok {
+		t.Fatalf("expected fields projection to drop description, got %v", page[0])
+	}
+
+	rec, page = get("sort=priority&limit=2&cursor=" + rec.Header().Get("X-Next-Cursor"))
+	if len(page) != 1 || rec.Header().Get("X-Next-Cursor") != "" {
+		t.Fatalf("expected a final page of one, got %v", page)
+	}
+	seen := map[any]bool{page[0]["id"]: true}
+	if !seen[string(a.ID)] && !seen[string(c.ID)] {
+		t.Fatalf("unexpected last page %v", page)
+	}
+
+	rec = httptest.NewRecorder()
+	h.TasksRoot(rec, httptest.NewRequest(http.MethodGet, "/api/tasks?cursor=bogus!", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad cursor, got %d", rec.Code)
+	}
+}
+
+func TestTasksRoot_CursorSurvivesChangesBetweenPages(t *testing.T) {
+	h, repo, _ := newTaskHandlerForTests(t, false)
+	var ids []model.TaskID
+	for _, title := range []string{"A", "B", "C", "D"} {
+		tk, _ := repo.Create(model.Task{Title: title})
+		ids = append(ids, tk.ID)
+	}
+
+	page := func(query string) ([]model.TaskID, string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.TasksRoot(rec, httptest.NewRequest(http.MethodGet, "/api/tasks?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 for %q, got %d body=%s", query, rec.Code, rec.Body.String())
+		}
+		var out []model.Task
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		got := make([]model.TaskID, 0, len(out))
+		for _, tk := range out {
+			got = append(got, tk.ID)
+		}
+		return got, rec.Header().Get("X-Next-Cursor")
+	}
+
+	// The cursor task is completed and drops out of status=pending.
+	first, next := page("status=pending&sort=title&limit=2")
+	if len(first) != 2 || first[1] != ids[1] {
+		t.Fatalf("unexpected first page %v", first)
+	}
+	done := true
+	if _, err := repo.Update(ids[1], Patch{Done: &done}); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	second, _ := page("status=pending&sort=title&limit=2&cursor=" + next)
+	if len(second) != 2 || second[0] != ids[2] || second[1] != ids[3] {
+		t.Fatalf("expected C and D after the completed cursor task, got %v", second)
+	}
+
+	// An edit moves a served task to the top of sort=updated; it is not
+	// served again.
+	first, next = page("sort=updated&limit=2")
+	title := "edited"
+	if _, err := repo.Update(first[0], Patch{Title: &title}); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	second, _ = page("sort=updated&limit=2&cursor=" + next)
+	for _, id := range second {
+		if id == first[0] || id == first[1] {
+			t.Fatalf("task %s served twice: %v then %v", id, first, second)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.TasksRoot(rec, httptest.NewRequest(http.MethodGet, "/api/tasks?sort=due&cursor="+next, nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a cursor from another sort order, got %d", rec.Code)
+	}
+}
+
+func TestTasksBulk_AllOrNothingAndCountsCompletions(t *testing.T) {
+	h, repo, playerRepo := newTaskHandlerForTests(t, true)
+	assigned := "villager-1"
+	a, _ := repo.Create(model.Task{Title: "A", AssignedVillagerID: &assigned})
+	b, _ := repo.Create(model.Task{Title: "B", AssignedVillagerID: &assigned})
+	c, _ := repo.Create(model.Task{Title: "C"})
+
+	rec := httptest.NewRecorder()
+	h.TasksBulk(rec, jsonReq(http.MethodPost, "/api/tasks/bulk", map[string]any{
+		"ids":    []model.TaskID{a.ID, b.ID, c.ID},
+		"action": "complete",
+	}))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 when one task has no villager, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got, _ := repo.Get(a.ID); got.Done {
+		t.Fatalf("expected no task completed after a failed batch")
+	}
+
+	if _, err := repo.Update(c.ID, Patch{AssignedVillagerID: Some(assigned)}); err != nil {
+		t.Fatalf("assign villager: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	h.TasksBulk(rec, jsonReq(http.MethodPost, "/api/tasks/bulk", map[string]any{
+		"ids":    []model.TaskID{a.ID, b.ID, c.ID, a.ID},
+		"action": "complete",
+	}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got := playerRepo.GetMetric(player.MetricTasksCompleted); got != 3 {
+		t.Fatalf("expected 3 completions counted once each, got %d", got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.TasksBulk(rec, jsonReq(http.MethodPost, "/api/tasks/bulk", map[string]any{
+		"filter": map[string]any{"status": "done"},
+		"action": "archive",
+	}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	h.TasksRoot(rec, httptest.NewRequest(http.MethodGet, "/api/tasks?status=all", nil))
+	if rec.Header().Get("X-Total-Count") != "0" {
+		t.Fatalf("expected archived tasks hidden from the listing, got %s", rec.Body.String())
+	}
+}
+
+func TestCalendarFeed_DatedTasksWithSequenceAndFilters(t *testing.T) {
+	h, repo, _ := newTaskHandlerForTests(t, false)
+	home, work := "home", "work"
+	due := "2026-02-12"
+	dueTime := "09:30"
+	long := strings.Repeat("Long description with ünïcödé ", 6)
+	trash, err := repo.Create(model.Task{
+		Title:       "Take out trash",
+		Description: long,
+		Project:     &home,
+		Tags:        []string{"chores"},
+		DueDate:     &due,
+		Recurrence:  &model.Recurrence{Type: "weekly", Interval: 1},
+	})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if _, err := repo.Create(model.Task{Title: "Standup", Project: &work, DueDate: &due, DueTime: &dueTime}); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if _, err := repo.Create(model.Task{Title: "Someday", Project: &home}); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	title := "Take out recycling"
+	if _, err := repo.Update(trash.ID, Patch{Title: &title}); err != nil {
+		t.Fatalf("update task: %v", err)
+	}
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		h.CalendarFeed(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d body=%s", target, rec.Code, rec.Body.String())
+		}
+		return rec.Body.String()
+	}
+
+	body := get("/api/calendar/feed.ics")
+	if n := strings.Count(body, "BEGIN:VEVENT"); n != 2 {
+		t.Fatalf("expected 2 events (undated task skipped), got %d; body=%s", n, body)
+	}
+	for _, want := range []string{
+		"UID:task-" + string(trash.ID) + "@donegeon",
+		"SUMMARY:Take out recycling",
+		"SEQUENCE:1",
+		"RRULE:FREQ=WEEKLY;INTERVAL=1",
+		"CATEGORIES:chores",
+		"DTSTART:",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected feed to contain %q; body=%s", want, body)
+		}
+	}
+	for _, line := range strings.Split(body, "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("line longer than 75 octets: %q", line)
+		}
+		if !utf8.ValidString(line) {
+			t.Fatalf("folding split a UTF-8 sequence: %q", line)
+		}
+	}
+	unfolded := strings.ReplaceAll(body, "\r\n ", "")
+	if !strings.Contains(unfolded, "DESCRIPTION:"+strings.TrimSpace(long)) {
+		t.Fatalf("expected description to unfold intact; body=%s", body)
+	}
+
+	body = get("/api/calendar/feed.ics?project=work&component=vtodo")
+	if strings.Count(body, "BEGIN:VTODO") != 1 || !strings.Contains(body, "SUMMARY:Standup") || !strings.Contains(body, "STATUS:NEEDS-ACTION") {
+		t.Fatalf("expected one work VTODO; body=%s", body)
+	}
+	body = get("/api/calendar/feed.ics?tag=chores")
+	if strings.Contains(body, "Standup") || !strings.Contains(body, "recycling") {
+		t.Fatalf("expected tag filter to keep only chores; body=%s", body)
+	}
+}
+
+func TestImportICS_DryRunThenIdempotentReimport(t *testing.T) {
+	h, repo, playerRepo := newTaskHandlerForTests(t, false)
+	for _, f := range []string{player.FeatureTaskDueDate, player.FeatureTaskRecurrence} {
+		if _, _, _, err := playerRepo.UnlockFeature(f, 0); err != nil {
+			t.Fatalf("unlock %s: %v", f, err)
+		}
+	}
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VTODO",
+		"UID:abc-123@example.com",
+		"SUMMARY:Pay rent\\, on time",
+		"DESCRIPTION:Line one\\nLine two that is long enough to be folded by a",
+		"  calendar app",
+		"DUE;VALUE=DATE:20260301",
+		"RRULE:FREQ=MONTHLY;INTERVAL=1",
+		"CATEGORIES:Bills,Home",
+		"STATUS:NEEDS-ACTION",
+		"BEGIN:VALARM",
+		"SUMMARY:ignored",
+		"END:VALARM",
+		"END:VTODO",
+		"BEGIN:VEVENT",
+		"UID:evt-1@example.com",
+		"SUMMARY:Dentist",
+		"DTSTART:20260305T143000Z",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:evt-2@example.com",
+		"SUMMARY:Cancelled thing",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+
+	post := func(target string) map[string]any {
+		rec := httptest.NewRecorder()
+		h.ImportICS(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(ics)))
+		if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+			t.Fatalf("%s: unexpected %d body=%s", target, rec.Code, rec.Body.String())
+		}
+		var out map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return out
+	}
+
+	out := post("/api/tasks/import/ics?dryRun=1")
+	if out["created"].(float64) != 2 || out["skipped"].(float64) != 1 {
+		t.Fatalf("unexpected dry-run counts: %+v", out)
+	}
+	if all, _ := repo.List(ListFilter{}); len(all) != 0 {
+		t.Fatalf("dry run must not write, got %d tasks", len(all))
+	}
+
+	out = post("/api/tasks/import/ics?project=home")
+	if out["created"].(float64) != 2 {
+		t.Fatalf("expected 2 created, got %+v", out)
+	}
+	all, _ := repo.List(ListFilter{})
+	var rent model.Task
+	for _, tk := range all {
+		if tk.ExternalRefs[ExternalRefICS] == "abc-123@example.com" {
+			rent = tk
+		}
+	}
+	if rent.Title != "Pay rent, on time" || rent.Description != "Line one\nLine two that is long enough to be folded by a calendar app" {
+		t.Fatalf("unexpected text fields: %+v", rent)
+	}
+	if rent.DueDate == nil || *rent.DueDate != "2026-03-01" || rent.Recurrence == nil || rent.Recurrence.Type != "monthly" {
+		t.Fatalf("unexpected schedule: %+v", rent)
+	}
+	if len(rent.Tags) != 2 || rent.Tags[0] != "bills" || rent.Project == nil || *rent.Project != "home" {
+		t.Fatalf("unexpected tags/project: %+v", rent)
+	}
+
+	out = post("/api/tasks/import/ics")
+	if out["created"].(float64) != 0 || out["updated"].(float64) != 0 || out["unchanged"].(float64) != 2 {
+		t.Fatalf("re-import should change nothing, got %+v", out)
+	}
+
+	ics = strings.Replace(ics, "STATUS:NEEDS-ACTION", "STATUS:COMPLETED", 1)
+	out = post("/api/tasks/import/ics")
+	if out["updated"].(float64) != 1 {
+		t.Fatalf("expected status change to update one task, got %+v", out)
+	}
+	if got, _ := repo.Get(rent.ID); !got.Done {
+		t.Fatalf("expected imported completion to mark task done")
+	}
+}
+
+func TestTasksExport_MarkdownAndTodoTxt(t *testing.T) {
+	h, repo, _ := newTaskHandlerForTests(t, false)
+	home, work := "home", "work"
+	due := "2026-03-14"
+	if _, err := repo.Create(model.Task{Title: "Pack *books*", Project: &home, DueDate: &due, Tags: []string{"moving"}}); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if _, err := repo.Create(model.Task{
+		Title:      "Send report",
+		Project:    &work,
+		Recurrence: &model.Recurrence{Type: "weekly", Interval: 1, Mode: model.RecurrenceModeSchedule},
+	}); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if _, err := repo.Create(model.Task{Title: "Buy milk", Done: true}); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.TasksExport(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/export?title=Chores", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Header().Get("Content-Type"), "text/markdown") {
+		t.Fatalf("expected markdown, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# Chores\n",
+		"## inbox\n\n- [x] Buy milk\n",
+		"## home\n\n- [ ] Pack \\*books\\* · due 2026-03-14 · `#moving`\n",
+		"## work\n\n- [ ] Send report\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in markdown:\n%s", want, body)
+		}
+	}
+	if strings.Index(body, "## inbox") > strings.Index(body, "## home") {
+		t.Fatalf("expected inbox first:\n%s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.TasksExport(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/export?format=todotxt&status=pending", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	body = rec.Body.String()
+	if strings.Contains(body, "Buy milk") {
+		t.Fatalf("expected done task filtered out:\n%s", body)
+	}
+	for _, want := range []string{"Pack *books* +home @moving due:2026-03-14\n", "Send report +work rec:+1w\n"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in todo.txt:\n%s", want, body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.TasksExport(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/export?template=poster", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown template, got %d", rec.Code)
+	}
+}
+
+type recordingPublisher struct {
+	events []string
+}
+
+func (p *recordingPublisher) Publish(event string, _ any) {
+	p.events = append(p.events, event)
+}
+
+func TestTasks_PublishCreatedAndCompletedEvents(t *testing.T) {
+	h, _, _ := newTaskHandlerForTests(t, false)
+	pub := &recordingPublisher{}
+	h.SetPublisherResolver(func(_ *http.Request) webhook.Publisher { return pub })
+
+	rec := httptest.NewRecorder()
+	h.TasksRoot(rec, httptest.NewRequest(http.MethodPost, "/api/tasks", strings.NewReader(`{"title":"Water plants"}`)))
+	if rec.Code != 201 {
+		t.Fatalf("expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var created model.Task
+	_ = json.NewDecoder(rec.Body).Decode(&created)
+
+	for i := 0; i < 2; i++ {
+		rec = httptest.NewRecorder()
+		h.TasksSub(rec, httptest.NewRequest(http.MethodPatch, "/api/tasks/"+string(created.ID), strings.NewReader(`{"done":true}`)))
+		if rec.Code != 200 {
+			t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+		}
+	}
+	want := []string{webhook.EventTaskCreated, webhook.EventTaskCompleted}
+	if strings.Join(pub.events, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v (completing twice publishes once), got %v", want, pub.events)
+	}
+}
+
+func TestImportICS_CompletionsFollowTheCompletionRules(t *testing.T) {
+	h, repo, playerRepo := newTaskHandlerForTests(t, true)
+	pub := &recordingPublisher{}
+	h.SetPublisherResolver(func(_ *http.Request) webhook.Publisher { return pub })
+	villager := "v-1"
+	loose, _ := repo.Create(model.Task{Title: "Sweep", ExternalRefs: map[string]string{ExternalRefICS: "sweep@example.com"}})
+	staffed, _ := repo.Create(model.Task{Title: "Mop", AssignedVillagerID: &villager, ExternalRefs: map[string]string{ExternalRefICS: "mop@example.com"}})
+
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VTODO",
+		"UID:sweep@example.com",
+		"SUMMARY:Sweep",
+		"STATUS:COMPLETED",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:mop@example.com",
+		"SUMMARY:Mop",
+		"STATUS:COMPLETED",
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	rec := httptest.NewRecorder()
+	h.ImportICS(rec, httptest.NewRequest(http.MethodPost, "/api/tasks/import/ics", strings.NewReader(ics)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "completion dropped: "+ErrVillagerRequired.Error()) {
+		t.Fatalf("expected a warning for the unassigned task, got %s", rec.Body.String())
+	}
+
+	if got, _ := repo.Get(loose.ID); got.Done {
+		t.Fatalf("import completed a task without an assigned villager")
+	}
+	got, _ := repo.Get(staffed.ID)
+	if !got.Done || got.CompletionCount != 1 {
+		t.Fatalf("expected the assigned task completed with habit progress, got %+v", got)
+	}
+	if n := playerRepo.GetMetric(player.MetricTasksCompleted); n != 1 {
+		t.Fatalf("expected tasks_completed 1, got %d", n)
+	}
+	if strings.Join(pub.events, ",") != webhook.EventTaskCompleted {
+		t.Fatalf("expected one task.completed event, got %v", pub.events)
+	}
+}
+
+func TestImportICS_PublishesTaskCreated(t *testing.T) {
+	h, _, _ := newTaskHandlerForTests(t, false)
+	pub := &recordingPublisher{}
+	h.SetPublisherResolver(func(_ *http.Request) webhook.Publisher { return pub })
+
+	ics := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:a@example.com\r\nSUMMARY:Sweep\r\nEND:VTODO\r\nBEGIN:VTODO\r\nUID:b@example.com\r\nSUMMARY:Mop\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.ImportICS(rec, httptest.NewRequest(http.MethodPost, "/api/tasks/import/ics", strings.NewReader(ics)))
+		if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+			t.Fatalf("import: unexpected %d body=%s", rec.Code, rec.Body.String())
+		}
+	}
+	want := []string{webhook.EventTaskCreated, webhook.EventTaskCreated}
+	if strings.Join(pub.events, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v (reimporting publishes nothing), got %v", want, pub.events)
+	}
+}
+
+func TestNormalizeTag_CutsLongTagsOnCharacterBoundaries(t *testing.T) {
+	tag := NormalizeTag("a" + strings.Repeat("É", 40))
+	if !utf8.ValidString(tag) {
+		t.Fatalf("expected valid UTF-8, got %q", tag)
+	}
+	if want := "a" + strings.Repeat("é", 31); tag != want {
+		t.Fatalf("expected %q (%d bytes), got %q (%d bytes)", want, len(want), tag, len(tag))
+	}
+	if got := NormalizeTag(strings.Repeat("x", 70)); len(got) != maxTagLen {
+		t.Fatalf("expected ASCII tags cut to %d bytes, got %d", maxTagLen, len(got))
+	}
+}
+
+func TestRepos_LiveChangesBumpTheRevision(t *testing.T) {
+	fileRepo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	for name, repo := range map[string]Repo{"memory": NewMemoryRepo(), "file": fileRepo.ForUser("u-live")} {
+		created, err := repo.Create(model.Task{Title: "Water plants"})
+		if err != nil {
+			t.Fatalf("%s: create: %v", name, err)
+		}
+		etag := TaskETag(created)
+
+		if err := repo.SetLive(created.ID, true); err != nil {
+			t.Fatalf("%s: set live: %v", name, err)
+		}
+		got, _ := repo.Get(created.ID)
+		if !got.Live || TaskETag(got) == etag {
+			t.Fatalf("%s: expected a live task under a new ETag, got live=%v etag=%s", name, got.Live, TaskETag(got))
+		}
+		etag = TaskETag(got)
+
+		if err := repo.SetLive(created.ID, true); err != nil {
+			t.Fatalf("%s: set live again: %v", name, err)
+		}
+		if got, _ := repo.Get(created.ID); TaskETag(got) != etag {
+			t.Fatalf("%s: expected no new revision when nothing changed, got %s", name, TaskETag(got))
+		}
+
+		if err := repo.SyncLive(nil); err != nil {
+			t.Fatalf("%s: sync live: %v", name, err)
+		}
+		if got, _ := repo.Get(created.ID); got.Live || TaskETag(got) == etag {
+			t.Fatalf("%s: expected the task off the board under a new ETag, got live=%v etag=%s", name, got.Live, TaskETag(got))
+		}
+	}
+}
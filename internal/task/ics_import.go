@@ -0,0 +1,323 @@
+package task
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/webhook"
+)
+
+// maxICSImportBytes caps an uploaded calendar.
+const maxICSImportBytes = 5 << 20
+
+// ExternalRefICS keys a task's iCalendar UID in Task.ExternalRefs.
+const ExternalRefICS = "ics"
+
+// Import actions reported per component.
+const (
+	ImportCreate    = "create"
+	ImportUpdate    = "update"
+	ImportUnchanged = "unchanged"
+	ImportSkip      = "skip"
+)
+
+// importResult reports what happened (or, in a dry run, would happen) to
+// one calendar component.
+type importResult struct {
+	UID      string       `json:"uid,omitempty"`
+	Title    string       `json:"title"`
+	Action   string       `json:"action"`
+	TaskID   model.TaskID `json:"taskId,omitempty"`
+	Changes  []string     `json:"changes,omitempty"` // fields an update touches
+	Warnings []string     `json:"warnings,omitempty"`
+	Reason   string       `json:"reason,omitempty"` // why it was skipped
+	Task     *model.Task  `json:"task,omitempty"`
+	Preview  *model.Task  `json:"preview,omitempty"` // the task a create would make
+
+	create   *model.Task
+	existing *model.Task
+	patch    *Patch
+}
+
+// POST /api/tasks/import/ics?dryRun=1&project=home
+//
+// The body is an iCalendar file. Each VEVENT and VTODO becomes a task, or
+// updates the task it was imported into before (matched by UID, or by the
+// UID our own exports write), so importing the same file twice changes
+// nothing. Fields behind locked features are dropped with a warning.
+// dryRun=1 returns the same report without writing.
+func (h *Handler) ImportICS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, 405, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	dryRun := false
+	switch strings.ToLower(strings.TrimSpace(q.Get("dryRun"))) {
+	case "1", "true", "yes":
+		dryRun = true
+	}
+	projectName := strings.TrimSpace(q.Get("project"))
+	project := normalizeProject(&projectName)
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxICSImportBytes))
+	if err != nil {
+		writeErr(w, 413, "calendar file is too large")
+		return
+	}
+	loc := h.locationFor(r)
+	items, err := ParseICS(data, loc)
+	if err != nil {
+		writeErr(w, 400, err.Error())
+		return
+	}
+
+	repo := h.repoForRequest(r).WithOrigin(SourceImport, "")
+	existing, err := repo.List(ListFilter{})
+	if err != nil {
+		writeErr(w, 500, err.Error())
+		return
+	}
+	byUID := make(map[string]model.Task, len(existing))
+	byID := make(map[model.TaskID]model.Task, len(existing))
+	for _, t := range existing {
+		byID[t.ID] = t
+		if uid := t.ExternalRefs[ExternalRefICS]; uid != "" {
+			byUID[uid] = t
+		}
+	}
+
+	playerRepo := h.playerForRequest(r)
+	results := make([]importResult, 0, len(items))
+	seen := map[string]bool{}
+	counts := map[string]int{}
+	for _, it := range items {
+		res := h.planICSItem(it, project, playerRepo, byUID, byID, seen)
+		counts[res.Action]++
+		results = append(results, res)
+	}
+
+	if !dryRun {
+		for i := range results {
+			res := &results[i]
+			switch res.Action {
+			case ImportCreate:
+				t, err := repo.Create(*res.create)
+				if err != nil {
+					writeErr(w, 500, err.Error())
+					return
+				}
+				res.TaskID, res.Task, res.Preview = t.ID, &t, nil
+				h.publish(r, webhook.EventTaskCreated, t)
+				h.taskChanged(r, t)
+			case ImportUpdate:
+				t, err := h.Apply(repo, playerRepo, h.publisherForRequest(r), res.existing.ID, *res.patch)
+				if err != nil {
+					writeErr(w, 500, err.Error())
+					return
+				}
+				res.Task = &t
+				h.taskChanged(r, t)
+			}
+		}
+	}
+
+	code := 200
+	if !dryRun && counts[ImportCreate] > 0 {
+		code = 201
+	}
+	writeJSON(w, code, map[string]any{
+		"dryRun":    dryRun,
+		"created":   counts[ImportCreate],
+		"updated":   counts[ImportUpdate],
+		"unchanged": counts[ImportUnchanged],
+		"skipped":   counts[ImportSkip],
+		"results":   results,
+	})
+}
+
+// planICSItem decides what importing one component does, without writing.
+func (h *Handler) planICSItem(it ICSItem, project *string, playerRepo *player.FileRepo, byUID map[string]model.Task, byID map[model.TaskID]model.Task, seen map[string]bool) importResult {
+	res := importResult{UID: it.UID, Title: it.Summary, Warnings: it.Warnings}
+	switch {
+	case it.Summary == "":
+		res.Action, res.Reason = ImportSkip, "no SUMMARY"
+		return res
+	case it.Cancelled:
+		res.Action, res.Reason = ImportSkip, "cancelled"
+		return res
+	case it.UID != "" && seen[it.UID]:
+		// Recurrence overrides share the master's UID; the master wins.
+		res.Action, res.Reason = ImportSkip, "duplicate UID"
+		return res
+	}
+	if it.UID != "" {
+		seen[it.UID] = true
+	}
+
+	res.Warnings = append(res.Warnings, StripLockedICSFields(&it, playerRepo)...)
+
+	cur, ok := byUID[it.UID]
+	if !ok && it.UID != "" {
+		if id, own := strings.CutSuffix(strings.TrimPrefix(it.UID, "task-"), "@donegeon"); own {
+			cur, ok = byID[model.TaskID(id)]
+		}
+	}
+	if !ok {
+		t := ICSTask(it, project)
+		res.Action, res.create, res.Preview = ImportCreate, &t, &t
+		return res
+	}
+
+	res.TaskID = cur.ID
+	res.existing = &cur
+	p, changes := ICSPatch(cur, it)
+	if p.Done != nil && *p.Done && h.completionRequiresAssignedVillager() &&
+		(cur.AssignedVillagerID == nil || strings.TrimSpace(*cur.AssignedVillagerID) == "") {
+		p.Done = nil
+		changes = removeString(changes, "done")
+		res.Warnings = append(res.Warnings, "completion dropped: "+ErrVillagerRequired.Error())
+	}
+	if len(changes) == 0 {
+		res.Action = ImportUnchanged
+		return res
+	}
+	res.Action, res.patch, res.Changes = ImportUpdate, &p, changes
+	return res
+}
+
+// StripLockedFields clears the schedule fields on t that the player has
+// not unlocked yet, so importers can keep the rest of an item, and says
+// what was dropped.
+func StripLockedFields(t *model.Task, playerRepo *player.FileRepo) []string {
+	var warnings []string
+	if t.DueDate != nil && !isUnlocked(playerRepo, player.FeatureTaskDueDate) {
+		t.DueDate, t.DueTime, t.Recurrence = nil, nil, nil
+		warnings = append(warnings, "due date dropped: feature locked: "+player.FeatureTaskDueDate)
+	}
+	if t.Recurrence != nil && !isUnlocked(playerRepo, player.FeatureTaskRecurrence) {
+		t.Recurrence = nil
+		warnings = append(warnings, "recurrence dropped: feature locked: "+player.FeatureTaskRecurrence)
+	}
+	return warnings
+}
+
+// StripLockedICSFields is StripLockedFields for a calendar item.
+func StripLockedICSFields(it *ICSItem, playerRepo *player.FileRepo) []string {
+	t := model.Task{DueDate: it.DueDate, DueTime: it.DueTime, Recurrence: it.Recurrence}
+	warnings := StripLockedFields(&t, playerRepo)
+	it.DueDate, it.DueTime, it.Recurrence = t.DueDate, t.DueTime, t.Recurrence
+	return warnings
+}
+
+// ICSTask builds a new task from a calendar item, remembering its UID.
+func ICSTask(it ICSItem, project *string) model.Task {
+	t := model.Task{
+		Title:       it.Summary,
+		Description: it.Description,
+		Done:        it.Completed,
+		Project:     project,
+		Tags:        it.Categories,
+		DueDate:     it.DueDate,
+		DueTime:     it.DueTime,
+		Recurrence:  it.Recurrence,
+	}
+	if it.UID != "" {
+		t.ExternalRefs = map[string]string{ExternalRefICS: it.UID}
+	}
+	return t
+}
+
+// ICSPatch returns the patch that brings cur in line with a calendar item,
+// and the JSON names of the fields it changes.
+func ICSPatch(cur model.Task, it ICSItem) (Patch, []string) {
+	var (
+		p       Patch
+		changes []string
+	)
+	if cur.Title != it.Summary {
+		p.Title = &it.Summary
+		changes = append(changes, "title")
+	}
+	if cur.Description != it.Description {
+		p.Description = Some(it.Description)
+		changes = append(changes, "description")
+	}
+	if cur.Done != it.Completed {
+		p.Done = &it.Completed
+		changes = append(changes, "done")
+	}
+	if !sameStrings(NormalizeTags(cur.Tags), it.Categories) {
+		p.Tags = Some(it.Categories)
+		changes = append(changes, "tags")
+	}
+	if !sameOptString(cur.DueDate, it.DueDate) {
+		p.DueDate = nullableString(it.DueDate)
+		changes = append(changes, "dueDate")
+	}
+	if !sameOptString(cur.DueTime, it.DueTime) {
+		p.DueTime = nullableString(it.DueTime)
+		changes = append(changes, "dueTime")
+	}
+	if !sameRecurrence(cur.Recurrence, it.Recurrence) {
+		if it.Recurrence != nil {
+			p.Recurrence = Some(*it.Recurrence)
+		} else {
+			p.Recurrence = Null[model.Recurrence]()
+		}
+		changes = append(changes, "recurrence")
+	}
+	return p, changes
+}
+
+func nullableString(s *string) Nullable[string] {
+	if s == nil {
+		return Null[string]()
+	}
+	return Some(*s)
+}
+
+func sameOptString(a, b *string) bool {
+	if a == nil || b == nil {
+		return (a == nil || *a == "") && (b == nil || *b == "")
+	}
+	return *a == *b
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func removeString(list []string, s string) []string {
+	out := list[:0]
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// sameRecurrence compares rules, ignoring the respawn counter.
+func sameRecurrence(a, b *model.Recurrence) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	x, y := *a, *b
+	NormalizeRecurrence(&x)
+	NormalizeRecurrence(&y)
+	x.Occurrences, y.Occurrences = 0, 0
+	return reflect.DeepEqual(x, y)
+}
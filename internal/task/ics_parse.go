@@ -0,0 +1,239 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+// ICSItem is one VEVENT or VTODO read from an iCalendar file, already
+// mapped onto task fields.
+type ICSItem struct {
+	UID         string
+	Component   string // ICSEvent or ICSTodo
+	Summary     string
+	Description string
+	DueDate     *string
+	DueTime     *string
+	Recurrence  *model.Recurrence
+	Categories  []string
+	Completed   bool
+	Cancelled   bool
+
+	// Warnings lists properties that were present but could not be used.
+	Warnings []string
+}
+
+// icsProperty is one unfolded content line.
+type icsProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// ParseICS reads the VEVENT and VTODO components of an iCalendar document.
+// Floating and date-only times are read in loc; times with a TZID or UTC
+// suffix are converted into loc. Components without a UID get none; the
+// caller decides what that means.
+func ParseICS(data []byte, loc *time.Location) ([]ICSItem, error) {
+	loc = locationOrLocal(loc)
+	props := unfoldICS(string(data))
+	if len(props) == 0 || !strings.EqualFold(props[0].Name, "BEGIN") || !strings.EqualFold(props[0].Value, "VCALENDAR") {
+		return nil, fmt.Errorf("not an iCalendar file (expected BEGIN:VCALENDAR)")
+	}
+
+	var (
+		out   []ICSItem
+		cur   *ICSItem
+		depth int // nesting inside the current component (VALARM etc.)
+	)
+	for _, p := range props {
+		name := strings.ToUpper(p.Name)
+		value := strings.ToUpper(strings.TrimSpace(p.Value))
+		switch {
+		case cur == nil && name == "BEGIN" && (value == "VEVENT" || value == "VTODO"):
+			cur = &ICSItem{Component: strings.ToLower(value)}
+			continue
+		case cur == nil:
+			continue
+		case name == "BEGIN":
+			depth++
+			continue
+		case name == "END" && depth > 0:
+			depth--
+			continue
+		case name == "END":
+			out = append(out, *cur)
+			cur = nil
+			continue
+		case depth > 0:
+			continue
+		}
+		cur.apply(p, loc)
+	}
+	if cur != nil {
+		return nil, fmt.Errorf("unterminated %s component", strings.ToUpper(cur.Component))
+	}
+	return out, nil
+}
+
+func (it *ICSItem) apply(p icsProperty, loc *time.Location) {
+	name := strings.ToUpper(p.Name)
+	switch name {
+	case "UID":
+		it.UID = strings.TrimSpace(unescapeICSText(p.Value))
+	case "SUMMARY":
+		it.Summary = strings.TrimSpace(unescapeICSText(p.Value))
+	case "DESCRIPTION":
+		it.Description = strings.TrimSpace(unescapeICSText(p.Value))
+	case "DUE", "DTSTART":
+		// VTODOs are due on DUE; DTSTART is only a fallback. Events only
+		// have DTSTART.
+		if name == "DTSTART" && it.Component == ICSTodo && it.DueDate != nil {
+			return
+		}
+		date, clock, err := parseICSTime(p, loc)
+		if err != nil {
+			it.Warnings = append(it.Warnings, name+": "+err.Error())
+			return
+		}
+		it.DueDate, it.DueTime = &date, nil
+		if clock != "" {
+			it.DueTime = &clock
+		}
+	case "RRULE":
+		rec, err := ParseRRULE(p.Value)
+		if err != nil {
+			it.Warnings = append(it.Warnings, "RRULE: "+err.Error())
+			return
+		}
+		it.Recurrence = rec
+	case "CATEGORIES":
+		for _, c := range splitICSList(p.Value) {
+			if tag := NormalizeTag(unescapeICSText(c)); tag != "" {
+				it.Categories = append(it.Categories, tag)
+			}
+		}
+	case "STATUS":
+		switch strings.ToUpper(strings.TrimSpace(p.Value)) {
+		case "COMPLETED":
+			it.Completed = true
+		case "CANCELLED":
+			it.Cancelled = true
+		}
+	case "COMPLETED":
+		it.Completed = true
+	}
+}
+
+// parseICSTime returns a date (YYYY-MM-DD) and, for date-times, a clock
+// (HH:MM), both in loc.
+func parseICSTime(p icsProperty, loc *time.Location) (string, string, error) {
+	raw := strings.TrimSpace(p.Value)
+	if strings.EqualFold(p.Params["VALUE"], "DATE") || len(raw) == 8 {
+		d, err := time.ParseInLocation(icsDateLayout, raw, loc)
+		if err != nil {
+			return "", "", fmt.Errorf("bad date %q", raw)
+		}
+		return d.Format(dateLayout), "", nil
+	}
+
+	var (
+		t   time.Time
+		err error
+	)
+	switch {
+	case strings.HasSuffix(raw, "Z"):
+		t, err = time.Parse(icsStampLayout, raw)
+	case p.Params["TZID"] != "":
+		zone, zerr := time.LoadLocation(strings.Trim(p.Params["TZID"], `"`))
+		if zerr != nil {
+			zone = loc
+		}
+		t, err = time.ParseInLocation("20060102T150405", raw, zone)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", raw, loc)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("bad date-time %q", raw)
+	}
+	t = t.In(loc)
+	return t.Format(dateLayout), t.Format(dueTimeLayout), nil
+}
+
+// unfoldICS joins folded lines and splits each into name, params and value.
+func unfoldICS(doc string) []icsProperty {
+	doc = strings.ReplaceAll(doc, "\r\n", "\n")
+	doc = strings.ReplaceAll(doc, "\r", "\n")
+	var lines []string
+	for _, line := range strings.Split(doc, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	out := make([]icsProperty, 0, len(lines))
+	for _, line := range lines {
+		// The value starts at the first colon outside a quoted parameter.
+		inQuote, split := false, -1
+		for i, c := range line {
+			if c == '"' {
+				inQuote = !inQuote
+			} else if c == ':' && !inQuote {
+				split = i
+				break
+			}
+		}
+		if split < 0 {
+			continue
+		}
+		head := strings.Split(line[:split], ";")
+		p := icsProperty{Name: strings.TrimSpace(head[0]), Params: map[string]string{}, Value: line[split+1:]}
+		for _, param := range head[1:] {
+			if k, v, ok := strings.Cut(param, "="); ok {
+				p.Params[strings.ToUpper(strings.TrimSpace(k))] = strings.TrimSpace(v)
+			}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// splitICSList splits a comma-separated value, honouring escaped commas.
+func splitICSList(v string) []string {
+	var (
+		out []string
+		b   strings.Builder
+	)
+	for i := 0; i < len(v); i++ {
+		switch {
+		case v[i] == '\\' && i+1 < len(v):
+			b.WriteByte(v[i])
+			b.WriteByte(v[i+1])
+			i++
+		case v[i] == ',':
+			out = append(out, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(v[i])
+		}
+	}
+	return append(out, b.String())
+}
+
+func unescapeICSText(s string) string {
+	repl := strings.NewReplacer(
+		"\\\\", "\\",
+		"\\;", ";",
+		"\\,", ",",
+		"\\n", "\n",
+		"\\N", "\n",
+	)
+	return repl.Replace(s)
+}
@@ -0,0 +1,109 @@
+package task
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+)
+
+// maxListLimit caps one page of GET /api/tasks.
+const maxListLimit = 500
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the opaque cursor that resumes a listing: the sort order
+// and the sort key of the last task on the previous page.
+type pageCursor struct {
+	By  string  `json:"by"`
+	Key sortKey `json:"key"`
+}
+
+func encodeCursor(by string, key sortKey) string {
+	b, _ := json.Marshal(pageCursor{By: by, Key: key})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(raw string) (pageCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Key.ID == "" {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// paginate returns up to limit tasks following the cursor in a listing
+// sorted by SortTasks(ts, by, cfg), plus the cursor for the next page (""
+// on the last page). Pages resume at the first task sorting after the
+// cursor's key, so the task it was taken from need not still be listed,
+// and an edit that reorders tasks cannot repeat a row already served.
+func paginate(ts []model.Task, by string, cfg *config.Config, limit int, cursor string) ([]model.Task, string, error) {
+	by = sortOrder(by)
+	start := 0
+	if cursor != "" {
+		c, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if c.By != by {
+			return nil, "", ErrInvalidCursor
+		}
+		start = sort.Search(len(ts), func(i int) bool {
+			return keyLess(by, c.Key, keyOf(ts[i], by, cfg))
+		})
+	}
+	if limit <= 0 {
+		return ts[start:], "", nil
+	}
+	end := start + limit
+	if end >= len(ts) {
+		return ts[start:], "", nil
+	}
+	return ts[start:end], encodeCursor(by, keyOf(ts[end-1], by, cfg)), nil
+}
+
+// parseFields splits a fields= list; id is always included.
+func parseFields(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	out := []string{"id"}
+	for _, f := range strings.Split(raw, ",") {
+		if f = strings.TrimSpace(f); f != "" && f != "id" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// projectFields trims each task to the named JSON fields. Unknown names are
+// ignored; empty values the task omits stay omitted.
+func projectFields(ts []model.Task, fields []string) ([]map[string]json.RawMessage, error) {
+	out := make([]map[string]json.RawMessage, 0, len(ts))
+	for _, t := range ts {
+		b, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(b, &all); err != nil {
+			return nil, err
+		}
+		m := make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			if v, ok := all[f]; ok {
+				m[f] = v
+			}
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
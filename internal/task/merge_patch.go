@@ -0,0 +1,116 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+
+	"donegeon/internal/model"
+)
+
+// MergePatchContentType selects RFC 7396 JSON Merge Patch semantics on PATCH.
+const MergePatchContentType = "application/merge-patch+json"
+
+// errNullTitle rejects a merge patch that would remove the title, which
+// every task must have.
+var errNullTitle = errors.New("title cannot be null")
+
+func isMergePatch(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == MergePatchContentType
+}
+
+// decodePatch decodes a task PATCH body. Plain JSON bodies replace nested
+// objects (recurrence) wholesale; merge patches merge them into the stored
+// task, so {"recurrence":{"until":null}} only drops UNTIL. In a merge patch
+// null clears a field as usual, except the title, which is refused.
+func decodePatch(r *http.Request, repo Repo, id model.TaskID) (Patch, error) {
+	var p Patch
+	if !isMergePatch(r) {
+		err := decodeJSON(r, &p)
+		return p, err
+	}
+
+	var doc map[string]json.RawMessage
+	if err := decodeJSON(r, &doc); err != nil {
+		return Patch{}, err
+	}
+	if doc == nil {
+		return Patch{}, errors.New("merge patch must be a JSON object")
+	}
+	if raw, ok := doc["title"]; ok && bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return Patch{}, errNullTitle
+	}
+
+	var current map[string]json.RawMessage
+	for key, raw := range doc {
+		if !isJSONObject(raw) {
+			continue
+		}
+		if current == nil {
+			cur, err := repo.Get(id)
+			if err != nil {
+				return Patch{}, err
+			}
+			buf, err := json.Marshal(cur)
+			if err != nil {
+				return Patch{}, err
+			}
+			if err := json.Unmarshal(buf, &current); err != nil {
+				return Patch{}, err
+			}
+		}
+		merged, err := mergePatchJSON(current[key], raw)
+		if err != nil {
+			return Patch{}, err
+		}
+		doc[key] = merged
+	}
+
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return Patch{}, err
+	}
+	err = json.Unmarshal(buf, &p)
+	return p, err
+}
+
+func isJSONObject(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// mergePatchJSON applies an RFC 7396 merge patch to target.
+func mergePatchJSON(target, patch json.RawMessage) (json.RawMessage, error) {
+	var t, p any
+	if len(bytes.TrimSpace(target)) > 0 {
+		if err := json.Unmarshal(target, &t); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatchValue(t, p))
+}
+
+func mergePatchValue(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = mergePatchValue(targetObj[k], v)
+	}
+	return targetObj
+}
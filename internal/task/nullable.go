@@ -0,0 +1,67 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Nullable is a tri-state patch field:
+//
+//	Set == false              => field absent, no change
+//	Set == true, Value == nil => explicit null, clear the field
+//	Set == true, Value != nil => set the field
+//
+// Tag fields with `json:",omitzero"` so absent values round-trip.
+type Nullable[T any] struct {
+	Set   bool
+	Value *T
+}
+
+// Some returns a Nullable that sets v.
+func Some[T any](v T) Nullable[T] {
+	return Nullable[T]{Set: true, Value: &v}
+}
+
+// Null returns a Nullable that clears the field.
+func Null[T any]() Nullable[T] {
+	return Nullable[T]{Set: true}
+}
+
+// IsNull reports an explicit null.
+func (n Nullable[T]) IsNull() bool {
+	return n.Set && n.Value == nil
+}
+
+// IsZero reports an absent field (used by omitzero).
+func (n Nullable[T]) IsZero() bool {
+	return !n.Set
+}
+
+// UnmarshalJSON is only invoked when the key is present, which is what
+// distinguishes "absent" from "null".
+func (n *Nullable[T]) UnmarshalJSON(b []byte) error {
+	n.Set = true
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		n.Value = nil
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	n.Value = &v
+	return nil
+}
+
+func (n Nullable[T]) MarshalJSON() ([]byte, error) {
+	if n.Value == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(*n.Value)
+}
+
+// nonEmptyString reports whether n sets a non-blank string.
+func nonEmptyString(n Nullable[string]) bool {
+	return n.Value != nil && strings.TrimSpace(*n.Value) != ""
+}
@@ -0,0 +1,195 @@
+package task
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+	"donegeon/internal/player"
+	"donegeon/internal/webhook"
+)
+
+// ErrVillagerRequired refuses a completion when the config requires an
+// assigned villager and the task has none.
+var ErrVillagerRequired = errors.New("task completion requires an assigned villager")
+
+// patchError is a patch rejected before it reaches the repo.
+type patchError struct {
+	code int
+	msg  string
+}
+
+func (e *patchError) err() error {
+	switch {
+	case e.code == http.StatusNotFound:
+		return ErrNotFound
+	case e.msg == ErrVillagerRequired.Error():
+		return ErrVillagerRequired
+	}
+	return errors.New(e.msg)
+}
+
+func lockedFeatureError(feature string) *patchError {
+	return &patchError{code: http.StatusForbidden, msg: "feature locked: " + feature}
+}
+
+// patchEffects are the player-side results of a patch, applied once it has
+// been stored.
+type patchEffects struct {
+	completed      int
+	habitBonusCoin int
+}
+
+func (fx patchEffects) add(o patchEffects) patchEffects {
+	fx.completed += o.completed
+	fx.habitBonusCoin += o.habitBonusCoin
+	return fx
+}
+
+func (fx patchEffects) apply(playerRepo *player.FileRepo) {
+	if playerRepo == nil {
+		return
+	}
+	if fx.habitBonusCoin > 0 {
+		_, _ = playerRepo.AddLoot(player.LootCoin, fx.habitBonusCoin)
+	}
+	if fx.completed > 0 {
+		_, _, _ = playerRepo.IncrementMetric(player.MetricTasksCompleted, fx.completed)
+	}
+}
+
+// validatePatch checks the client-supplied values of a patch, canonicalizing
+// its recurrence in place.
+func (h *Handler) validatePatch(p *Patch) error {
+	if err := h.normalizeRecurrenceInput(p.Recurrence.Value); err != nil {
+		return err
+	}
+	if p.EstimateMinutes.Value != nil && *p.EstimateMinutes.Value < 0 {
+		return errors.New("estimateMinutes must be >= 0")
+	}
+	if err := ValidateSchedule(p.DueDate.Value, p.DueTime.Value, p.StartDate.Value); err != nil {
+		return err
+	}
+	if nonEmptyString(p.DueTime) && p.DueDate.Set && !nonEmptyString(p.DueDate) {
+		return ErrDueTimeNoDate
+	}
+	return nil
+}
+
+// planPatch applies the unlock, modifier and villager rules to a validated
+// patch for one task and fills in the habit fields of a completion. It
+// returns the patch to store and the effects to apply after storing it.
+func (h *Handler) planPatch(repo Repo, playerRepo *player.FileRepo, id model.TaskID, p Patch) (Patch, patchEffects, *patchError) {
+	var (
+		cur       model.Task
+		needCur   bool
+		curLoaded bool
+		fx        patchEffects
+	)
+	if !p.Modifiers.Set && (p.DueDate.Set || p.NextAction != nil || p.Recurrence.Set) {
+		needCur = true
+	}
+	if p.Done != nil && *p.Done {
+		needCur = true
+	}
+	modTags := []string{}
+	if p.Modifiers.Value != nil {
+		modTags = ModifierTags(h.cfg, *p.Modifiers.Value)
+	}
+	if len(modTags) > 0 && !p.Tags.Set {
+		needCur = true
+	}
+	if needCur {
+		var err error
+		cur, err = repo.Get(id)
+		if err == ErrNotFound {
+			return p, fx, &patchError{code: 404, msg: "not found"}
+		}
+		if err != nil {
+			return p, fx, &patchError{code: 500, msg: err.Error()}
+		}
+		curLoaded = true
+	}
+
+	effectiveMods := []model.TaskModifierSlot{}
+	if p.Modifiers.Value != nil {
+		effectiveMods = *p.Modifiers.Value
+	} else if !p.Modifiers.Set && curLoaded {
+		effectiveMods = cur.Modifiers
+	}
+	// Modifiers like context_filter add their tags to the task.
+	if len(modTags) > 0 {
+		base := cur.Tags
+		if p.Tags.Set {
+			base = nil
+			if p.Tags.Value != nil {
+				base = *p.Tags.Value
+			}
+		}
+		p.Tags = Some(append(append([]string{}, base...), modTags...))
+	}
+
+	if nonEmptyString(p.DueDate) &&
+		!isUnlocked(playerRepo, player.FeatureTaskDueDate) &&
+		!allowsDueDateViaModifier(effectiveMods) {
+		return p, fx, lockedFeatureError(player.FeatureTaskDueDate)
+	}
+	if p.NextAction != nil &&
+		*p.NextAction &&
+		!isUnlocked(playerRepo, player.FeatureTaskNextAction) &&
+		!allowsNextActionViaModifier(effectiveMods) {
+		return p, fx, lockedFeatureError(player.FeatureTaskNextAction)
+	}
+	// Clearing a recurrence is always allowed; setting one is gated.
+	if p.Recurrence.Value != nil &&
+		!isUnlocked(playerRepo, player.FeatureTaskRecurrence) &&
+		!allowsRecurrenceViaModifier(effectiveMods) {
+		return p, fx, lockedFeatureError(player.FeatureTaskRecurrence)
+	}
+	if p.Done != nil && *p.Done && h.completionRequiresAssignedVillager() {
+		if cur.AssignedVillagerID == nil || strings.TrimSpace(*cur.AssignedVillagerID) == "" {
+			return p, fx, &patchError{code: 400, msg: ErrVillagerRequired.Error()}
+		}
+	}
+	if p.Done != nil && *p.Done && curLoaded && !cur.Done {
+		habitPatch, habitResult := BuildHabitCompletionUpdate(cur, time.Now().In(playerRepo.Location()))
+		p.CompletionCountDelta = habitPatch.CompletionCountDelta
+		p.Habit = habitPatch.Habit
+		p.HabitTier = habitPatch.HabitTier
+		p.HabitStreak = habitPatch.HabitStreak
+		p.LastCompletedDate = habitPatch.LastCompletedDate
+		fx.completed = 1
+		fx.habitBonusCoin = habitResult.BonusCoin
+	}
+	return p, fx, nil
+}
+
+// Complete marks a task done outside a request, as a plugin result does,
+// under the same rules as PATCH {"done":true}: the villager requirement,
+// habit progress, the completion metric and task.completed. playerRepo and
+// pub may be nil.
+func (h *Handler) Complete(repo Repo, playerRepo *player.FileRepo, pub webhook.Publisher, id model.TaskID) (model.Task, error) {
+	done := true
+	return h.Apply(repo, playerRepo, pub, id, Patch{Done: &done})
+}
+
+// Apply stores a patch made outside a task request (a CalDAV PUT, a
+// calendar import) under the same rules and effects as PATCH, so a patch
+// that completes the task goes through Complete's rules too.
+func (h *Handler) Apply(repo Repo, playerRepo *player.FileRepo, pub webhook.Publisher, id model.TaskID, p Patch) (model.Task, error) {
+	p, fx, perr := h.planPatch(repo, playerRepo, id, p)
+	if perr != nil {
+		return model.Task{}, perr.err()
+	}
+	t, err := repo.Update(id, p)
+	if err != nil {
+		return model.Task{}, err
+	}
+	fx.apply(playerRepo)
+	if fx.completed > 0 && pub != nil {
+		pub.Publish(webhook.EventTaskCompleted, t)
+	}
+	return t, nil
+}
@@ -0,0 +1,47 @@
+package task
+
+import (
+	"net/http"
+	"strings"
+
+	"donegeon/internal/model"
+)
+
+// PluginCardPrefix starts the card def IDs of plugin modifier cards.
+const PluginCardPrefix = "mod.plugin_"
+
+// PluginRunner calls the installed plugins whose cards are on a task. Calls
+// run in the background; plugins write results back through the task repo
+// as SourcePlugin.
+type PluginRunner interface {
+	// CardAttached runs the plugin behind cardDefID for t.
+	CardAttached(t model.Task, cardDefID string)
+	// TaskChanged runs every plugin whose card is on t.
+	TaskChanged(t model.Task)
+}
+
+// PluginCards returns the plugin card def IDs among t's modifiers.
+func PluginCards(t model.Task) []string {
+	var out []string
+	for _, m := range t.Modifiers {
+		if strings.HasPrefix(m.DefID, PluginCardPrefix) {
+			out = append(out, m.DefID)
+		}
+	}
+	return out
+}
+
+// SetPluginResolver runs the requester's plugins when a task carrying
+// plugin cards is created or changed.
+func (h *Handler) SetPluginResolver(fn func(*http.Request) PluginRunner) {
+	h.pluginResolver = fn
+}
+
+func (h *Handler) taskChanged(r *http.Request, t model.Task) {
+	if h.pluginResolver == nil || len(PluginCards(t)) == 0 {
+		return
+	}
+	if p := h.pluginResolver(r); p != nil {
+		p.TaskChanged(t)
+	}
+}
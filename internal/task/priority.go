@@ -0,0 +1,66 @@
+package task
+
+import (
+	"strings"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+)
+
+// defaultPriorityLevels is used when the config does not list any.
+var defaultPriorityLevels = []string{"none", "low", "medium", "high"}
+
+func priorityLevels(cfg *config.Config) []string {
+	if cfg == nil || len(cfg.Tasks.Priorities.Levels) == 0 {
+		return defaultPriorityLevels
+	}
+	return cfg.Tasks.Priorities.Levels
+}
+
+// Priority returns the level set by the task's set_priority modifiers, or the
+// lowest level when none applies. The highest level wins if several do.
+func Priority(cfg *config.Config, mods []model.TaskModifierSlot) string {
+	levels := priorityLevels(cfg)
+	best := levels[0]
+	if cfg == nil {
+		return best
+	}
+	for _, m := range mods {
+		modID := strings.TrimPrefix(strings.TrimSpace(m.DefID), "mod.")
+		for _, mt := range cfg.Modifiers.Types {
+			if mt.ID != modID {
+				continue
+			}
+			level, _ := mt.Effects["set_priority"].(string)
+			if level != "" && PriorityRank(cfg, level) > PriorityRank(cfg, best) {
+				best = level
+			}
+		}
+	}
+	return best
+}
+
+// PriorityRank is the level's index in the configured levels (higher is more
+// urgent); unknown levels rank with the lowest.
+func PriorityRank(cfg *config.Config, level string) int {
+	for i, l := range priorityLevels(cfg) {
+		if l == level {
+			return i
+		}
+	}
+	return 0
+}
+
+// PriorityModifier returns the DefID of a configured modifier that sets
+// level, for importers mapping another tool's priorities.
+func PriorityModifier(cfg *config.Config, level string) (string, bool) {
+	if cfg == nil || level == "" {
+		return "", false
+	}
+	for _, mt := range cfg.Modifiers.Types {
+		if l, _ := mt.Effects["set_priority"].(string); l == level {
+			return "mod." + mt.ID, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,529 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence")
+
+// maxRecurrencePeriods bounds the occurrence search (e.g. "the 31st of every
+// month" skips short months; a rule that never matches must still terminate).
+const maxRecurrencePeriods = 1000
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+type byDaySpec struct {
+	ordinal int // 0 = every matching weekday in the period
+	weekday time.Weekday
+}
+
+// NormalizeRecurrence canonicalizes casing and defaults in place.
+func NormalizeRecurrence(rec *model.Recurrence) {
+	if rec == nil {
+		return
+	}
+	rec.Type = strings.ToLower(strings.TrimSpace(rec.Type))
+	if rec.Type == "" {
+		rec.Type = "daily"
+	}
+	if rec.Interval <= 0 {
+		rec.Interval = 1
+	}
+	rec.Mode = strings.ToLower(strings.TrimSpace(rec.Mode))
+	if rec.Mode == model.RecurrenceModeSchedule {
+		rec.Mode = ""
+	}
+	for i, d := range rec.ByDay {
+		rec.ByDay[i] = strings.ToUpper(strings.TrimSpace(d))
+	}
+	if rec.Until != nil {
+		until := strings.TrimSpace(*rec.Until)
+		if until == "" {
+			rec.Until = nil
+		} else {
+			rec.Until = &until
+		}
+	}
+}
+
+// ValidateRecurrence reports whether rec is a rule this engine can evaluate.
+func ValidateRecurrence(rec *model.Recurrence) error {
+	if rec == nil {
+		return nil
+	}
+	switch rec.Type {
+	case "daily", "weekly", "monthly", "yearly":
+	default:
+		return fmt.Errorf("%w: unsupported type %q", ErrInvalidRecurrence, rec.Type)
+	}
+	switch rec.Mode {
+	case "", model.RecurrenceModeSchedule:
+	case model.RecurrenceModeAfterCompletion:
+		if len(rec.ByDay) > 0 || len(rec.ByMonthDay) > 0 || len(rec.BySetPos) > 0 {
+			return fmt.Errorf("%w: after_completion does not take BYDAY/BYMONTHDAY/BYSETPOS", ErrInvalidRecurrence)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported mode %q", ErrInvalidRecurrence, rec.Mode)
+	}
+	if rec.Interval < 0 {
+		return fmt.Errorf("%w: interval must be positive", ErrInvalidRecurrence)
+	}
+	for _, d := range rec.ByDay {
+		spec, err := parseByDay(d)
+		if err != nil {
+			return err
+		}
+		if spec.ordinal != 0 && rec.Type != "monthly" && rec.Type != "yearly" {
+			return fmt.Errorf("%w: ordinal BYDAY %q requires monthly or yearly", ErrInvalidRecurrence, d)
+		}
+	}
+	for _, d := range rec.ByMonthDay {
+		if d == 0 || d < -31 || d > 31 {
+			return fmt.Errorf("%w: BYMONTHDAY %d out of range", ErrInvalidRecurrence, d)
+		}
+	}
+	for _, p := range rec.BySetPos {
+		if p == 0 || p < -366 || p > 366 {
+			return fmt.Errorf("%w: BYSETPOS %d out of range", ErrInvalidRecurrence, p)
+		}
+	}
+	if len(rec.BySetPos) > 0 && len(rec.ByDay) == 0 && len(rec.ByMonthDay) == 0 {
+		return fmt.Errorf("%w: BYSETPOS requires BYDAY or BYMONTHDAY", ErrInvalidRecurrence)
+	}
+	if rec.Count < 0 {
+		return fmt.Errorf("%w: count must be positive", ErrInvalidRecurrence)
+	}
+	if rec.Until != nil {
+		if _, err := time.Parse("2006-01-02", *rec.Until); err != nil {
+			return fmt.Errorf("%w: until must be YYYY-MM-DD", ErrInvalidRecurrence)
+		}
+	}
+	return nil
+}
+
+func parseByDay(raw string) (byDaySpec, error) {
+	s := strings.ToUpper(strings.TrimSpace(raw))
+	if len(s) < 2 {
+		return byDaySpec{}, fmt.Errorf("%w: bad BYDAY %q", ErrInvalidRecurrence, raw)
+	}
+	wd, ok := icsWeekdays[s[len(s)-2:]]
+	if !ok {
+		return byDaySpec{}, fmt.Errorf("%w: bad BYDAY %q", ErrInvalidRecurrence, raw)
+	}
+	spec := byDaySpec{weekday: wd}
+	if prefix := s[:len(s)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return byDaySpec{}, fmt.Errorf("%w: bad BYDAY %q", ErrInvalidRecurrence, raw)
+		}
+		spec.ordinal = n
+	}
+	return spec, nil
+}
+
+func civilDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// NextOccurrence returns the first occurrence of a schedule-mode rule that is
+// on or after from, generating occurrences from anchor (the current due date).
+// Dates are evaluated in anchor's location. ok is false once UNTIL is passed
+// or the rule never matches.
+func NextOccurrence(rec model.Recurrence, anchor, from time.Time) (time.Time, bool) {
+	loc := anchor.Location()
+	anchor = civilDay(anchor)
+	from = civilDay(from.In(loc))
+
+	var until time.Time
+	hasUntil := false
+	if rec.Until != nil {
+		if parsed, err := time.ParseInLocation("2006-01-02", *rec.Until, loc); err == nil {
+			until, hasUntil = parsed, true
+		}
+	}
+
+	interval := rec.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		for _, c := range expandRecurrencePeriod(rec, anchor, period*interval) {
+			if c.Before(anchor) {
+				continue
+			}
+			if hasUntil && c.After(until) {
+				return time.Time{}, false
+			}
+			if !c.Before(from) {
+				return c, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// expandRecurrencePeriod lists the candidate dates of the period that is
+// offset frequency units after anchor's period, after BYSETPOS selection.
+func expandRecurrencePeriod(rec model.Recurrence, anchor time.Time, offset int) []time.Time {
+	specs := make([]byDaySpec, 0, len(rec.ByDay))
+	for _, d := range rec.ByDay {
+		if spec, err := parseByDay(d); err == nil {
+			specs = append(specs, spec)
+		}
+	}
+
+	var out []time.Time
+	switch rec.Type {
+	case "weekly":
+		// Weeks start on Monday (RRULE WKST default).
+		back := (int(anchor.Weekday()) + 6) % 7
+		weekStart := anchor.AddDate(0, 0, -back+7*offset)
+		if len(specs) == 0 {
+			out = []time.Time{weekStart.AddDate(0, 0, back)}
+			break
+		}
+		for i := 0; i < 7; i++ {
+			d := weekStart.AddDate(0, 0, i)
+			if matchesWeekday(specs, d.Weekday()) {
+				out = append(out, d)
+			}
+		}
+	case "monthly":
+		first := time.Date(anchor.Year(), anchor.Month()+time.Month(offset), 1, 0, 0, 0, 0, anchor.Location())
+		out = expandMonth(rec, specs, first, anchor.Day())
+	case "yearly":
+		first := time.Date(anchor.Year()+offset, anchor.Month(), 1, 0, 0, 0, 0, anchor.Location())
+		out = expandMonth(rec, specs, first, anchor.Day())
+	default:
+		d := anchor.AddDate(0, 0, offset)
+		if len(specs) > 0 && !matchesWeekday(specs, d.Weekday()) {
+			break
+		}
+		if len(rec.ByMonthDay) > 0 && !matchesMonthDay(rec.ByMonthDay, d) {
+			break
+		}
+		out = []time.Time{d}
+	}
+	return applySetPos(out, rec.BySetPos)
+}
+
+func expandMonth(rec model.Recurrence, specs []byDaySpec, first time.Time, anchorDay int) []time.Time {
+	days := first.AddDate(0, 1, -1).Day()
+	dayOf := func(n int) time.Time { return first.AddDate(0, 0, n-1) }
+
+	if len(specs) == 0 && len(rec.ByMonthDay) == 0 {
+		if anchorDay > days {
+			return nil
+		}
+		return []time.Time{dayOf(anchorDay)}
+	}
+
+	selected := make(map[int]bool)
+	if len(specs) > 0 {
+		for _, spec := range specs {
+			matches := make([]int, 0, 5)
+			for n := 1; n <= days; n++ {
+				if dayOf(n).Weekday() == spec.weekday {
+					matches = append(matches, n)
+				}
+			}
+			switch {
+			case spec.ordinal == 0:
+				for _, n := range matches {
+					selected[n] = true
+				}
+			case spec.ordinal > 0 && spec.ordinal <= len(matches):
+				selected[matches[spec.ordinal-1]] = true
+			case spec.ordinal < 0 && -spec.ordinal <= len(matches):
+				selected[matches[len(matches)+spec.ordinal]] = true
+			}
+		}
+	}
+	if len(rec.ByMonthDay) > 0 {
+		byMonthDay := make(map[int]bool)
+		for _, md := range rec.ByMonthDay {
+			n := md
+			if md < 0 {
+				n = days + md + 1
+			}
+			if n >= 1 && n <= days {
+				byMonthDay[n] = true
+			}
+		}
+		if len(specs) > 0 {
+			for n := range selected {
+				if !byMonthDay[n] {
+					delete(selected, n)
+				}
+			}
+		} else {
+			selected = byMonthDay
+		}
+	}
+
+	out := make([]time.Time, 0, len(selected))
+	for n := range selected {
+		out = append(out, dayOf(n))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+func matchesWeekday(specs []byDaySpec, wd time.Weekday) bool {
+	for _, spec := range specs {
+		if spec.weekday == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesMonthDay(monthDays []int, d time.Time) bool {
+	days := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, d.Location()).Day()
+	for _, md := range monthDays {
+		if md == d.Day() || (md < 0 && days+md+1 == d.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func applySetPos(set []time.Time, positions []int) []time.Time {
+	if len(positions) == 0 || len(set) == 0 {
+		return set
+	}
+	picked := make(map[int]bool)
+	for _, p := range positions {
+		idx := p - 1
+		if p < 0 {
+			idx = len(set) + p
+		}
+		if idx >= 0 && idx < len(set) {
+			picked[idx] = true
+		}
+	}
+	out := make([]time.Time, 0, len(picked))
+	for i, d := range set {
+		if picked[i] {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// addRecurrenceInterval moves base forward by one interval of the rule's
+// frequency.
+func addRecurrenceInterval(base time.Time, rec model.Recurrence) time.Time {
+	interval := rec.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+	switch rec.Type {
+	case "weekly":
+		return base.AddDate(0, 0, 7*interval)
+	case "monthly":
+		return base.AddDate(0, interval, 0)
+	case "yearly":
+		return base.AddDate(interval, 0, 0)
+	default:
+		return base.AddDate(0, 0, interval)
+	}
+}
+
+// NextRecurrenceDueDate computes the due date a completed recurring task
+// respawns with on the day tick. tickDate carries the user's location.
+// ok is false when the rule is exhausted (COUNT reached or past UNTIL).
+func NextRecurrenceDueDate(t model.Task, tickDate time.Time) (string, bool) {
+	if t.Recurrence == nil {
+		return "", false
+	}
+	rec := *t.Recurrence
+	NormalizeRecurrence(&rec)
+	if rec.Count > 0 && rec.Occurrences+1 >= rec.Count {
+		return "", false
+	}
+	loc := tickDate.Location()
+	tickDate = civilDay(tickDate)
+
+	parseDay := func(raw *string) (time.Time, bool) {
+		if raw == nil {
+			return time.Time{}, false
+		}
+		parsed, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(*raw), loc)
+		return parsed, err == nil
+	}
+
+	if rec.Mode == model.RecurrenceModeAfterCompletion {
+		base, ok := parseDay(t.LastCompletedDate)
+		if !ok {
+			base = civilDay(t.UpdatedAt.In(loc))
+		}
+		next := addRecurrenceInterval(base, rec)
+		if next.Before(tickDate) {
+			next = tickDate
+		}
+		if until, ok := parseDay(rec.Until); ok && next.After(until) {
+			return "", false
+		}
+		return next.Format("2006-01-02"), true
+	}
+
+	anchor, ok := parseDay(t.DueDate)
+	if !ok {
+		anchor = tickDate
+	}
+	next, ok := NextOccurrence(rec, anchor, tickDate)
+	if !ok {
+		return "", false
+	}
+	return next.Format("2006-01-02"), true
+}
+
+// RecurrenceToRRULE renders a schedule-mode recurrence as an RFC 5545 RRULE
+// value. Completion-based rules have no calendar equivalent and render empty.
+// COUNT is emitted as the number of occurrences remaining. dueDate is the
+// rule's anchor: a yearly rule with BYDAY or BYMONTHDAY only expands the
+// anchor's month, which other calendars need spelled out as BYMONTH.
+func RecurrenceToRRULE(rec *model.Recurrence, dueDate *string) string {
+	if rec == nil {
+		return ""
+	}
+	r := *rec
+	NormalizeRecurrence(&r)
+	if r.Mode == model.RecurrenceModeAfterCompletion || ValidateRecurrence(&r) != nil {
+		return ""
+	}
+
+	parts := []string{
+		"FREQ=" + strings.ToUpper(r.Type),
+		fmt.Sprintf("INTERVAL=%d", r.Interval),
+	}
+	if len(r.ByDay) > 0 {
+		parts = append(parts, "BYDAY="+strings.Join(r.ByDay, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if r.Type == "yearly" && (len(r.ByDay) > 0 || len(r.ByMonthDay) > 0) && dueDate != nil {
+		if anchor, err := time.Parse("2006-01-02", *dueDate); err == nil {
+			parts = append(parts, fmt.Sprintf("BYMONTH=%d", int(anchor.Month())))
+		}
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.Count > 0 {
+		remaining := r.Count - r.Occurrences
+		if remaining < 1 {
+			remaining = 1
+		}
+		parts = append(parts, fmt.Sprintf("COUNT=%d", remaining))
+	} else if r.Until != nil {
+		until, _ := time.Parse("2006-01-02", *r.Until)
+		parts = append(parts, "UNTIL="+until.Format(icsDateLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// ParseRRULE parses an RRULE value (with or without the "RRULE:" prefix)
+// into the supported subset. A single BYMONTH is accepted on yearly rules
+// and taken to be DTSTART's month, the only month they expand here.
+func ParseRRULE(raw string) (*model.Recurrence, error) {
+	s := strings.TrimSpace(raw)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	rec := &model.Recurrence{}
+	byMonth := ""
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed rule part %q", ErrInvalidRecurrence, part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+		var err error
+		switch key {
+		case "FREQ":
+			rec.Type = strings.ToLower(val)
+		case "INTERVAL":
+			rec.Interval, err = strconv.Atoi(val)
+		case "BYDAY":
+			rec.ByDay = strings.Split(val, ",")
+		case "BYMONTHDAY":
+			rec.ByMonthDay, err = splitInts(val)
+		case "BYMONTH":
+			byMonth = val
+		case "BYSETPOS":
+			rec.BySetPos, err = splitInts(val)
+		case "COUNT":
+			rec.Count, err = strconv.Atoi(val)
+		case "UNTIL":
+			if len(val) < 8 {
+				return nil, fmt.Errorf("%w: bad UNTIL %q", ErrInvalidRecurrence, val)
+			}
+			until, perr := time.Parse(icsDateLayout, val[:8])
+			if perr != nil {
+				return nil, fmt.Errorf("%w: bad UNTIL %q", ErrInvalidRecurrence, val)
+			}
+			day := until.Format("2006-01-02")
+			rec.Until = &day
+		case "WKST":
+			// Weeks always start on Monday here.
+		default:
+			return nil, fmt.Errorf("%w: unsupported rule part %s", ErrInvalidRecurrence, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad %s %q", ErrInvalidRecurrence, key, val)
+		}
+	}
+	if byMonth != "" {
+		if m, err := strconv.Atoi(byMonth); err != nil || m < 1 || m > 12 || !strings.EqualFold(rec.Type, "yearly") {
+			return nil, fmt.Errorf("%w: unsupported BYMONTH %q", ErrInvalidRecurrence, byMonth)
+		}
+	}
+	NormalizeRecurrence(rec)
+	if err := ValidateRecurrence(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func joinInts(vals []int) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitInts(raw string) ([]int, error) {
+	parts := strings.Split(raw, ",")
+	out := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
@@ -0,0 +1,140 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+func TestNextRecurrenceDueDate_RuleSubset(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+
+	cases := []struct {
+		name string
+		due  string
+		tick string
+		rec  model.Recurrence
+		want string
+		ok   bool
+	}{
+		{
+			name: "every other tuesday",
+			due:  "2026-02-03", // Tuesday
+			tick: "2026-02-04",
+			rec:  model.Recurrence{Type: "weekly", Interval: 2, ByDay: []string{"TU"}},
+			want: "2026-02-17",
+			ok:   true,
+		},
+		{
+			name: "last business day of the month",
+			due:  "2026-01-30", // Friday
+			tick: "2026-01-31",
+			rec:  model.Recurrence{Type: "monthly", Interval: 1, ByDay: []string{"MO", "TU", "WE", "TH", "FR"}, BySetPos: []int{-1}},
+			want: "2026-02-27",
+			ok:   true,
+		},
+		{
+			name: "last friday of the month",
+			due:  "2026-02-27",
+			tick: "2026-02-28",
+			rec:  model.Recurrence{Type: "monthly", Interval: 1, ByDay: []string{"-1FR"}},
+			want: "2026-03-27",
+			ok:   true,
+		},
+		{
+			name: "month day skips short months",
+			due:  "2026-01-31",
+			tick: "2026-02-01",
+			rec:  model.Recurrence{Type: "monthly", Interval: 1, ByMonthDay: []int{31}},
+			want: "2026-03-31",
+			ok:   true,
+		},
+		{
+			name: "until stops the series",
+			due:  "2026-02-03",
+			tick: "2026-02-04",
+			rec:  model.Recurrence{Type: "weekly", Interval: 1, Until: strPtr("2026-02-09")},
+			ok:   false,
+		},
+		{
+			name: "count stops the series",
+			due:  "2026-02-03",
+			tick: "2026-02-04",
+			rec:  model.Recurrence{Type: "daily", Interval: 1, Count: 3, Occurrences: 2},
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tick, err := time.ParseInLocation("2006-01-02", tc.tick, time.UTC)
+			if err != nil {
+				t.Fatalf("parse tick: %v", err)
+			}
+			due := tc.due
+			rec := tc.rec
+			got, ok := NextRecurrenceDueDate(model.Task{DueDate: &due, Recurrence: &rec}, tick)
+			if ok != tc.ok || got != tc.want {
+				t.Fatalf("expected (%q, %v), got (%q, %v)", tc.want, tc.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestNextRecurrenceDueDate_AfterCompletion(t *testing.T) {
+	due := "2026-02-01"
+	completed := "2026-02-05"
+	tick := time.Date(2026, 2, 6, 0, 0, 0, 0, time.UTC)
+	got, ok := NextRecurrenceDueDate(model.Task{
+		DueDate:           &due,
+		LastCompletedDate: &completed,
+		Recurrence: &model.Recurrence{
+			Type:     "daily",
+			Interval: 10,
+			Mode:     model.RecurrenceModeAfterCompletion,
+		},
+	}, tick)
+	if !ok || got != "2026-02-15" {
+		t.Fatalf("expected 2026-02-15, got (%q, %v)", got, ok)
+	}
+}
+
+func TestParseRRULE_RoundTrip(t *testing.T) {
+	rec, err := ParseRRULE("RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;UNTIL=20261231")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got, want := RecurrenceToRRULE(rec, nil), "FREQ=MONTHLY;INTERVAL=1;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;UNTIL=20261231"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	// Yearly rules expand only the anchor's month; the export says which.
+	due := "2026-11-26"
+	yearly := &model.Recurrence{Type: "yearly", Interval: 1, ByDay: []string{"4TH"}}
+	rrule := RecurrenceToRRULE(yearly, &due)
+	if want := "FREQ=YEARLY;INTERVAL=1;BYDAY=4TH;BYMONTH=11"; rrule != want {
+		t.Fatalf("expected %q, got %q", want, rrule)
+	}
+	back, err := ParseRRULE(rrule)
+	if err != nil {
+		t.Fatalf("parse yearly: %v", err)
+	}
+	if got := RecurrenceToRRULE(back, &due); got != rrule {
+		t.Fatalf("yearly round trip: expected %q, got %q", rrule, got)
+	}
+	anchor := time.Date(2026, 11, 26, 0, 0, 0, 0, time.UTC)
+	if next, ok := NextOccurrence(*back, anchor, anchor.AddDate(0, 0, 1)); !ok || next.Format("2006-01-02") != "2027-11-25" {
+		t.Fatalf("expected the next Thanksgiving 2027-11-25, got (%v, %v)", next, ok)
+	}
+	if _, err := ParseRRULE("FREQ=YEARLY;BYMONTH=3,9;BYMONTHDAY=1"); err == nil {
+		t.Fatalf("expected several BYMONTH values to fail")
+	}
+
+	if _, err := ParseRRULE("FREQ=HOURLY"); err == nil {
+		t.Fatalf("expected unsupported frequency to fail")
+	}
+	if _, err := ParseRRULE("FREQ=WEEKLY;BYDAY=2TU"); err == nil {
+		t.Fatalf("expected ordinal BYDAY on weekly rule to fail")
+	}
+}
@@ -15,47 +15,93 @@ import (
 var (
 	ErrNotFound    = errors.New("task not found")
 	ErrTooManyMods = errors.New("too many modifiers (max 4)")
+
+	// ErrRevisionConflict is returned by Update when Patch.IfRevision no
+	// longer matches the stored task.
+	ErrRevisionConflict = errors.New("task revision conflict")
 )
 
 // Patch represents a partial update.
-// nil pointer => "no change"
-// empty string for pointer fields (Project/DueDate) => clear (set to nil)
+// nil pointer / unset Nullable => "no change"
+// Nullable set to null => clear the field
+// empty string for Project/DueDate is still accepted as "clear"
 type Patch struct {
-	Title       *string   `json:"title,omitempty"`
-	Description *string   `json:"description,omitempty"`
-	Done        *bool     `json:"done,omitempty"`
-	Project     *string   `json:"project,omitempty"`
-	Tags        *[]string `json:"tags,omitempty"`
-
-	Modifiers  *[]model.TaskModifierSlot `json:"modifiers,omitempty"`
-	DueDate    *string                   `json:"dueDate,omitempty"`
-	NextAction *bool                     `json:"nextAction,omitempty"`
-	Recurrence *model.Recurrence         `json:"recurrence,omitempty"`
+	Title       *string            `json:"title,omitempty"`
+	Description Nullable[string]   `json:"description,omitzero"`
+	Done        *bool              `json:"done,omitempty"`
+	Archived    *bool              `json:"archived,omitempty"`
+	Project     Nullable[string]   `json:"project,omitzero"`
+	Tags        Nullable[[]string] `json:"tags,omitzero"`
+
+	Modifiers  Nullable[[]model.TaskModifierSlot] `json:"modifiers,omitzero"`
+	DueDate    Nullable[string]                   `json:"dueDate,omitzero"`
+	DueTime    Nullable[string]                   `json:"dueTime,omitzero"`
+	StartDate  Nullable[string]                   `json:"startDate,omitzero"`
+	NextAction *bool                              `json:"nextAction,omitempty"`
+	Recurrence Nullable[model.Recurrence]         `json:"recurrence,omitzero"`
+
+	// EstimateMinutes: null or 0 clears the estimate.
+	EstimateMinutes Nullable[int] `json:"estimateMinutes,omitzero"`
 
 	// Internal fields (not exposed via JSON API directly).
-	AssignedVillagerID   *string `json:"-"`
-	WorkedToday          *bool   `json:"-"`
-	ProcessedCountDelta  *int    `json:"-"`
-	CompletionCountDelta *int    `json:"-"`
-	Habit                *bool   `json:"-"`
-	HabitTier            *int    `json:"-"`
-	HabitStreak          *int    `json:"-"`
-	LastCompletedDate    *string `json:"-"`
+	AssignedVillagerID   Nullable[string] `json:"-"`
+	WorkedToday          *bool            `json:"-"`
+	ProcessedCountDelta  *int             `json:"-"`
+	CompletionCountDelta *int             `json:"-"`
+	Habit                *bool            `json:"-"`
+	HabitTier            *int             `json:"-"`
+	HabitStreak          *int             `json:"-"`
+	LastCompletedDate    Nullable[string] `json:"-"`
+	TrackedSecondsDelta  *int64           `json:"-"`
+
+	// ExternalRefs sets the given keys; an empty value removes one.
+	ExternalRefs map[string]string `json:"-"`
+
+	// IfRevision makes the update conditional on the stored revision.
+	IfRevision *int64 `json:"-"`
+}
+
+// BatchUpdate is one task's patch within Repo.UpdateMany.
+type BatchUpdate struct {
+	ID    model.TaskID
+	Patch Patch
 }
 
 type ListFilter struct {
 	// Status:
 	//   "" | "all" | "pending" | "done" | "due_today" | "upcoming" | "overdue"
+	//   or several of them comma-separated ("due_today,overdue")
 	Status string
 
 	// Project:
 	//   "" | "any" | "inbox" | "projects" | "<exact project name>"
 	Project string
 
+	// Tags: tasks must carry every listed tag.
+	Tags []string
+
+	// ParentID: only the step tasks of this parent.
+	ParentID model.TaskID
+
 	// Live:
 	//   nil = don't care
 	//   true/false = filter tasks by "live" state (board tasks)
 	Live *bool
+
+	// Deferred (tasks whose start date is still in the future):
+	//   "" = hidden when Project is "inbox", shown otherwise
+	//   "include" | "only" | "exclude"
+	Deferred string
+
+	// Archived:
+	//   "" | "include" = archived tasks are listed
+	//   "only" | "exclude"
+	// The HTTP listing defaults to "exclude"; internal callers that walk
+	// every task (renames, exports) keep the zero value.
+	Archived string
+
+	// Location is the user's timezone for "today"; nil means time.Local.
+	Location *time.Location
 }
 
 type Repo interface {
@@ -64,20 +110,124 @@ type Repo interface {
 	Update(id model.TaskID, patch Patch) (model.Task, error)
 	List(filter ListFilter) ([]model.Task, error)
 	SetModifiers(id model.TaskID, mods []model.TaskModifierSlot) (model.Task, error)
+
+	// SyncLive and SetLive move tasks on or off the board; each task whose
+	// live flag changes gets a new revision.
 	SyncLive(taskIDs []model.TaskID) error
 	SetLive(id model.TaskID, live bool) error
+
+	// UpdateMany applies every patch or none: the first failure (unknown
+	// task, revision conflict, ...) leaves all tasks unchanged. Results are in
+	// input order.
+	UpdateMany(updates []BatchUpdate) ([]model.Task, error)
+
+	// WithOrigin returns a view that attributes history entries to source
+	// (SourceAPI, SourceBoard, ...) and actor ("" keeps the default actor).
+	WithOrigin(source, actor string) Repo
+	// History returns a task's activity log, oldest first.
+	History(id model.TaskID) ([]model.TaskActivity, error)
+
+	// Comment thread, oldest first.
+	Comments(id model.TaskID) ([]model.TaskComment, error)
+	AddComment(id model.TaskID, c model.TaskComment) (model.TaskComment, error)
+	UpdateComment(id model.TaskID, commentID, body string) (model.TaskComment, error)
+	DeleteComment(id model.TaskID, commentID string) error
 }
 
-type MemoryRepo struct {
+type memoryStore struct {
 	mu        sync.RWMutex
 	tasks     map[model.TaskID]model.Task
 	liveIndex map[model.TaskID]bool
+	history   map[model.TaskID][]model.TaskActivity
+	comments  map[model.TaskID][]model.TaskComment
+}
+
+type MemoryRepo struct {
+	*memoryStore
+	origin activityOrigin
 }
 
 func NewMemoryRepo() *MemoryRepo {
 	return &MemoryRepo{
-		tasks:     map[model.TaskID]model.Task{},
-		liveIndex: map[model.TaskID]bool{},
+		memoryStore: &memoryStore{
+			tasks:     map[model.TaskID]model.Task{},
+			liveIndex: map[model.TaskID]bool{},
+			history:   map[model.TaskID][]model.TaskActivity{},
+			comments:  map[model.TaskID][]model.TaskComment{},
+		},
+	}
+}
+
+func (r *MemoryRepo) WithOrigin(source, actor string) Repo {
+	return &MemoryRepo{
+		memoryStore: r.memoryStore,
+		origin:      activityOrigin{source: source, actor: actor},
+	}
+}
+
+func (r *MemoryRepo) History(id model.TaskID) ([]model.TaskActivity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.tasks[id]; !ok {
+		return nil, ErrNotFound
+	}
+	return append([]model.TaskActivity{}, r.history[id]...), nil
+}
+
+func (r *MemoryRepo) Comments(id model.TaskID) ([]model.TaskComment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.tasks[id]; !ok {
+		return nil, ErrNotFound
+	}
+	return append([]model.TaskComment{}, r.comments[id]...), nil
+}
+
+func (r *MemoryRepo) AddComment(id model.TaskID, c model.TaskComment) (model.TaskComment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tasks[id]; !ok {
+		return model.TaskComment{}, ErrNotFound
+	}
+	c, err := newComment(id, c)
+	if err != nil {
+		return model.TaskComment{}, err
+	}
+	r.comments[id] = append(r.comments[id], c)
+	return c, nil
+}
+
+func (r *MemoryRepo) UpdateComment(id model.TaskID, commentID, body string) (model.TaskComment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tasks[id]; !ok {
+		return model.TaskComment{}, ErrNotFound
+	}
+	return editComment(r.comments[id], commentID, body)
+}
+
+func (r *MemoryRepo) DeleteComment(id model.TaskID, commentID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tasks[id]; !ok {
+		return ErrNotFound
+	}
+	next, err := removeComment(r.comments[id], commentID)
+	if err != nil {
+		return err
+	}
+	r.comments[id] = next
+	return nil
+}
+
+func (r *MemoryRepo) recordLocked(before, after model.Task, created bool) {
+	if entry, ok := newActivity(r.origin.withDefaults(""), before, after, created, after.UpdatedAt); ok {
+		r.history[after.ID] = append(r.history[after.ID], entry)
 	}
 }
 
@@ -86,16 +236,20 @@ func (r *MemoryRepo) SyncLive(taskIDs []model.TaskID) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	next := make(map[model.TaskID]bool, len(taskIDs))
+	want := make(map[model.TaskID]bool, len(taskIDs))
 	for _, id := range taskIDs {
-		if id == "" {
-			continue
+		if id != "" {
+			want[id] = true
 		}
-		if t, ok := r.tasks[id]; ok && !t.Done {
-			next[id] = true
+	}
+	for id := range r.liveIndex {
+		if !want[id] {
+			setLiveLocked(r.tasks, r.liveIndex, id, false)
 		}
 	}
-	r.liveIndex = next
+	for id := range want {
+		setLiveLocked(r.tasks, r.liveIndex, id, true)
+	}
 	return nil
 }
 
@@ -103,23 +257,43 @@ func (r *MemoryRepo) SetLive(id model.TaskID, live bool) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	t, ok := r.tasks[id]
-	if !ok {
+	if _, ok := r.tasks[id]; !ok {
 		return ErrNotFound
 	}
+	setLiveLocked(r.tasks, r.liveIndex, id, live)
+	return nil
+}
 
-	// done tasks can never be live
-	if t.Done {
-		delete(r.liveIndex, id)
-		return nil
-	}
+// isLive reports whether t is on the board; done tasks never are.
+func isLive(index map[model.TaskID]bool, t model.Task) bool {
+	return index[t.ID] && !t.Done
+}
 
-	if live {
-		r.liveIndex[id] = true
+// setLiveLocked puts id on or off the board. Live is part of the task as
+// served, so a change bumps its revision (and with it the ETag).
+func setLiveLocked(tasks map[model.TaskID]model.Task, index map[model.TaskID]bool, id model.TaskID, live bool) {
+	t, ok := tasks[id]
+	if !ok {
+		delete(index, id)
+		return
+	}
+	was := isLive(index, t)
+	if live && !t.Done {
+		index[id] = true
 	} else {
-		delete(r.liveIndex, id)
+		delete(index, id)
 	}
-	return nil
+	if isLive(index, t) != was {
+		normalizeTask(&t)
+		t.Revision++
+		tasks[id] = t
+	}
+}
+
+// NewID returns a fresh task ID, for callers that restore tasks under new
+// IDs.
+func NewID() model.TaskID {
+	return newID("task")
 }
 
 func newID(prefix string) model.TaskID {
@@ -135,69 +309,100 @@ func normalizeTask(t *model.Task) {
 	if t.Modifiers == nil {
 		t.Modifiers = []model.TaskModifierSlot{}
 	}
+	// Tasks stored before revisions existed start at 1.
+	if t.Revision < 1 {
+		t.Revision = 1
+	}
+}
+
+// checkRevision enforces Patch.IfRevision against the stored task.
+func checkRevision(t model.Task, p Patch) error {
+	if p.IfRevision != nil && *p.IfRevision != t.Revision {
+		return ErrRevisionConflict
+	}
+	return nil
 }
 
 func applyPatch(t *model.Task, p Patch) error {
 	if p.Title != nil {
 		t.Title = *p.Title
 	}
-	if p.Description != nil {
-		t.Description = *p.Description
+	if p.Description.Set {
+		t.Description = ""
+		if p.Description.Value != nil {
+			t.Description = *p.Description.Value
+		}
 	}
 	if p.Done != nil {
 		t.Done = *p.Done
 	}
+	if p.Archived != nil {
+		t.Archived = *p.Archived
+	}
 
-	// pointer string field with "empty clears" semantics
-	if p.Project != nil {
-		if *p.Project == "" {
-			t.Project = nil
-		} else {
-			t.Project = p.Project
-		}
+	if p.Project.Set {
+		t.Project = optionalString(p.Project)
 	}
-	if p.DueDate != nil {
-		if *p.DueDate == "" {
-			t.DueDate = nil
-		} else {
-			t.DueDate = p.DueDate
-		}
+	if p.DueDate.Set {
+		t.DueDate = optionalString(p.DueDate)
+	}
+	if p.DueTime.Set {
+		t.DueTime = optionalString(p.DueTime)
+	}
+	if t.DueDate == nil {
+		// a time of day means nothing without a date
+		t.DueTime = nil
+	}
+	if p.StartDate.Set {
+		t.StartDate = optionalString(p.StartDate)
 	}
 
-	if p.Tags != nil {
-		// treat nil slice as empty slice
-		if *p.Tags == nil {
+	if p.Tags.Set {
+		// null and nil slices both clear
+		if p.Tags.Value == nil || *p.Tags.Value == nil {
 			t.Tags = []string{}
 		} else {
-			t.Tags = *p.Tags
+			t.Tags = NormalizeTags(*p.Tags.Value)
 		}
 	}
 
-	if p.Modifiers != nil {
-		if *p.Modifiers == nil {
+	if p.Modifiers.Set {
+		if p.Modifiers.Value == nil || *p.Modifiers.Value == nil {
 			t.Modifiers = []model.TaskModifierSlot{}
 		} else {
-			if len(*p.Modifiers) > 4 {
+			if len(*p.Modifiers.Value) > 4 {
 				return ErrTooManyMods
 			}
-			t.Modifiers = *p.Modifiers
+			t.Modifiers = *p.Modifiers.Value
 		}
 	}
 
 	if p.NextAction != nil {
 		t.NextAction = *p.NextAction
 	}
-	if p.Recurrence != nil {
-		// NOTE: if you need "clear recurrence" via JSON null, you’ll want a pointer-to-pointer.
-		t.Recurrence = p.Recurrence
-	}
-	if p.AssignedVillagerID != nil {
-		if strings.TrimSpace(*p.AssignedVillagerID) == "" {
-			t.AssignedVillagerID = nil
+	if p.Recurrence.Set {
+		if p.Recurrence.Value == nil {
+			t.Recurrence = nil
 		} else {
-			v := strings.TrimSpace(*p.AssignedVillagerID)
-			t.AssignedVillagerID = &v
+			rec := *p.Recurrence.Value
+			t.Recurrence = &rec
+		}
+	}
+	if p.EstimateMinutes.Set {
+		t.EstimateMinutes = 0
+		if p.EstimateMinutes.Value != nil && *p.EstimateMinutes.Value > 0 {
+			t.EstimateMinutes = *p.EstimateMinutes.Value
+		}
+	}
+	if p.TrackedSecondsDelta != nil && *p.TrackedSecondsDelta != 0 {
+		next := t.TrackedSeconds + *p.TrackedSecondsDelta
+		if next < 0 {
+			next = 0
 		}
+		t.TrackedSeconds = next
+	}
+	if p.AssignedVillagerID.Set {
+		t.AssignedVillagerID = optionalString(p.AssignedVillagerID)
 	}
 	if p.WorkedToday != nil {
 		t.WorkedToday = *p.WorkedToday
@@ -229,18 +434,40 @@ func applyPatch(t *model.Task, p Patch) error {
 		}
 		t.HabitStreak = next
 	}
-	if p.LastCompletedDate != nil {
-		if strings.TrimSpace(*p.LastCompletedDate) == "" {
-			t.LastCompletedDate = nil
-		} else {
-			v := strings.TrimSpace(*p.LastCompletedDate)
-			t.LastCompletedDate = &v
+	if p.LastCompletedDate.Set {
+		t.LastCompletedDate = optionalString(p.LastCompletedDate)
+	}
+	if len(p.ExternalRefs) > 0 {
+		refs := make(map[string]string, len(t.ExternalRefs)+len(p.ExternalRefs))
+		for k, v := range t.ExternalRefs {
+			refs[k] = v
+		}
+		for k, v := range p.ExternalRefs {
+			if v == "" {
+				delete(refs, k)
+			} else {
+				refs[k] = v
+			}
 		}
+		t.ExternalRefs = refs
 	}
 
 	return nil
 }
 
+// optionalString resolves a Nullable string for a pointer field: null and
+// blank values clear it.
+func optionalString(n Nullable[string]) *string {
+	if n.Value == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*n.Value)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
 func (r *MemoryRepo) Create(t model.Task) (model.Task, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -249,10 +476,13 @@ func (r *MemoryRepo) Create(t model.Task) (model.Task, error) {
 	t.ID = newID("task")
 	t.CreatedAt = now
 	t.UpdatedAt = now
+	t.Revision = 1
+	t.Tags = NormalizeTags(t.Tags)
 
 	normalizeTask(&t)
 
 	r.tasks[t.ID] = t
+	r.recordLocked(model.Task{}, t, true)
 	return t, nil
 }
 
@@ -265,6 +495,8 @@ func (r *MemoryRepo) Get(id model.TaskID) (model.Task, error) {
 		return model.Task{}, ErrNotFound
 	}
 	normalizeTask(&t)
+	t.Live = isLive(r.liveIndex, t)
+	t.CommentCount = len(r.comments[id])
 	return t, nil
 }
 
@@ -276,31 +508,89 @@ func (r *MemoryRepo) Update(id model.TaskID, p Patch) (model.Task, error) {
 	if !ok {
 		return model.Task{}, ErrNotFound
 	}
+	normalizeTask(&t)
+	if err := checkRevision(t, p); err != nil {
+		return model.Task{}, err
+	}
+	before := t
 
 	if err := applyPatch(&t, p); err != nil {
 		return model.Task{}, err
 	}
 
-	// If a task is marked done, it can no longer be live.
-	if t.Done {
+	// If a task is marked done or archived, it can no longer be live.
+	if t.Done || t.Archived {
 		r.liveIndex[t.ID] = false
 	}
 
 	t.UpdatedAt = time.Now()
+	t.Revision++
 	normalizeTask(&t)
 
 	r.tasks[id] = t
+	r.recordLocked(before, t, false)
+	t.Live = isLive(r.liveIndex, t)
+	t.CommentCount = len(r.comments[id])
 	return t, nil
 }
 
+func (r *MemoryRepo) UpdateMany(updates []BatchUpdate) ([]model.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Stage every change first so a failure leaves the store untouched.
+	staged := make(map[model.TaskID]model.Task, len(updates))
+	befores := make(map[model.TaskID]model.Task, len(updates))
+	order := make([]model.TaskID, 0, len(updates))
+	for _, u := range updates {
+		t, ok := staged[u.ID]
+		if !ok {
+			if t, ok = r.tasks[u.ID]; !ok {
+				return nil, ErrNotFound
+			}
+			normalizeTask(&t)
+			befores[u.ID] = t
+			order = append(order, u.ID)
+		}
+		if err := checkRevision(t, u.Patch); err != nil {
+			return nil, err
+		}
+		if err := applyPatch(&t, u.Patch); err != nil {
+			return nil, err
+		}
+		staged[u.ID] = t
+	}
+
+	now := time.Now()
+	for _, id := range order {
+		t := staged[id]
+		if t.Done || t.Archived {
+			r.liveIndex[id] = false
+		}
+		t.UpdatedAt = now
+		t.Revision++
+		normalizeTask(&t)
+		r.tasks[id] = t
+		r.recordLocked(befores[id], t, false)
+		staged[id] = t
+	}
+
+	out := make([]model.Task, 0, len(updates))
+	for _, u := range updates {
+		t := staged[u.ID]
+		t.Live = isLive(r.liveIndex, t)
+		t.CommentCount = len(r.comments[u.ID])
+		out = append(out, t)
+	}
+	return out, nil
+}
+
 func (r *MemoryRepo) List(filter ListFilter) ([]model.Task, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	// "today" in server local time (YYYY-MM-DD)
-	today := time.Now().Format("2006-01-02")
+	now := time.Now()
 
-	status := strings.ToLower(strings.TrimSpace(filter.Status))
 	projectFilter := strings.TrimSpace(filter.Project)
 	projectFilterLower := strings.ToLower(projectFilter)
 
@@ -310,15 +600,10 @@ func (r *MemoryRepo) List(filter ListFilter) ([]model.Task, error) {
 		// work on a copy so we can normalize + compute Live safely
 		t := t0
 		normalizeTask(&t)
+		t.CommentCount = len(r.comments[t.ID])
 
 		// ✅ compute live from the server index
-		if t.Done {
-			t.Live = false
-		} else if r.liveIndex != nil {
-			t.Live = r.liveIndex[t.ID] && !t.Done
-		} else {
-			t.Live = false
-		}
+		t.Live = isLive(r.liveIndex, t)
 
 		// --- live filter ---
 		if filter.Live != nil {
@@ -351,32 +636,9 @@ func (r *MemoryRepo) List(filter ListFilter) ([]model.Task, error) {
 			}
 		}
 
-		// --- status filter ---
-		switch status {
-		case "", "all":
-			// no-op
-		case "pending":
-			if t.Done {
-				continue
-			}
-		case "done":
-			if !t.Done {
-				continue
-			}
-		case "due_today":
-			if t.Done || t.DueDate == nil || *t.DueDate != today {
-				continue
-			}
-		case "overdue":
-			if t.Done || t.DueDate == nil || *t.DueDate >= today {
-				continue
-			}
-		case "upcoming":
-			if t.Done || t.DueDate == nil || *t.DueDate <= today {
-				continue
-			}
-		default:
-			// unknown => treat as "all"
+		// --- status, dates (user timezone) and tags ---
+		if !matchesFilter(t, filter, now) {
+			continue
 		}
 
 		out = append(out, t)
@@ -384,19 +646,7 @@ func (r *MemoryRepo) List(filter ListFilter) ([]model.Task, error) {
 
 	// Sort: due soonest first (nil due dates last), then updated desc
 	sort.Slice(out, func(i, j int) bool {
-		di, dj := out[i].DueDate, out[j].DueDate
-		switch {
-		case di == nil && dj == nil:
-			return out[i].UpdatedAt.After(out[j].UpdatedAt)
-		case di == nil:
-			return false
-		case dj == nil:
-			return true
-		case *di != *dj:
-			return *di < *dj
-		default:
-			return out[i].UpdatedAt.After(out[j].UpdatedAt)
-		}
+		return dueLess(out[i], out[j])
 	})
 
 	return out, nil
@@ -404,6 +654,6 @@ func (r *MemoryRepo) List(filter ListFilter) ([]model.Task, error) {
 
 func (r *MemoryRepo) SetModifiers(id model.TaskID, mods []model.TaskModifierSlot) (model.Task, error) {
 	// Make this just a wrapper around Update to avoid duplicate logic.
-	p := Patch{Modifiers: &mods}
+	p := Patch{Modifiers: Some(mods)}
 	return r.Update(id, p)
 }
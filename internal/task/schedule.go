@@ -0,0 +1,83 @@
+package task
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+const (
+	dateLayout    = "2006-01-02"
+	dueTimeLayout = "15:04"
+)
+
+var (
+	ErrInvalidDate    = errors.New("dates must be YYYY-MM-DD")
+	ErrInvalidDueTime = errors.New("dueTime must be HH:MM")
+	ErrDueTimeNoDate  = errors.New("dueTime requires a dueDate")
+)
+
+// ValidateSchedule checks the date/time fields a client may send.
+func ValidateSchedule(dueDate, dueTime, startDate *string) error {
+	for _, d := range []*string{dueDate, startDate} {
+		if d == nil || strings.TrimSpace(*d) == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, strings.TrimSpace(*d)); err != nil {
+			return ErrInvalidDate
+		}
+	}
+	if dueTime != nil && strings.TrimSpace(*dueTime) != "" {
+		if _, err := time.Parse(dueTimeLayout, strings.TrimSpace(*dueTime)); err != nil {
+			return ErrInvalidDueTime
+		}
+	}
+	return nil
+}
+
+func locationOrLocal(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Today returns the calendar date of now in loc as YYYY-MM-DD.
+func Today(now time.Time, loc *time.Location) string {
+	return now.In(locationOrLocal(loc)).Format(dateLayout)
+}
+
+// DueAt returns the instant a task falls due in loc: its due time on the due
+// date, or the end of the due date when no time is set.
+func DueAt(t model.Task, loc *time.Location) (time.Time, bool) {
+	if t.DueDate == nil {
+		return time.Time{}, false
+	}
+	loc = locationOrLocal(loc)
+	day, err := time.ParseInLocation(dateLayout, strings.TrimSpace(*t.DueDate), loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if t.DueTime != nil {
+		if tod, err := time.Parse(dueTimeLayout, strings.TrimSpace(*t.DueTime)); err == nil {
+			return time.Date(day.Year(), day.Month(), day.Day(), tod.Hour(), tod.Minute(), 0, 0, loc), true
+		}
+	}
+	return day.AddDate(0, 0, 1), true
+}
+
+// IsOverdue reports whether an open task is past its due instant.
+func IsOverdue(t model.Task, now time.Time, loc *time.Location) bool {
+	if t.Done {
+		return false
+	}
+	due, ok := DueAt(t, loc)
+	return ok && !now.Before(due)
+}
+
+// IsDeferred reports whether the task's start date is after today.
+func IsDeferred(t model.Task, today string) bool {
+	return t.StartDate != nil && strings.TrimSpace(*t.StartDate) > today
+}
@@ -0,0 +1,64 @@
+package task
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+)
+
+const maxTagLen = 64
+
+// NormalizeTag lowercases a tag and collapses inner whitespace to "-", so
+// "@Home" and " @home " are the same tag. It returns "" for blank input.
+// Long tags are cut to maxTagLen bytes without splitting a character.
+func NormalizeTag(tag string) string {
+	tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+	if len(tag) > maxTagLen {
+		cut := maxTagLen
+		for cut > 0 && !utf8.RuneStart(tag[cut]) {
+			cut--
+		}
+		tag = tag[:cut]
+	}
+	return tag
+}
+
+// NormalizeTags normalizes and de-duplicates tags, keeping first-seen order.
+func NormalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+// ModifierTags collects the add_tags effects of the given modifiers.
+func ModifierTags(cfg *config.Config, mods []model.TaskModifierSlot) []string {
+	if cfg == nil || len(mods) == 0 {
+		return nil
+	}
+	var out []string
+	for _, m := range mods {
+		modID := strings.TrimPrefix(strings.TrimSpace(m.DefID), "mod.")
+		for _, mt := range cfg.Modifiers.Types {
+			if mt.ID != modID {
+				continue
+			}
+			raw, _ := mt.Effects["add_tags"].([]any)
+			for _, v := range raw {
+				if s, ok := v.(string); ok {
+					out = append(out, s)
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,281 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"donegeon/internal/config"
+	"donegeon/internal/model"
+)
+
+// Text export formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatTodoTxt  = "todotxt"
+)
+
+// Markdown templates.
+const (
+	TemplateByProject = "project"   // a checklist per project (default)
+	TemplateChecklist = "checklist" // one flat checklist
+	TemplateStatus    = "status"    // Done / Overdue / Open, for status docs
+)
+
+// TextExport selects how RenderTasks writes a task list.
+type TextExport struct {
+	Title    string // Markdown heading; none when blank
+	Intro    string // Markdown written under the heading
+	Format   string // FormatMarkdown (default) or FormatTodoTxt
+	Template string // Markdown only
+	Config   *config.Config
+	Now      time.Time // in the user's location; decides what is overdue
+}
+
+// ParseTextExport reads format and template from query values, with the
+// defaults filled in.
+func ParseTextExport(format, template string) (TextExport, error) {
+	out := TextExport{
+		Format:   strings.ToLower(strings.TrimSpace(format)),
+		Template: strings.ToLower(strings.TrimSpace(template)),
+	}
+	switch out.Format {
+	case "", "md", FormatMarkdown:
+		out.Format = FormatMarkdown
+	case "todo.txt", "txt", FormatTodoTxt:
+		out.Format = FormatTodoTxt
+	default:
+		return TextExport{}, fmt.Errorf("format must be markdown or todotxt")
+	}
+	switch out.Template {
+	case "":
+		out.Template = TemplateByProject
+	case TemplateByProject, TemplateChecklist, TemplateStatus:
+	default:
+		return TextExport{}, fmt.Errorf("template must be project, checklist or status")
+	}
+	return out, nil
+}
+
+// ContentType is the response type for the export's format.
+func (e TextExport) ContentType() string {
+	if e.Format == FormatTodoTxt {
+		return "text/plain; charset=utf-8"
+	}
+	return "text/markdown; charset=utf-8"
+}
+
+// Filename is a download name for the export's format.
+func (e TextExport) Filename(base string) string {
+	if e.Format == FormatTodoTxt {
+		return base + ".txt"
+	}
+	return base + ".md"
+}
+
+// RenderTasks writes ts in the selected format, keeping their order within
+// each group.
+func RenderTasks(ts []model.Task, e TextExport) string {
+	if e.Format == FormatTodoTxt {
+		return RenderTodoTxt(ts, e.Config)
+	}
+	return RenderMarkdown(ts, e)
+}
+
+// RenderMarkdown writes ts as GitHub-flavoured checklists.
+func RenderMarkdown(ts []model.Task, e TextExport) string {
+	var b strings.Builder
+	if e.Title != "" {
+		b.WriteString("# " + e.Title + "\n\n")
+	}
+	if e.Intro != "" {
+		b.WriteString(strings.TrimRight(e.Intro, "\n") + "\n\n")
+	}
+	if len(ts) == 0 {
+		b.WriteString("_No tasks._\n")
+		return b.String()
+	}
+
+	switch e.Template {
+	case TemplateChecklist:
+		for _, t := range ts {
+			b.WriteString(markdownTaskLine(t, e, true))
+		}
+	case TemplateStatus:
+		today := e.Now.Format(dateLayout)
+		var done, overdue, open []model.Task
+		for _, t := range ts {
+			switch {
+			case t.Done:
+				done = append(done, t)
+			case t.DueDate != nil && *t.DueDate < today:
+				overdue = append(overdue, t)
+			default:
+				open = append(open, t)
+			}
+		}
+		for _, sec := range []struct {
+			name  string
+			tasks []model.Task
+		}{{"Done", done}, {"Overdue", overdue}, {"Open", open}} {
+			if len(sec.tasks) == 0 {
+				continue
+			}
+			b.WriteString("## " + sec.name + "\n\n")
+			for _, t := range sec.tasks {
+				b.WriteString(markdownTaskLine(t, e, true))
+			}
+			b.WriteString("\n")
+		}
+	default:
+		for _, g := range groupByProject(ts) {
+			b.WriteString("## " + g.name + "\n\n")
+			for _, t := range g.tasks {
+				b.WriteString(markdownTaskLine(t, e, false))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func markdownTaskLine(t model.Task, e TextExport, withProject bool) string {
+	box := "[ ]"
+	if t.Done {
+		box = "[x]"
+	}
+	parts := []string{"- " + box + " " + markdownEscape(t.Title)}
+	if t.DueDate != nil && *t.DueDate != "" {
+		due := "due " + *t.DueDate
+		if t.DueTime != nil && *t.DueTime != "" {
+			due += " " + *t.DueTime
+		}
+		parts = append(parts, due)
+	}
+	if p := Priority(e.Config, t.Modifiers); e.Config != nil && PriorityRank(e.Config, p) > 0 {
+		parts = append(parts, "priority "+p)
+	}
+	if withProject && t.Project != nil && *t.Project != "" {
+		parts = append(parts, *t.Project)
+	}
+	if len(t.Tags) > 0 {
+		tags := make([]string, 0, len(t.Tags))
+		for _, tag := range t.Tags {
+			tags = append(tags, "`#"+tag+"`")
+		}
+		parts = append(parts, strings.Join(tags, " "))
+	}
+	return strings.Join(parts, " · ") + "\n"
+}
+
+// markdownEscape keeps a title from turning into markup.
+func markdownEscape(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.NewReplacer(
+		`\`, `\\`,
+		"*", `\*`,
+		"_", `\_`,
+		"`", "\\`",
+		"[", `\[`,
+		"]", `\]`,
+		"<", `\<`,
+	).Replace(s)
+}
+
+type projectGroup struct {
+	name  string
+	tasks []model.Task
+}
+
+// groupByProject groups ts by project, inbox first, then by name.
+func groupByProject(ts []model.Task) []projectGroup {
+	idx := map[string]int{}
+	var out []projectGroup
+	for _, t := range ts {
+		name := "inbox"
+		if t.Project != nil && strings.TrimSpace(*t.Project) != "" {
+			name = *t.Project
+		}
+		i, ok := idx[name]
+		if !ok {
+			i = len(out)
+			idx[name] = i
+			out = append(out, projectGroup{name: name})
+		}
+		out[i].tasks = append(out[i].tasks, t)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if (out[i].name == "inbox") != (out[j].name == "inbox") {
+			return out[i].name == "inbox"
+		}
+		return strings.ToLower(out[i].name) < strings.ToLower(out[j].name)
+	})
+	return out
+}
+
+// todoTxtPriorities maps priority levels onto todo.txt's letters.
+var todoTxtPriorities = map[string]string{"high": "(A)", "medium": "(B)", "low": "(C)"}
+
+// RenderTodoTxt writes ts in the todo.txt format: one task per line, with
+// projects as +project, tags as @context and the due date, time and
+// repeat rule as key:value extensions.
+func RenderTodoTxt(ts []model.Task, cfg *config.Config) string {
+	var b strings.Builder
+	for _, t := range ts {
+		var parts []string
+		if t.Done {
+			parts = append(parts, "x", t.UpdatedAt.Format(dateLayout))
+		} else if p, ok := todoTxtPriorities[Priority(cfg, t.Modifiers)]; ok && cfg != nil {
+			parts = append(parts, p)
+		}
+		if !t.CreatedAt.IsZero() {
+			parts = append(parts, t.CreatedAt.Format(dateLayout))
+		}
+		parts = append(parts, strings.Join(strings.Fields(t.Title), " "))
+		if t.Project != nil && strings.TrimSpace(*t.Project) != "" && *t.Project != "inbox" {
+			parts = append(parts, "+"+todoTxtWord(*t.Project))
+		}
+		for _, tag := range t.Tags {
+			parts = append(parts, "@"+todoTxtWord(tag))
+		}
+		if t.DueDate != nil && *t.DueDate != "" {
+			parts = append(parts, "due:"+*t.DueDate)
+			if t.DueTime != nil && *t.DueTime != "" {
+				parts = append(parts, "t:"+*t.DueTime)
+			}
+		}
+		if rec := todoTxtRecurrence(t.Recurrence); rec != "" {
+			parts = append(parts, "rec:"+rec)
+		}
+		b.WriteString(strings.Join(parts, " ") + "\n")
+	}
+	return b.String()
+}
+
+func todoTxtWord(s string) string {
+	return strings.Join(strings.Fields(s), "-")
+}
+
+// todoTxtRecurrence writes the rec: extension ("1w", "+2m"). A leading +
+// repeats on schedule rather than from completion. Rules it cannot say
+// (weekdays, month days) are left out.
+func todoTxtRecurrence(rec *model.Recurrence) string {
+	if rec == nil || len(rec.ByDay) > 0 || len(rec.ByMonthDay) > 0 || len(rec.BySetPos) > 0 {
+		return ""
+	}
+	unit := map[string]string{"daily": "d", "weekly": "w", "monthly": "m", "yearly": "y"}[rec.Type]
+	if unit == "" {
+		return ""
+	}
+	n := rec.Interval
+	if n < 1 {
+		n = 1
+	}
+	out := strconv.Itoa(n) + unit
+	if rec.Mode != model.RecurrenceModeAfterCompletion {
+		out = "+" + out
+	}
+	return out
+}
@@ -0,0 +1,297 @@
+package timetrack
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+type fileState struct {
+	Users map[string]userTimeState `json:"users"`
+}
+
+type userTimeState struct {
+	Entries map[model.TimeEntryID]model.TimeEntry `json:"entries"`
+}
+
+type fileStore struct {
+	mu   sync.RWMutex
+	path string
+	s    fileState
+}
+
+// FileRepo is a persistent time-entry repo scoped by user.
+type FileRepo struct {
+	store  *fileStore
+	userID string
+}
+
+func NewFileRepo(dataDir string) (*FileRepo, error) {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return nil, err
+	}
+	st := &fileStore{
+		path: filepath.Join(dataDir, "time_entries.json"),
+		s: fileState{
+			Users: map[string]userTimeState{},
+		},
+	}
+	if err := st.load(); err != nil {
+		return nil, err
+	}
+	return &FileRepo{
+		store:  st,
+		userID: "default",
+	}, nil
+}
+
+func (s *fileStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.s.Users = map[string]userTimeState{}
+			return nil
+		}
+		return err
+	}
+	var loaded fileState
+	if err := json.Unmarshal(b, &loaded); err != nil {
+		return err
+	}
+	if loaded.Users == nil {
+		loaded.Users = map[string]userTimeState{}
+	}
+	s.s = loaded
+	return nil
+}
+
+func (s *fileStore) saveLocked() error {
+	b, err := json.MarshalIndent(s.s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o644)
+}
+
+func (r *FileRepo) ForUser(userID string) *FileRepo {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		userID = "default"
+	}
+	return &FileRepo{
+		store:  r.store,
+		userID: userID,
+	}
+}
+
+func (r *FileRepo) userStateLocked() userTimeState {
+	us := r.store.s.Users[r.userID]
+	if us.Entries == nil {
+		us.Entries = map[model.TimeEntryID]model.TimeEntry{}
+		r.store.s.Users[r.userID] = us
+	}
+	return us
+}
+
+func (r *FileRepo) runningLocked(us userTimeState) (model.TimeEntry, bool) {
+	for _, e := range us.Entries {
+		if e.Running() {
+			return e, true
+		}
+	}
+	return model.TimeEntry{}, false
+}
+
+func (r *FileRepo) Create(e model.TimeEntry) (model.TimeEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if e.End == nil {
+		// Open entries only come from Start, which keeps one timer per user.
+		return model.TimeEntry{}, ErrInvalidRange
+	}
+	normalizeEntry(&e)
+	if err := validateEntry(e); err != nil {
+		return model.TimeEntry{}, err
+	}
+	us := r.userStateLocked()
+	if strings.TrimSpace(string(e.ID)) == "" {
+		e.ID = newID("te")
+	}
+	now := nowUTC()
+	e.CreatedAt = now
+	e.UpdatedAt = now
+	us.Entries[e.ID] = e
+	if err := r.store.saveLocked(); err != nil {
+		return model.TimeEntry{}, err
+	}
+	return e, nil
+}
+
+func (r *FileRepo) Get(id model.TimeEntryID) (model.TimeEntry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	e, ok := r.store.s.Users[r.userID].Entries[id]
+	if !ok {
+		return model.TimeEntry{}, ErrNotFound
+	}
+	return e, nil
+}
+
+func (r *FileRepo) Update(id model.TimeEntryID, p Patch) (model.TimeEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	us := r.userStateLocked()
+	e, ok := us.Entries[id]
+	if !ok {
+		return model.TimeEntry{}, ErrNotFound
+	}
+	applyPatch(&e, p)
+	normalizeEntry(&e)
+	if err := validateEntry(e); err != nil {
+		return model.TimeEntry{}, err
+	}
+	e.UpdatedAt = nowUTC()
+	us.Entries[id] = e
+	if err := r.store.saveLocked(); err != nil {
+		return model.TimeEntry{}, err
+	}
+	return e, nil
+}
+
+func (r *FileRepo) Delete(id model.TimeEntryID) (model.TimeEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	us := r.userStateLocked()
+	e, ok := us.Entries[id]
+	if !ok {
+		return model.TimeEntry{}, ErrNotFound
+	}
+	delete(us.Entries, id)
+	if err := r.store.saveLocked(); err != nil {
+		return model.TimeEntry{}, err
+	}
+	return e, nil
+}
+
+func (r *FileRepo) List(f ListFilter) ([]model.TimeEntry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	now := nowUTC()
+	out := []model.TimeEntry{}
+	for _, e := range r.store.s.Users[r.userID].Entries {
+		if f.TaskID != "" && e.TaskID != f.TaskID {
+			continue
+		}
+		if !overlaps(e, f.From, f.To, now) {
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Start.After(out[j].Start)
+	})
+	return out, nil
+}
+
+func (r *FileRepo) Running() (model.TimeEntry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	e, ok := r.runningLocked(r.store.s.Users[r.userID])
+	if !ok {
+		return model.TimeEntry{}, ErrNoTimer
+	}
+	return e, nil
+}
+
+func (r *FileRepo) Start(taskID model.TaskID, note string, at time.Time) (model.TimeEntry, *model.TimeEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	e := model.TimeEntry{
+		TaskID: taskID,
+		Start:  at,
+		Note:   note,
+		Source: model.TimeSourceTimer,
+	}
+	normalizeEntry(&e)
+	if err := validateEntry(e); err != nil {
+		return model.TimeEntry{}, nil, err
+	}
+
+	us := r.userStateLocked()
+	now := nowUTC()
+	var stopped *model.TimeEntry
+	if cur, ok := r.runningLocked(us); ok {
+		stopAt := e.Start
+		if !stopAt.After(cur.Start) {
+			stopAt = cur.Start
+		}
+		cur.End = &stopAt
+		cur.UpdatedAt = now
+		us.Entries[cur.ID] = cur
+		stopped = &cur
+	}
+
+	e.ID = newID("te")
+	e.CreatedAt = now
+	e.UpdatedAt = now
+	us.Entries[e.ID] = e
+	if err := r.store.saveLocked(); err != nil {
+		return model.TimeEntry{}, nil, err
+	}
+	return e, stopped, nil
+}
+
+func (r *FileRepo) Stop(at time.Time) (model.TimeEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	us := r.userStateLocked()
+	cur, ok := r.runningLocked(us)
+	if !ok {
+		return model.TimeEntry{}, ErrNoTimer
+	}
+	end := at.UTC().Truncate(time.Second)
+	if end.Before(cur.Start) {
+		end = cur.Start
+	}
+	cur.End = &end
+	cur.UpdatedAt = nowUTC()
+	us.Entries[cur.ID] = cur
+	if err := r.store.saveLocked(); err != nil {
+		return model.TimeEntry{}, err
+	}
+	return cur, nil
+}
+
+// LogWork records a finished session from /api/tasks/{id}/process.
+func (r *FileRepo) LogWork(taskID model.TaskID, start, end time.Time, note string) error {
+	end = end.UTC()
+	_, err := r.Create(model.TimeEntry{
+		TaskID: taskID,
+		Start:  start,
+		End:    &end,
+		Note:   note,
+		Source: model.TimeSourceProcess,
+	})
+	return err
+}
+
+func nowUTC() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,396 @@
+package timetrack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+type Handler struct {
+	repo             Repo
+	repoResolver     func(*http.Request) Repo
+	taskRepoResolver func(*http.Request) task.Repo
+}
+
+func NewHandler(repo Repo) *Handler {
+	return &Handler{repo: repo}
+}
+
+func (h *Handler) SetRepoResolver(fn func(*http.Request) Repo) {
+	h.repoResolver = fn
+}
+
+// SetTaskRepoResolver lets the handler validate task ids, keep
+// Task.TrackedSeconds in sync and label reports.
+func (h *Handler) SetTaskRepoResolver(fn func(*http.Request) task.Repo) {
+	h.taskRepoResolver = fn
+}
+
+func (h *Handler) repoForRequest(r *http.Request) Repo {
+	if h.repoResolver != nil {
+		if repo := h.repoResolver(r); repo != nil {
+			return repo
+		}
+	}
+	return h.repo
+}
+
+func (h *Handler) taskRepoForRequest(r *http.Request) task.Repo {
+	if h.taskRepoResolver == nil {
+		return nil
+	}
+	return h.taskRepoResolver(r)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeErr(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]any{"error": msg})
+}
+
+func decodeJSON(r *http.Request, out any) error {
+	return json.NewDecoder(r.Body).Decode(out)
+}
+
+func writeRepoErr(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrNotFound, ErrNoTimer:
+		writeErr(w, http.StatusNotFound, err.Error())
+	case ErrInvalidRange, ErrTaskRequired:
+		writeErr(w, http.StatusBadRequest, err.Error())
+	default:
+		writeErr(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// checkTask reports whether taskID exists; it writes the error response
+// when it does not.
+func (h *Handler) checkTask(w http.ResponseWriter, r *http.Request, taskID model.TaskID) bool {
+	if strings.TrimSpace(string(taskID)) == "" {
+		writeErr(w, http.StatusBadRequest, ErrTaskRequired.Error())
+		return false
+	}
+	taskRepo := h.taskRepoForRequest(r)
+	if taskRepo == nil {
+		return true
+	}
+	if _, err := taskRepo.Get(taskID); err != nil {
+		if err == task.ErrNotFound {
+			writeErr(w, http.StatusNotFound, "task not found")
+			return false
+		}
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	return true
+}
+
+// syncTracked applies a change in an entry's length to its task's total.
+func (h *Handler) syncTracked(r *http.Request, taskID model.TaskID, delta int64) {
+	if delta == 0 {
+		return
+	}
+	taskRepo := h.taskRepoForRequest(r)
+	if taskRepo == nil {
+		return
+	}
+	_, _ = taskRepo.Update(taskID, task.Patch{TrackedSecondsDelta: &delta})
+}
+
+// trackedSeconds is what an entry contributes to Task.TrackedSeconds;
+// running timers count once they stop.
+func trackedSeconds(e model.TimeEntry) int64 {
+	if e.Running() {
+		return 0
+	}
+	return e.Seconds(time.Time{})
+}
+
+// /api/time/entries
+func (h *Handler) Entries(w http.ResponseWriter, r *http.Request) {
+	repo := h.repoForRequest(r)
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		from, to, err := parseRange(q.Get("from"), q.Get("to"), false)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		items, err := repo.List(ListFilter{
+			From:   from,
+			To:     to,
+			TaskID: model.TaskID(strings.TrimSpace(q.Get("taskId"))),
+		})
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, items)
+		return
+	case http.MethodPost:
+		var in struct {
+			TaskID  model.TaskID `json:"taskId"`
+			Start   *time.Time   `json:"start"`
+			End     *time.Time   `json:"end"`
+			Minutes int          `json:"minutes"`
+			Note    string       `json:"note"`
+		}
+		if err := decodeJSON(r, &in); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad json")
+			return
+		}
+		if in.Minutes < 0 {
+			writeErr(w, http.StatusBadRequest, "minutes must be >= 0")
+			return
+		}
+		span := time.Duration(in.Minutes) * time.Minute
+		switch {
+		case in.Start != nil && in.End == nil && span > 0:
+			end := in.Start.Add(span)
+			in.End = &end
+		case in.Start == nil && in.End != nil && span > 0:
+			start := in.End.Add(-span)
+			in.Start = &start
+		case in.Start == nil && in.End == nil && span > 0:
+			end := time.Now().UTC()
+			start := end.Add(-span)
+			in.Start, in.End = &start, &end
+		}
+		if in.Start == nil || in.End == nil {
+			writeErr(w, http.StatusBadRequest, "start and end (or minutes) are required")
+			return
+		}
+		if !h.checkTask(w, r, in.TaskID) {
+			return
+		}
+		e, err := repo.Create(model.TimeEntry{
+			TaskID: in.TaskID,
+			Start:  *in.Start,
+			End:    in.End,
+			Note:   in.Note,
+			Source: model.TimeSourceManual,
+		})
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		h.syncTracked(r, e.TaskID, trackedSeconds(e))
+		writeJSON(w, http.StatusCreated, e)
+		return
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+}
+
+// /api/time/entries/{id}
+func (h *Handler) EntriesSub(w http.ResponseWriter, r *http.Request) {
+	repo := h.repoForRequest(r)
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/time/entries/"), "/")
+	if path == "" || strings.Contains(path, "/") {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+	id := model.TimeEntryID(path)
+
+	switch r.Method {
+	case http.MethodGet:
+		e, err := repo.Get(id)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, e)
+		return
+	case http.MethodPatch:
+		var p Patch
+		if err := decodeJSON(r, &p); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad json")
+			return
+		}
+		before, err := repo.Get(id)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		if before.Running() && p.End != nil {
+			writeErr(w, http.StatusBadRequest, "stop the running timer instead")
+			return
+		}
+		after, err := repo.Update(id, p)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		h.syncTracked(r, after.TaskID, trackedSeconds(after)-trackedSeconds(before))
+		writeJSON(w, http.StatusOK, after)
+		return
+	case http.MethodDelete:
+		e, err := repo.Delete(id)
+		if err != nil {
+			writeRepoErr(w, err)
+			return
+		}
+		h.syncTracked(r, e.TaskID, -trackedSeconds(e))
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+		return
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+}
+
+// /api/time/timer
+func (h *Handler) Timer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	e, err := h.repoForRequest(r).Running()
+	if err == ErrNoTimer {
+		writeJSON(w, http.StatusOK, map[string]any{"running": false})
+		return
+	}
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"running": true,
+		"entry":   e,
+		"elapsed": e.Seconds(time.Now().UTC()),
+	})
+}
+
+// /api/time/timer/start
+func (h *Handler) TimerStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var in struct {
+		TaskID model.TaskID `json:"taskId"`
+		Note   string       `json:"note"`
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad json")
+		return
+	}
+	if !h.checkTask(w, r, in.TaskID) {
+		return
+	}
+	started, stopped, err := h.repoForRequest(r).Start(in.TaskID, in.Note, time.Now().UTC())
+	if err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+	if stopped != nil {
+		h.syncTracked(r, stopped.TaskID, trackedSeconds(*stopped))
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"entry":   started,
+		"stopped": stopped,
+	})
+}
+
+// /api/time/timer/stop
+func (h *Handler) TimerStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	e, err := h.repoForRequest(r).Stop(time.Now().UTC())
+	if err != nil {
+		writeRepoErr(w, err)
+		return
+	}
+	h.syncTracked(r, e.TaskID, trackedSeconds(e))
+	writeJSON(w, http.StatusOK, e)
+}
+
+// /api/time/report?from=YYYY-MM-DD&to=YYYY-MM-DD&groupBy=task|project|tag
+func (h *Handler) Report(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	from, to, err := parseRange(q.Get("from"), q.Get("to"), true)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	groupBy := strings.TrimSpace(strings.ToLower(q.Get("groupBy")))
+	switch groupBy {
+	case "":
+		groupBy = GroupByTask
+	case GroupByTask, GroupByProject, GroupByTag:
+	default:
+		writeErr(w, http.StatusBadRequest, "groupBy must be task, project or tag")
+		return
+	}
+
+	entries, err := h.repoForRequest(r).List(ListFilter{From: from, To: to})
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	tasks := map[model.TaskID]model.Task{}
+	if taskRepo := h.taskRepoForRequest(r); taskRepo != nil {
+		items, err := taskRepo.List(task.ListFilter{Status: "all"})
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, t := range items {
+			tasks[t.ID] = t
+		}
+	}
+	writeJSON(w, http.StatusOK, buildReport(entries, tasks, from, to, groupBy, time.Now().UTC()))
+}
+
+var errBadRange = errors.New("to must not be before from")
+
+func errBadDate(field string) error {
+	return fmt.Errorf("%s must be YYYY-MM-DD", field)
+}
+
+// parseRange turns inclusive YYYY-MM-DD bounds into [from, to). Reports
+// default to the last seven days; entry lists default to unbounded.
+func parseRange(fromRaw, toRaw string, withDefaults bool) (time.Time, time.Time, error) {
+	var from, to time.Time
+	if s := strings.TrimSpace(toRaw); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return time.Time{}, time.Time{}, errBadDate("to")
+		}
+		to = d.AddDate(0, 0, 1)
+	} else if withDefaults {
+		now := time.Now().UTC()
+		to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	}
+	if s := strings.TrimSpace(fromRaw); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return time.Time{}, time.Time{}, errBadDate("from")
+		}
+		from = d
+	} else if withDefaults {
+		from = to.AddDate(0, 0, -7)
+	}
+	if !from.IsZero() && !to.IsZero() && !to.After(from) {
+		return time.Time{}, time.Time{}, errBadRange
+	}
+	return from, to, nil
+}
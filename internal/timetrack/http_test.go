@@ -0,0 +1,118 @@
+package timetrack
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"donegeon/internal/model"
+	"donegeon/internal/task"
+)
+
+func newTimeHandlerForTests(t *testing.T) (*Handler, *FileRepo, task.Repo) {
+	t.Helper()
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file repo: %v", err)
+	}
+	repo = repo.ForUser("u-time")
+	tasks := task.NewMemoryRepo()
+	h := NewHandler(repo)
+	h.SetTaskRepoResolver(func(*http.Request) task.Repo { return tasks })
+	return h, repo, tasks
+}
+
+func TestTimerStart_StopsRunningTimerAndSyncsTrackedSeconds(t *testing.T) {
+	h, repo, tasks := newTimeHandlerForTests(t)
+	a, _ := tasks.Create(model.Task{Title: "Write report"})
+	b, _ := tasks.Create(model.Task{Title: "Review PR"})
+
+	// A timer that has been running for half an hour.
+	if _, _, err := repo.Start(a.ID, "", time.Now().Add(-30*time.Minute)); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	body, _ := json.Marshal(map[string]any{"taskId": string(b.ID)})
+	req := httptest.NewRequest(http.MethodPost, "/api/time/timer/start", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.TimerStart(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	running, err := repo.Running()
+	if err != nil || running.TaskID != b.ID {
+		t.Fatalf("expected timer on %s, got %+v err=%v", b.ID, running, err)
+	}
+	got, _ := tasks.Get(a.ID)
+	if got.TrackedSeconds < 29*60 || got.TrackedSeconds > 31*60 {
+		t.Fatalf("expected ~30m tracked on stopped task, got %ds", got.TrackedSeconds)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/time/timer/stop", nil)
+	rec = httptest.NewRecorder()
+	h.TimerStop(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	h.TimerStop(rec, httptest.NewRequest(http.MethodPost, "/api/time/timer/stop", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 with no timer, got %d", rec.Code)
+	}
+}
+
+func TestReport_GroupsByProjectAndTag(t *testing.T) {
+	h, _, tasks := newTimeHandlerForTests(t)
+	work := "work"
+	a, _ := tasks.Create(model.Task{Title: "Spec", Project: &work, Tags: []string{"writing"}})
+	b, _ := tasks.Create(model.Task{Title: "Email", Tags: []string{"writing", "admin"}})
+
+	post := func(taskID model.TaskID, start string, minutes int) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"taskId": string(taskID), "start": start, "minutes": minutes})
+		rec := httptest.NewRecorder()
+		h.Entries(rec, httptest.NewRequest(http.MethodPost, "/api/time/entries", bytes.NewReader(body)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", rec.Code, rec.Body.String())
+		}
+	}
+	post(a.ID, "2026-03-02T09:00:00Z", 90)
+	post(b.ID, "2026-03-03T10:00:00Z", 30)
+	post(b.ID, "2026-03-09T10:00:00Z", 30) // outside the range
+
+	report := func(groupBy string) Report {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.Report(rec, httptest.NewRequest(http.MethodGet, "/api/time/report?from=2026-03-02&to=2026-03-08&groupBy="+groupBy, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+		}
+		var out Report
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatalf("decode report: %v", err)
+		}
+		return out
+	}
+
+	byProject := report("project")
+	if byProject.TotalSeconds != 120*60 {
+		t.Fatalf("expected 2h total, got %ds", byProject.TotalSeconds)
+	}
+	if len(byProject.Groups) != 2 || byProject.Groups[0].Key != "work" || byProject.Groups[1].Key != "inbox" {
+		t.Fatalf("unexpected project groups: %+v", byProject.Groups)
+	}
+
+	byTag := report("tag")
+	if len(byTag.Groups) != 2 || byTag.Groups[0].Key != "writing" || byTag.Groups[0].Seconds != 120*60 {
+		t.Fatalf("unexpected tag groups: %+v", byTag.Groups)
+	}
+
+	got, _ := tasks.Get(b.ID)
+	if got.TrackedSeconds != 60*60 {
+		t.Fatalf("expected 1h tracked on task, got %ds", got.TrackedSeconds)
+	}
+}
@@ -0,0 +1,106 @@
+package timetrack
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+var (
+	ErrNotFound     = errors.New("time entry not found")
+	ErrNoTimer      = errors.New("no timer is running")
+	ErrInvalidRange = errors.New("entry end must be after start")
+	ErrTaskRequired = errors.New("taskId is required")
+)
+
+// Patch represents a partial entry update.
+// nil pointer => "no change"
+type Patch struct {
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+	Note  *string    `json:"note,omitempty"`
+}
+
+type ListFilter struct {
+	// From/To bound entries that overlap [From, To); zero means open.
+	From time.Time
+	To   time.Time
+
+	TaskID model.TaskID
+}
+
+type Repo interface {
+	Create(e model.TimeEntry) (model.TimeEntry, error)
+	Get(id model.TimeEntryID) (model.TimeEntry, error)
+	Update(id model.TimeEntryID, patch Patch) (model.TimeEntry, error)
+	Delete(id model.TimeEntryID) (model.TimeEntry, error)
+	List(filter ListFilter) ([]model.TimeEntry, error)
+
+	// Running returns the active timer, or ErrNoTimer.
+	Running() (model.TimeEntry, error)
+	// Start stops any running timer at `at` and starts a new one; the
+	// stopped entry (if any) is returned too.
+	Start(taskID model.TaskID, note string, at time.Time) (started model.TimeEntry, stopped *model.TimeEntry, err error)
+	// Stop ends the running timer at `at`.
+	Stop(at time.Time) (model.TimeEntry, error)
+}
+
+func newID(prefix string) model.TimeEntryID {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return model.TimeEntryID(prefix + "_" + hex.EncodeToString(b[:]))
+}
+
+func normalizeEntry(e *model.TimeEntry) {
+	e.Note = strings.TrimSpace(e.Note)
+	e.Start = e.Start.UTC().Truncate(time.Second)
+	if e.End != nil {
+		end := e.End.UTC().Truncate(time.Second)
+		e.End = &end
+	}
+	if e.Source == "" {
+		e.Source = model.TimeSourceManual
+	}
+}
+
+func validateEntry(e model.TimeEntry) error {
+	if strings.TrimSpace(string(e.TaskID)) == "" {
+		return ErrTaskRequired
+	}
+	if e.End != nil && !e.End.After(e.Start) {
+		return ErrInvalidRange
+	}
+	return nil
+}
+
+func applyPatch(e *model.TimeEntry, p Patch) {
+	if p.Start != nil {
+		e.Start = *p.Start
+	}
+	if p.End != nil {
+		end := *p.End
+		e.End = &end
+	}
+	if p.Note != nil {
+		e.Note = *p.Note
+	}
+}
+
+// overlaps reports whether e intersects [from, to) (zero bounds are open).
+func overlaps(e model.TimeEntry, from, to time.Time, now time.Time) bool {
+	end := now
+	if e.End != nil {
+		end = *e.End
+	}
+	if !from.IsZero() && !end.After(from) {
+		return false
+	}
+	if !to.IsZero() && !e.Start.Before(to) {
+		return false
+	}
+	return true
+}
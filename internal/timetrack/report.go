@@ -0,0 +1,118 @@
+package timetrack
+
+import (
+	"sort"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+// Report groupings.
+const (
+	GroupByTask    = "task"
+	GroupByProject = "project"
+	GroupByTag     = "tag"
+)
+
+type ReportGroup struct {
+	Key             string `json:"key"`
+	Label           string `json:"label"`
+	Seconds         int64  `json:"seconds"`
+	Entries         int    `json:"entries"`
+	EstimateMinutes int    `json:"estimateMinutes,omitempty"`
+}
+
+type Report struct {
+	From         string        `json:"from"`
+	To           string        `json:"to"`
+	GroupBy      string        `json:"groupBy"`
+	TotalSeconds int64         `json:"totalSeconds"`
+	Groups       []ReportGroup `json:"groups"`
+}
+
+// buildReport sums entries clipped to [from, to). An entry counts toward
+// every tag of its task, so tag groups can add up to more than the total.
+func buildReport(entries []model.TimeEntry, tasks map[model.TaskID]model.Task, from, to time.Time, groupBy string, now time.Time) Report {
+	groups := map[string]*ReportGroup{}
+	add := func(key, label string, secs int64, estimate int) {
+		g, ok := groups[key]
+		if !ok {
+			g = &ReportGroup{Key: key, Label: label, EstimateMinutes: estimate}
+			groups[key] = g
+		}
+		g.Seconds += secs
+		g.Entries++
+	}
+
+	var total int64
+	for _, e := range entries {
+		secs := clippedSeconds(e, from, to, now)
+		if secs <= 0 {
+			continue
+		}
+		total += secs
+
+		t, known := tasks[e.TaskID]
+		switch groupBy {
+		case GroupByProject:
+			project := "inbox"
+			if known && t.Project != nil && *t.Project != "" {
+				project = *t.Project
+			}
+			add(project, project, secs, 0)
+		case GroupByTag:
+			if !known || len(t.Tags) == 0 {
+				add("", "(untagged)", secs, 0)
+				continue
+			}
+			for _, tag := range t.Tags {
+				add(tag, tag, secs, 0)
+			}
+		default:
+			label := string(e.TaskID)
+			estimate := 0
+			if known {
+				label = t.Title
+				estimate = t.EstimateMinutes
+			}
+			add(string(e.TaskID), label, secs, estimate)
+		}
+	}
+
+	out := make([]ReportGroup, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Seconds != out[j].Seconds {
+			return out[i].Seconds > out[j].Seconds
+		}
+		return out[i].Key < out[j].Key
+	})
+
+	return Report{
+		From:         from.Format("2006-01-02"),
+		To:           to.AddDate(0, 0, -1).Format("2006-01-02"),
+		GroupBy:      groupBy,
+		TotalSeconds: total,
+		Groups:       out,
+	}
+}
+
+func clippedSeconds(e model.TimeEntry, from, to, now time.Time) int64 {
+	start := e.Start
+	end := now
+	if e.End != nil {
+		end = *e.End
+	}
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return int64(end.Sub(start) / time.Second)
+}
@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+const (
+	defaultMaxAttempts = 6
+	defaultBackoff     = 30 * time.Second
+	defaultMaxBackoff  = time.Hour
+	deliveryTimeout    = 10 * time.Second
+)
+
+// Dispatcher signs events and sends them to the webhooks subscribed to
+// them. Every delivery is logged before its first attempt; failed attempts
+// are retried with exponential backoff until MaxAttempts. Pending retries
+// live in timers, so call Resume after a restart to pick them back up.
+type Dispatcher struct {
+	repo   *FileRepo
+	client *http.Client
+
+	MaxAttempts int
+	Backoff     time.Duration // before the first retry; doubles after each attempt
+	MaxBackoff  time.Duration
+
+	wg sync.WaitGroup
+}
+
+func NewDispatcher(repo *FileRepo) *Dispatcher {
+	return &Dispatcher{
+		repo:        repo,
+		client:      Guard{}.Client(deliveryTimeout),
+		MaxAttempts: defaultMaxAttempts,
+		Backoff:     defaultBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+	}
+}
+
+// SetGuard sets which hosts deliveries may reach; see Guard.
+func (d *Dispatcher) SetGuard(g Guard) {
+	d.client = g.Client(deliveryTimeout)
+}
+
+// SetClient replaces the HTTP client used for deliveries.
+func (d *Dispatcher) SetClient(c *http.Client) {
+	d.client = c
+}
+
+// ForUser is the Publisher for userID's events.
+func (d *Dispatcher) ForUser(userID string) Publisher {
+	return userPublisher{d: d, repo: d.repo.ForUser(userID)}
+}
+
+type userPublisher struct {
+	d    *Dispatcher
+	repo *FileRepo
+}
+
+func (p userPublisher) Publish(event string, data any) {
+	hooks, err := p.repo.Subscribers(event)
+	if err != nil || len(hooks) == 0 {
+		return
+	}
+	env := newEnvelope(event, data)
+	for _, h := range hooks {
+		dl, err := p.d.enqueue(p.repo, h, env)
+		if err != nil {
+			continue
+		}
+		p.d.schedule(p.repo, dl, 0)
+	}
+}
+
+// Fire sends a webhook.test event to h alone, ignoring its event filter,
+// and returns the delivery after the first attempt. Failures are retried
+// like any other delivery.
+func (d *Dispatcher) Fire(repo *FileRepo, h model.Webhook) (model.WebhookDelivery, error) {
+	env := newEnvelope(EventWebhookTest, map[string]any{
+		"webhookId": h.ID,
+		"message":   "Test delivery from Donegeon.",
+	})
+	dl, err := d.enqueue(repo, h, env)
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	d.wg.Add(1)
+	defer d.wg.Done()
+	return d.attempt(repo, dl), nil
+}
+
+// Resume schedules every pending delivery in the log, at its next attempt
+// time or right away when that has passed.
+func (d *Dispatcher) Resume() {
+	now := nowUTC()
+	for _, p := range d.repo.pending() {
+		delay := time.Duration(0)
+		if next := p.delivery.NextAttemptAt; next != nil && next.After(now) {
+			delay = next.Sub(now)
+		}
+		d.schedule(d.repo.ForUser(p.userID), p.delivery, delay)
+	}
+}
+
+// Wait blocks until no delivery is in flight or scheduled.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+func newEnvelope(event string, data any) Envelope {
+	return Envelope{
+		ID:        newID("evt"),
+		Event:     event,
+		CreatedAt: nowUTC(),
+		Data:      data,
+	}
+}
+
+func (d *Dispatcher) enqueue(repo *FileRepo, h model.Webhook, env Envelope) (model.WebhookDelivery, error) {
+	body, err := json.Marshal(env)
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	return repo.AddDelivery(model.WebhookDelivery{
+		WebhookID: h.ID,
+		EventID:   env.ID,
+		Event:     env.Event,
+		Payload:   string(body),
+	})
+}
+
+func (d *Dispatcher) schedule(repo *FileRepo, dl model.WebhookDelivery, delay time.Duration) {
+	d.wg.Add(1)
+	time.AfterFunc(delay, func() {
+		defer d.wg.Done()
+		d.attempt(repo, dl)
+	})
+}
+
+// attempt makes one delivery attempt, logs it, and schedules the retry if
+// one is due.
+func (d *Dispatcher) attempt(repo *FileRepo, dl model.WebhookDelivery) model.WebhookDelivery {
+	now := nowUTC()
+	dl.NextAttemptAt = nil
+	h, err := repo.Get(dl.WebhookID)
+	if err != nil {
+		dl.Status = model.DeliveryFailed
+		dl.Error = "webhook was deleted"
+		_ = repo.SaveDelivery(dl)
+		return dl
+	}
+
+	dl.Attempts++
+	dl.LastAttemptAt = &now
+	code, retry, err := d.send(h, dl)
+	dl.ResponseCode = code
+	dl.Error = ""
+	switch {
+	case err == nil:
+		dl.Status = model.DeliverySucceeded
+	case retry && dl.Attempts < d.MaxAttempts:
+		dl.Status = model.DeliveryPending
+		dl.Error = err.Error()
+		delay := d.backoff(dl.Attempts)
+		next := now.Add(delay)
+		dl.NextAttemptAt = &next
+	default:
+		dl.Status = model.DeliveryFailed
+		dl.Error = err.Error()
+	}
+	_ = repo.SaveDelivery(dl)
+	if dl.NextAttemptAt != nil {
+		d.schedule(repo, dl, dl.NextAttemptAt.Sub(now))
+	}
+	return dl
+}
+
+// send posts the payload. retry reports whether a failure is worth trying
+// again: network errors, timeouts, 429 and 5xx are; other statuses mean the
+// receiver rejected the event.
+func (d *Dispatcher) send(h model.Webhook, dl model.WebhookDelivery) (code int, retry bool, err error) {
+	body := []byte(dl.Payload)
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Donegeon-Webhook/1")
+	req.Header.Set(EventHeader, dl.Event)
+	req.Header.Set(DeliveryHeader, dl.ID)
+	req.Header.Set(SignatureHeader, Sign(h.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if errors.Is(err, ErrBlockedHost) {
+		return 0, false, err
+	}
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode >= 500
+	return resp.StatusCode, retry, fmt.Errorf("receiver answered %d", resp.StatusCode)
+}
+
+// backoff is the delay after the given number of attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.Backoff
+	for i := 1; i < attempts && delay < d.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if d.MaxBackoff > 0 && delay > d.MaxBackoff {
+		delay = d.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Event names.
+const (
+	EventTaskCreated     = "task.created"
+	EventTaskCompleted   = "task.completed"
+	EventZombieSpawned   = "zombie.spawned"
+	EventDeckOpened      = "deck.opened"
+	EventVillagerLevelUp = "villager.level_up"
+	EventDayEnded        = "day.ended"
+	EventFeatureUnlocked = "feature.unlocked"
+	EventWebhookTest     = "webhook.test" // only sent by the test endpoint
+)
+
+// Delivery request headers.
+const (
+	SignatureHeader = "X-Donegeon-Signature"
+	EventHeader     = "X-Donegeon-Event"
+	DeliveryHeader  = "X-Donegeon-Delivery"
+)
+
+const signaturePrefix = "sha256="
+
+// Events are the event names a webhook can subscribe to.
+var Events = []string{
+	EventTaskCreated,
+	EventTaskCompleted,
+	EventZombieSpawned,
+	EventDeckOpened,
+	EventVillagerLevelUp,
+	EventDayEnded,
+	EventFeatureUnlocked,
+}
+
+func isEvent(name string) bool {
+	for _, e := range Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Publisher takes domain events from the handlers. Publishing never blocks
+// on delivery and never fails the request that caused the event.
+type Publisher interface {
+	Publish(event string, data any)
+}
+
+// Envelope is the JSON body of every delivery.
+type Envelope struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// Sign returns the X-Donegeon-Signature value for body: "sha256=" and the
+// hex HMAC-SHA256 of the raw body under the webhook's secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is body's signature under secret.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
@@ -0,0 +1,340 @@
+package webhook
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+const (
+	// maxWebhooksPerUser bounds how many endpoints one event can fan out to.
+	maxWebhooksPerUser = 20
+	// maxDeliveriesPerUser bounds the delivery log; the oldest finished
+	// deliveries are dropped.
+	maxDeliveriesPerUser = 200
+)
+
+type fileState struct {
+	Users      map[string]map[model.WebhookID]model.Webhook `json:"users"`
+	Deliveries map[string][]model.WebhookDelivery           `json:"deliveries"`
+}
+
+type fileStore struct {
+	mu   sync.RWMutex
+	path string
+	s    fileState
+}
+
+// FileRepo is a persistent webhook and delivery-log repo scoped by user.
+type FileRepo struct {
+	store  *fileStore
+	userID string
+}
+
+func NewFileRepo(dataDir string) (*FileRepo, error) {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return nil, err
+	}
+	st := &fileStore{
+		path: filepath.Join(dataDir, "webhooks.json"),
+		s: fileState{
+			Users:      map[string]map[model.WebhookID]model.Webhook{},
+			Deliveries: map[string][]model.WebhookDelivery{},
+		},
+	}
+	if err := st.load(); err != nil {
+		return nil, err
+	}
+	return &FileRepo{
+		store:  st,
+		userID: "default",
+	}, nil
+}
+
+func (s *fileStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var loaded fileState
+	if err := json.Unmarshal(b, &loaded); err != nil {
+		return err
+	}
+	if loaded.Users == nil {
+		loaded.Users = map[string]map[model.WebhookID]model.Webhook{}
+	}
+	for uid, m := range loaded.Users {
+		if m == nil {
+			loaded.Users[uid] = map[model.WebhookID]model.Webhook{}
+		}
+	}
+	if loaded.Deliveries == nil {
+		loaded.Deliveries = map[string][]model.WebhookDelivery{}
+	}
+	s.s = loaded
+	return nil
+}
+
+func (s *fileStore) saveLocked() error {
+	b, err := json.MarshalIndent(s.s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o644)
+}
+
+func (r *FileRepo) ForUser(userID string) *FileRepo {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		userID = "default"
+	}
+	return &FileRepo{
+		store:  r.store,
+		userID: userID,
+	}
+}
+
+// UserID is the user the repo is scoped to.
+func (r *FileRepo) UserID() string {
+	return r.userID
+}
+
+func (r *FileRepo) userMapLocked() map[model.WebhookID]model.Webhook {
+	m, ok := r.store.s.Users[r.userID]
+	if !ok || m == nil {
+		m = map[model.WebhookID]model.Webhook{}
+		r.store.s.Users[r.userID] = m
+	}
+	return m
+}
+
+func (r *FileRepo) Create(h model.Webhook) (model.Webhook, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	if len(m) >= maxWebhooksPerUser {
+		return model.Webhook{}, ErrTooMany
+	}
+	if strings.TrimSpace(string(h.ID)) == "" {
+		h.ID = model.WebhookID(newID("wh"))
+	}
+	if strings.TrimSpace(h.Secret) == "" {
+		h.Secret = newSecret()
+	}
+	now := nowUTC()
+	h.CreatedAt = now
+	h.UpdatedAt = now
+	m[h.ID] = h
+	if err := r.store.saveLocked(); err != nil {
+		return model.Webhook{}, err
+	}
+	return h, nil
+}
+
+func (r *FileRepo) Get(id model.WebhookID) (model.Webhook, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	h, ok := r.store.s.Users[r.userID][id]
+	if !ok {
+		return model.Webhook{}, ErrNotFound
+	}
+	return h, nil
+}
+
+// Save replaces a stored webhook, keeping its secret and creation time.
+func (r *FileRepo) Save(h model.Webhook) (model.Webhook, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	cur, ok := m[h.ID]
+	if !ok {
+		return model.Webhook{}, ErrNotFound
+	}
+	h.Secret = cur.Secret
+	h.CreatedAt = cur.CreatedAt
+	h.UpdatedAt = nowUTC()
+	m[h.ID] = h
+	if err := r.store.saveLocked(); err != nil {
+		return model.Webhook{}, err
+	}
+	return h, nil
+}
+
+// Delete removes a webhook and its deliveries.
+func (r *FileRepo) Delete(id model.WebhookID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := r.userMapLocked()
+	if _, ok := m[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m, id)
+	kept := r.store.s.Deliveries[r.userID][:0]
+	for _, d := range r.store.s.Deliveries[r.userID] {
+		if d.WebhookID != id {
+			kept = append(kept, d)
+		}
+	}
+	r.store.s.Deliveries[r.userID] = kept
+	return r.store.saveLocked()
+}
+
+// List returns the user's webhooks, oldest first.
+func (r *FileRepo) List() ([]model.Webhook, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	m := r.store.s.Users[r.userID]
+	out := make([]model.Webhook, 0, len(m))
+	for _, h := range m {
+		out = append(out, h)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// Subscribers returns the active webhooks that receive event.
+func (r *FileRepo) Subscribers(event string) ([]model.Webhook, error) {
+	hooks, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]model.Webhook, 0, len(hooks))
+	for _, h := range hooks {
+		if h.Active && subscribes(h, event) {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func subscribes(h model.Webhook, event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event || e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// AddDelivery logs a new delivery.
+func (r *FileRepo) AddDelivery(d model.WebhookDelivery) (model.WebhookDelivery, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if strings.TrimSpace(d.ID) == "" {
+		d.ID = newID("dlv")
+	}
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = nowUTC()
+	}
+	if d.Status == "" {
+		d.Status = model.DeliveryPending
+	}
+	log := append(r.store.s.Deliveries[r.userID], d)
+	r.store.s.Deliveries[r.userID] = pruneDeliveries(log)
+	if err := r.store.saveLocked(); err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	return d, nil
+}
+
+// SaveDelivery records an attempt on a logged delivery. A delivery pruned
+// from the log in the meantime is not brought back.
+func (r *FileRepo) SaveDelivery(d model.WebhookDelivery) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	log := r.store.s.Deliveries[r.userID]
+	for i := range log {
+		if log[i].ID == d.ID {
+			log[i] = d
+			return r.store.saveLocked()
+		}
+	}
+	return nil
+}
+
+// Deliveries returns a webhook's delivery log, newest first.
+func (r *FileRepo) Deliveries(id model.WebhookID) ([]model.WebhookDelivery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	out := make([]model.WebhookDelivery, 0)
+	for _, d := range r.store.s.Deliveries[r.userID] {
+		if d.WebhookID == id {
+			out = append(out, d)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+// pendingDelivery is a delivery still waiting for an attempt, with the user
+// it belongs to.
+type pendingDelivery struct {
+	userID   string
+	delivery model.WebhookDelivery
+}
+
+// pending returns every user's unfinished deliveries.
+func (r *FileRepo) pending() []pendingDelivery {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	out := make([]pendingDelivery, 0)
+	for uid, log := range r.store.s.Deliveries {
+		for _, d := range log {
+			if d.Status == model.DeliveryPending {
+				out = append(out, pendingDelivery{userID: uid, delivery: d})
+			}
+		}
+	}
+	return out
+}
+
+// pruneDeliveries drops the oldest finished deliveries beyond
+// maxDeliveriesPerUser. Pending ones are kept until they finish.
+func pruneDeliveries(log []model.WebhookDelivery) []model.WebhookDelivery {
+	extra := len(log) - maxDeliveriesPerUser
+	if extra <= 0 {
+		return log
+	}
+	out := make([]model.WebhookDelivery, 0, len(log))
+	for _, d := range log {
+		if extra > 0 && d.Status != model.DeliveryPending {
+			extra--
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+func nowUTC() time.Time {
+	return time.Now().UTC()
+}
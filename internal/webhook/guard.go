@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrBlockedHost = errors.New("url must not point at a private, loopback or link-local address")
+
+// cgnat is the carrier-grade NAT range, private in practice though not in
+// netip's sense.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// Guard keeps requests to user-supplied URLs (webhooks, plugin endpoints)
+// off the server's own network. The zero value blocks loopback, private,
+// link-local, unspecified and multicast addresses. URLs are checked when
+// they are saved and the address is checked again at dial time, so a name
+// that later resolves somewhere internal is still refused.
+type Guard struct {
+	// Allow lets hosts through anyway, for local development: host names
+	// ("localhost", "hooks.internal"), addresses ("127.0.0.1") or networks
+	// ("10.0.0.0/8").
+	Allow []string
+}
+
+// GuardFromEnv reads the allowlist from DONEGEON_OUTBOUND_ALLOW
+// (comma-separated).
+func GuardFromEnv() Guard {
+	var g Guard
+	for _, a := range strings.Split(os.Getenv("DONEGEON_OUTBOUND_ALLOW"), ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			g.Allow = append(g.Allow, a)
+		}
+	}
+	return g
+}
+
+// CheckURL resolves raw's host and rejects it when any of its addresses is
+// blocked. raw must already be an absolute http or https URL.
+func (g Guard) CheckURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ErrBadURL
+	}
+	host := u.Hostname()
+	if g.allowsName(host) {
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: %s does not resolve", ErrBadURL, host)
+	}
+	for _, ip := range addrs {
+		if !g.allowsIP(ip) {
+			return ErrBlockedHost
+		}
+	}
+	return nil
+}
+
+// Client is an HTTP client whose connections, redirects included, only
+// reach addresses the guard allows.
+func (g Guard) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: g.control}
+	open := &net.Dialer{Timeout: timeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // a proxy would dial on our behalf, unchecked
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(addr); err == nil && g.allowsName(host) {
+			return open.DialContext(ctx, network, addr)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// control runs after name resolution, on the address about to be dialed.
+func (g Guard) control(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return ErrBlockedHost
+	}
+	if !g.allowsIP(ap.Addr()) {
+		return ErrBlockedHost
+	}
+	return nil
+}
+
+func (g Guard) allowsName(host string) bool {
+	for _, a := range g.Allow {
+		if strings.EqualFold(a, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g Guard) allowsIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, a := range g.Allow {
+		if p, err := netip.ParsePrefix(a); err == nil && p.Contains(ip) {
+			return true
+		}
+		if addr, err := netip.ParseAddr(a); err == nil && addr.Unmap() == ip {
+			return true
+		}
+	}
+	return !blocked(ip)
+}
+
+func blocked(ip netip.Addr) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() || cgnat.Contains(ip)
+}
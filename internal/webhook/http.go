@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"donegeon/internal/model"
+)
+
+type Handler struct {
+	repo         *FileRepo
+	dispatcher   *Dispatcher
+	guard        Guard
+	repoResolver func(*http.Request) *FileRepo
+}
+
+func NewHandler(repo *FileRepo, dispatcher *Dispatcher) *Handler {
+	return &Handler{repo: repo, dispatcher: dispatcher}
+}
+
+func (h *Handler) SetRepoResolver(fn func(*http.Request) *FileRepo) {
+	h.repoResolver = fn
+}
+
+// SetGuard sets which hosts webhook URLs may point at. By default private,
+// loopback and link-local addresses are refused.
+func (h *Handler) SetGuard(g Guard) {
+	h.guard = g
+}
+
+func (h *Handler) repoForRequest(r *http.Request) *FileRepo {
+	if h.repoResolver != nil {
+		if repo := h.repoResolver(r); repo != nil {
+			return repo
+		}
+	}
+	return h.repo
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeErr(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]any{"error": msg})
+}
+
+func decodeJSON(r *http.Request, out any) error {
+	return json.NewDecoder(r.Body).Decode(out)
+}
+
+// withoutSecret is a webhook as listed: the secret is only returned when
+// the webhook is created.
+func withoutSecret(hook model.Webhook) model.Webhook {
+	hook.Secret = ""
+	return hook
+}
+
+type webhookInput struct {
+	URL         *string   `json:"url"`
+	Events      *[]string `json:"events"`
+	Description *string   `json:"description"`
+	Active      *bool     `json:"active"`
+}
+
+// apply validates in and writes it onto hook. A URL must resolve to
+// addresses the guard allows.
+func (in webhookInput) apply(ctx context.Context, guard Guard, hook *model.Webhook) error {
+	if in.URL != nil {
+		raw := strings.TrimSpace(*in.URL)
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrBadURL
+		}
+		if err := guard.CheckURL(ctx, raw); err != nil {
+			return err
+		}
+		hook.URL = raw
+	}
+	if in.Events != nil {
+		events, err := normalizeEvents(*in.Events)
+		if err != nil {
+			return err
+		}
+		hook.Events = events
+	}
+	if in.Description != nil {
+		hook.Description = strings.TrimSpace(*in.Description)
+	}
+	if in.Active != nil {
+		hook.Active = *in.Active
+	}
+	return nil
+}
+
+// normalizeEvents dedupes an event filter. "*" means every event, the same
+// as an empty filter.
+func normalizeEvents(in []string) ([]string, error) {
+	out := make([]string, 0, len(in))
+	seen := map[string]bool{}
+	for _, e := range in {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" || seen[e] {
+			continue
+		}
+		if e == "*" {
+			return nil, nil
+		}
+		if !isEvent(e) {
+			return nil, fmt.Errorf("%w: %s", ErrBadEvent, e)
+		}
+		seen[e] = true
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// GET /api/webhooks/events
+func (h *Handler) EventsList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"events": Events})
+}
+
+// /api/webhooks
+//
+// GET lists the user's webhooks. POST { url, events?, description?,
+// active? } creates one, active unless told otherwise; the response is the
+// only time its signing secret is shown.
+func (h *Handler) Root(w http.ResponseWriter, r *http.Request) {
+	repo := h.repoForRequest(r)
+	switch r.Method {
+	case http.MethodGet:
+		hooks, err := repo.List()
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for i := range hooks {
+			hooks[i] = withoutSecret(hooks[i])
+		}
+		writeJSON(w, http.StatusOK, hooks)
+
+	case http.MethodPost:
+		var in webhookInput
+		if err := decodeJSON(r, &in); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad json")
+			return
+		}
+		if in.URL == nil {
+			writeErr(w, http.StatusBadRequest, ErrBadURL.Error())
+			return
+		}
+		hook := model.Webhook{Active: true}
+		if err := in.apply(r.Context(), h.guard, &hook); err != nil {
+			writeErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		created, err := repo.Create(hook)
+		if errors.Is(err, ErrTooMany) {
+			writeErr(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// /api/webhooks/{id}
+// /api/webhooks/{id}/deliveries
+// /api/webhooks/{id}/test
+func (h *Handler) Sub(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/webhooks/"), "/")
+	parts := strings.Split(rest, "/")
+	if rest == "" || len(parts) > 2 {
+		writeErr(w, http.StatusNotFound, "not found")
+		return
+	}
+	repo := h.repoForRequest(r)
+	hook, err := repo.Get(model.WebhookID(parts[0]))
+	if errors.Is(err, ErrNotFound) {
+		writeErr(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if len(parts) == 2 {
+		switch parts[1] {
+		case "deliveries":
+			if r.Method != http.MethodGet {
+				writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			log, err := repo.Deliveries(hook.ID)
+			if err != nil {
+				writeErr(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			writeJSON(w, http.StatusOK, log)
+		case "test":
+			if r.Method != http.MethodPost {
+				writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			dl, err := h.dispatcher.Fire(repo, hook)
+			if err != nil {
+				writeErr(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			writeJSON(w, http.StatusOK, dl)
+		default:
+			writeErr(w, http.StatusNotFound, "not found")
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, withoutSecret(hook))
+
+	case http.MethodPatch:
+		var in webhookInput
+		if err := decodeJSON(r, &in); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad json")
+			return
+		}
+		if err := in.apply(r.Context(), h.guard, &hook); err != nil {
+			writeErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		saved, err := repo.Save(hook)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, withoutSecret(saved))
+
+	case http.MethodDelete:
+		if err := repo.Delete(hook.ID); err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
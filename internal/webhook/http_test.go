@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"donegeon/internal/model"
+)
+
+type received struct {
+	event     string
+	signature string
+	body      []byte
+}
+
+// receiver is a local webhook endpoint that answers with codes in turn
+// (200 once they run out).
+func receiver(t *testing.T, codes ...int) (*httptest.Server, func() []received) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []received
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, received{r.Header.Get(EventHeader), r.Header.Get(SignatureHeader), body})
+		code := http.StatusOK
+		if len(got) <= len(codes) {
+			code = codes[len(got)-1]
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]received(nil), got...)
+	}
+}
+
+func newTestHandler(t *testing.T) (*Handler, *Dispatcher, *FileRepo) {
+	t.Helper()
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	repo = repo.ForUser("u-hooks")
+	d := NewDispatcher(repo)
+	d.Backoff = time.Millisecond
+	d.MaxBackoff = 5 * time.Millisecond
+	// Test receivers listen on loopback, which the guard refuses by default.
+	guard := Guard{Allow: []string{"127.0.0.1"}}
+	d.SetGuard(guard)
+	h := NewHandler(repo, d)
+	h.SetGuard(guard)
+	return h, d, repo
+}
+
+func createHook(t *testing.T, h *Handler, body map[string]any) model.Webhook {
+	t.Helper()
+	buf, _ := json.Marshal(body)
+	rec := httptest.NewRecorder()
+	h.Root(rec, httptest.NewRequest(http.MethodPost, "/api/webhooks", bytes.NewReader(buf)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var hook model.Webhook
+	_ = json.NewDecoder(rec.Body).Decode(&hook)
+	return hook
+}
+
+func TestPublish_SignsFilteredEventsAndRetries(t *testing.T) {
+	h, d, repo := newTestHandler(t)
+	srv, got := receiver(t, http.StatusServiceUnavailable, http.StatusInternalServerError)
+
+	hook := createHook(t, h, map[string]any{"url": srv.URL, "events": []string{"task.completed"}})
+	if hook.Secret == "" || !hook.Active {
+		t.Fatalf("expected an active webhook with a secret, got %+v", hook)
+	}
+	rec := httptest.NewRecorder()
+	h.Root(rec, httptest.NewRequest(http.MethodGet, "/api/webhooks", nil))
+	var listed []model.Webhook
+	_ = json.NewDecoder(rec.Body).Decode(&listed)
+	if len(listed) != 1 || listed[0].Secret != "" {
+		t.Fatalf("expected the secret hidden when listing, got %+v", listed)
+	}
+
+	pub := d.ForUser("u-hooks")
+	pub.Publish(EventTaskCreated, map[string]any{"id": "task_1"})
+	pub.Publish(EventTaskCompleted, map[string]any{"id": "task_1"})
+	d.Wait()
+
+	calls := got()
+	if len(calls) != 3 {
+		t.Fatalf("expected one event delivered in 3 attempts, got %d calls", len(calls))
+	}
+	last := calls[2]
+	if last.event != EventTaskCompleted || !Verify(hook.Secret, last.body, last.signature) {
+		t.Fatalf("expected a signed task.completed delivery, got %q %q", last.event, last.signature)
+	}
+	var env Envelope
+	if err := json.Unmarshal(last.body, &env); err != nil || env.Event != EventTaskCompleted || env.ID == "" {
+		t.Fatalf("unexpected envelope %s: %v", last.body, err)
+	}
+
+	log, _ := repo.Deliveries(hook.ID)
+	if len(log) != 1 || log[0].Status != model.DeliverySucceeded || log[0].Attempts != 3 || log[0].ResponseCode != 200 {
+		t.Fatalf("unexpected delivery log: %+v", log)
+	}
+}
+
+func TestPublish_ClientErrorIsNotRetried(t *testing.T) {
+	h, d, repo := newTestHandler(t)
+	srv, got := receiver(t, http.StatusGone)
+	hook := createHook(t, h, map[string]any{"url": srv.URL})
+
+	d.ForUser("u-hooks").Publish(EventDayEnded, map[string]any{"tickDate": "2026-10-19"})
+	d.Wait()
+
+	log, _ := repo.Deliveries(hook.ID)
+	if len(got()) != 1 || len(log) != 1 || log[0].Status != model.DeliveryFailed || log[0].ResponseCode != http.StatusGone {
+		t.Fatalf("expected one failed attempt, got %d calls, log %+v", len(got()), log)
+	}
+}
+
+func TestSub_TestFireAndValidation(t *testing.T) {
+	h, d, _ := newTestHandler(t)
+	srv, got := receiver(t)
+	hook := createHook(t, h, map[string]any{"url": srv.URL, "events": []string{"deck.opened"}})
+
+	rec := httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodPost, "/api/webhooks/"+string(hook.ID)+"/test", nil))
+	d.Wait()
+	var dl model.WebhookDelivery
+	_ = json.NewDecoder(rec.Body).Decode(&dl)
+	if rec.Code != http.StatusOK || dl.Status != model.DeliverySucceeded || dl.Event != EventWebhookTest {
+		t.Fatalf("expected a successful test delivery, got %d %+v", rec.Code, dl)
+	}
+	if calls := got(); len(calls) != 1 || calls[0].event != EventWebhookTest {
+		t.Fatalf("expected the test event delivered, got %+v", calls)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodGet, "/api/webhooks/"+string(hook.ID)+"/deliveries", nil))
+	var log []model.WebhookDelivery
+	_ = json.NewDecoder(rec.Body).Decode(&log)
+	if len(log) != 1 {
+		t.Fatalf("expected the test delivery logged, got %+v", log)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Sub(rec, httptest.NewRequest(http.MethodPatch, "/api/webhooks/"+string(hook.ID), bytes.NewReader([]byte(`{"active":false}`))))
+	d.ForUser("u-hooks").Publish(EventDeckOpened, map[string]any{"id": "deck.first_day"})
+	d.Wait()
+	if rec.Code != http.StatusOK || len(got()) != 1 {
+		t.Fatalf("expected paused webhook to receive nothing, got %d and %d calls", rec.Code, len(got()))
+	}
+
+	for _, body := range []string{`{"url":"ftp://example.com"}`, `{"url":"https://example.com","events":["task.exploded"]}`} {
+		rec = httptest.NewRecorder()
+		h.Root(rec, httptest.NewRequest(http.MethodPost, "/api/webhooks", bytes.NewReader([]byte(body))))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestGuard_RefusesInternalURLsOnSaveAndAtDialTime(t *testing.T) {
+	repo, err := NewFileRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	repo = repo.ForUser("u-hooks")
+	d := NewDispatcher(repo)
+	h := NewHandler(repo, d)
+
+	for _, u := range []string{"http://127.0.0.1:8080/", "http://localhost/", "http://10.1.2.3/", "http://169.254.169.254/latest/meta-data", "http://[::1]/", "http://[::ffff:192.168.0.1]/"} {
+		buf, _ := json.Marshal(map[string]any{"url": u})
+		rec := httptest.NewRecorder()
+		h.Root(rec, httptest.NewRequest(http.MethodPost, "/api/webhooks", bytes.NewReader(buf)))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", u, rec.Code)
+		}
+	}
+
+	// A URL saved while it pointed elsewhere is still refused when the
+	// delivery dials.
+	srv, got := receiver(t)
+	hook, err := repo.Create(model.Webhook{URL: srv.URL, Active: true})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	dl, err := d.Fire(repo, hook)
+	if err != nil {
+		t.Fatalf("fire: %v", err)
+	}
+	if dl.Status != model.DeliveryFailed || len(got()) != 0 {
+		t.Fatalf("expected a failed, unretried delivery and no request, got %+v and %d calls", dl, len(got()))
+	}
+}
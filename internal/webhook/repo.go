@@ -0,0 +1,27 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+)
+
+var (
+	ErrNotFound = errors.New("webhook not found")
+	ErrBadURL   = errors.New("url must be an absolute http or https URL")
+	ErrBadEvent = errors.New("unknown event")
+	ErrTooMany  = errors.New("too many webhooks")
+)
+
+func newID(prefix string) string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return prefix + "_" + hex.EncodeToString(b[:])
+}
+
+// newSecret is a random signing secret, shown to the user once.
+func newSecret() string {
+	var b [24]byte
+	_, _ = rand.Read(b[:])
+	return "whsec_" + hex.EncodeToString(b[:])
+}